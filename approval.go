@@ -0,0 +1,191 @@
+package dynconf
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/hashicorp/consul/api"
+)
+
+// AddApprovedWatch adds a watch on the given key under dual control, and then
+// returns the watch. New data are written to the staged key, i.e. the key with the
+// suffix StagedKeySuffix, and only applied once the approval key, i.e. the key with
+// the suffix ApprovalKeySuffix, has been written, by a second person or tool, with
+// the approval hash of the staged data returned by ApprovalHash. While the staged
+// data are pending approval, the watch keeps the data last approved, or if there
+// are none since the watch was added, e.g. after a restart, takes the data of the
+// key itself, to which tools should promote the data approved, as long as they
+// match the approval key, so that direct writes to the key can't bypass the dual
+// control.
+func (w *Watcher) AddApprovedWatch(ctx context.Context, key string, valueFactory ValueFactory,
+	options ...WatchOption) (*Watch, error) {
+	backend := approvalBackend{
+		Backend: w.backend,
+		Logger:  w.logger,
+	}
+
+	return w.doAddWatch(ctx, &backend, key, valueFactory, options)
+}
+
+// ApprovalHash returns the approval hash of the given staged data, i.e. the SHA-256
+// digest in hex, which should be written to the approval key to approve the data.
+func ApprovalHash(stagedData []byte) string {
+	return hashData(stagedData)
+}
+
+const (
+	// StagedKeySuffix is the suffix of the keys to which new data of the keys
+	// watched by AddApprovedWatch are written.
+	StagedKeySuffix = ".staged"
+
+	// ApprovalKeySuffix is the suffix of the keys to which the approval hashes of
+	// the staged data of the keys watched by AddApprovedWatch are written.
+	ApprovalKeySuffix = ".approve"
+)
+
+type approvalBackend struct {
+	Backend Backend
+	Logger  Logger
+
+	mu           sync.Mutex
+	entry        *approvalEntry
+	approvedData []byte
+	lastIndex    uint64
+}
+
+var _ Backend = (*approvalBackend)(nil)
+
+func (ab *approvalBackend) Get(key string, queryOptions *api.QueryOptions) (*api.KVPair, *api.QueryMeta, error) {
+	if queryOptions == nil {
+		queryOptions = new(api.QueryOptions)
+	}
+
+	ab.mu.Lock()
+	entry := ab.entry
+	ab.mu.Unlock()
+
+	if entry != nil && entry.Key == key && queryOptions.WaitIndex != 0 && queryOptions.WaitIndex == entry.Index {
+		changed, err := ab.waitForChange(key, entry.KeyIndexes, queryOptions)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if !changed {
+			return entry.Result()
+		}
+	}
+
+	return ab.refresh(key, queryOptions)
+}
+
+func (ab *approvalBackend) List(prefix string, queryOptions *api.QueryOptions) (api.KVPairs, *api.QueryMeta, error) {
+	return nil, nil, fmt.Errorf("%w; prefix=%q", ErrListNotSupported, prefix)
+}
+
+func (ab *approvalBackend) waitForChange(key string, keyIndexes []uint64, queryOptions *api.QueryOptions) (bool, error) {
+	keys := approvalKeys(key)
+	queries := make([]query, len(keys))
+
+	for i := range keys {
+		key := keys[i]
+		queries[i] = func(queryOptions *api.QueryOptions) (*api.QueryMeta, error) {
+			_, queryMeta, err := ab.Backend.Get(key, queryOptions)
+			return queryMeta, err
+		}
+	}
+
+	return waitForAnyChange(queries, keyIndexes, queryOptions)
+}
+
+func (ab *approvalBackend) refresh(key string, queryOptions *api.QueryOptions) (*api.KVPair, *api.QueryMeta, error) {
+	keyQueryOptions := queryOptions.WithContext(queryOptions.Context())
+	keyQueryOptions.WaitIndex = 0
+	keys := approvalKeys(key)
+	kvPairs := make([]*api.KVPair, len(keys))
+	keyIndexes := make([]uint64, len(keys))
+
+	for i, key := range keys {
+		kvPair, queryMeta, err := ab.Backend.Get(key, keyQueryOptions)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		kvPairs[i] = kvPair
+		keyIndexes[i] = queryMeta.LastIndex
+	}
+
+	ownKVPair, stagedKVPair, approvalKVPair := kvPairs[0], kvPairs[1], kvPairs[2]
+	ab.mu.Lock()
+	defer ab.mu.Unlock()
+	entry := ab.entry
+
+	if entry != nil && entry.Key == key && uint64sEqual(entry.KeyIndexes, keyIndexes) {
+		return entry.Result()
+	}
+
+	newEntry := approvalEntry{
+		Key:        key,
+		KeyIndexes: keyIndexes,
+	}
+
+	if stagedKVPair != nil && approvalKVPair != nil &&
+		string(bytes.TrimSpace(approvalKVPair.Value)) == ApprovalHash(stagedKVPair.Value) {
+		ab.approvedData = stagedKVPair.Value
+	} else if stagedKVPair != nil && (entry == nil || !bytes.Equal(entry.StagedData, stagedKVPair.Value)) {
+		ab.Logger.Log(LogWarn, "dynconf_approval_pending", "key", key, "approval_hash", ApprovalHash(stagedKVPair.Value))
+	}
+
+	if stagedKVPair != nil {
+		newEntry.StagedData = stagedKVPair.Value
+	}
+
+	if ab.approvedData == nil && ownKVPair != nil {
+		if approvalKVPair != nil && string(bytes.TrimSpace(approvalKVPair.Value)) == ApprovalHash(ownKVPair.Value) {
+			ab.approvedData = ownKVPair.Value
+		} else {
+			ab.Logger.Log(LogWarn, "dynconf_unapproved_data_ignored", "key", key)
+		}
+	}
+
+	if ab.approvedData != nil {
+		newEntry.Exists = true
+		newEntry.Data = ab.approvedData
+	}
+
+	if entry != nil && entry.Key == key && entry.Exists == newEntry.Exists && bytes.Equal(entry.Data, newEntry.Data) {
+		newEntry.Index = entry.Index
+	} else {
+		ab.lastIndex++
+		newEntry.Index = ab.lastIndex
+	}
+
+	ab.entry = &newEntry
+	return newEntry.Result()
+}
+
+func approvalKeys(key string) []string {
+	return []string{key, key + StagedKeySuffix, key + ApprovalKeySuffix}
+}
+
+type approvalEntry struct {
+	Key        string
+	KeyIndexes []uint64
+	StagedData []byte
+	Index      uint64
+	Exists     bool
+	Data       []byte
+}
+
+func (ae *approvalEntry) Result() (*api.KVPair, *api.QueryMeta, error) {
+	queryMeta := api.QueryMeta{LastIndex: ae.Index}
+
+	if !ae.Exists {
+		return nil, &queryMeta, nil
+	}
+
+	return &api.KVPair{Key: ae.Key, Value: ae.Data, ModifyIndex: ae.Index}, &queryMeta, nil
+}
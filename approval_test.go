@@ -0,0 +1,88 @@
+package dynconf_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/roy2220/dynconf"
+	"github.com/roy2220/dynconf/dynconftest"
+)
+
+func TestWatcherAddApprovedWatch(t *testing.T) {
+	clock := dynconftest.NewClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	b := &countingBackend{Backend: new(dynconftest.Backend).InitWithClock(clock)}
+	wr := dynconf.NewWatcherWithBackend(b, dynconf.WithLogger(dynconftest.Logger{T: t}), dynconf.WithClock(clock))
+	b.Set("limit", []byte("1"))
+	b.Set("limit.approve", []byte(dynconf.ApprovalHash([]byte("1"))))
+
+	w, err := wr.AddApprovedWatch(context.Background(), "limit", dynconf.NewIntValue)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer w.Remove()
+	assert.Equal(t, int64(1), w.Value().(*dynconf.IntValue).Get())
+
+	// set sets the data of the given key, and then waits until the watch has re-read
+	// the three keys and gone back to the blocking queries on them
+	set := func(key string, data []byte) {
+		numberOfGets := atomic.LoadInt32(&b.NumberOfGets)
+		b.Set(key, data)
+		assert.Eventually(t, func() bool {
+			return atomic.LoadInt32(&b.NumberOfGets) >= numberOfGets+6 && clock.NumberOfTimers() == 3
+		}, time.Second, time.Millisecond)
+	}
+	clock.BlockUntil(3)
+
+	events := w.Events()
+	set("limit.staged", []byte("2"))
+	assert.Equal(t, int64(1), w.Value().(*dynconf.IntValue).Get())
+
+	set("limit.approve", []byte("bad"))
+	assert.Equal(t, int64(1), w.Value().(*dynconf.IntValue).Get())
+
+	b.Set("limit.approve", []byte(dynconf.ApprovalHash([]byte("2"))+"\n"))
+	<-events
+	assert.Equal(t, int64(2), w.Value().(*dynconf.IntValue).Get())
+
+	set("limit.staged", []byte("3"))
+	assert.Equal(t, int64(2), w.Value().(*dynconf.IntValue).Get())
+
+	b.Set("limit.approve", []byte(dynconf.ApprovalHash([]byte("3"))))
+	<-events
+	assert.Equal(t, int64(3), w.Value().(*dynconf.IntValue).Get())
+
+	set("limit", []byte("9"))
+	assert.Equal(t, int64(3), w.Value().(*dynconf.IntValue).Get())
+}
+
+func TestWatcherAddApprovedWatchAfterRestart(t *testing.T) {
+	wr, b := dynconftest.NewWatcher(t)
+	defer wr.Close(context.Background())
+	b.Set("limit", []byte("9"))
+	b.Set("limit.approve", []byte(dynconf.ApprovalHash([]byte("3"))))
+
+	// the data of the key itself don't match the approval key
+	_, err := wr.AddApprovedWatch(context.Background(), "limit", dynconf.NewIntValue)
+	assert.True(t, errors.Is(err, dynconf.ErrKeyNotFound))
+
+	// the data approved have been promoted to the key
+	b.Set("limit", []byte("3"))
+	w, err := wr.AddApprovedWatch(context.Background(), "limit", dynconf.NewIntValue)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, int64(3), w.Value().(*dynconf.IntValue).Get())
+
+	events := w.Events()
+	b.Set("limit", []byte("9"))
+	b.Set("limit.staged", []byte("4"))
+	b.Set("limit.approve", []byte(dynconf.ApprovalHash([]byte("4"))))
+	for w.Value().(*dynconf.IntValue).Get() != 4 {
+		<-events
+	}
+}
@@ -0,0 +1,213 @@
+package dynconf
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+)
+
+// WithAuditSink returns an option to record every value applied by the watch, i.e.
+// the value populated when the watch is added and every change of the value since
+// then, to the given sink, so that which data each instance was running at any time
+// can be told. The sink is called synchronously by the watch and should return
+// quickly, failures of the sink are logged but otherwise ignored. It takes effect
+// only for watches on keys.
+func WithAuditSink(sink AuditSink) WatchOption {
+	return func(wo *watchOptions) {
+		wo.AuditSink = sink
+	}
+}
+
+// AuditRecord represents a record of a value applied by a watch.
+type AuditRecord struct {
+	// Key is the key of the watch.
+	Key string `json:"key"`
+
+	// OldHash is the SHA-256 hash, in hex, of the data of the old value, which is
+	// empty for the value populated when the watch is added.
+	OldHash string `json:"old_hash,omitempty"`
+
+	// NewHash is the SHA-256 hash, in hex, of the data of the new value.
+	NewHash string `json:"new_hash"`
+
+	// Index is the modify index of the data of the new value, which is zero for the
+	// default value.
+	Index uint64 `json:"index"`
+
+	// Time is the time when the new value was applied.
+	Time time.Time `json:"time"`
+
+	// Hostname is the hostname of the instance.
+	Hostname string `json:"hostname"`
+}
+
+// AuditSink represents a sink of audit records.
+type AuditSink interface {
+	// Record records the given audit record.
+	Record(ctx context.Context, record *AuditRecord) error
+}
+
+// LogAuditSink presents an audit sink logging records with the event
+// "dynconf_value_audited".
+type LogAuditSink struct {
+	Logger Logger
+}
+
+var _ AuditSink = LogAuditSink{}
+
+// Record implements AuditSink.Record.
+func (las LogAuditSink) Record(_ context.Context, record *AuditRecord) error {
+	las.Logger.Log(LogInfo, "dynconf_value_audited", "key", record.Key, "old_hash", record.OldHash,
+		"new_hash", record.NewHash, "index", record.Index, "time", record.Time.Format(time.RFC3339Nano),
+		"hostname", record.Hostname)
+	return nil
+}
+
+// FileAuditSink presents an audit sink appending records in JSON, one per line, to
+// the file of the given path.
+type FileAuditSink struct {
+	Path string
+
+	mu sync.Mutex
+}
+
+var _ AuditSink = (*FileAuditSink)(nil)
+
+// Record implements AuditSink.Record.
+func (fas *FileAuditSink) Record(_ context.Context, record *AuditRecord) error {
+	line, err := json.Marshal(record)
+
+	if err != nil {
+		return err
+	}
+
+	fas.mu.Lock()
+	defer fas.mu.Unlock()
+	file, err := os.OpenFile(fas.Path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+
+	if err != nil {
+		return err
+	}
+
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
+
+// ConsulAuditSink presents an audit sink writing records in JSON to the KV store of
+// Consul, under the key "<prefix><hostname>/<key>/<time>", where the time is in
+// nanoseconds since the epoch, padded to 20 digits, so that the records of each
+// instance and key are ordered by time.
+type ConsulAuditSink struct {
+	KV     *api.KV
+	Prefix string
+}
+
+var _ AuditSink = ConsulAuditSink{}
+
+// Record implements AuditSink.Record.
+func (cas ConsulAuditSink) Record(ctx context.Context, record *AuditRecord) error {
+	data, err := json.Marshal(record)
+
+	if err != nil {
+		return err
+	}
+
+	key := fmt.Sprintf("%s%s/%s/%020d", cas.Prefix, record.Hostname, record.Key, record.Time.UnixNano())
+
+	if _, err := cas.KV.Put(&api.KVPair{Key: key, Value: data}, (&api.WriteOptions{}).WithContext(ctx)); err != nil {
+		return fmt.Errorf("dynconf: kv put failed; key=%q: %w", key, err)
+	}
+
+	return nil
+}
+
+// HTTPAuditSink presents an audit sink posting records in JSON to the given URL
+// with the given client, which defaults to one timing out after 5 seconds.
+type HTTPAuditSink struct {
+	URL    string
+	Client *http.Client
+}
+
+var _ AuditSink = HTTPAuditSink{}
+
+// Record implements AuditSink.Record.
+func (has HTTPAuditSink) Record(ctx context.Context, record *AuditRecord) error {
+	data, err := json.Marshal(record)
+
+	if err != nil {
+		return err
+	}
+
+	request, err := http.NewRequest(http.MethodPost, has.URL, bytes.NewReader(data))
+
+	if err != nil {
+		return err
+	}
+
+	request = request.WithContext(ctx)
+	request.Header.Set("Content-Type", "application/json")
+	client := has.Client
+
+	if client == nil {
+		client = defaultAuditHTTPClient
+	}
+
+	response, err := client.Do(request)
+
+	if err != nil {
+		return err
+	}
+
+	response.Body.Close()
+
+	if response.StatusCode/100 != 2 {
+		return fmt.Errorf("dynconf: audit post failed; url=%q status_code=%d", has.URL, response.StatusCode)
+	}
+
+	return nil
+}
+
+var defaultAuditHTTPClient = &http.Client{Timeout: 5 * time.Second}
+
+func (w *Watch) audit() {
+	sink := w.options.AuditSink
+
+	if sink == nil {
+		return
+	}
+
+	raw, _ := w.raw.Load().(rawData)
+	record := AuditRecord{
+		Key:      w.key,
+		OldHash:  w.auditedHash,
+		NewHash:  hashData(raw.Data),
+		Index:    raw.ModifyIndex,
+		Time:     w.options.Clock.Now(),
+		Hostname: auditHostname,
+	}
+	w.auditedHash = record.NewHash
+
+	if err := w.protect("audit", func() error { return sink.Record(w.ctx, &record) }); err != nil {
+		w.logger.Log(LogWarn, "dynconf_audit_failed", "key", w.key, "error", err)
+	}
+}
+
+func hashData(data []byte) string {
+	hash := sha256.Sum256(data)
+	return hex.EncodeToString(hash[:])
+}
+
+var auditHostname, _ = os.Hostname()
@@ -0,0 +1,71 @@
+package dynconf_test
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/roy2220/dynconf"
+	"github.com/roy2220/dynconf/dynconftest"
+)
+
+func TestWithAuditSink(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dynconf")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer os.RemoveAll(dir)
+	sink := &dynconf.FileAuditSink{Path: filepath.Join(dir, "audit.log")}
+
+	wr, b := dynconftest.NewWatcher(t)
+	b.Set("hello", []byte("1"))
+	w, err := wr.AddWatch(context.Background(), "hello", dynconf.NewIntValue,
+		dynconf.WithAuditSink(sink), dynconf.WithDefaultValue([]byte("0")))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer w.Remove()
+
+	events := w.Events()
+	b.Set("hello", []byte("2"))
+	<-events
+	b.Delete("hello")
+	<-events
+	<-events
+
+	file, err := os.Open(sink.Path)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer file.Close()
+	var records []dynconf.AuditRecord
+	for scanner := bufio.NewScanner(file); scanner.Scan(); {
+		var record dynconf.AuditRecord
+		assert.NoError(t, json.Unmarshal(scanner.Bytes(), &record))
+		records = append(records, record)
+	}
+
+	if !assert.Len(t, records, 3) {
+		t.FailNow()
+	}
+	hostname, _ := os.Hostname()
+	hash1 := "6b86b273ff34fce19d6b804eff5a3f5747ada4eaa22f1d49c01e52ddb7875b4b"
+	hash2 := "d4735e3a265e16eee03f59718b9b5d03019c07d8b6c51f90da3a666eec13ab35"
+	hash0 := "5feceb66ffc86f38d952786c6d696c79c2dbc239dd4e91b46729d73a27fb57e9"
+	assert.Equal(t, "hello", records[0].Key)
+	assert.Equal(t, hostname, records[0].Hostname)
+	assert.Equal(t, "", records[0].OldHash)
+	assert.Equal(t, hash1, records[0].NewHash)
+	assert.Equal(t, hash1, records[1].OldHash)
+	assert.Equal(t, hash2, records[1].NewHash)
+	assert.NotZero(t, records[1].Index)
+	assert.Equal(t, hash2, records[2].OldHash)
+	assert.Equal(t, hash0, records[2].NewHash)
+	assert.Zero(t, records[2].Index)
+}
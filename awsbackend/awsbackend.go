@@ -0,0 +1,132 @@
+// Package awsbackend implements fetchers reading configurations from AWS AppConfig
+// and AWS Systems Manager Parameter Store, to be used with dynconf.PollingBackend.
+package awsbackend
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/roy2220/dynconf"
+)
+
+// AppConfigFetcher presents a fetcher reading configurations from the AWS AppConfig
+// Agent, which polls AppConfig on behalf of the application, respecting the poll
+// interval and the deployment strategy of AppConfig, and serves the configurations
+// deployed locally. A key is in the form of "<application>/<environment>/<profile>",
+// and the version of the data is the version of the configuration.
+type AppConfigFetcher struct {
+	httpClient *http.Client
+	address    string
+}
+
+var _ dynconf.Fetcher = (*AppConfigFetcher)(nil)
+
+// Init initializes the fetcher with the given HTTP client, which defaults to
+// http.DefaultClient, and the given address of the agent, which defaults to
+// "http://localhost:2772", and then returns the fetcher.
+func (acf *AppConfigFetcher) Init(httpClient *http.Client, address string) *AppConfigFetcher {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	if address == "" {
+		address = "http://localhost:2772"
+	}
+
+	acf.httpClient = httpClient
+	acf.address = strings.TrimSuffix(address, "/")
+	return acf
+}
+
+// Fetch implements dynconf.Fetcher.Fetch.
+func (acf *AppConfigFetcher) Fetch(ctx context.Context, key string) ([]byte, string, bool, error) {
+	parts := strings.Split(key, "/")
+
+	if len(parts) != 3 {
+		return nil, "", false, fmt.Errorf("awsbackend: invalid key; key=%q", key)
+	}
+
+	path := fmt.Sprintf("/applications/%s/environments/%s/configurations/%s",
+		url.PathEscape(parts[0]), url.PathEscape(parts[1]), url.PathEscape(parts[2]))
+	request, err := http.NewRequest(http.MethodGet, acf.address+path, nil)
+
+	if err != nil {
+		return nil, "", false, fmt.Errorf("awsbackend: request creation failed; key=%q: %w", key, err)
+	}
+
+	response, err := acf.httpClient.Do(request.WithContext(ctx))
+
+	if err != nil {
+		return nil, "", false, fmt.Errorf("awsbackend: request failed; key=%q: %w", key, err)
+	}
+
+	defer response.Body.Close()
+	data, err := ioutil.ReadAll(response.Body)
+
+	if err != nil {
+		return nil, "", false, fmt.Errorf("awsbackend: response read failed; key=%q: %w", key, err)
+	}
+
+	if response.StatusCode == http.StatusNotFound {
+		return nil, "", false, nil
+	}
+
+	if response.StatusCode != http.StatusOK {
+		return nil, "", false, fmt.Errorf("awsbackend: unexpected response; key=%q status_code=%d body=%q",
+			key, response.StatusCode, data)
+	}
+
+	version := response.Header.Get("Configuration-Version")
+
+	if version == "" {
+		version = string(data)
+	}
+
+	return data, version, true, nil
+}
+
+// GetParameterFunc is the type of the function getting the value and the version
+// of the parameter with the given name from Parameter Store, e.g. with the
+// GetParameter API of the AWS SDK, it should return ErrParameterNotFound if the
+// parameter is missing.
+type GetParameterFunc func(ctx context.Context, name string) (value string, version int64, err error)
+
+// ErrParameterNotFound is returned by GetParameterFunc if the parameter is missing.
+var ErrParameterNotFound = errors.New("awsbackend: parameter not found")
+
+// SSMFetcher presents a fetcher reading parameters from Parameter Store. A key is
+// the name of a parameter, and the version of the data is the version of the
+// parameter.
+type SSMFetcher struct {
+	getParameter GetParameterFunc
+}
+
+var _ dynconf.Fetcher = (*SSMFetcher)(nil)
+
+// Init initializes the fetcher with the given function getting parameters and
+// then returns the fetcher.
+func (sf *SSMFetcher) Init(getParameter GetParameterFunc) *SSMFetcher {
+	sf.getParameter = getParameter
+	return sf
+}
+
+// Fetch implements dynconf.Fetcher.Fetch.
+func (sf *SSMFetcher) Fetch(ctx context.Context, key string) ([]byte, string, bool, error) {
+	value, version, err := sf.getParameter(ctx, key)
+
+	if err != nil {
+		if errors.Is(err, ErrParameterNotFound) {
+			return nil, "", false, nil
+		}
+
+		return nil, "", false, fmt.Errorf("awsbackend: parameter get failed; key=%q: %w", key, err)
+	}
+
+	return []byte(value), strconv.FormatInt(version, 10), true, nil
+}
@@ -0,0 +1,66 @@
+package awsbackend_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/roy2220/dynconf/awsbackend"
+)
+
+func TestAppConfigFetcherFetch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/applications/app/environments/prod/configurations/main" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Configuration-Version", "7")
+		w.Write([]byte(`{"foo": 1}`))
+	}))
+	defer server.Close()
+
+	f := new(awsbackend.AppConfigFetcher).Init(nil, server.URL)
+	data, version, ok, err := f.Fetch(context.Background(), "app/prod/main")
+	if assert.NoError(t, err) && assert.True(t, ok) {
+		assert.Equal(t, `{"foo": 1}`, string(data))
+		assert.Equal(t, "7", version)
+	}
+
+	_, _, ok, err = f.Fetch(context.Background(), "app/prod/other")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	_, _, _, err = f.Fetch(context.Background(), "app/prod")
+	assert.Error(t, err)
+}
+
+func TestSSMFetcherFetch(t *testing.T) {
+	f := new(awsbackend.SSMFetcher).Init(func(_ context.Context, name string) (string, int64, error) {
+		switch name {
+		case "/app/config":
+			return `{"foo": 1}`, 2, nil
+		case "/app/broken":
+			return "", 0, errors.New("throttled")
+		default:
+			return "", 0, awsbackend.ErrParameterNotFound
+		}
+	})
+
+	data, version, ok, err := f.Fetch(context.Background(), "/app/config")
+	if assert.NoError(t, err) && assert.True(t, ok) {
+		assert.Equal(t, `{"foo": 1}`, string(data))
+		assert.Equal(t, "2", version)
+	}
+
+	_, _, ok, err = f.Fetch(context.Background(), "/app/missing")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	_, _, _, err = f.Fetch(context.Background(), "/app/broken")
+	assert.EqualError(t, err, "awsbackend: parameter get failed; key=\"/app/broken\": throttled")
+}
@@ -0,0 +1,26 @@
+package dynconf
+
+import (
+	"errors"
+
+	"github.com/hashicorp/consul/api"
+)
+
+// Backend represents a key/value store in which keys are watched. The KV store of
+// Consul, i.e. *api.KV, is the default backend, and other backends should behave
+// in the same way: the data of keys are versioned with indexes, which must be
+// non-zero, and if the wait index of the query options is non-zero, the query
+// blocks until the index differs from the wait index, the wait time elapses, or
+// the context of the query options is done.
+type Backend interface {
+	// Get returns the data of the given key, or nil if the key is missing.
+	Get(key string, queryOptions *api.QueryOptions) (kvPair *api.KVPair, queryMeta *api.QueryMeta, err error)
+
+	// List returns the data of the keys with the given prefix.
+	List(prefix string, queryOptions *api.QueryOptions) (kvPairs api.KVPairs, queryMeta *api.QueryMeta, err error)
+}
+
+var _ Backend = (*api.KV)(nil)
+
+// ErrListNotSupported is returned by backends which do not support listing keys.
+var ErrListNotSupported = errors.New("dynconf: list not supported")
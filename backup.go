@@ -0,0 +1,273 @@
+package dynconf
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// BackupPolicy represents a policy for backing up keys.
+type BackupPolicy struct {
+	// Interval is the interval between backups, which defaults to an hour.
+	Interval time.Duration
+
+	// Prefix is the prefix of the keys to back up, which are read from the backend
+	// of the watcher. If empty, the keys watched by the watcher are backed up.
+	Prefix string
+
+	// MaxBackups is the maximum number of backups kept in the store, older backups
+	// beyond which are deleted. Zero means unlimited.
+	MaxBackups int
+}
+
+// BackupStore represents a store of backups, e.g. a local directory or a bucket
+// of an object storage like S3, which can be implemented on the client of the
+// object storage.
+type BackupStore interface {
+	// Save saves the given data of a backup under the given name.
+	Save(ctx context.Context, name string, data []byte) error
+
+	// List returns the names of the backups saved, in any order.
+	List(ctx context.Context) (names []string, err error)
+
+	// Delete deletes the backup of the given name.
+	Delete(ctx context.Context, name string) error
+}
+
+// StartBackup makes the watcher periodically back up keys to the given store with
+// the given policy, starting right away, and then returns a function to stop it.
+// Each backup is the raw data of the keys in the format of ExportJSON, which can be
+// loaded with LoadSnapshot, e.g. to restore keys deleted by accident, and named
+// after the time of the backup, so that the names sort by time. Backups identical
+// to the last one saved are skipped, so that unchanged keys don't rotate earlier
+// backups out of the store. The keys watched by redacting watches are left out,
+// as they are by Snapshot. Failures are logged and retried at the next interval.
+func (w *Watcher) StartBackup(store BackupStore, policy BackupPolicy) (stop func()) {
+	if policy.Interval <= 0 {
+		policy.Interval = time.Hour
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+		ticker := w.clock().NewTicker(policy.Interval)
+		defer ticker.Stop()
+		var lastValues []byte
+
+		for {
+			values, err := w.backUp(ctx, store, policy, lastValues)
+
+			if err != nil {
+				w.logger.Log(LogWarn, "dynconf_backup_failed", "error", err)
+			} else {
+				lastValues = values
+			}
+
+			select {
+			case <-ticker.C():
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// backUp saves a backup of the keys to the given store unless the keys are identical
+// to the given last values backed up, deletes the backups beyond the maximum number,
+// and then returns the values backed up.
+func (w *Watcher) backUp(ctx context.Context, store BackupStore, policy BackupPolicy, lastValues []byte) ([]byte, error) {
+	snapshot, err := w.backupSnapshot(ctx, policy.Prefix)
+
+	if err != nil {
+		return nil, err
+	}
+
+	values, err := json.Marshal(snapshot)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if bytes.Equal(values, lastValues) {
+		return values, nil
+	}
+
+	now := w.clock().Now()
+	data, err := json.MarshalIndent(snapshotFile{
+		Time:   now,
+		Values: snapshot,
+	}, "", "  ")
+
+	if err != nil {
+		return nil, err
+	}
+
+	name := backupNamePrefix + now.UTC().Format(backupTimeLayout) + backupNameSuffix
+
+	if err := store.Save(ctx, name, data); err != nil {
+		return nil, fmt.Errorf("dynconf: backup save failed; name=%q: %w", name, err)
+	}
+
+	w.logger.Log(LogInfo, "dynconf_backup_saved", "name", name, "number_of_keys", len(snapshot))
+
+	if policy.MaxBackups >= 1 {
+		if err := deleteOldBackups(ctx, store, policy.MaxBackups); err != nil {
+			return nil, err
+		}
+	}
+
+	return values, nil
+}
+
+func (w *Watcher) backupSnapshot(ctx context.Context, prefix string) (map[string]RawValue, error) {
+	if prefix == "" {
+		return w.Snapshot(), nil
+	}
+
+	var watchOptions watchOptions
+	watchOptions.Init(w.defaultOptions)
+	queryOptions := watchOptions.makeQueryOptions(ctx, watchOptions.QueryOptions)
+	kvPairs, _, err := w.backend.List(prefix, queryOptions)
+
+	if err != nil {
+		return nil, fmt.Errorf("dynconf: kv list failed; prefix=%q: %w", prefix, err)
+	}
+
+	snapshot := make(map[string]RawValue, len(kvPairs))
+	isRedactedKey := w.redactedKeyMatcher()
+
+	for _, kvPair := range kvPairs {
+		if isRedactedKey(kvPair.Key) {
+			continue
+		}
+
+		snapshot[kvPair.Key] = RawValue{
+			Data:        kvPair.Value,
+			ModifyIndex: kvPair.ModifyIndex,
+			Flags:       kvPair.Flags,
+		}
+	}
+
+	return snapshot, nil
+}
+
+func deleteOldBackups(ctx context.Context, store BackupStore, maxBackups int) error {
+	names, err := store.List(ctx)
+
+	if err != nil {
+		return fmt.Errorf("dynconf: backup list failed: %w", err)
+	}
+
+	var backupNames []string
+
+	for _, name := range names {
+		if strings.HasPrefix(name, backupNamePrefix) && strings.HasSuffix(name, backupNameSuffix) {
+			backupNames = append(backupNames, name)
+		}
+	}
+
+	sort.Strings(backupNames)
+
+	for len(backupNames) > maxBackups {
+		name := backupNames[0]
+
+		if err := store.Delete(ctx, name); err != nil {
+			return fmt.Errorf("dynconf: backup deletion failed; name=%q: %w", name, err)
+		}
+
+		backupNames = backupNames[1:]
+	}
+
+	return nil
+}
+
+const (
+	backupNamePrefix = "dynconf-backup-"
+	backupNameSuffix = ".json"
+	backupTimeLayout = "20060102T150405.000000000Z"
+)
+
+// DirBackupStore presents a backup store saving backups in files under the
+// directory of the given path.
+type DirBackupStore struct {
+	Dir string
+}
+
+var _ BackupStore = DirBackupStore{}
+
+// Save implements BackupStore.Save.
+func (dbs DirBackupStore) Save(_ context.Context, name string, data []byte) error {
+	if err := os.MkdirAll(dbs.Dir, 0755); err != nil {
+		return err
+	}
+
+	file, err := ioutil.TempFile(dbs.Dir, ".tmp-")
+
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(file.Name())
+
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(file.Name(), filepath.Join(dbs.Dir, name))
+}
+
+// List implements BackupStore.List.
+func (dbs DirBackupStore) List(_ context.Context) ([]string, error) {
+	fileInfos, err := ioutil.ReadDir(dbs.Dir)
+
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	var names []string
+
+	for _, fileInfo := range fileInfos {
+		if fileInfo.Mode().IsRegular() && !strings.HasPrefix(fileInfo.Name(), ".") {
+			names = append(names, fileInfo.Name())
+		}
+	}
+
+	return names, nil
+}
+
+// Delete implements BackupStore.Delete.
+func (dbs DirBackupStore) Delete(_ context.Context, name string) error {
+	if err := os.Remove(filepath.Join(dbs.Dir, name)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,134 @@
+package dynconf_test
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/roy2220/dynconf"
+	"github.com/roy2220/dynconf/dynconftest"
+)
+
+func TestWatcherStartBackup(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dynconf")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	clock := dynconftest.NewClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	wr, b := dynconftest.NewWatcherWithClock(t, clock)
+	b.Set("hello", []byte("1"))
+	w, err := wr.AddWatch(context.Background(), "hello", dynconf.NewIntValue)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer w.Remove()
+
+	store := dynconf.DirBackupStore{Dir: dir}
+	stop := wr.StartBackup(store, dynconf.BackupPolicy{Interval: time.Hour, MaxBackups: 2})
+	defer stop()
+
+	listBackups := func() []string {
+		names, err := store.List(context.Background())
+		assert.NoError(t, err)
+		sort.Strings(names)
+		return names
+	}
+	loadBackup := func(name string) map[string]dynconf.RawValue {
+		file, err := os.Open(filepath.Join(dir, name))
+		if !assert.NoError(t, err) {
+			return nil
+		}
+		defer file.Close()
+		snapshot, err := dynconf.LoadSnapshot(file)
+		assert.NoError(t, err)
+		return snapshot
+	}
+
+	assert.Eventually(t, func() bool { return len(listBackups()) == 1 }, time.Second, time.Millisecond)
+	firstName := listBackups()[0]
+	assert.Equal(t, []byte("1"), loadBackup(firstName)["hello"].Data)
+
+	// the keys are unchanged, so no backup is saved
+	clock.Advance(time.Hour)
+
+	events := w.Events()
+
+	for i := 2; i <= 3; i++ {
+		b.Set("hello", []byte{byte('0' + i)})
+		<-events
+		clock.Advance(time.Hour)
+		data := []byte{byte('0' + i)}
+		assert.Eventually(t, func() bool {
+			names := listBackups()
+			return len(names) >= 1 && bytes.Equal(loadBackup(names[len(names)-1])["hello"].Data, data)
+		}, time.Second, time.Millisecond)
+
+		if i == 2 {
+			assert.Equal(t, []string{firstName}, listBackups()[:1])
+		}
+	}
+
+	names := listBackups()
+	if assert.Len(t, names, 2) {
+		assert.Equal(t, []byte("2"), loadBackup(names[0])["hello"].Data)
+		assert.Equal(t, []byte("3"), loadBackup(names[1])["hello"].Data)
+	}
+}
+
+func TestWatcherStartBackupPrefix(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dynconf")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	wr, b := dynconftest.NewWatcher(t)
+	b.SetWithFlags("app/a", []byte("1"), 1)
+	b.Set("app/b", []byte("2"))
+	b.Set("other", []byte("3"))
+	b.Set("app/password", []byte("secret"))
+	w, err := wr.AddWatch(context.Background(), "app/password", newPasswordValue)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer w.Remove()
+
+	store := dynconf.DirBackupStore{Dir: filepath.Join(dir, "backups")}
+	stop := wr.StartBackup(store, dynconf.BackupPolicy{Prefix: "app/"})
+	defer stop()
+
+	var names []string
+	assert.Eventually(t, func() bool {
+		names, err = store.List(context.Background())
+		assert.NoError(t, err)
+		return len(names) == 1
+	}, time.Second, time.Millisecond)
+	data, err := ioutil.ReadFile(filepath.Join(dir, "backups", names[0]))
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.NotContains(t, string(data), "app/password")
+
+	b2 := new(dynconf.SnapshotBackend)
+	snapshot, err := dynconf.LoadSnapshot(bytes.NewReader(data))
+	if !assert.NoError(t, err) {
+		return
+	}
+	b2.Init(snapshot)
+	kvPairs, _, err := b2.List("", nil)
+	assert.NoError(t, err)
+	if assert.Len(t, kvPairs, 2) {
+		assert.Equal(t, "app/a", kvPairs[0].Key)
+		assert.Equal(t, uint64(1), kvPairs[0].Flags)
+		assert.Equal(t, "app/b", kvPairs[1].Key)
+	}
+}
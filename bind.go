@@ -0,0 +1,315 @@
+package dynconf
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Bind adds a watch on the given key and binds the struct, pointed to by the given
+// pointer, to the watch, and then returns the binding. The struct is updated in place
+// each time the value of the key has been updated, readers of the struct should hold
+// the read lock of the binding.
+//
+// The format of data is specified by the tag `dynconf:"format=<format>"` of the blank
+// field of the struct, and defaults to "json".
+//
+// The exported fields of the struct may have the tag `dynconf:"default=<default>"`,
+// the default of which is set to the field if the data leave the field out, and the
+// tag `dynconf:"required"`, with which the data leaving the field out or setting the
+// field to its zero value are rejected with ErrRequiredFieldMissing, e.g.
+// `dynconf:"default=5s,required"`. The defaults are in the format of the Go literals
+// of the types of the fields, or in JSON for composite types, and can't contain ",".
+func Bind(ctx context.Context, watcher *Watcher, key string, structPtr interface{}, options ...WatchOption) (*Binding, error) {
+	target := reflect.ValueOf(structPtr)
+
+	if target.Kind() != reflect.Ptr || target.IsNil() || target.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("dynconf: bind failed; key=%q type=%T: non-nil pointer to struct required", key, structPtr)
+	}
+
+	structType := target.Elem().Type()
+	format := "json"
+
+	if tag, ok := lookUpStructTag(structType); ok {
+		if value, ok := tag["format"]; ok {
+			format = value
+		}
+	}
+
+	fieldTags, err := parseFieldTags(structType)
+
+	if err != nil {
+		return nil, fmt.Errorf("dynconf: bind failed; key=%q type=%T: %w", key, structPtr, err)
+	}
+
+	unmarshal := func(strict bool) UnmarshalFunc {
+		return func(data []byte) (interface{}, error) {
+			object := reflect.New(structType)
+			fieldTags.SetDefaults(object.Elem())
+
+			if err := unmarshalWithCodec(format, data, object.Interface(), strict); err != nil {
+				return nil, err
+			}
+
+			if err := fieldTags.CheckRequired(object.Elem()); err != nil {
+				return nil, err
+			}
+
+			return object.Interface(), nil
+		}
+	}
+
+	valueFactory := func() Value {
+		return &ObjectValue{
+			unmarshal:       unmarshal(false),
+			unmarshalStrict: unmarshal(true),
+			marshal: func(object interface{}) ([]byte, error) {
+				return Marshal(format, object)
+			},
+		}
+	}
+
+	watch, err := watcher.AddWatch(ctx, key, valueFactory, options...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	binding := Binding{
+		watch:  watch,
+		target: target.Elem(),
+	}
+
+	binding.unsubscribe = watch.Subscribe(func(Value, Value) { binding.update() })
+	// The value may have been updated before subscribing.
+	binding.update()
+
+	return &binding, nil
+}
+
+// Binding presents a binding of a struct to a watch.
+type Binding struct {
+	watch       *Watch
+	target      reflect.Value
+	mu          sync.RWMutex
+	unsubscribe func()
+}
+
+// RLock locks the binding for reading the struct.
+func (b *Binding) RLock() {
+	b.watch.recordRead()
+	b.mu.RLock()
+}
+
+// RUnlock undoes a single RLock call.
+func (b *Binding) RUnlock() {
+	b.mu.RUnlock()
+}
+
+// Field returns the value of the exported field of the struct with the given name,
+// or nil if there's no such field, and the read is tracked if the watch has been
+// added with WithUsageTracking. It is safe for concurrent use.
+func (b *Binding) Field(name string) interface{} {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	field := b.target.FieldByName(name)
+
+	if !field.IsValid() || !field.CanInterface() {
+		return nil
+	}
+
+	b.watch.recordFieldRead(name)
+	return field.Interface()
+}
+
+// Watch returns the watch to which the struct is bound.
+func (b *Binding) Watch() *Watch {
+	return b.watch
+}
+
+// Unbind unbinds the struct and then removes the watch.
+func (b *Binding) Unbind() {
+	b.unsubscribe()
+	b.watch.Remove()
+}
+
+// update copies the latest value of the watch to the struct, which is read with the
+// lock held, so that a value copied earlier never overwrites a newer one.
+func (b *Binding) update() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	source := reflect.ValueOf(b.watch.latestValue().(*ObjectValue).Object()).Elem()
+	b.target.Set(source)
+}
+
+func lookUpStructTag(structType reflect.Type) (map[string]string, bool) {
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+
+		if field.Name != "_" {
+			continue
+		}
+
+		if tag, ok := field.Tag.Lookup("dynconf"); ok {
+			return parseTag(tag), true
+		}
+	}
+
+	return nil, false
+}
+
+func parseTag(tag string) map[string]string {
+	items := make(map[string]string)
+
+	for _, item := range strings.Split(tag, ",") {
+		item = strings.TrimSpace(item)
+
+		if item == "" {
+			continue
+		}
+
+		if i := strings.IndexByte(item, '='); i >= 0 {
+			items[item[:i]] = item[i+1:]
+		} else {
+			items[item] = ""
+		}
+	}
+
+	return items
+}
+
+type fieldTag struct {
+	Index      int
+	Name       string
+	Default    string
+	HasDefault bool
+	Required   bool
+}
+
+type fieldTags []fieldTag
+
+func parseFieldTags(structType reflect.Type) (fieldTags, error) {
+	var fieldTags fieldTags
+
+	for i := 0; i < structType.NumField(); i++ {
+		field := structType.Field(i)
+
+		if field.Name == "_" || field.PkgPath != "" {
+			continue
+		}
+
+		tag, ok := field.Tag.Lookup("dynconf")
+
+		if !ok {
+			continue
+		}
+
+		items := parseTag(tag)
+		fieldTag := fieldTag{Index: i, Name: field.Name}
+
+		if defaultValue, ok := items["default"]; ok {
+			if _, err := parseFieldDefault(field.Type, defaultValue); err != nil {
+				return nil, fmt.Errorf("invalid default of field %q: %w", field.Name, err)
+			}
+
+			fieldTag.Default = defaultValue
+			fieldTag.HasDefault = true
+		}
+
+		_, fieldTag.Required = items["required"]
+
+		if fieldTag.HasDefault || fieldTag.Required {
+			fieldTags = append(fieldTags, fieldTag)
+		}
+	}
+
+	return fieldTags, nil
+}
+
+func (fts fieldTags) SetDefaults(structValue reflect.Value) {
+	for _, fieldTag := range fts {
+		if fieldTag.HasDefault {
+			// parse again so that composite defaults are never shared between objects
+			value, _ := parseFieldDefault(structValue.Field(fieldTag.Index).Type(), fieldTag.Default)
+			structValue.Field(fieldTag.Index).Set(value)
+		}
+	}
+}
+
+func (fts fieldTags) CheckRequired(structValue reflect.Value) error {
+	for _, fieldTag := range fts {
+		if fieldTag.Required && structValue.Field(fieldTag.Index).IsZero() {
+			return fmt.Errorf("%w; field=%q", ErrRequiredFieldMissing, fieldTag.Name)
+		}
+	}
+
+	return nil
+}
+
+func parseFieldDefault(fieldType reflect.Type, defaultValue string) (reflect.Value, error) {
+	value := reflect.New(fieldType).Elem()
+
+	if fieldType == reflect.TypeOf(time.Duration(0)) {
+		duration, err := time.ParseDuration(defaultValue)
+
+		if err != nil {
+			return reflect.Value{}, err
+		}
+
+		value.SetInt(int64(duration))
+		return value, nil
+	}
+
+	switch fieldType.Kind() {
+	case reflect.String:
+		value.SetString(defaultValue)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(defaultValue)
+
+		if err != nil {
+			return reflect.Value{}, err
+		}
+
+		value.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(defaultValue, 0, fieldType.Bits())
+
+		if err != nil {
+			return reflect.Value{}, err
+		}
+
+		value.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		u, err := strconv.ParseUint(defaultValue, 0, fieldType.Bits())
+
+		if err != nil {
+			return reflect.Value{}, err
+		}
+
+		value.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(defaultValue, fieldType.Bits())
+
+		if err != nil {
+			return reflect.Value{}, err
+		}
+
+		value.SetFloat(f)
+	default:
+		if err := json.Unmarshal([]byte(defaultValue), value.Addr().Interface()); err != nil {
+			return reflect.Value{}, err
+		}
+	}
+
+	return value, nil
+}
+
+// ErrRequiredFieldMissing is returned when data bound to a struct leave out a field
+// required by the tag `dynconf:"required"`.
+var ErrRequiredFieldMissing = errors.New("dynconf: required field missing")
@@ -0,0 +1,104 @@
+package dynconf_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/roy2220/dynconf"
+	"github.com/roy2220/dynconf/dynconftest"
+	_ "github.com/roy2220/dynconf/yamlcodec"
+)
+
+func TestBind(t *testing.T) {
+	wr, c := makeWatcher(t)
+	_, err := c.KV().Put(&api.KVPair{
+		Key:   "hello10",
+		Value: []byte("foo: 1\nbar: a\n"),
+	}, &api.WriteOptions{})
+	assert.NoError(t, err)
+
+	var cfg struct {
+		_   struct{} `dynconf:"format=yaml"`
+		Foo int
+		Bar string
+	}
+	b, err := dynconf.Bind(context.Background(), wr, "hello10", &cfg)
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer b.Unbind()
+
+	b.RLock()
+	assert.Equal(t, 1, cfg.Foo)
+	assert.Equal(t, "a", cfg.Bar)
+	b.RUnlock()
+
+	_, err = c.KV().Put(&api.KVPair{
+		Key:   "hello10",
+		Value: []byte("foo: 2\nbar: b\n"),
+	}, &api.WriteOptions{})
+	assert.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		b.RLock()
+		defer b.RUnlock()
+		return cfg.Foo == 2 && cfg.Bar == "b"
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestBindInvalidTarget(t *testing.T) {
+	var foo int
+	_, err := dynconf.Bind(context.Background(), nil, "hello", &foo)
+	assert.EqualError(t, err, "dynconf: bind failed; key=\"hello\" type=*int: non-nil pointer to struct required")
+}
+
+func TestBindFieldTags(t *testing.T) {
+	wr, b := dynconftest.NewWatcher(t)
+	b.Set("hello", []byte(`{"Name": "a"}`))
+
+	var cfg struct {
+		Name    string        `dynconf:"required"`
+		Timeout time.Duration `dynconf:"default=5s"`
+		Retries int           `dynconf:"default=3,required"`
+		Tags    []string      `dynconf:"default=[\"x\"]"`
+	}
+	bd, err := dynconf.Bind(context.Background(), wr, "hello", &cfg)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer bd.Unbind()
+	bd.RLock()
+	assert.Equal(t, "a", cfg.Name)
+	assert.Equal(t, 5*time.Second, cfg.Timeout)
+	assert.Equal(t, 3, cfg.Retries)
+	assert.Equal(t, []string{"x"}, cfg.Tags)
+	bd.RUnlock()
+
+	events := bd.Watch().Events()
+	b.Set("hello", []byte(`{"Name": "b", "Timeout": 1000000000, "Tags": ["y"]}`))
+	<-events
+	bd.RLock()
+	assert.Equal(t, "b", cfg.Name)
+	assert.Equal(t, time.Second, cfg.Timeout)
+	assert.Equal(t, 3, cfg.Retries)
+	assert.Equal(t, []string{"y"}, cfg.Tags)
+	bd.RUnlock()
+
+	b.Set("hello", []byte(`{"Retries": 1}`))
+	<-events
+	assert.True(t, errors.Is(bd.Watch().LastError(), dynconf.ErrRequiredFieldMissing))
+	bd.RLock()
+	assert.Equal(t, "b", cfg.Name)
+	bd.RUnlock()
+
+	var cfg2 struct {
+		Timeout time.Duration `dynconf:"default=five"`
+	}
+	_, err = dynconf.Bind(context.Background(), wr, "hello", &cfg2)
+	assert.Error(t, err)
+}
@@ -0,0 +1,163 @@
+package dynconf
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+
+	"github.com/hashicorp/consul/api"
+)
+
+// AddCanaryWatch adds a watch on the given key with canary overrides, and then
+// returns the watch. If the canary key, that is the key suffixed with ".canary",
+// exists and the given instance matches the targeting rule stored in the key
+// suffixed with ".canary.targeting", the data of the canary key are used, otherwise
+// the data of the key are used. Promotion, i.e. the key being updated and then the
+// canary key being deleted, is seamless as the value is only updated once the data
+// have been changed.
+func (w *Watcher) AddCanaryWatch(ctx context.Context, key string, instanceID string, valueFactory ValueFactory,
+	options ...WatchOption) (*Watch, error) {
+	backend := canaryBackend{
+		Backend:    w.backend,
+		Key:        key,
+		InstanceID: instanceID,
+	}
+
+	return w.doAddWatch(ctx, &backend, key, valueFactory, options)
+}
+
+// CanaryTargeting represents a targeting rule of a canary key, which is stored in
+// JSON. An instance matches the rule if its ID is listed or it falls in the bucket
+// of the percentage.
+type CanaryTargeting struct {
+	Percent     int      `json:"percent"`
+	InstanceIDs []string `json:"instance_ids"`
+}
+
+// Match returns whether the given instance matches the targeting rule of the
+// given key.
+func (ct *CanaryTargeting) Match(key string, instanceID string) bool {
+	for _, targetInstanceID := range ct.InstanceIDs {
+		if targetInstanceID == instanceID {
+			return true
+		}
+	}
+
+	return rolloutBucket(key, instanceID) < ct.Percent
+}
+
+type canaryBackend struct {
+	Backend    Backend
+	Key        string
+	InstanceID string
+
+	mu        sync.Mutex
+	entry     compositeEntry
+	lastIndex uint64
+}
+
+var _ Backend = (*canaryBackend)(nil)
+
+func (cb *canaryBackend) Get(key string, queryOptions *api.QueryOptions) (*api.KVPair, *api.QueryMeta, error) {
+	if queryOptions == nil {
+		queryOptions = new(api.QueryOptions)
+	}
+
+	cb.mu.Lock()
+	entry := cb.entry
+	cb.mu.Unlock()
+
+	if entry.Index != 0 && queryOptions.WaitIndex != 0 && queryOptions.WaitIndex == entry.Index {
+		keys := cb.keys()
+		queries := make([]query, len(keys))
+
+		for i := range keys {
+			key := keys[i]
+			queries[i] = func(queryOptions *api.QueryOptions) (*api.QueryMeta, error) {
+				_, queryMeta, err := cb.Backend.Get(key, queryOptions)
+				return queryMeta, err
+			}
+		}
+
+		changed, err := waitForAnyChange(queries, entry.LayerIndexes, queryOptions)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if !changed {
+			return entry.Result()
+		}
+	}
+
+	return cb.refresh(key, queryOptions)
+}
+
+func (cb *canaryBackend) List(prefix string, _ *api.QueryOptions) (api.KVPairs, *api.QueryMeta, error) {
+	return nil, nil, fmt.Errorf("%w; prefix=%q", ErrListNotSupported, prefix)
+}
+
+func (cb *canaryBackend) keys() []string {
+	return []string{cb.Key, cb.Key + canaryKeySuffix, cb.Key + canaryTargetingKeySuffix}
+}
+
+func (cb *canaryBackend) refresh(key string, queryOptions *api.QueryOptions) (*api.KVPair, *api.QueryMeta, error) {
+	queryOptions = queryOptions.WithContext(queryOptions.Context())
+	queryOptions.WaitIndex = 0
+	keys := cb.keys()
+	kvPairs := make([]*api.KVPair, len(keys))
+	waitIndexes := make([]uint64, len(keys))
+
+	for i, key := range keys {
+		kvPair, queryMeta, err := cb.Backend.Get(key, queryOptions)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		kvPairs[i] = kvPair
+		waitIndexes[i] = queryMeta.LastIndex
+	}
+
+	kvPair := kvPairs[0]
+
+	if canaryKVPair, targetingKVPair := kvPairs[1], kvPairs[2]; canaryKVPair != nil && targetingKVPair != nil {
+		var targeting CanaryTargeting
+
+		if err := json.Unmarshal(targetingKVPair.Value, &targeting); err != nil {
+			return nil, nil, fmt.Errorf("dynconf: canary targeting unmarshal failed; key=%q: %w", targetingKVPair.Key, err)
+		}
+
+		if targeting.Match(cb.Key, cb.InstanceID) {
+			kvPair = canaryKVPair
+		}
+	}
+
+	cb.mu.Lock()
+	entry := cb.entry
+
+	if entry.Index == 0 || !uint64sEqual(entry.LayerIndexes, waitIndexes) {
+		cb.lastIndex++
+		entry = compositeEntry{
+			Key:          key,
+			LayerIndexes: waitIndexes,
+			Index:        cb.lastIndex,
+			Exists:       kvPair != nil,
+		}
+
+		if kvPair != nil {
+			entry.Data = kvPair.Value
+		}
+
+		cb.entry = entry
+	}
+
+	cb.mu.Unlock()
+	return entry.Result()
+}
+
+const (
+	canaryKeySuffix          = ".canary"
+	canaryTargetingKeySuffix = ".canary.targeting"
+)
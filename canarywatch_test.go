@@ -0,0 +1,60 @@
+package dynconf_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/roy2220/dynconf"
+	"github.com/roy2220/dynconf/dynconftest"
+)
+
+func TestWatcherAddCanaryWatch(t *testing.T) {
+	wr, b := dynconftest.NewWatcher(t)
+	b.Set("limit", []byte("100"))
+	b.Set("limit.canary", []byte("200"))
+	w, err := wr.AddCanaryWatch(context.Background(), "limit", "instance-1", dynconf.NewIntValue)
+	if assert.NoError(t, err) {
+		defer w.Remove()
+	}
+
+	assert.Equal(t, int64(100), w.Value().(*dynconf.IntValue).Get())
+
+	newValues := make(chan dynconf.Value, 3)
+	unsubscribe := w.Subscribe(func(_, newValue dynconf.Value) { newValues <- newValue })
+	defer unsubscribe()
+
+	b.Set("limit.canary.targeting", []byte(`{"instance_ids": ["instance-1"]}`))
+	assert.Equal(t, int64(200), (<-newValues).(*dynconf.IntValue).Get())
+
+	// promotion
+	b.Set("limit", []byte("200"))
+	b.Delete("limit.canary")
+	b.Delete("limit.canary.targeting")
+	b.Set("limit", []byte("300"))
+	assert.Equal(t, int64(300), (<-newValues).(*dynconf.IntValue).Get())
+	assert.Len(t, newValues, 0)
+}
+
+func TestCanaryTargetingMatch(t *testing.T) {
+	ct := dynconf.CanaryTargeting{InstanceIDs: []string{"a"}}
+	assert.True(t, ct.Match("key", "a"))
+	assert.False(t, ct.Match("key", "b"))
+
+	ct = dynconf.CanaryTargeting{Percent: 100}
+	assert.True(t, ct.Match("key", "b"))
+
+	n := 0
+	ct = dynconf.CanaryTargeting{Percent: 30}
+
+	for _, instanceID := range []string{"0", "1", "2", "3", "4", "5", "6", "7", "8", "9"} {
+		if ct.Match("key", instanceID) {
+			n++
+		}
+
+		assert.Equal(t, ct.Match("key", instanceID), ct.Match("key", instanceID))
+	}
+
+	assert.True(t, n < 10)
+}
@@ -0,0 +1,134 @@
+package dynconf
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+)
+
+// AddCertificateWatch adds a watch on the given certificate key and the given
+// private key key, both holding PEM data, and then returns the watch. The pair
+// are validated before being applied, against the time of the clock of the watch
+// set by WithClock, and the latest certificate can be provided
+// to tls.Config with CertificateWatch.GetCertificate or
+// CertificateWatch.GetClientCertificate. The certificate and the private key may
+// be held in the same key.
+func (w *Watcher) AddCertificateWatch(ctx context.Context, certKey string, keyKey string,
+	options ...WatchOption) (*CertificateWatch, error) {
+	var watchOptions watchOptions
+	watchOptions.Init(w.watchOptions(options))
+	valueFactory := func() Value { return &CertificateValue{clock: watchOptions.Clock} }
+	var watch *Watch
+	var err error
+
+	if certKey == keyKey {
+		watch, err = w.AddWatch(ctx, certKey, valueFactory, options...)
+	} else {
+		watch, err = w.AddLayeredWatch(ctx, []string{certKey, keyKey}, valueFactory, concatPEMs, options...)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &CertificateWatch{watch}, nil
+}
+
+// CertificateWatch presents a watch on a certificate and a private key.
+type CertificateWatch struct {
+	*Watch
+}
+
+// Certificate returns the latest certificate.
+func (cw *CertificateWatch) Certificate() *tls.Certificate {
+	return cw.Value().(*CertificateValue).Get()
+}
+
+// GetCertificate returns the latest certificate, it is suitable for
+// tls.Config.GetCertificate.
+func (cw *CertificateWatch) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return cw.Certificate(), nil
+}
+
+// GetClientCertificate returns the latest certificate, it is suitable for
+// tls.Config.GetClientCertificate.
+func (cw *CertificateWatch) GetClientCertificate(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+	return cw.Certificate(), nil
+}
+
+// NewCertificateValue returns a new value of type *CertificateValue, it is a ValueFactory.
+func NewCertificateValue() Value {
+	return new(CertificateValue)
+}
+
+// CertificateValue presents a value holding a certificate and a private key, which
+// are in PEM.
+type CertificateValue struct {
+	certificate tls.Certificate
+	clock       Clock
+}
+
+var (
+	_ Value          = (*CertificateValue)(nil)
+	_ ValueValidator = (*CertificateValue)(nil)
+)
+
+// Unmarshal implements Value.Unmarshal.
+func (cv *CertificateValue) Unmarshal(data []byte) error {
+	// X509KeyPair picks certificate blocks and a private key block respectively.
+	certificate, err := tls.X509KeyPair(data, data)
+
+	if err != nil {
+		return err
+	}
+
+	leaf, err := x509.ParseCertificate(certificate.Certificate[0])
+
+	if err != nil {
+		return err
+	}
+
+	certificate.Leaf = leaf
+	cv.certificate = certificate
+	return nil
+}
+
+// Validate implements ValueValidator.Validate, it fails if the certificate is not
+// valid at present, per the clock of the watch if added by AddCertificateWatch, or
+// per SystemClock otherwise.
+func (cv *CertificateValue) Validate() error {
+	clock := cv.clock
+
+	if clock == nil {
+		clock = SystemClock
+	}
+
+	now := clock.Now()
+	leaf := cv.certificate.Leaf
+
+	if now.Before(leaf.NotBefore) || now.After(leaf.NotAfter) {
+		return fmt.Errorf("not_before=%v not_after=%v: %w", leaf.NotBefore, leaf.NotAfter, errCertificateNotValid)
+	}
+
+	return nil
+}
+
+// String implements Value.String.
+func (cv *CertificateValue) String() string {
+	leaf := cv.certificate.Leaf
+	return fmt.Sprintf("subject=%q serial_number=%v not_after=%v", leaf.Subject.String(), leaf.SerialNumber, leaf.NotAfter)
+}
+
+// Get returns the certificate held.
+func (cv *CertificateValue) Get() *tls.Certificate {
+	return &cv.certificate
+}
+
+func concatPEMs(datas [][]byte) ([]byte, error) {
+	return bytes.Join(datas, []byte("\n")), nil
+}
+
+var errCertificateNotValid = errors.New("certificate not valid")
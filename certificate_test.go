@@ -0,0 +1,95 @@
+package dynconf_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/roy2220/dynconf"
+	"github.com/roy2220/dynconf/dynconftest"
+)
+
+func TestWatcherAddCertificateWatch(t *testing.T) {
+	wr, b := dynconftest.NewWatcher(t)
+	certPEM, keyPEM := generateCertificate(t, 1, time.Now().Add(time.Hour))
+	b.Set("tls/cert", certPEM)
+	b.Set("tls/key", keyPEM)
+	cw, err := wr.AddCertificateWatch(context.Background(), "tls/cert", "tls/key")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer cw.Remove()
+
+	c, err := cw.GetCertificate(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), c.Leaf.SerialNumber.Int64())
+
+	newValues := make(chan dynconf.Value, 3)
+	unsubscribe := cw.Subscribe(func(_, newValue dynconf.Value) { newValues <- newValue })
+	defer unsubscribe()
+
+	certPEM, keyPEM = generateCertificate(t, 2, time.Now().Add(-time.Minute))
+	b.Set("tls/cert", certPEM)
+	b.Set("tls/key", keyPEM)
+	certPEM, keyPEM = generateCertificate(t, 3, time.Now().Add(time.Hour))
+	b.Set("tls/cert", certPEM)
+	b.Set("tls/key", keyPEM)
+	c = (<-newValues).(*dynconf.CertificateValue).Get()
+	assert.Equal(t, int64(3), c.Leaf.SerialNumber.Int64())
+	c, err = cw.GetClientCertificate(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), c.Leaf.SerialNumber.Int64())
+}
+
+func TestWatcherAddCertificateWatchWithClock(t *testing.T) {
+	clock := dynconftest.NewClock(time.Now().Add(2 * time.Hour))
+	wr, b := dynconftest.NewWatcherWithClock(t, clock)
+	certPEM, keyPEM := generateCertificate(t, 1, time.Now().Add(time.Hour))
+	b.Set("tls/cert", append(certPEM, keyPEM...))
+	_, err := wr.AddCertificateWatch(context.Background(), "tls/cert", "tls/cert")
+	assert.Error(t, err)
+
+	certPEM, keyPEM = generateCertificate(t, 2, time.Now().Add(3*time.Hour))
+	b.Set("tls/cert", append(certPEM, keyPEM...))
+	cw, err := wr.AddCertificateWatch(context.Background(), "tls/cert", "tls/cert")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer cw.Remove()
+	assert.Equal(t, int64(2), cw.Certificate().Leaf.SerialNumber.Int64())
+}
+
+func generateCertificate(t *testing.T, serialNumber int64, notAfter time.Time) ([]byte, []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	template := x509.Certificate{
+		SerialNumber: big.NewInt(serialNumber),
+		Subject:      pkix.Name{CommonName: "test"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &key.PublicKey, key)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
@@ -0,0 +1,88 @@
+package dynconf
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// WithChangeLimit returns an option to reject the updates changing any of the
+// numbers, located by the given JSON pointers (RFC 6901) in the JSON data, or the
+// data themselves for the pointer "", by more than the given ratio, e.g. 0.5 for
+// 50%, relative to the current numbers, with ErrChangeTooLarge, so that a typo like
+// a rate limit of 10 instead of 10000 can't take effect. Such an update is only
+// accepted if the data have the top-level field "dynconf_override_change_limit" set
+// to true. Numbers missing in the current or the new data, and numbers changing from
+// zero, are not limited.
+func WithChangeLimit(maxChangeRatio float64, pointers ...string) WatchOption {
+	return func(wo *watchOptions) {
+		wo.MaxChangeRatio = maxChangeRatio
+		wo.ChangeLimitPointers = pointers
+	}
+}
+
+func (w *Watch) checkChangeLimit(data []byte) error {
+	if len(w.options.ChangeLimitPointers) == 0 || w.valueData == nil {
+		return nil
+	}
+
+	for _, pointer := range w.options.ChangeLimitPointers {
+		tokens, err := parseJSONPointer(pointer)
+
+		if err != nil {
+			return err
+		}
+
+		oldNumber, ok := resolveJSONNumber(w.valueData, tokens)
+
+		if !ok || oldNumber == 0 {
+			continue
+		}
+
+		newNumber, ok := resolveJSONNumber(data, tokens)
+
+		if !ok {
+			continue
+		}
+
+		changeRatio := math.Abs(newNumber-oldNumber) / math.Abs(oldNumber)
+
+		if changeRatio <= w.options.MaxChangeRatio {
+			continue
+		}
+
+		if object, ok := decodeJSONObject(data); ok && object[changeLimitOverrideFieldName] == true {
+			w.logger.Log(LogWarn, "dynconf_change_limit_overridden", "key", w.key, "pointer", pointer,
+				"old_number", oldNumber, "new_number", newNumber)
+			return nil
+		}
+
+		return fmt.Errorf("%w; key=%q pointer=%q old_number=%v new_number=%v max_change_ratio=%v",
+			ErrChangeTooLarge, w.key, pointer, oldNumber, newNumber, w.options.MaxChangeRatio)
+	}
+
+	return nil
+}
+
+func resolveJSONNumber(data []byte, tokens []string) (float64, bool) {
+	numberData, ok, err := resolveJSONPointer(data, tokens)
+
+	if err != nil || !ok {
+		return 0, false
+	}
+
+	number, err := strconv.ParseFloat(string(numberData), 64)
+
+	if err != nil {
+		return 0, false
+	}
+
+	return number, true
+}
+
+const changeLimitOverrideFieldName = "dynconf_override_change_limit"
+
+// ErrChangeTooLarge is returned when an update changes a number by more than the
+// ratio set by WithChangeLimit.
+var ErrChangeTooLarge = errors.New("dynconf: change too large")
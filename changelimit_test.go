@@ -0,0 +1,63 @@
+package dynconf_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/roy2220/dynconf"
+	"github.com/roy2220/dynconf/dynconftest"
+)
+
+func TestWithChangeLimit(t *testing.T) {
+	wr, b := dynconftest.NewWatcher(t)
+	b.Set("limits", []byte(`{"rate_limit": 10000, "burst": 100}`))
+	b.Set("limit", []byte("100"))
+
+	var cfg struct {
+		RateLimit int `json:"rate_limit"`
+		Burst     int `json:"burst"`
+	}
+	bd, err := dynconf.Bind(context.Background(), wr, "limits", &cfg, dynconf.WithChangeLimit(0.5, "/rate_limit"))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer bd.Unbind()
+
+	events := bd.Watch().Events()
+	b.Set("limits", []byte(`{"rate_limit": 12000, "burst": 1}`))
+	<-events
+	assert.NoError(t, bd.Watch().LastError())
+
+	b.Set("limits", []byte(`{"rate_limit": 10, "burst": 1}`))
+	<-events
+	assert.True(t, errors.Is(bd.Watch().LastError(), dynconf.ErrChangeTooLarge))
+	bd.RLock()
+	assert.Equal(t, 12000, cfg.RateLimit)
+	bd.RUnlock()
+
+	b.Set("limits", []byte(`{"rate_limit": 10, "burst": 1, "dynconf_override_change_limit": true}`))
+	<-events
+	assert.NoError(t, bd.Watch().LastError())
+	bd.RLock()
+	assert.Equal(t, 10, cfg.RateLimit)
+	bd.RUnlock()
+
+	w, err := wr.AddWatch(context.Background(), "limit", dynconf.NewIntValue, dynconf.WithChangeLimit(1, ""))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer w.Remove()
+
+	events = w.Events()
+	b.Set("limit", []byte("300"))
+	<-events
+	assert.True(t, errors.Is(w.LastError(), dynconf.ErrChangeTooLarge))
+	assert.Equal(t, int64(100), w.Value().(*dynconf.IntValue).Get())
+
+	b.Set("limit", []byte("200"))
+	<-events
+	assert.Equal(t, int64(200), w.Value().(*dynconf.IntValue).Get())
+}
@@ -0,0 +1,182 @@
+package dynconf
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/consul/api"
+)
+
+// AddChunkedWatch adds a watch on the given key, whose data are split into chunks
+// stored in the keys suffixed with "/000", "/001" and so on, and then returns the
+// watch. The key holds the manifest of the chunks in JSON, see ChunkManifest. The
+// manifest and the chunks are read in a single blocking query on the key as the
+// prefix, and the chunks are reassembled before unmarshaling. If the chunks don't
+// match the manifest, e.g. in the middle of an update, the data reassembled last
+// are kept until the manifest and the chunks become consistent, so chunks should be
+// written before the manifest.
+func (w *Watcher) AddChunkedWatch(ctx context.Context, key string, valueFactory ValueFactory,
+	options ...WatchOption) (*Watch, error) {
+	backend := chunkedBackend{
+		Backend: w.backend,
+		Key:     key,
+	}
+
+	return w.doAddWatch(ctx, &backend, key, valueFactory, options)
+}
+
+// ChunkManifest represents a manifest of chunks, which is stored in JSON.
+type ChunkManifest struct {
+	// NumberOfChunks is the number of the chunks.
+	NumberOfChunks int `json:"number_of_chunks"`
+
+	// SHA256 is the SHA-256 checksum of the reassembled data in hex, which is
+	// optional but recommended, as a partially written update is detected only
+	// by the checksum if the number of the chunks remains unchanged.
+	SHA256 string `json:"sha256,omitempty"`
+}
+
+// SplitIntoChunks splits the given data into chunks of the given size, and then
+// returns the manifest and the chunks.
+func SplitIntoChunks(data []byte, chunkSize int) (ChunkManifest, [][]byte) {
+	var chunks [][]byte
+
+	for len(data) > chunkSize {
+		chunks = append(chunks, data[:chunkSize])
+		data = data[chunkSize:]
+	}
+
+	chunks = append(chunks, data)
+	checksum := sha256.Sum256(bytes.Join(chunks, nil))
+	manifest := ChunkManifest{
+		NumberOfChunks: len(chunks),
+		SHA256:         hex.EncodeToString(checksum[:]),
+	}
+
+	return manifest, chunks
+}
+
+// ChunkKey returns the key of the chunk with the given index for the given key.
+func ChunkKey(key string, chunkIndex int) string {
+	return fmt.Sprintf("%s/%03d", key, chunkIndex)
+}
+
+type chunkedBackend struct {
+	Backend Backend
+	Key     string
+
+	mu         sync.Mutex
+	lastKVPair *api.KVPair
+}
+
+var _ Backend = (*chunkedBackend)(nil)
+
+func (cb *chunkedBackend) Get(key string, queryOptions *api.QueryOptions) (*api.KVPair, *api.QueryMeta, error) {
+	kvPairs, queryMeta, err := cb.Backend.List(cb.Key, queryOptions)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	kvPair, err := cb.reassemble(kvPairs)
+
+	cb.mu.Lock()
+	defer cb.mu.Unlock()
+
+	if err != nil {
+		if cb.lastKVPair == nil {
+			return nil, nil, err
+		}
+
+		// Keep the data reassembled last until the chunks become consistent.
+		return cb.lastKVPair, queryMeta, nil
+	}
+
+	cb.lastKVPair = kvPair
+	return kvPair, queryMeta, nil
+}
+
+func (cb *chunkedBackend) List(prefix string, _ *api.QueryOptions) (api.KVPairs, *api.QueryMeta, error) {
+	return nil, nil, fmt.Errorf("%w; prefix=%q", ErrListNotSupported, prefix)
+}
+
+func (cb *chunkedBackend) reassemble(kvPairs api.KVPairs) (*api.KVPair, error) {
+	var manifestKVPair *api.KVPair
+	chunkKVPairs := make(map[string]*api.KVPair, len(kvPairs))
+
+	for _, kvPair := range kvPairs {
+		if kvPair.Key == cb.Key {
+			manifestKVPair = kvPair
+			continue
+		}
+
+		if !strings.HasPrefix(kvPair.Key, cb.Key+"/") {
+			continue
+		}
+
+		if _, err := strconv.Atoi(kvPair.Key[len(cb.Key)+1:]); err == nil {
+			chunkKVPairs[kvPair.Key] = kvPair
+		}
+	}
+
+	if manifestKVPair == nil {
+		return nil, nil
+	}
+
+	var manifest ChunkManifest
+
+	if err := json.Unmarshal(manifestKVPair.Value, &manifest); err != nil {
+		return nil, fmt.Errorf("dynconf: chunk manifest unmarshal failed; key=%q: %w", cb.Key, err)
+	}
+
+	if manifest.NumberOfChunks < 1 || manifest.NumberOfChunks > len(chunkKVPairs) {
+		// Some chunks must be missing if there are more than the chunk keys.
+		return nil, fmt.Errorf("%w; key=%q number_of_chunks=%d", errChunksInconsistent, cb.Key,
+			manifest.NumberOfChunks)
+	}
+
+	chunks := make([][]byte, manifest.NumberOfChunks)
+	modifyIndex := manifestKVPair.ModifyIndex
+
+	for i := range chunks {
+		chunkKey := ChunkKey(cb.Key, i)
+		chunkKVPair, ok := chunkKVPairs[chunkKey]
+
+		if !ok {
+			return nil, fmt.Errorf("%w; key=%q chunk_key=%q", errChunksInconsistent, cb.Key, chunkKey)
+		}
+
+		chunks[i] = chunkKVPair.Value
+
+		if chunkKVPair.ModifyIndex > modifyIndex {
+			modifyIndex = chunkKVPair.ModifyIndex
+		}
+	}
+
+	data := bytes.Join(chunks, nil)
+
+	if manifest.SHA256 != "" {
+		checksum := sha256.Sum256(data)
+
+		if hex.EncodeToString(checksum[:]) != strings.ToLower(manifest.SHA256) {
+			return nil, fmt.Errorf("%w; key=%q: checksum mismatch", errChunksInconsistent, cb.Key)
+		}
+	}
+
+	return &api.KVPair{
+		Key:         cb.Key,
+		Value:       data,
+		ModifyIndex: modifyIndex,
+		Flags:       manifestKVPair.Flags,
+	}, nil
+}
+
+var errChunksInconsistent = errors.New("dynconf: chunks inconsistent")
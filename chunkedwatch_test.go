@@ -0,0 +1,73 @@
+package dynconf_test
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/roy2220/dynconf"
+	"github.com/roy2220/dynconf/dynconftest"
+)
+
+func TestWatcherAddChunkedWatch(t *testing.T) {
+	wr, b := dynconftest.NewWatcher(t)
+	setChunks(t, b, "routes", strings.Repeat("a", 10), 4)
+	b.Set("routes-other", []byte("x"))
+	w, err := wr.AddChunkedWatch(context.Background(), "routes", dynconf.NewStringValue)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer w.Remove()
+
+	assert.Equal(t, strings.Repeat("a", 10), w.Value().(*dynconf.StringValue).Get())
+
+	newValues := make(chan dynconf.Value, 2)
+	unsubscribe := w.Subscribe(func(_, newValue dynconf.Value) { newValues <- newValue })
+	defer unsubscribe()
+
+	// partially written update
+	b.Set(dynconf.ChunkKey("routes", 0), []byte("bbbb"))
+	setChunks(t, b, "routes", strings.Repeat("b", 14), 4)
+	assert.Equal(t, strings.Repeat("b", 14), (<-newValues).(*dynconf.StringValue).Get())
+	assert.Len(t, newValues, 0)
+
+	b.Delete("routes")
+	assert.Eventually(t, func() bool { return !w.Exists() }, time.Second, 10*time.Millisecond)
+}
+
+func TestWatcherAddChunkedWatchBadManifest(t *testing.T) {
+	wr, b := dynconftest.NewWatcher(t)
+	b.Set(dynconf.ChunkKey("routes", 0), []byte("a"))
+
+	for _, manifest := range []string{`{"number_of_chunks": -1}`, `{"number_of_chunks": 0}`,
+		`{"number_of_chunks": 1000000000000}`} {
+		b.Set("routes", []byte(manifest))
+		_, err := wr.AddChunkedWatch(context.Background(), "routes", dynconf.NewStringValue)
+		if assert.Error(t, err) {
+			assert.Contains(t, err.Error(), "dynconf: chunks inconsistent")
+		}
+	}
+}
+
+func TestSplitIntoChunks(t *testing.T) {
+	manifest, chunks := dynconf.SplitIntoChunks([]byte("hello world"), 5)
+	assert.Equal(t, 3, manifest.NumberOfChunks)
+	assert.Equal(t, [][]byte{[]byte("hello"), []byte(" worl"), []byte("d")}, chunks)
+	assert.Len(t, manifest.SHA256, 64)
+}
+
+func setChunks(t *testing.T, b *dynconftest.Backend, key string, data string, chunkSize int) {
+	manifest, chunks := dynconf.SplitIntoChunks([]byte(data), chunkSize)
+
+	for i, chunk := range chunks {
+		b.Set(dynconf.ChunkKey(key, i), chunk)
+	}
+
+	manifestData, err := json.Marshal(manifest)
+	assert.NoError(t, err)
+	b.Set(key, manifestData)
+}
@@ -0,0 +1,107 @@
+package dynconf
+
+import (
+	"time"
+)
+
+// WithClock returns an option to set the clock of the watch, which drives the
+// timing of the watch, i.e. the backoffs of retries, the staleness set by
+// WithMaxStaleness, the TTL set by WithTTL, the activation and the expiry of data
+// with WithSchedule, the wait for dependencies set by WithDependencies, the
+// validity of certificates watched by AddCertificateWatch, the status reports and
+// the times recorded, and defaults to SystemClock. Given to a watcher, it also drives the backups and the health
+// reports of the watcher. A fake clock, e.g. dynconftest.Clock, makes tests able to
+// fast-forward time rather than sleep. Blocking queries on local overrides wait
+// with the clock, while blocking queries on the backend, including those of
+// debouncing, wait as the backend does, e.g. with the clock given to InitWithClock
+// of PollingBackend, SnapshotBackend or dynconftest.Backend.
+func WithClock(clock Clock) WatchOption {
+	return func(wo *watchOptions) {
+		wo.Clock = clock
+	}
+}
+
+// Clock represents a source of time.
+type Clock interface {
+	// Now returns the current time.
+	Now() time.Time
+
+	// NewTimer creates a timer sending the current time on its channel once the
+	// given duration has elapsed, and then returns the timer.
+	NewTimer(d time.Duration) Timer
+
+	// AfterFunc creates a timer calling the given function in its own goroutine
+	// once the given duration has elapsed, and then returns the timer, of which the
+	// channel is nil.
+	AfterFunc(d time.Duration, f func()) Timer
+
+	// NewTicker creates a ticker sending the current time on its channel every
+	// given period, and then returns the ticker.
+	NewTicker(d time.Duration) Ticker
+}
+
+// Timer represents a timer, which behaves as time.Timer.
+type Timer interface {
+	// C returns the channel on which the time is sent.
+	C() <-chan time.Time
+
+	// Stop stops the timer, and then returns whether the timer was active.
+	Stop() bool
+
+	// Reset changes the timer to expire after the given duration, and then returns
+	// whether the timer was active.
+	Reset(d time.Duration) bool
+}
+
+// Ticker represents a ticker, which behaves as time.Ticker.
+type Ticker interface {
+	// C returns the channel on which the ticks are sent.
+	C() <-chan time.Time
+
+	// Stop stops the ticker.
+	Stop()
+}
+
+// SystemClock is the clock based on the system time, i.e. the package time.
+var SystemClock Clock = systemClock{}
+
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
+
+func (systemClock) NewTimer(d time.Duration) Timer {
+	return systemTimer{time.NewTimer(d)}
+}
+
+func (systemClock) AfterFunc(d time.Duration, f func()) Timer {
+	return systemTimer{time.AfterFunc(d, f)}
+}
+
+func (systemClock) NewTicker(d time.Duration) Ticker {
+	return systemTicker{time.NewTicker(d)}
+}
+
+type systemTimer struct {
+	*time.Timer
+}
+
+func (st systemTimer) C() <-chan time.Time {
+	return st.Timer.C
+}
+
+type systemTicker struct {
+	*time.Ticker
+}
+
+func (st systemTicker) C() <-chan time.Time {
+	return st.Ticker.C
+}
+
+// clock returns the clock set for the watcher by WithClock.
+func (w *Watcher) clock() Clock {
+	var watchOptions watchOptions
+	watchOptions.Init(w.defaultOptions)
+	return watchOptions.Clock
+}
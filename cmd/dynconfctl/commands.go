@@ -0,0 +1,372 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+
+	"github.com/hashicorp/consul/api"
+
+	"github.com/roy2220/dynconf"
+	"github.com/roy2220/dynconf/jsonschema"
+)
+
+func get(ctx context.Context, cc *commandContext, args []string) error {
+	flagSet := cc.NewFlagSet("get", "<key>")
+	verbose := flagSet.Bool("v", false, "print the modify index and the flags of the key to the standard error")
+	key, _, err := cc.ParseArgs(flagSet, args, false)
+
+	if err != nil {
+		return err
+	}
+
+	kvPair, err := cc.GetKVPair(ctx, key)
+
+	if err != nil {
+		return err
+	}
+
+	if kvPair == nil {
+		return fmt.Errorf("key not found; key=%q", key)
+	}
+
+	if *verbose {
+		fmt.Fprintf(cc.Stderr, "modify_index=%d flags=%d\n", kvPair.ModifyIndex, kvPair.Flags)
+	}
+
+	_, err = cc.Stdout.Write(kvPair.Value)
+	return err
+}
+
+func put(ctx context.Context, cc *commandContext, args []string) error {
+	flagSet := cc.NewFlagSet("put", "<key> [file]")
+	var validationFlags validationFlags
+	validationFlags.Register(flagSet)
+	cas := flagSet.Int64("cas", -1, "the modify index the key must have, 0 if the key must be missing,\n"+
+		"which is required and should be the one printed by get -v for the data edited")
+	flags := flagSet.Uint64("flags", 0, "the flags of the key")
+	key, fileName, err := cc.ParseArgs(flagSet, args, true)
+
+	if err != nil {
+		return err
+	}
+
+	if *cas < 0 {
+		return fmt.Errorf("%w; key=%q", errModifyIndexRequired, key)
+	}
+
+	data, err := cc.ReadData(fileName)
+
+	if err != nil {
+		return err
+	}
+
+	options, err := validationFlags.WatchOptions()
+
+	if err != nil {
+		return err
+	}
+
+	if err := dynconf.Validate(key, data, newRawValue, options...); err != nil {
+		return err
+	}
+
+	modifyIndex := uint64(*cas)
+	kvPair := api.KVPair{
+		Key:         key,
+		Value:       data,
+		Flags:       *flags,
+		ModifyIndex: modifyIndex,
+	}
+	ok, _, err := cc.Client.KV().CAS(&kvPair, new(api.WriteOptions).WithContext(ctx))
+
+	if err != nil {
+		return fmt.Errorf("kv cas failed; key=%q: %w", key, err)
+	}
+
+	if !ok {
+		return fmt.Errorf("%w; key=%q modify_index=%d", dynconf.ErrPublishConflict, key, modifyIndex)
+	}
+
+	return nil
+}
+
+func validate(ctx context.Context, cc *commandContext, args []string) error {
+	flagSet := cc.NewFlagSet("validate", "<key> [file]")
+	var validationFlags validationFlags
+	validationFlags.Register(flagSet)
+	key, fileName, err := cc.ParseArgs(flagSet, args, true)
+
+	if err != nil {
+		return err
+	}
+
+	data, err := cc.ReadData(fileName)
+
+	if err != nil {
+		return err
+	}
+
+	options, err := validationFlags.WatchOptions()
+
+	if err != nil {
+		return err
+	}
+
+	return dynconf.Validate(key, data, newRawValue, options...)
+}
+
+func watch(ctx context.Context, cc *commandContext, args []string) error {
+	flagSet := cc.NewFlagSet("watch", "<key>")
+	var validationFlags validationFlags
+	validationFlags.Register(flagSet)
+	key, _, err := cc.ParseArgs(flagSet, args, false)
+
+	if err != nil {
+		return err
+	}
+
+	options, err := validationFlags.WatchOptions()
+
+	if err != nil {
+		return err
+	}
+
+	watcher := dynconf.NewWatcher(cc.Client)
+	defer watcher.Close(context.Background())
+	w, err := watcher.AddWatch(ctx, key, newRawValue, options...)
+
+	if err != nil {
+		return err
+	}
+
+	events := w.Events()
+
+	if w.Exists() {
+		fmt.Fprintf(cc.Stdout, "%s\n", w.Raw())
+	} else {
+		fmt.Fprintf(cc.Stderr, "key not found; key=%q\n", key)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-events:
+			if !ok {
+				return nil
+			}
+
+			switch event := event.(type) {
+			case *dynconf.UpdatedEvent:
+				fmt.Fprintf(cc.Stdout, "%s\n", event.NewValue)
+			case *dynconf.UpdateRejectedEvent:
+				fmt.Fprintf(cc.Stderr, "update rejected: %v\n", event.Err)
+			case *dynconf.KeyDeletedEvent:
+				fmt.Fprintf(cc.Stderr, "key deleted; key=%q\n", key)
+			}
+		}
+	}
+}
+
+func diff(ctx context.Context, cc *commandContext, args []string) error {
+	flagSet := cc.NewFlagSet("diff", "<key> [file]")
+	key, fileName, err := cc.ParseArgs(flagSet, args, true)
+
+	if err != nil {
+		return err
+	}
+
+	data, err := cc.ReadData(fileName)
+
+	if err != nil {
+		return err
+	}
+
+	kvPair, err := cc.GetKVPair(ctx, key)
+
+	if err != nil {
+		return err
+	}
+
+	if kvPair == nil {
+		fmt.Fprintf(cc.Stdout, "key not found; key=%q\n", key)
+		return errDifferent
+	}
+
+	changes, err := dynconf.DiffJSON(kvPair.Value, data)
+
+	if err != nil {
+		if string(kvPair.Value) == string(data) {
+			return nil
+		}
+
+		fmt.Fprintf(cc.Stdout, "data differ; key=%q\n", key)
+		return errDifferent
+	}
+
+	if len(changes) == 0 {
+		return nil
+	}
+
+	encoder := json.NewEncoder(cc.Stdout)
+
+	for _, change := range changes {
+		if err := encoder.Encode(change); err != nil {
+			return err
+		}
+	}
+
+	return errDifferent
+}
+
+func diffEnv(ctx context.Context, cc *commandContext, args []string) error {
+	flagSet := cc.NewFlagSet("diffenv", "<prefix>")
+	otherConfig := *cc.Config
+	flagSet.StringVar(&otherConfig.Address, "other-address", otherConfig.Address, "the address of the other Consul")
+	flagSet.StringVar(&otherConfig.Token, "other-token", otherConfig.Token, "the ACL token for the other Consul")
+	otherPrefix := flagSet.String("other-prefix", "", "the prefix of the keys to compare with, which defaults to the prefix")
+	prefix, _, err := cc.ParseArgs(flagSet, args, false)
+
+	if err != nil {
+		return err
+	}
+
+	if *otherPrefix == "" {
+		*otherPrefix = prefix
+	}
+
+	otherClient, err := api.NewClient(&otherConfig)
+
+	if err != nil {
+		return fmt.Errorf("client creation failed: %w", err)
+	}
+
+	keyDiffs, err := dynconf.DiffPrefixes(ctx, cc.Client.KV(), prefix, nil, otherClient.KV(), *otherPrefix, nil)
+
+	if err != nil {
+		return err
+	}
+
+	if len(keyDiffs) == 0 {
+		return nil
+	}
+
+	encoder := json.NewEncoder(cc.Stdout)
+
+	for _, keyDiff := range keyDiffs {
+		if err := encoder.Encode(keyDiffOutput{
+			Key:      keyDiff.Key,
+			Type:     keyDiff.Type,
+			OldData:  string(keyDiff.OldData),
+			OldFlags: keyDiff.OldFlags,
+			NewData:  string(keyDiff.NewData),
+			NewFlags: keyDiff.NewFlags,
+			Changes:  keyDiff.Changes,
+		}); err != nil {
+			return err
+		}
+	}
+
+	return errDifferent
+}
+
+type keyDiffOutput struct {
+	Key      string               `json:"key"`
+	Type     dynconf.KeyDiffType  `json:"type"`
+	OldData  string               `json:"old_data,omitempty"`
+	OldFlags uint64               `json:"old_flags,omitempty"`
+	NewData  string               `json:"new_data,omitempty"`
+	NewFlags uint64               `json:"new_flags,omitempty"`
+	Changes  []dynconf.JSONChange `json:"changes,omitempty"`
+}
+
+// errDifferent is returned by diff and diffenv when the data compared are different.
+var errDifferent = errors.New("different data")
+
+// errModifyIndexRequired is returned by put when the modify index to check-and-set
+// on is not given.
+var errModifyIndexRequired = errors.New("modify index required; read it with get -v and pass it with -cas")
+
+type validationFlags struct {
+	SchemaFileName string
+	MaxDataSize    int
+	JSONRequired   bool
+}
+
+func (vf *validationFlags) Register(flagSet *flag.FlagSet) {
+	flagSet.StringVar(&vf.SchemaFileName, "schema", "", "the file of the JSON schema the data must conform to")
+	flagSet.IntVar(&vf.MaxDataSize, "max-size", 0, "the maximum size of the data in bytes")
+	flagSet.BoolVar(&vf.JSONRequired, "json", false, "require the data to be valid JSON")
+}
+
+func (vf *validationFlags) WatchOptions() ([]dynconf.WatchOption, error) {
+	var options []dynconf.WatchOption
+
+	if vf.MaxDataSize >= 1 {
+		options = append(options, dynconf.WithMaxDataSize(vf.MaxDataSize))
+	}
+
+	var dataValidators []func([]byte) error
+
+	if vf.JSONRequired {
+		dataValidators = append(dataValidators, func(data []byte) error {
+			if !json.Valid(data) {
+				return errors.New("invalid JSON")
+			}
+
+			return nil
+		})
+	}
+
+	if vf.SchemaFileName != "" {
+		schema, err := ioutil.ReadFile(vf.SchemaFileName)
+
+		if err != nil {
+			return nil, fmt.Errorf("schema read failed: %w", err)
+		}
+
+		validator, err := jsonschema.NewValidator(schema)
+
+		if err != nil {
+			return nil, err
+		}
+
+		dataValidators = append(dataValidators, validator.Validate)
+	}
+
+	if len(dataValidators) >= 1 {
+		options = append(options, dynconf.WithDataValidator(func(data []byte) error {
+			for _, dataValidator := range dataValidators {
+				if err := dataValidator(data); err != nil {
+					return err
+				}
+			}
+
+			return nil
+		}))
+	}
+
+	return options, nil
+}
+
+func newRawValue() dynconf.Value {
+	return new(rawValue)
+}
+
+// rawValue presents a value holding raw data.
+type rawValue struct {
+	data []byte
+}
+
+func (rv *rawValue) Unmarshal(data []byte) error {
+	rv.data = data
+	return nil
+}
+
+func (rv *rawValue) String() string {
+	return string(rv.data)
+}
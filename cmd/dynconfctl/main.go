@@ -0,0 +1,202 @@
+// Command dynconfctl reads, writes and watches keys in the KV store of Consul with
+// the guard rails of dynconf, i.e. data are validated before being written, and
+// writes are protected by check-and-set.
+//
+// Usage:
+//
+//	dynconfctl [-address address] [-token token] <command> [arguments]
+//
+// The commands are:
+//
+//	get       print the data of a key
+//	put       validate data and write them to a key with check-and-set
+//	validate  validate data for a key without writing them
+//	watch     print the data of a key on each update, and its deletions
+//	diff      compare the data of a key with local data
+//	diffenv   compare the keys with a prefix with those of another cluster or prefix
+//
+// Data are read from the file given after the key, or from the standard input if
+// the file is missing or "-". The address and the token of Consul default to the
+// environment variables CONSUL_HTTP_ADDR and CONSUL_HTTP_TOKEN respectively.
+//
+// The put command requires the modify index the key must have with -cas, i.e. the
+// one printed by get -v for the data edited, or 0 if the key must be missing, so
+// that concurrent writes are never lost.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/hashicorp/consul/api"
+)
+
+func main() {
+	ctx, cancel := context.WithCancel(context.Background())
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		<-signals
+		cancel()
+	}()
+
+	err := run(ctx, os.Args[1:], os.Stdin, os.Stdout, os.Stderr)
+	cancel()
+
+	if err == nil {
+		return
+	}
+
+	if errors.Is(err, errDifferent) {
+		os.Exit(1)
+	}
+
+	if !errors.Is(err, flag.ErrHelp) {
+		fmt.Fprintf(os.Stderr, "dynconfctl: %v\n", err)
+	}
+
+	os.Exit(2)
+}
+
+// run runs the command given by the given arguments, and then returns errDifferent
+// if the data compared by diff or diffenv are different, or any other error if the command
+// failed.
+func run(ctx context.Context, args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) error {
+	flagSet := flag.NewFlagSet("dynconfctl", flag.ContinueOnError)
+	flagSet.SetOutput(stderr)
+	config := api.DefaultConfig()
+	flagSet.StringVar(&config.Address, "address", config.Address, "the address of Consul")
+	flagSet.StringVar(&config.Token, "token", config.Token, "the ACL token for Consul")
+	flagSet.Usage = func() {
+		fmt.Fprintf(flagSet.Output(), "usage: dynconfctl [flags] <get|put|validate|watch|diff|diffenv> [arguments]\n")
+		flagSet.PrintDefaults()
+	}
+
+	if err := flagSet.Parse(args); err != nil {
+		return err
+	}
+
+	if flagSet.NArg() == 0 {
+		flagSet.Usage()
+		return flag.ErrHelp
+	}
+
+	command, ok := commands[flagSet.Arg(0)]
+
+	if !ok {
+		flagSet.Usage()
+		return fmt.Errorf("unknown command %q", flagSet.Arg(0))
+	}
+
+	client, err := api.NewClient(config)
+
+	if err != nil {
+		return fmt.Errorf("client creation failed: %w", err)
+	}
+
+	c := commandContext{
+		Config: config,
+		Client: client,
+		Stdin:  stdin,
+		Stdout: stdout,
+		Stderr: stderr,
+	}
+
+	return command(ctx, &c, flagSet.Args()[1:])
+}
+
+var commands = map[string]func(context.Context, *commandContext, []string) error{
+	"get":      get,
+	"put":      put,
+	"validate": validate,
+	"watch":    watch,
+	"diff":     diff,
+	"diffenv":  diffEnv,
+}
+
+type commandContext struct {
+	Config *api.Config
+	Client *api.Client
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+func (cc *commandContext) NewFlagSet(name string, usage string) *flag.FlagSet {
+	flagSet := flag.NewFlagSet(name, flag.ContinueOnError)
+	flagSet.SetOutput(cc.Stderr)
+	flagSet.Usage = func() {
+		fmt.Fprintf(flagSet.Output(), "usage: dynconfctl %s [flags] %s\n", name, usage)
+		flagSet.PrintDefaults()
+	}
+
+	return flagSet
+}
+
+// ParseArgs parses the given arguments with the given flag set, and then returns
+// the key and the name of the data file, which is "-" if omitted.
+func (cc *commandContext) ParseArgs(flagSet *flag.FlagSet, args []string, withFile bool) (string, string, error) {
+	if err := flagSet.Parse(args); err != nil {
+		return "", "", err
+	}
+
+	maxNArgs := 1
+
+	if withFile {
+		maxNArgs = 2
+	}
+
+	if flagSet.NArg() == 0 || flagSet.NArg() > maxNArgs {
+		flagSet.Usage()
+		return "", "", flag.ErrHelp
+	}
+
+	fileName := "-"
+
+	if flagSet.NArg() == 2 {
+		fileName = flagSet.Arg(1)
+	}
+
+	return flagSet.Arg(0), fileName, nil
+}
+
+// ReadData reads the data from the file with the given name, or the standard input
+// if the name is "-".
+func (cc *commandContext) ReadData(fileName string) ([]byte, error) {
+	if fileName == "-" {
+		data, err := ioutil.ReadAll(cc.Stdin)
+
+		if err != nil {
+			return nil, fmt.Errorf("stdin read failed: %w", err)
+		}
+
+		return data, nil
+	}
+
+	data, err := ioutil.ReadFile(fileName)
+
+	if err != nil {
+		return nil, fmt.Errorf("file read failed: %w", err)
+	}
+
+	return data, nil
+}
+
+// GetKVPair returns the key-value pair of the given key, or nil if the key is missing.
+func (cc *commandContext) GetKVPair(ctx context.Context, key string) (*api.KVPair, error) {
+	kvPair, _, err := cc.Client.KV().Get(key, new(api.QueryOptions).WithContext(ctx))
+
+	if err != nil {
+		return nil, fmt.Errorf("kv get failed; key=%q: %w", key, err)
+	}
+
+	return kvPair, nil
+}
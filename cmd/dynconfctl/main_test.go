@@ -0,0 +1,194 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/roy2220/dynconf"
+)
+
+func TestValidate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dynconfctl")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+	schemaFileName := filepath.Join(dir, "schema.json")
+	assert.NoError(t, ioutil.WriteFile(schemaFileName, []byte(`{
+		"type": "object",
+		"properties": {"Foo": {"type": "integer", "minimum": 0}},
+		"required": ["Foo"]
+	}`), 0644))
+
+	runValidate := func(data string, args ...string) error {
+		args = append([]string{"validate"}, args...)
+		return run(context.Background(), append(args, "hello"), strings.NewReader(data), ioutil.Discard, ioutil.Discard)
+	}
+
+	assert.NoError(t, runValidate(`{"Foo": 1}`, "-schema", schemaFileName))
+	err = runValidate(`{"Foo": -1}`, "-schema", schemaFileName)
+	assert.EqualError(t, err, `dynconf: data validation failed; key="hello": jsonschema: schema violated: Foo: Must be greater than or equal to 0`)
+	assert.NoError(t, runValidate(`not json`))
+	assert.EqualError(t, runValidate(`not json`, "-json"), `dynconf: data validation failed; key="hello": invalid JSON`)
+	err = runValidate(`{"Foo": 1}`, "-max-size", "5")
+	assert.True(t, errors.Is(err, dynconf.ErrDataTooLarge))
+}
+
+func TestUsage(t *testing.T) {
+	var stderr bytes.Buffer
+	err := run(context.Background(), nil, nil, ioutil.Discard, &stderr)
+	assert.True(t, errors.Is(err, flag.ErrHelp))
+	assert.Contains(t, stderr.String(), "usage: dynconfctl")
+
+	err = run(context.Background(), []string{"foo"}, nil, ioutil.Discard, ioutil.Discard)
+	assert.EqualError(t, err, `unknown command "foo"`)
+
+	stderr.Reset()
+	err = run(context.Background(), []string{"get"}, nil, ioutil.Discard, &stderr)
+	assert.True(t, errors.Is(err, flag.ErrHelp))
+	assert.Contains(t, stderr.String(), "usage: dynconfctl get [flags] <key>")
+
+	err = run(context.Background(), []string{"put", "hello"}, strings.NewReader(`{"Foo": 1}`), ioutil.Discard, ioutil.Discard)
+	assert.True(t, errors.Is(err, errModifyIndexRequired))
+}
+
+func TestPutGetDiff(t *testing.T) {
+	args := []string{"-address", os.Getenv("TEST_CONSUL_ADDRESS")}
+	runCommand := func(stdin string, stdout *bytes.Buffer, commandArgs ...string) error {
+		if stdout == nil {
+			stdout = new(bytes.Buffer)
+		}
+		return run(context.Background(), append(args, commandArgs...), strings.NewReader(stdin), stdout, ioutil.Discard)
+	}
+
+	deleteKeys(t, "hello23")
+
+	err := runCommand(`{"Foo": 1}`, nil, "put", "-json", "hello23")
+	assert.True(t, errors.Is(err, errModifyIndexRequired))
+	assert.NoError(t, runCommand(`{"Foo": 1}`, nil, "put", "-json", "-cas", "0", "hello23"))
+	var stdout bytes.Buffer
+	assert.NoError(t, runCommand("", &stdout, "get", "hello23"))
+	assert.Equal(t, `{"Foo": 1}`, stdout.String())
+
+	modifyIndex := getModifyIndex(t, args, "hello23")
+	err = runCommand(`{"Foo": 2}`, nil, "put", "-cas", "0", "hello23")
+	assert.True(t, errors.Is(err, dynconf.ErrPublishConflict))
+	err = runCommand(`{"Foo": 2`, nil, "put", "-json", "-cas", modifyIndex, "hello23")
+	assert.EqualError(t, err, `dynconf: data validation failed; key="hello23": invalid JSON`)
+
+	assert.NoError(t, runCommand(`{"Foo": 1}`, nil, "diff", "hello23"))
+	stdout.Reset()
+	err = runCommand(`{"Foo": 2}`, &stdout, "diff", "hello23")
+	assert.True(t, errors.Is(err, errDifferent))
+	assert.Equal(t, `{"path":"/Foo","old_value":1,"new_value":2}`+"\n", stdout.String())
+}
+
+func TestDiffEnv(t *testing.T) {
+	args := []string{"-address", os.Getenv("TEST_CONSUL_ADDRESS")}
+	runCommand := func(stdin string, stdout io.Writer, commandArgs ...string) error {
+		return run(context.Background(), append(args, commandArgs...), strings.NewReader(stdin), stdout, ioutil.Discard)
+	}
+
+	deleteKeys(t, "hello24/a", "hello25/a")
+	assert.NoError(t, runCommand(`{"Foo": 1}`, ioutil.Discard, "put", "-cas", "0", "hello24/a"))
+	assert.NoError(t, runCommand(`{"Foo": 2}`, ioutil.Discard, "put", "-cas", "0", "hello25/a"))
+	assert.NoError(t, runCommand("", ioutil.Discard, "diffenv", "-other-prefix", "hello24/", "hello24/"))
+
+	var stdout bytes.Buffer
+	err := runCommand("", &stdout, "diffenv", "-other-prefix", "hello25/", "hello24/")
+	assert.True(t, errors.Is(err, errDifferent))
+	assert.Equal(t, `{"key":"a","type":"modified","old_data":"{\"Foo\": 1}","new_data":"{\"Foo\": 2}",`+
+		`"changes":[{"path":"/Foo","old_value":1,"new_value":2}]}`+"\n", stdout.String())
+}
+
+func TestWatch(t *testing.T) {
+	args := []string{"-address", os.Getenv("TEST_CONSUL_ADDRESS")}
+	deleteKeys(t, "hello28")
+	assert.NoError(t, run(context.Background(), append(args, "put", "-cas", "0", "hello28"), strings.NewReader(`{"Foo": 1}`),
+		ioutil.Discard, ioutil.Discard))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var stdout, stderr syncBuffer
+	errs := make(chan error, 1)
+	go func() {
+		errs <- run(ctx, append(args, "watch", "hello28"), nil, &stdout, &stderr)
+	}()
+	assert.Eventually(t, func() bool { return stdout.String() == `{"Foo": 1}`+"\n" }, time.Second, 10*time.Millisecond)
+
+	modifyIndex := getModifyIndex(t, args, "hello28")
+	assert.NoError(t, run(context.Background(), append(args, "put", "-cas", modifyIndex, "hello28"),
+		strings.NewReader(`{"Foo": 2}`), ioutil.Discard, ioutil.Discard))
+	assert.Eventually(t, func() bool { return stdout.String() == `{"Foo": 1}`+"\n"+`{"Foo": 2}`+"\n" }, time.Second,
+		10*time.Millisecond)
+
+	client, err := api.NewClient(&api.Config{Address: os.Getenv("TEST_CONSUL_ADDRESS")})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	_, err = client.KV().Delete("hello28", nil)
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool { return stderr.String() == `key deleted; key="hello28"`+"\n" }, time.Second,
+		10*time.Millisecond)
+
+	cancel()
+	assert.NoError(t, <-errs)
+}
+
+func deleteKeys(t *testing.T, keys ...string) {
+	client, err := api.NewClient(&api.Config{Address: os.Getenv("TEST_CONSUL_ADDRESS")})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	for _, key := range keys {
+		_, err := client.KV().Delete(key, nil)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+	}
+}
+
+func getModifyIndex(t *testing.T, args []string, key string) string {
+	var stderr bytes.Buffer
+	err := run(context.Background(), append(args, "get", "-v", key), nil, ioutil.Discard, &stderr)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	var modifyIndex, flags uint64
+	_, err = fmt.Sscanf(stderr.String(), "modify_index=%d flags=%d\n", &modifyIndex, &flags)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	return strconv.FormatUint(modifyIndex, 10)
+}
+
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (sb *syncBuffer) Write(p []byte) (int, error) {
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+	return sb.buf.Write(p)
+}
+
+func (sb *syncBuffer) String() string {
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+	return sb.buf.String()
+}
@@ -0,0 +1,181 @@
+package dynconf
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// Codec represents a codec for data of a format.
+type Codec interface {
+	// Marshal marshals the given object into data.
+	Marshal(object interface{}) (data []byte, err error)
+
+	// Unmarshal unmarshals the given data into the given object.
+	Unmarshal(data []byte, object interface{}) (err error)
+}
+
+// StrictCodec represents a codec which can also unmarshal data in strict mode.
+type StrictCodec interface {
+	Codec
+
+	// UnmarshalStrict unmarshals the given data into the given object, and fails
+	// if the data have fields unknown to the object.
+	UnmarshalStrict(data []byte, object interface{}) (err error)
+}
+
+// RegisterCodec registers the given codec for the given format, replacing the
+// codec previously registered for the format if any. The codec for format "json"
+// is registered by default.
+func RegisterCodec(format string, codec Codec) {
+	codecsMu.Lock()
+	defer codecsMu.Unlock()
+	codecs[format] = codec
+}
+
+// LookupCodec returns the codec registered for the given format.
+func LookupCodec(format string) (Codec, bool) {
+	codecsMu.RLock()
+	defer codecsMu.RUnlock()
+	codec, ok := codecs[format]
+	return codec, ok
+}
+
+// Marshal marshals the given object into data of the given format.
+func Marshal(format string, object interface{}) ([]byte, error) {
+	codec, ok := LookupCodec(format)
+
+	if !ok {
+		return nil, fmt.Errorf("%w; format=%q", ErrCodecNotFound, format)
+	}
+
+	return codec.Marshal(object)
+}
+
+// Unmarshal unmarshals the given data of the given format into the given object.
+func Unmarshal(format string, data []byte, object interface{}) error {
+	codec, ok := LookupCodec(format)
+
+	if !ok {
+		return fmt.Errorf("%w; format=%q", ErrCodecNotFound, format)
+	}
+
+	return codec.Unmarshal(data, object)
+}
+
+// UnmarshalStrict unmarshals the given data of the given format into the given
+// object in strict mode, i.e. failing if the data have fields unknown to the
+// object, or falls back to Unmarshal if the codec for the format is not a
+// StrictCodec.
+func UnmarshalStrict(format string, data []byte, object interface{}) error {
+	codec, ok := LookupCodec(format)
+
+	if !ok {
+		return fmt.Errorf("%w; format=%q", ErrCodecNotFound, format)
+	}
+
+	if strictCodec, ok := codec.(StrictCodec); ok {
+		return strictCodec.UnmarshalStrict(data, object)
+	}
+
+	return codec.Unmarshal(data, object)
+}
+
+// CodecValueFactory returns a value factory creating values of type *ObjectValue,
+// which unmarshal objects, created by the given function, from data of the
+// given format.
+func CodecValueFactory(format string, newObject func() interface{}) ValueFactory {
+	return func() Value {
+		return newCodecValue(format, newObject)
+	}
+}
+
+// NewCodecValue returns a new value of type *ObjectValue holding the given object,
+// which marshals the object into data of the given format, e.g. for publishing.
+func NewCodecValue(format string, object interface{}) *ObjectValue {
+	objectType := reflect.TypeOf(object)
+	value := newCodecValue(format, func() interface{} {
+		if objectType.Kind() == reflect.Ptr {
+			return reflect.New(objectType.Elem()).Interface()
+		}
+
+		return reflect.New(objectType).Interface()
+	})
+	value.object = object
+	return value
+}
+
+func newCodecValue(format string, newObject func() interface{}) *ObjectValue {
+	unmarshal := func(strict bool) UnmarshalFunc {
+		return func(data []byte) (interface{}, error) {
+			object := newObject()
+
+			if err := unmarshalWithCodec(format, data, object, strict); err != nil {
+				return nil, err
+			}
+
+			return object, nil
+		}
+	}
+
+	return &ObjectValue{
+		unmarshal:       unmarshal(false),
+		unmarshalStrict: unmarshal(true),
+		marshal: func(object interface{}) ([]byte, error) {
+			return Marshal(format, object)
+		},
+	}
+}
+
+func unmarshalWithCodec(format string, data []byte, object interface{}, strict bool) error {
+	if strict {
+		return UnmarshalStrict(format, data, object)
+	}
+
+	return Unmarshal(format, data, object)
+}
+
+// JSONValueFactory returns a value factory creating values of type *ObjectValue,
+// which unmarshal objects, created by the given function, from JSON data.
+func JSONValueFactory(newObject func() interface{}) ValueFactory {
+	return CodecValueFactory("json", newObject)
+}
+
+// ErrCodecNotFound is returned when no codec has been registered for a format.
+var ErrCodecNotFound = errors.New("dynconf: codec not found")
+
+// ErrMarshalNotSupported is returned when a value or a codec does not support
+// marshaling.
+var ErrMarshalNotSupported = errors.New("dynconf: marshal not supported")
+
+var (
+	codecsMu sync.RWMutex
+	codecs   = map[string]Codec{
+		"json": jsonCodec{},
+	}
+)
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(object interface{}) ([]byte, error) { return json.Marshal(object) }
+func (jsonCodec) Unmarshal(data []byte, object interface{}) error {
+	return json.Unmarshal(data, object)
+}
+
+func (jsonCodec) UnmarshalStrict(data []byte, object interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	if err := decoder.Decode(object); err != nil {
+		return err
+	}
+
+	if decoder.More() {
+		return errors.New("invalid character after top-level value")
+	}
+
+	return nil
+}
@@ -0,0 +1,38 @@
+package dynconf_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/roy2220/dynconf"
+	"github.com/roy2220/dynconf/yamlcodec"
+)
+
+func TestCodecValueFactory(t *testing.T) {
+	newObject := func() interface{} { return new(config) }
+
+	v := dynconf.JSONValueFactory(newObject)()
+	assert.NoError(t, v.Unmarshal([]byte(`{"Foo": 1, "Bar": "a"}`)))
+	v.(*dynconf.ObjectValue).Object().(*config).Equals(t, &config{Foo: 1, Bar: "a"})
+
+	v = yamlcodec.ValueFactory(newObject)()
+	assert.NoError(t, v.Unmarshal([]byte("foo: 2\nbar: b\n")))
+	v.(*dynconf.ObjectValue).Object().(*config).Equals(t, &config{Foo: 2, Bar: "b"})
+
+	v = dynconf.CodecValueFactory("xml", newObject)()
+	err := v.Unmarshal([]byte(`<config/>`))
+	assert.True(t, errors.Is(err, dynconf.ErrCodecNotFound))
+	assert.EqualError(t, err, "dynconf: codec not found; format=\"xml\"")
+}
+
+func TestNewCodecValue(t *testing.T) {
+	v := dynconf.NewCodecValue("json", &struct{ Foo int }{Foo: 1})
+	data, err := v.Marshal()
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"Foo": 1}`, string(data))
+
+	_, err = dynconf.ObjectValueFactory(nil)().(*dynconf.ObjectValue).Marshal()
+	assert.True(t, errors.Is(err, dynconf.ErrMarshalNotSupported))
+}
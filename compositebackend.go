@@ -0,0 +1,236 @@
+package dynconf
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/hashicorp/consul/api"
+)
+
+// CompositeBackend presents a backend merging the data of keys in multiple layers,
+// e.g. a local override file, a per-service key and a global key in Consul, in the
+// order of precedence. The merged data are re-evaluated once any layer has been
+// changed.
+type CompositeBackend struct {
+	layers []Layer
+	merge  MergeFunc
+
+	mu        sync.Mutex
+	entries   map[string]compositeEntry
+	lastIndex uint64
+}
+
+var _ Backend = (*CompositeBackend)(nil)
+
+// Layer represents a layer of a composite backend.
+type Layer struct {
+	// Backend is the backend of the layer.
+	Backend Backend
+
+	// MapKey optionally maps keys of the composite backend to keys of the layer.
+	MapKey func(key string) (layerKey string)
+}
+
+// MergeFunc is the type of the function merging the data of a key in multiple
+// layers, which are in the order of precedence, i.e. the data of the layer with
+// the highest precedence are the first.
+type MergeFunc func(datas [][]byte) (mergedData []byte, err error)
+
+// Init initializes the backend with the given layers, which are in the order of
+// precedence, i.e. the layer with the highest precedence is the first, and the
+// given merge function, which defaults to the one taking the data of the layer
+// with the highest precedence, and then returns the backend.
+func (cb *CompositeBackend) Init(layers []Layer, merge MergeFunc) *CompositeBackend {
+	if merge == nil {
+		merge = takeFirst
+	}
+
+	cb.layers = layers
+	cb.merge = merge
+	cb.entries = make(map[string]compositeEntry)
+	return cb
+}
+
+// Get implements Backend.Get.
+func (cb *CompositeBackend) Get(key string, queryOptions *api.QueryOptions) (*api.KVPair, *api.QueryMeta, error) {
+	if queryOptions == nil {
+		queryOptions = new(api.QueryOptions)
+	}
+
+	cb.mu.Lock()
+	entry, ok := cb.entries[key]
+	cb.mu.Unlock()
+
+	if ok && queryOptions.WaitIndex != 0 && queryOptions.WaitIndex == entry.Index {
+		changed, err := cb.waitForChange(key, entry.LayerIndexes, queryOptions)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if !changed {
+			return entry.Result()
+		}
+	}
+
+	return cb.refresh(key, queryOptions)
+}
+
+// List implements Backend.List, it always returns ErrListNotSupported.
+func (cb *CompositeBackend) List(prefix string, queryOptions *api.QueryOptions) (api.KVPairs, *api.QueryMeta, error) {
+	return nil, nil, fmt.Errorf("%w; prefix=%q", ErrListNotSupported, prefix)
+}
+
+func (cb *CompositeBackend) waitForChange(key string, layerIndexes []uint64, queryOptions *api.QueryOptions) (bool, error) {
+	queries := make([]query, len(cb.layers))
+
+	for i := range cb.layers {
+		layer := &cb.layers[i]
+		layerKey := layer.mapKey(key)
+		queries[i] = func(queryOptions *api.QueryOptions) (*api.QueryMeta, error) {
+			_, queryMeta, err := layer.Backend.Get(layerKey, queryOptions)
+			return queryMeta, err
+		}
+	}
+
+	return waitForAnyChange(queries, layerIndexes, queryOptions)
+}
+
+func (cb *CompositeBackend) refresh(key string, queryOptions *api.QueryOptions) (*api.KVPair, *api.QueryMeta, error) {
+	layerQueryOptions := queryOptions.WithContext(queryOptions.Context())
+	layerQueryOptions.WaitIndex = 0
+	layerIndexes := make([]uint64, len(cb.layers))
+	var datas [][]byte
+
+	for i := range cb.layers {
+		layer := &cb.layers[i]
+		kvPair, queryMeta, err := layer.Backend.Get(layer.mapKey(key), layerQueryOptions)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		layerIndexes[i] = queryMeta.LastIndex
+
+		if kvPair != nil {
+			datas = append(datas, kvPair.Value)
+		}
+	}
+
+	var mergedData []byte
+
+	if len(datas) >= 1 {
+		var err error
+		mergedData, err = cb.merge(datas)
+
+		if err != nil {
+			return nil, nil, fmt.Errorf("dynconf: data merge failed; key=%q: %w", key, err)
+		}
+	}
+
+	cb.mu.Lock()
+	entry, ok := cb.entries[key]
+
+	if !ok || !uint64sEqual(entry.LayerIndexes, layerIndexes) {
+		cb.lastIndex++
+		entry = compositeEntry{
+			Key:          key,
+			LayerIndexes: layerIndexes,
+			Index:        cb.lastIndex,
+			Exists:       len(datas) >= 1,
+			Data:         mergedData,
+		}
+		cb.entries[key] = entry
+	}
+
+	cb.mu.Unlock()
+	return entry.Result()
+}
+
+func (l *Layer) mapKey(key string) string {
+	if l.MapKey == nil {
+		return key
+	}
+
+	return l.MapKey(key)
+}
+
+type compositeEntry struct {
+	Key          string
+	LayerIndexes []uint64
+	Index        uint64
+	Exists       bool
+	Data         []byte
+}
+
+func (ce compositeEntry) Result() (*api.KVPair, *api.QueryMeta, error) {
+	queryMeta := api.QueryMeta{LastIndex: ce.Index}
+
+	if !ce.Exists {
+		return nil, &queryMeta, nil
+	}
+
+	return &api.KVPair{Key: ce.Key, Value: ce.Data, ModifyIndex: ce.Index}, &queryMeta, nil
+}
+
+type query func(queryOptions *api.QueryOptions) (queryMeta *api.QueryMeta, err error)
+
+// waitForAnyChange makes the given blocking queries concurrently with the given
+// wait indexes, and then returns whether any query has returned a different index,
+// as soon as it happens.
+func waitForAnyChange(queries []query, waitIndexes []uint64, queryOptions *api.QueryOptions) (bool, error) {
+	ctx, cancel := context.WithCancel(queryOptions.Context())
+	defer cancel()
+
+	type result struct {
+		WaitIndex uint64
+		QueryMeta *api.QueryMeta
+		Err       error
+	}
+
+	results := make(chan result, len(queries))
+
+	for i := range queries {
+		query := queries[i]
+		queryOptions := queryOptions.WithContext(ctx)
+		queryOptions.WaitIndex = waitIndexes[i]
+
+		go func() {
+			queryMeta, err := query(queryOptions)
+			results <- result{queryOptions.WaitIndex, queryMeta, err}
+		}()
+	}
+
+	for range queries {
+		result := <-results
+
+		if result.Err != nil {
+			return false, result.Err
+		}
+
+		if result.QueryMeta.LastIndex != result.WaitIndex {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func takeFirst(datas [][]byte) ([]byte, error) {
+	return datas[0], nil
+}
+
+func uint64sEqual(a []uint64, b []uint64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
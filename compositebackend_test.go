@@ -0,0 +1,129 @@
+package dynconf_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/roy2220/dynconf"
+	"github.com/roy2220/dynconf/dynconftest"
+)
+
+func TestCompositeBackend(t *testing.T) {
+	b1 := new(dynconftest.Backend).Init()
+	b2 := new(dynconftest.Backend).Init()
+	b := new(dynconf.CompositeBackend).Init([]dynconf.Layer{
+		{Backend: b1, MapKey: func(key string) string { return "service/" + key }},
+		{Backend: b2, MapKey: func(key string) string { return "global/" + key }},
+	}, dynconf.MergeJSON)
+	b2.Set("global/hello", []byte(`{"Foo": 1, "Bar": "global"}`))
+	wr := new(dynconf.Watcher).InitWithBackend(b, dynconftest.Logger{T: t})
+	w, err := wr.AddWatch(context.Background(), "hello", newValue)
+	if assert.NoError(t, err) {
+		defer w.Remove()
+	}
+
+	cfg := w.Value().(*config)
+	cfg.Equals(t, &config{Foo: 1, Bar: "global"})
+
+	b1.Set("service/hello", []byte(`{"Bar": "service"}`))
+	<-cfg.OutdatedEvent()
+	cfg = w.Value().(*config)
+	cfg.Equals(t, &config{Foo: 1, Bar: "service"})
+
+	b2.Set("global/hello", []byte(`{"Foo": 2, "Bar": "global"}`))
+	<-cfg.OutdatedEvent()
+	cfg = w.Value().(*config)
+	cfg.Equals(t, &config{Foo: 2, Bar: "service"})
+
+	b1.Delete("service/hello")
+	b2.Delete("global/hello")
+	assert.Eventually(t, func() bool { return !w.Exists() }, time.Second, time.Millisecond)
+}
+
+func TestMergeJSON(t *testing.T) {
+	data, err := dynconf.MergeJSON([][]byte{
+		[]byte(`{"a": {"b": 1}, "c": [1]}`),
+		[]byte(`{"a": {"b": 2, "d": 3}, "c": [2, 3], "e": 4}`),
+	})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"a": {"b": 1, "d": 3}, "c": [1], "e": 4}`, string(data))
+}
+
+func TestWatcherAddLayeredWatch(t *testing.T) {
+	wr, b := dynconftest.NewWatcher(t)
+	b.Set("config/global", []byte(`{"Foo": 1, "Bar": "global"}`))
+	b.Set("config/svc", []byte(`{"Bar": "svc"}`))
+	w, err := wr.AddLayeredWatch(context.Background(),
+		[]string{"config/svc/i1", "config/svc", "config/global"}, newValue, nil)
+	if assert.NoError(t, err) {
+		defer w.Remove()
+	}
+
+	assert.Equal(t, "config/svc/i1,config/svc,config/global", w.Key())
+	cfg := w.Value().(*config)
+	cfg.Equals(t, &config{Foo: 1, Bar: "svc"})
+
+	b.Set("config/svc/i1", []byte(`{"Foo": 2}`))
+	<-cfg.OutdatedEvent()
+	w.Value().(*config).Equals(t, &config{Foo: 2, Bar: "svc"})
+}
+
+func TestWatcherAddPatchedWatch(t *testing.T) {
+	wr, b := dynconftest.NewWatcher(t)
+	b.Set("config", []byte(`{"Foo": 1, "Bar": "base"}`))
+	w, err := wr.AddPatchedWatch(context.Background(), "config", "config.patch", newValue)
+	if assert.NoError(t, err) {
+		defer w.Remove()
+	}
+
+	cfg := w.Value().(*config)
+	cfg.Equals(t, &config{Foo: 1, Bar: "base"})
+
+	b.Set("config.patch", []byte(`{"Bar": null}`))
+	<-cfg.OutdatedEvent()
+	cfg = w.Value().(*config)
+	cfg.Equals(t, &config{Foo: 1})
+
+	b.Set("config", []byte(`{"Foo": 2, "Bar": "base"}`))
+	<-cfg.OutdatedEvent()
+	w.Value().(*config).Equals(t, &config{Foo: 2})
+}
+
+func TestWatcherAddAliasedWatch(t *testing.T) {
+	wr, b := dynconftest.NewWatcher(t)
+	b.Set("legacy/config", []byte(`{"Foo": 1}`))
+	w, err := wr.AddAliasedWatch(context.Background(), []string{"config", "legacy/config"}, newValue)
+	if assert.NoError(t, err) {
+		defer w.Remove()
+	}
+
+	cfg := w.Value().(*config)
+	cfg.Equals(t, &config{Foo: 1})
+
+	b.Set("config", []byte(`{"Foo": 2}`))
+	<-cfg.OutdatedEvent()
+	cfg = w.Value().(*config)
+	cfg.Equals(t, &config{Foo: 2})
+
+	b.Delete("legacy/config")
+	b.Set("config", []byte(`{"Foo": 3}`))
+	<-cfg.OutdatedEvent()
+	cfg = w.Value().(*config)
+	cfg.Equals(t, &config{Foo: 3})
+
+	b.Delete("config")
+	<-cfg.DeletedEvent()
+	assert.False(t, w.Exists())
+}
+
+func TestMergeJSONPatches(t *testing.T) {
+	data, err := dynconf.MergeJSONPatches([][]byte{
+		[]byte(`{"a": {"b": null, "e": "f"}, "c": null}`),
+		[]byte(`{"a": {"b": 1, "d": 2}, "c": 3}`),
+	})
+	assert.NoError(t, err)
+	assert.JSONEq(t, `{"a": {"d": 2, "e": "f"}}`, string(data))
+}
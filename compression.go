@@ -0,0 +1,114 @@
+package dynconf
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"sync"
+)
+
+// DecompressorFactory is the type of the function returning a reader decompressing
+// data read from the given reader.
+type DecompressorFactory func(r io.Reader) (io.ReadCloser, error)
+
+// RegisterCompression registers the given decompressor factory for the compression
+// format with the given name, whose compressed data start with the given magic
+// number, replacing the decompressor factory previously registered for the format
+// if any. The compression format "gzip" is registered by default.
+func RegisterCompression(name string, magic []byte, decompressorFactory DecompressorFactory) {
+	compressionsMu.Lock()
+	defer compressionsMu.Unlock()
+
+	for i := range compressions {
+		if compressions[i].Name == name {
+			compressions[i] = compression{name, magic, decompressorFactory}
+			return
+		}
+	}
+
+	compressions = append(compressions, compression{name, magic, decompressorFactory})
+}
+
+// ErrUnknownCompression is returned when compressed data are not of any registered
+// compression format.
+var ErrUnknownCompression = errors.New("dynconf: unknown compression")
+
+type compression struct {
+	Name                string
+	Magic               []byte
+	DecompressorFactory DecompressorFactory
+}
+
+var (
+	compressionsMu sync.RWMutex
+	compressions   = []compression{
+		{"gzip", []byte{0x1f, 0x8b}, func(r io.Reader) (io.ReadCloser, error) { return gzip.NewReader(r) }},
+	}
+)
+
+func lookupCompression(data []byte) (compression, bool) {
+	compressionsMu.RLock()
+	defer compressionsMu.RUnlock()
+
+	for _, compression := range compressions {
+		if bytes.HasPrefix(data, compression.Magic) {
+			return compression, true
+		}
+	}
+
+	return compression{}, false
+}
+
+func (wo *watchOptions) decompressData(key string, data []byte, flags uint64) ([]byte, error) {
+	if !wo.DecompressionEnabled {
+		return data, nil
+	}
+
+	if wo.CompressionFlag != 0 && flags&wo.CompressionFlag == 0 {
+		return data, nil
+	}
+
+	compression, ok := lookupCompression(data)
+
+	if !ok {
+		if wo.CompressionFlag != 0 {
+			return nil, fmt.Errorf("%w; key=%q flags=%d", ErrUnknownCompression, key, flags)
+		}
+
+		return data, nil
+	}
+
+	data, err := decompress(compression, data, wo.MaxDataSize)
+
+	if err != nil {
+		return nil, fmt.Errorf("dynconf: data decompression failed; key=%q compression=%q: %w", key, compression.Name, err)
+	}
+
+	if err := wo.checkDataSize(key, data); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+func decompress(compression compression, data []byte, maxDataSize int) ([]byte, error) {
+	decompressor, err := compression.DecompressorFactory(bytes.NewReader(data))
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer decompressor.Close()
+	var r io.Reader = decompressor
+
+	if maxDataSize >= 1 {
+		// Read one more byte so that data exceeding the size can be detected,
+		// without ever decompressing a bomb entirely.
+		r = io.LimitReader(r, int64(maxDataSize)+1)
+	}
+
+	return ioutil.ReadAll(r)
+}
@@ -0,0 +1,73 @@
+package dynconf_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/roy2220/dynconf"
+	"github.com/roy2220/dynconf/dynconftest"
+)
+
+func TestWithDecompression(t *testing.T) {
+	wr, b := dynconftest.NewWatcher(t)
+	b.Set("hello", gzipData(t, "100"))
+	w, err := wr.AddWatch(context.Background(), "hello", dynconf.NewIntValue, dynconf.WithDecompression(0))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer w.Remove()
+
+	assert.Equal(t, int64(100), w.Value().(*dynconf.IntValue).Get())
+	assert.Equal(t, gzipData(t, "100"), w.Raw())
+
+	newValues := make(chan dynconf.Value, 2)
+	unsubscribe := w.Subscribe(func(_, newValue dynconf.Value) { newValues <- newValue })
+	defer unsubscribe()
+
+	b.Set("hello", []byte("200"))
+	assert.Equal(t, int64(200), (<-newValues).(*dynconf.IntValue).Get())
+	b.Set("hello", gzipData(t, "300"))
+	assert.Equal(t, int64(300), (<-newValues).(*dynconf.IntValue).Get())
+}
+
+func TestWithDecompressionCompressionFlag(t *testing.T) {
+	const compressionFlag = 1 << 3
+	wr, b := dynconftest.NewWatcher(t)
+	b.SetWithFlags("hello", gzipData(t, "100"), compressionFlag)
+	errs := make(chan error, 1)
+	w, err := wr.AddWatch(context.Background(), "hello", dynconf.NewIntValue,
+		dynconf.WithDecompression(compressionFlag),
+		dynconf.WithUpdateRejectedCallback(func(err error, _ []byte) { errs <- err }))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer w.Remove()
+
+	assert.Equal(t, int64(100), w.Value().(*dynconf.IntValue).Get())
+
+	b.SetWithFlags("hello", []byte("200"), compressionFlag)
+	assert.True(t, errors.Is(<-errs, dynconf.ErrUnknownCompression))
+	assert.Equal(t, int64(100), w.Value().(*dynconf.IntValue).Get())
+}
+
+func TestWithDecompressionMaxDataSize(t *testing.T) {
+	wr, b := dynconftest.NewWatcher(t)
+	b.Set("hello", gzipData(t, string(bytes.Repeat([]byte("1"), 1000))))
+	_, err := wr.AddWatch(context.Background(), "hello", dynconf.NewStringValue,
+		dynconf.WithDecompression(0), dynconf.WithMaxDataSize(100))
+	assert.True(t, errors.Is(err, dynconf.ErrDataTooLarge))
+}
+
+func gzipData(t *testing.T, s string) []byte {
+	var buffer bytes.Buffer
+	writer := gzip.NewWriter(&buffer)
+	_, err := writer.Write([]byte(s))
+	assert.NoError(t, err)
+	assert.NoError(t, writer.Close())
+	return buffer.Bytes()
+}
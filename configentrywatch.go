@@ -0,0 +1,172 @@
+package dynconf
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/consul/api"
+)
+
+// AddConfigEntryWatch adds a watch on the configuration entry of the given kind,
+// e.g. api.ServiceDefaults or api.ProxyDefaults, with the given name, and then
+// returns the watch. The entry is marshaled into JSON, from which the value is
+// unmarshaled, e.g. with JSONValueFactory creating objects of type
+// *api.ServiceConfigEntry, and the watch is set on the key "config:<kind>/<name>".
+// The watcher must be initialized with a client.
+func (w *Watcher) AddConfigEntryWatch(ctx context.Context, kind string, name string, valueFactory ValueFactory,
+	options ...WatchOption) (*Watch, error) {
+	if w.client == nil {
+		return nil, fmt.Errorf("dynconf: config entry watch failed; kind=%q name=%q: %w", kind, name, errClientRequired)
+	}
+
+	backend := configEntryBackend{
+		ConfigEntries: w.client.ConfigEntries(),
+		Kind:          kind,
+		Name:          name,
+	}
+
+	return w.doAddWatch(ctx, w.limitQueryRate(&backend), "config:"+kind+"/"+name, valueFactory, options)
+}
+
+// AddIntentionsWatch adds a watch on the intentions of Connect, and then returns
+// the watch. The value of the watch is of type *IntentionsValue, and the watch is
+// set on the key "intentions". The watcher must be initialized with a client.
+func (w *Watcher) AddIntentionsWatch(ctx context.Context, options ...WatchOption) (*IntentionsWatch, error) {
+	if w.client == nil {
+		return nil, fmt.Errorf("dynconf: intentions watch failed: %w", errClientRequired)
+	}
+
+	backend := intentionsBackend{
+		Connect: w.client.Connect(),
+	}
+
+	watch, err := w.doAddWatch(ctx, w.limitQueryRate(&backend), "intentions", NewIntentionsValue, options)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &IntentionsWatch{watch}, nil
+}
+
+// IntentionsWatch presents a watch on the intentions of Connect.
+type IntentionsWatch struct {
+	*Watch
+}
+
+// Intentions returns the latest intentions. The returned slice is read-only.
+func (iw *IntentionsWatch) Intentions() []*api.Intention {
+	return iw.Value().(*IntentionsValue).Get()
+}
+
+// NewIntentionsValue returns a new value of type *IntentionsValue, it is a ValueFactory.
+func NewIntentionsValue() Value {
+	return new(IntentionsValue)
+}
+
+// IntentionsValue presents a value holding intentions, which are in JSON.
+type IntentionsValue struct {
+	intentions []*api.Intention
+}
+
+var _ Value = (*IntentionsValue)(nil)
+
+// Unmarshal implements Value.Unmarshal.
+func (iv *IntentionsValue) Unmarshal(data []byte) error {
+	var intentions []*api.Intention
+
+	if err := json.Unmarshal(data, &intentions); err != nil {
+		return err
+	}
+
+	iv.intentions = intentions
+	return nil
+}
+
+// String implements Value.String.
+func (iv *IntentionsValue) String() string {
+	intentions := make([]string, len(iv.intentions))
+
+	for i, intention := range iv.intentions {
+		intentions[i] = intention.String()
+	}
+
+	return strings.Join(intentions, ", ")
+}
+
+// Get returns the intentions held. The returned slice is read-only.
+func (iv *IntentionsValue) Get() []*api.Intention {
+	return iv.intentions
+}
+
+type configEntryBackend struct {
+	ConfigEntries *api.ConfigEntries
+	Kind          string
+	Name          string
+}
+
+var _ Backend = (*configEntryBackend)(nil)
+
+func (ceb *configEntryBackend) Get(key string, queryOptions *api.QueryOptions) (*api.KVPair, *api.QueryMeta, error) {
+	// The entries are listed so that the index of the kind is known even if the
+	// entry doesn't exist.
+	configEntries, queryMeta, err := ceb.ConfigEntries.List(ceb.Kind, queryOptions)
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("dynconf: config entries list failed; kind=%q: %w", ceb.Kind, err)
+	}
+
+	for _, configEntry := range configEntries {
+		if configEntry.GetName() != ceb.Name {
+			continue
+		}
+
+		data, err := json.Marshal(configEntry)
+
+		if err != nil {
+			return nil, nil, fmt.Errorf("dynconf: config entry marshal failed; kind=%q name=%q: %w", ceb.Kind, ceb.Name, err)
+		}
+
+		// The modify index of the entry is never used as the wait index of the
+		// list, otherwise the query wouldn't block once another entry of the kind
+		// has been modified.
+		return &api.KVPair{Key: key, Value: data, ModifyIndex: queryMeta.LastIndex}, queryMeta, nil
+	}
+
+	return nil, queryMeta, nil
+}
+
+func (ceb *configEntryBackend) List(prefix string, _ *api.QueryOptions) (api.KVPairs, *api.QueryMeta, error) {
+	return nil, nil, fmt.Errorf("%w; prefix=%q", ErrListNotSupported, prefix)
+}
+
+type intentionsBackend struct {
+	Connect *api.Connect
+}
+
+var _ Backend = (*intentionsBackend)(nil)
+
+func (ib *intentionsBackend) Get(key string, queryOptions *api.QueryOptions) (*api.KVPair, *api.QueryMeta, error) {
+	intentions, queryMeta, err := ib.Connect.Intentions(queryOptions)
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("dynconf: intentions list failed: %w", err)
+	}
+
+	// Sort the intentions so that the data remain unchanged if the intentions do.
+	sort.Slice(intentions, func(i, j int) bool { return intentions[i].ID < intentions[j].ID })
+	data, err := json.Marshal(intentions)
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("dynconf: intentions marshal failed: %w", err)
+	}
+
+	return &api.KVPair{Key: key, Value: data, ModifyIndex: queryMeta.LastIndex}, queryMeta, nil
+}
+
+func (ib *intentionsBackend) List(prefix string, _ *api.QueryOptions) (api.KVPairs, *api.QueryMeta, error) {
+	return nil, nil, fmt.Errorf("%w; prefix=%q", ErrListNotSupported, prefix)
+}
@@ -0,0 +1,55 @@
+package dynconf_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/roy2220/dynconf"
+)
+
+func TestWatcherAddConfigEntryWatch(t *testing.T) {
+	wr, c := makeWatcher(t)
+	_, _, err := c.ConfigEntries().Set(&api.ServiceConfigEntry{
+		Kind:     api.ServiceDefaults,
+		Name:     "hello",
+		Protocol: "http",
+	}, nil)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer c.ConfigEntries().Delete(api.ServiceDefaults, "hello", nil)
+
+	w, err := wr.AddConfigEntryWatch(context.Background(), api.ServiceDefaults, "hello",
+		dynconf.JSONValueFactory(func() interface{} { return new(api.ServiceConfigEntry) }))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer w.Remove()
+
+	assert.Equal(t, "http", w.Value().(*dynconf.ObjectValue).Object().(*api.ServiceConfigEntry).Protocol)
+
+	newValues := make(chan dynconf.Value, 1)
+	unsubscribe := w.Subscribe(func(_, newValue dynconf.Value) { newValues <- newValue })
+	defer unsubscribe()
+
+	_, _, err = c.ConfigEntries().Set(&api.ServiceConfigEntry{
+		Kind:     api.ServiceDefaults,
+		Name:     "hello",
+		Protocol: "grpc",
+	}, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "grpc", (<-newValues).(*dynconf.ObjectValue).Object().(*api.ServiceConfigEntry).Protocol)
+}
+
+func TestIntentionsValue(t *testing.T) {
+	v := dynconf.NewIntentionsValue()
+	err := v.Unmarshal([]byte(`[{"ID": "1", "SourceNS": "default", "SourceName": "web",
+		"DestinationNS": "default", "DestinationName": "db", "Action": "allow"}]`))
+	if assert.NoError(t, err) {
+		assert.Len(t, v.(*dynconf.IntentionsValue).Get(), 1)
+		assert.Equal(t, "web => db (allow)", v.String())
+	}
+}
@@ -0,0 +1,211 @@
+package dynconf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+)
+
+// Coordinator presents a coordinator of instances, e.g. controllers reconciling
+// configuration, which acquires a lock on a key with a session of Consul, so that
+// only the leader, i.e. the instance holding the lock, publishes values of keys.
+// The publications of the leader are fenced by the session of the lock, i.e. they
+// fail once the leadership has been lost, even if the leader hasn't noticed yet.
+type Coordinator struct {
+	client     *api.Client
+	logger     Logger
+	lockKey    string
+	sessionTTL time.Duration
+	publisher  Publisher
+
+	mu           sync.Mutex
+	sessionID    string
+	lock         *api.Lock
+	stopRenewing chan struct{}
+	leadership   <-chan struct{}
+}
+
+// Init initializes the coordinator with the given client, the given key of the lock
+// and the given logger, which may be nil, and then returns the coordinator.
+func (c *Coordinator) Init(client *api.Client, lockKey string, logger Logger) *Coordinator {
+	if logger == nil {
+		logger = nopLogger{}
+	}
+
+	c.client = client
+	c.logger = logger
+	c.lockKey = lockKey
+	c.sessionTTL = DefaultCoordinatorSessionTTL
+	c.publisher.Init(client)
+	return c
+}
+
+// DefaultCoordinatorSessionTTL is the TTL of the sessions created by coordinators.
+// The leadership is lost once the session hasn't been renewed within the TTL, e.g.
+// due to a network partition.
+const DefaultCoordinatorSessionTTL = 15 * time.Second
+
+// Lead waits until the leadership has been acquired or the given context is done,
+// and then returns a channel which is closed once the leadership has been lost,
+// after which Lead should be called again to regain the leadership. The session
+// created for the lock is destroyed if the leadership isn't acquired. Lead shouldn't
+// be called concurrently.
+func (c *Coordinator) Lead(ctx context.Context) (<-chan struct{}, error) {
+	c.mu.Lock()
+
+	if c.leadership != nil {
+		select {
+		case <-c.leadership:
+			c.resign()
+		default:
+			c.mu.Unlock()
+			return c.leadership, nil
+		}
+	}
+
+	c.mu.Unlock()
+	writeOptions := (&api.WriteOptions{}).WithContext(ctx)
+	sessionID, _, err := c.client.Session().Create(&api.SessionEntry{
+		Name:     "dynconf-coordinator: " + c.lockKey,
+		TTL:      c.sessionTTL.String(),
+		Behavior: api.SessionBehaviorRelease,
+	}, writeOptions)
+
+	if err != nil {
+		return nil, fmt.Errorf("dynconf: session create failed; lock_key=%q: %w", c.lockKey, err)
+	}
+
+	stopRenewing := make(chan struct{})
+	go c.client.Session().RenewPeriodic(c.sessionTTL.String(), sessionID, nil, stopRenewing)
+	lock, err := c.client.LockOpts(&api.LockOptions{
+		Key:     c.lockKey,
+		Session: sessionID,
+	})
+
+	if err != nil {
+		c.destroySession(sessionID, stopRenewing)
+		return nil, fmt.Errorf("dynconf: lock failed; lock_key=%q: %w", c.lockKey, err)
+	}
+
+	leadership, err := lock.Lock(ctx.Done())
+
+	if err != nil {
+		c.destroySession(sessionID, stopRenewing)
+		return nil, fmt.Errorf("dynconf: lock failed; lock_key=%q: %w", c.lockKey, err)
+	}
+
+	if leadership == nil {
+		c.destroySession(sessionID, stopRenewing)
+		return nil, fmt.Errorf("dynconf: lock failed; lock_key=%q: %w", c.lockKey, ctx.Err())
+	}
+
+	c.mu.Lock()
+	c.sessionID = sessionID
+	c.lock = lock
+	c.stopRenewing = stopRenewing
+	c.leadership = leadership
+	c.mu.Unlock()
+	c.logger.Log(LogInfo, "dynconf_leadership_acquired", "lock_key", c.lockKey, "session_id", sessionID)
+
+	go func() {
+		<-leadership
+		c.logger.Log(LogWarn, "dynconf_leadership_lost", "lock_key", c.lockKey, "session_id", sessionID)
+	}()
+
+	return leadership, nil
+}
+
+// IsLeader returns whether the coordinator holds the leadership.
+func (c *Coordinator) IsLeader() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.leadership == nil {
+		return false
+	}
+
+	select {
+	case <-c.leadership:
+		return false
+	default:
+		return true
+	}
+}
+
+// Resign gives up the leadership if held, and destroys the session of the lock, so
+// that another instance can take over the leadership promptly. Resign is
+// idempotent.
+func (c *Coordinator) Resign() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.resign()
+}
+
+func (c *Coordinator) resign() {
+	if c.leadership == nil {
+		return
+	}
+
+	if err := c.lock.Unlock(); err != nil && !errors.Is(err, api.ErrLockNotHeld) {
+		c.logger.Log(LogWarn, "dynconf_unlock_failed", "lock_key", c.lockKey, "error", err)
+	}
+
+	c.destroySession(c.sessionID, c.stopRenewing)
+	c.sessionID = ""
+	c.lock = nil
+	c.stopRenewing = nil
+	c.leadership = nil
+}
+
+// destroySession stops renewing the given session and destroys it, so that neither
+// the session nor its lock outlives the attempt at or the end of the leadership.
+func (c *Coordinator) destroySession(sessionID string, stopRenewing chan struct{}) {
+	close(stopRenewing)
+
+	if _, err := c.client.Session().Destroy(sessionID, nil); err != nil {
+		c.logger.Log(LogWarn, "dynconf_session_destroy_failed", "lock_key", c.lockKey, "session_id", sessionID,
+			"error", err)
+	}
+}
+
+// Publish publishes the given value to the given key like Publisher.Publish, if the
+// coordinator holds the leadership, and otherwise fails with ErrNotLeader.
+func (c *Coordinator) Publish(ctx context.Context, key string, value Value, options ...WatchOption) error {
+	return c.publish(ctx, key, value, options, nil)
+}
+
+// PublishCAS publishes the given value to the given key like Publisher.PublishCAS,
+// if the coordinator holds the leadership, and otherwise fails with ErrNotLeader.
+func (c *Coordinator) PublishCAS(ctx context.Context, key string, value Value, modifyIndex uint64,
+	options ...WatchOption) error {
+	return c.publish(ctx, key, value, options, &modifyIndex)
+}
+
+func (c *Coordinator) publish(ctx context.Context, key string, value Value, options []WatchOption,
+	modifyIndex *uint64) error {
+	c.mu.Lock()
+	sessionID := c.sessionID
+	c.mu.Unlock()
+
+	if sessionID == "" {
+		return fmt.Errorf("%w; key=%q lock_key=%q", ErrNotLeader, key, c.lockKey)
+	}
+
+	return c.publisher.publish(ctx, key, value, options, modifyIndex, api.TxnOps{
+		{
+			KV: &api.KVTxnOp{
+				Verb:    api.KVCheckSession,
+				Key:     c.lockKey,
+				Session: sessionID,
+			},
+		},
+	}, ErrNotLeader)
+}
+
+// ErrNotLeader is returned when a coordinator publishes without holding the
+// leadership.
+var ErrNotLeader = errors.New("dynconf: not leader")
@@ -0,0 +1,87 @@
+package dynconf_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/roy2220/dynconf"
+)
+
+func TestCoordinator(t *testing.T) {
+	c := makeClient(t)
+	_, err := c.KV().Delete("hello20", &api.WriteOptions{})
+	assert.NoError(t, err)
+	cr1 := new(dynconf.Coordinator).Init(c, "hello20.lock", makeLogger(t))
+	cr2 := new(dynconf.Coordinator).Init(c, "hello20.lock", makeLogger(t))
+
+	err = cr1.Publish(context.Background(), "hello20", dynconf.NewCodecValue("json", &config{Foo: 1}))
+	assert.True(t, errors.Is(err, dynconf.ErrNotLeader))
+
+	leadership, err := cr1.Lead(context.Background())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.True(t, cr1.IsLeader())
+	assert.NoError(t, cr1.PublishCAS(context.Background(), "hello20", dynconf.NewCodecValue("json", &config{Foo: 1}), 0))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	_, err = cr2.Lead(ctx)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.False(t, cr2.IsLeader())
+	assert.Equal(t, 1, countCoordinatorSessions(t, c, "hello20.lock"))
+
+	errs := make(chan error, 1)
+	go func() {
+		_, err := cr2.Lead(context.Background())
+		errs <- err
+	}()
+
+	cr1.Resign()
+	assert.False(t, cr1.IsLeader())
+	<-leadership
+
+	select {
+	case err := <-errs:
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("leadership not taken over")
+	}
+	defer cr2.Resign()
+	assert.True(t, cr2.IsLeader())
+	assert.Equal(t, 1, countCoordinatorSessions(t, c, "hello20.lock"))
+	err = cr2.Publish(context.Background(), "hello20", dynconf.NewCodecValue("json", &config{Foo: 2}))
+	assert.True(t, errors.Is(err, dynconf.ErrModifyIndexUnknown))
+
+	kvPair, _, err := c.KV().Get("hello20", &api.QueryOptions{})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.NoError(t, cr2.PublishCAS(context.Background(), "hello20", dynconf.NewCodecValue("json", &config{Foo: 2}), kvPair.ModifyIndex))
+
+	kvPair, _, err = c.KV().Get("hello20", &api.QueryOptions{})
+	if assert.NoError(t, err) {
+		assert.Equal(t, `{"Foo":2,"Bar":""}`, string(kvPair.Value))
+	}
+}
+
+func countCoordinatorSessions(t *testing.T, c *api.Client, lockKey string) int {
+	sessionEntries, _, err := c.Session().List(&api.QueryOptions{})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	n := 0
+	for _, sessionEntry := range sessionEntries {
+		if sessionEntry.Name == "dynconf-coordinator: "+lockKey {
+			n++
+		}
+	}
+	return n
+}
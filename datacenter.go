@@ -0,0 +1,223 @@
+package dynconf
+
+import (
+	"sync"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+)
+
+// WithDatacenter returns an option to set the datacenter, in which the key of the
+// watch is read, overriding the datacenter set by WithQueryOptions and the default
+// one of the client.
+func WithDatacenter(datacenter string) WatchOption {
+	return func(wo *watchOptions) {
+		wo.Datacenter = datacenter
+	}
+}
+
+// WithFailoverDatacenter returns an option to fail over to the given secondary
+// datacenter once the queries of the watch to the primary datacenter have been
+// failing for longer than the given threshold. While failing over, the primary
+// datacenter is probed before each query, with the queries to the secondary
+// datacenter waiting no longer than the threshold, and the watch fails back to the
+// primary datacenter as soon as it becomes reachable.
+func WithFailoverDatacenter(datacenter string, threshold time.Duration) WatchOption {
+	return func(wo *watchOptions) {
+		wo.FailoverDatacenter = datacenter
+		wo.FailoverThreshold = threshold
+	}
+}
+
+func (wo *watchOptions) withFailover(backend Backend, logger Logger, key string) Backend {
+	if wo.FailoverDatacenter == "" {
+		return backend
+	}
+
+	return &failoverBackend{
+		Backend:    backend,
+		Logger:     logger,
+		Key:        key,
+		Datacenter: wo.FailoverDatacenter,
+		Threshold:  wo.FailoverThreshold,
+		Clock:      wo.Clock,
+	}
+}
+
+// failoverBackend presents a backend failing over to a secondary datacenter. As
+// the indexes of different datacenters are unrelated, the indexes returned are
+// offset on each switch of the datacenters, so that they never go backwards.
+type failoverBackend struct {
+	Backend    Backend
+	Logger     Logger
+	Key        string
+	Datacenter string
+	Threshold  time.Duration
+	Clock      Clock
+
+	mu           sync.Mutex
+	failingSince time.Time
+	failedOver   bool
+	secondary    bool
+	indexOffset  uint64
+	maxIndex     uint64
+}
+
+var _ Backend = (*failoverBackend)(nil)
+
+func (fb *failoverBackend) Get(key string, queryOptions *api.QueryOptions) (*api.KVPair, *api.QueryMeta, error) {
+	var kvPair *api.KVPair
+
+	queryMeta, err := fb.query(queryOptions, func(queryOptions *api.QueryOptions) (*api.QueryMeta, error) {
+		var queryMeta *api.QueryMeta
+		var err error
+		kvPair, queryMeta, err = fb.Backend.Get(key, queryOptions)
+		return queryMeta, err
+	}, func(indexOffset uint64) {
+		if kvPair != nil {
+			kvPair = offsetKVPair(kvPair, indexOffset)
+		}
+	})
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return kvPair, queryMeta, nil
+}
+
+func (fb *failoverBackend) List(prefix string, queryOptions *api.QueryOptions) (api.KVPairs, *api.QueryMeta, error) {
+	var kvPairs api.KVPairs
+
+	queryMeta, err := fb.query(queryOptions, func(queryOptions *api.QueryOptions) (*api.QueryMeta, error) {
+		var queryMeta *api.QueryMeta
+		var err error
+		kvPairs, queryMeta, err = fb.Backend.List(prefix, queryOptions)
+		return queryMeta, err
+	}, func(indexOffset uint64) {
+		for i, kvPair := range kvPairs {
+			kvPairs[i] = offsetKVPair(kvPair, indexOffset)
+		}
+	})
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return kvPairs, queryMeta, nil
+}
+
+func (fb *failoverBackend) query(queryOptions *api.QueryOptions, query query,
+	offsetResult func(indexOffset uint64)) (*api.QueryMeta, error) {
+	// While failing over, the wait index is of the secondary datacenter, so the
+	// primary datacenter is probed without blocking.
+	queryMeta, err := query(fb.makeQueryOptions(queryOptions, false))
+
+	if err == nil {
+		fb.recordSuccess()
+		return fb.offsetResult(false, queryMeta, offsetResult), nil
+	}
+
+	if queryOptions.Context().Err() != nil || !fb.recordFailure() {
+		return nil, err
+	}
+
+	secondaryQueryOptions := fb.makeQueryOptions(queryOptions, true)
+	secondaryQueryOptions.Datacenter = fb.Datacenter
+
+	if fb.Threshold > 0 && (secondaryQueryOptions.WaitTime <= 0 || secondaryQueryOptions.WaitTime > fb.Threshold) {
+		secondaryQueryOptions.WaitTime = fb.Threshold
+	}
+
+	queryMeta, err = query(secondaryQueryOptions)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return fb.offsetResult(true, queryMeta, offsetResult), nil
+}
+
+// makeQueryOptions maps the wait index of the given query options to the one of
+// the given datacenter.
+func (fb *failoverBackend) makeQueryOptions(queryOptions *api.QueryOptions, secondary bool) *api.QueryOptions {
+	fb.mu.Lock()
+	defer fb.mu.Unlock()
+	queryOptions = queryOptions.WithContext(queryOptions.Context())
+
+	if secondary != fb.secondary || queryOptions.WaitIndex <= fb.indexOffset {
+		queryOptions.WaitIndex = 0
+	} else {
+		queryOptions.WaitIndex -= fb.indexOffset
+	}
+
+	return queryOptions
+}
+
+func (fb *failoverBackend) offsetResult(secondary bool, queryMeta *api.QueryMeta,
+	offsetResult func(indexOffset uint64)) *api.QueryMeta {
+	fb.mu.Lock()
+	defer fb.mu.Unlock()
+
+	if secondary != fb.secondary {
+		fb.secondary = secondary
+		fb.indexOffset = fb.maxIndex
+	}
+
+	queryMetaCopy := *queryMeta
+	queryMetaCopy.LastIndex += fb.indexOffset
+
+	if queryMetaCopy.LastIndex > fb.maxIndex {
+		fb.maxIndex = queryMetaCopy.LastIndex
+	}
+
+	offsetResult(fb.indexOffset)
+	return &queryMetaCopy
+}
+
+func (fb *failoverBackend) recordSuccess() {
+	fb.mu.Lock()
+	defer fb.mu.Unlock()
+	fb.failingSince = time.Time{}
+
+	if fb.failedOver {
+		fb.failedOver = false
+		fb.Logger.Log(LogInfo, "dynconf_datacenter_failed_back", "key", fb.Key)
+	}
+}
+
+// recordFailure records a failure of the primary datacenter, and then returns
+// whether to fail over.
+func (fb *failoverBackend) recordFailure() bool {
+	fb.mu.Lock()
+	defer fb.mu.Unlock()
+
+	if fb.failedOver {
+		return true
+	}
+
+	now := fb.Clock.Now()
+
+	if fb.failingSince.IsZero() {
+		fb.failingSince = now
+	}
+
+	if now.Sub(fb.failingSince) < fb.Threshold {
+		return false
+	}
+
+	fb.failedOver = true
+	fb.Logger.Log(LogWarn, "dynconf_datacenter_failed_over", "key", fb.Key, "datacenter", fb.Datacenter)
+	return true
+}
+
+func offsetKVPair(kvPair *api.KVPair, indexOffset uint64) *api.KVPair {
+	if indexOffset == 0 {
+		return kvPair
+	}
+
+	kvPairCopy := *kvPair
+	kvPairCopy.CreateIndex += indexOffset
+	kvPairCopy.ModifyIndex += indexOffset
+	return &kvPairCopy
+}
@@ -0,0 +1,73 @@
+package dynconf_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/roy2220/dynconf"
+	"github.com/roy2220/dynconf/dynconftest"
+)
+
+func TestWithFailoverDatacenter(t *testing.T) {
+	b := datacenterBackend{
+		Primary:   new(dynconftest.Backend).Init(),
+		Secondary: new(dynconftest.Backend).Init(),
+	}
+	b.Primary.Set("hello", []byte("1"))
+	b.Secondary.Set("hello", []byte("2"))
+	wr := new(dynconf.Watcher).InitWithBackend(&b, dynconftest.Logger{T: t})
+	w, err := wr.AddWatch(context.Background(), "hello", dynconf.NewIntValue,
+		dynconf.WithDatacenter("dc1"),
+		dynconf.WithFailoverDatacenter("dc2", 50*time.Millisecond),
+		dynconf.WithRetryPolicy(dynconf.ExponentialBackoff{MinBackoff: 10 * time.Millisecond, MaxBackoff: 10 * time.Millisecond}))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer w.Remove()
+
+	assert.Equal(t, int64(1), w.Value().(*dynconf.IntValue).Get())
+
+	newValues := make(chan dynconf.Value, 3)
+	unsubscribe := w.Subscribe(func(_, newValue dynconf.Value) { newValues <- newValue })
+	defer unsubscribe()
+
+	atomic.StoreInt32(&b.PrimaryDown, 1)
+	b.Primary.Set("hello", []byte("3"))
+	assert.Equal(t, int64(2), (<-newValues).(*dynconf.IntValue).Get())
+
+	atomic.StoreInt32(&b.PrimaryDown, 0)
+	assert.Equal(t, int64(3), (<-newValues).(*dynconf.IntValue).Get())
+}
+
+type datacenterBackend struct {
+	Primary     *dynconftest.Backend
+	Secondary   *dynconftest.Backend
+	PrimaryDown int32
+}
+
+func (db *datacenterBackend) Get(key string, queryOptions *api.QueryOptions) (*api.KVPair, *api.QueryMeta, error) {
+	switch queryOptions.Datacenter {
+	case "dc1":
+		kvPair, queryMeta, err := db.Primary.Get(key, queryOptions)
+
+		if atomic.LoadInt32(&db.PrimaryDown) == 1 {
+			return nil, nil, errors.New("unreachable")
+		}
+
+		return kvPair, queryMeta, err
+	case "dc2":
+		return db.Secondary.Get(key, queryOptions)
+	default:
+		return nil, nil, errors.New("unknown datacenter")
+	}
+}
+
+func (db *datacenterBackend) List(prefix string, queryOptions *api.QueryOptions) (api.KVPairs, *api.QueryMeta, error) {
+	return nil, nil, dynconf.ErrListNotSupported
+}
@@ -0,0 +1,141 @@
+package dynconf
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// DebugHandler returns an HTTP handler reporting the status of the watches added
+// to the watcher in JSON, like expvar. A GET request lists the watches, and a POST
+// request with the query parameter "key" refreshes the watch on the key, if the
+// refresh is enabled.
+func (w *Watcher) DebugHandler(refreshEnabled bool) http.Handler {
+	return debugHandler{
+		Watcher:        w,
+		RefreshEnabled: refreshEnabled,
+	}
+}
+
+type debugHandler struct {
+	Watcher        *Watcher
+	RefreshEnabled bool
+}
+
+func (dh debugHandler) ServeHTTP(responseWriter http.ResponseWriter, request *http.Request) {
+	switch request.Method {
+	case http.MethodGet:
+		dh.listWatches(responseWriter)
+	case http.MethodPost:
+		if !dh.RefreshEnabled {
+			http.Error(responseWriter, "refresh disabled", http.StatusForbidden)
+			return
+		}
+
+		dh.refreshWatch(responseWriter, request)
+	default:
+		responseWriter.Header().Set("Allow", "GET, POST")
+		http.Error(responseWriter, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (dh debugHandler) listWatches(responseWriter http.ResponseWriter) {
+	var statuses struct {
+		Watches       []watchStatus       `json:"watches"`
+		PrefixWatches []prefixWatchStatus `json:"prefix_watches"`
+	}
+
+	for _, watch := range dh.Watcher.watchLoops() {
+		switch watch := watch.(type) {
+		case *Watch:
+			status := watchStatus{
+				Key:                 watch.Key(),
+				Exists:              watch.Exists(),
+				Value:               watch.valueString(watch.latestValue()),
+				ModifyIndex:         watch.ModifyIndex(),
+				LastUpdateTime:      watch.LastUpdateTime(),
+				ConsecutiveFailures: watch.ConsecutiveFailures(),
+			}
+
+			if err := watch.LastError(); err != nil {
+				status.LastError = err.Error()
+			}
+
+			if watch.options.UsageTracking {
+				usage := watch.Usage()
+				status.Usage = &usage
+			}
+
+			statuses.Watches = append(statuses.Watches, status)
+		case *PrefixWatch:
+			status := prefixWatchStatus{
+				Prefix: watch.Prefix(),
+				Values: make(map[string]string),
+			}
+
+			for key, value := range watch.Values() {
+				status.Values[key] = watch.valueString(key, value)
+			}
+
+			statuses.PrefixWatches = append(statuses.PrefixWatches, status)
+		}
+	}
+
+	sort.Slice(statuses.Watches, func(i, j int) bool { return statuses.Watches[i].Key < statuses.Watches[j].Key })
+	sort.Slice(statuses.PrefixWatches, func(i, j int) bool {
+		return statuses.PrefixWatches[i].Prefix < statuses.PrefixWatches[j].Prefix
+	})
+
+	responseWriter.Header().Set("Content-Type", "application/json")
+	encoder := json.NewEncoder(responseWriter)
+	encoder.SetIndent("", "  ")
+	encoder.Encode(&statuses)
+}
+
+func (dh debugHandler) refreshWatch(responseWriter http.ResponseWriter, request *http.Request) {
+	key := request.URL.Query().Get("key")
+	var watches []*Watch
+
+	for _, watch := range dh.Watcher.watchLoops() {
+		if watch, ok := watch.(*Watch); ok && watch.Key() == key {
+			watches = append(watches, watch)
+		}
+	}
+
+	if len(watches) == 0 {
+		http.Error(responseWriter, "watch not found", http.StatusNotFound)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(request.Context(), debugRefreshTimeout)
+	defer cancel()
+
+	for _, watch := range watches {
+		if err := watch.Refresh(ctx); err != nil {
+			http.Error(responseWriter, err.Error(), http.StatusBadGateway)
+			return
+		}
+	}
+
+	responseWriter.WriteHeader(http.StatusNoContent)
+}
+
+type watchStatus struct {
+	Key                 string    `json:"key"`
+	Exists              bool      `json:"exists"`
+	Value               string    `json:"value"`
+	ModifyIndex         uint64    `json:"modify_index"`
+	LastUpdateTime      time.Time `json:"last_update_time"`
+	LastError           string    `json:"last_error,omitempty"`
+	ConsecutiveFailures int       `json:"consecutive_failures"`
+	Usage               *Usage    `json:"usage,omitempty"`
+}
+
+type prefixWatchStatus struct {
+	Prefix string            `json:"prefix"`
+	Values map[string]string `json:"values"`
+}
+
+const debugRefreshTimeout = 10 * time.Second
@@ -0,0 +1,71 @@
+package dynconf_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/roy2220/dynconf"
+	"github.com/roy2220/dynconf/dynconftest"
+)
+
+func TestWatcherDebugHandler(t *testing.T) {
+	wr, b := dynconftest.NewWatcher(t)
+	b.Set("hello", []byte("1"))
+	b.Set("world/a", []byte("2"))
+	w, err := wr.AddWatch(context.Background(), "hello", dynconf.NewIntValue)
+	if assert.NoError(t, err) {
+		defer w.Remove()
+	}
+
+	pw, err := wr.AddPrefixWatch(context.Background(), "world/", dynconf.NewIntValue, dynconf.PrefixWatchCallbacks{})
+	if assert.NoError(t, err) {
+		defer pw.Remove()
+	}
+
+	server := httptest.NewServer(wr.DebugHandler(true))
+	defer server.Close()
+
+	response, err := http.Get(server.URL)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer response.Body.Close()
+
+	var statuses struct {
+		Watches []struct {
+			Key         string `json:"key"`
+			Value       string `json:"value"`
+			ModifyIndex uint64 `json:"modify_index"`
+		} `json:"watches"`
+		PrefixWatches []struct {
+			Prefix string            `json:"prefix"`
+			Values map[string]string `json:"values"`
+		} `json:"prefix_watches"`
+	}
+	assert.NoError(t, json.NewDecoder(response.Body).Decode(&statuses))
+	if assert.Len(t, statuses.Watches, 1) {
+		assert.Equal(t, "hello", statuses.Watches[0].Key)
+		assert.Equal(t, "1", statuses.Watches[0].Value)
+		assert.Equal(t, w.ModifyIndex(), statuses.Watches[0].ModifyIndex)
+	}
+	if assert.Len(t, statuses.PrefixWatches, 1) {
+		assert.Equal(t, map[string]string{"world/a": "2"}, statuses.PrefixWatches[0].Values)
+	}
+
+	response2, err := http.Post(server.URL+"?key=hello", "", nil)
+	if assert.NoError(t, err) {
+		response2.Body.Close()
+		assert.Equal(t, http.StatusNoContent, response2.StatusCode)
+	}
+
+	response3, err := http.Post(server.URL+"?key=unknown", "", nil)
+	if assert.NoError(t, err) {
+		response3.Body.Close()
+		assert.Equal(t, http.StatusNotFound, response3.StatusCode)
+	}
+}
@@ -0,0 +1,39 @@
+package dynconf
+
+import (
+	"context"
+	"time"
+)
+
+// WithDependencies returns an option to make the watch depend on the given
+// watches, e.g. a watch on rules depending on a watch on the schema referenced by
+// the rules. Before a new value of the watch is applied, the dependencies are
+// refreshed, so that the updates of the dependencies which have arrived no later
+// than the update of the watch are applied first. The dependencies are waited for
+// no longer than 10s, after which the new value is applied anyway. As dependencies
+// must be added before, dependencies can't be circular.
+func WithDependencies(dependencies ...*Watch) WatchOption {
+	return func(wo *watchOptions) {
+		wo.Dependencies = dependencies
+	}
+}
+
+func (w *Watch) waitForDependencies() {
+	if len(w.options.Dependencies) == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(w.ctx)
+	defer cancel()
+	timer := w.options.Clock.AfterFunc(dependencyWaitTimeout, cancel)
+	defer timer.Stop()
+
+	for _, dependency := range w.options.Dependencies {
+		if err := dependency.Refresh(ctx); err != nil {
+			w.logger.Log(LogWarn, "dynconf_dependency_wait_failed",
+				"key", w.key, "dependency_key", dependency.Key(), "error", err)
+		}
+	}
+}
+
+const dependencyWaitTimeout = 10 * time.Second
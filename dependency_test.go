@@ -0,0 +1,94 @@
+package dynconf_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/roy2220/dynconf"
+	"github.com/roy2220/dynconf/dynconftest"
+)
+
+func TestWithDependencies(t *testing.T) {
+	b := new(dynconftest.Backend).Init()
+	b.Set("schema", []byte("1"))
+	b.Set("rules", []byte("1"))
+	wr := dynconf.NewWatcherWithBackend(b, dynconf.WithLogger(dynconftest.Logger{T: t}))
+	schemaWatch, err := dynconf.NewWatcherWithBackend(&slowBackend{b}).AddWatch(context.Background(), "schema",
+		dynconf.NewIntValue)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer schemaWatch.Remove()
+	rulesWatch, err := wr.AddWatch(context.Background(), "rules", dynconf.NewIntValue,
+		dynconf.WithDependencies(schemaWatch))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer rulesWatch.Remove()
+
+	schemaValues := make(chan string, 1)
+	unsubscribe := rulesWatch.Subscribe(func(_, _ dynconf.Value) { schemaValues <- schemaWatch.Value().String() })
+	defer unsubscribe()
+
+	b.Set("schema", []byte("2"))
+	b.Set("rules", []byte("2"))
+	assert.Equal(t, "2", <-schemaValues)
+}
+
+func TestWithDependenciesTimeout(t *testing.T) {
+	clock := dynconftest.NewClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	b := new(dynconftest.Backend).Init()
+	b.Set("schema", []byte("1"))
+	b.Set("rules", []byte("1"))
+	wr := dynconf.NewWatcherWithBackend(b, dynconf.WithLogger(dynconftest.Logger{T: t}), dynconf.WithClock(clock))
+	b2 := &stallingBackend{Backend: b, Release: make(chan struct{})}
+	schemaWatch, err := dynconf.NewWatcherWithBackend(b2).AddWatch(context.Background(), "schema", dynconf.NewIntValue)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer schemaWatch.Remove()
+	rulesWatch, err := wr.AddWatch(context.Background(), "rules", dynconf.NewIntValue,
+		dynconf.WithDependencies(schemaWatch))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer rulesWatch.Remove()
+
+	atomic.StoreInt32(&b2.Stall, 1)
+	events := rulesWatch.Events()
+	b.Set("rules", []byte("2"))
+	// the refresh of the dependency stalls until the wait times out
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&b2.NumberOfStalls) == 1 }, time.Second, time.Millisecond)
+	clock.Advance(10 * time.Second)
+
+	select {
+	case <-events:
+		assert.Equal(t, int64(2), rulesWatch.Value().(*dynconf.IntValue).Get())
+	case <-time.After(time.Second):
+		t.Fatal("update not applied")
+	}
+	close(b2.Release)
+}
+
+type slowBackend struct {
+	*dynconftest.Backend
+}
+
+func (sb *slowBackend) Get(key string, queryOptions *api.QueryOptions) (*api.KVPair, *api.QueryMeta, error) {
+	kvPair, queryMeta, err := sb.Backend.Get(key, queryOptions)
+	if err != nil || queryOptions.WaitIndex == 0 {
+		return kvPair, queryMeta, err
+	}
+
+	select {
+	case <-time.After(200 * time.Millisecond):
+		return kvPair, queryMeta, nil
+	case <-queryOptions.Context().Done():
+		return nil, nil, queryOptions.Context().Err()
+	}
+}
@@ -0,0 +1,58 @@
+package dynconf
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// WithDeprecation returns an option to mark the key of the watch deprecated in
+// favor of the given replacement key. Reads of the value with Watch.Value, and the
+// accessors built on it, and updates of the value are logged, reads at most once a
+// minute, and counted with the counters "dynconf_deprecated_key_reads" and
+// "dynconf_deprecated_key_updates". If the given dual-read flag is true, the watch
+// reads the replacement key and falls back to the deprecated key while the
+// replacement key is missing, so that the data can be migrated to the replacement
+// key before the code is. It takes effect only for watches on keys.
+func WithDeprecation(replacementKey string, dualRead bool) WatchOption {
+	return func(wo *watchOptions) {
+		wo.ReplacementKey = replacementKey
+		wo.DualRead = dualRead
+	}
+}
+
+func (wo *watchOptions) withDeprecation(backend Backend) Backend {
+	if wo.ReplacementKey == "" || !wo.DualRead {
+		return backend
+	}
+
+	layers := keyLayers(backend, []string{wo.ReplacementKey})
+	return new(CompositeBackend).Init(append(layers, Layer{Backend: backend}), nil)
+}
+
+func (w *Watch) recordDeprecatedRead() {
+	if w.options.ReplacementKey == "" {
+		return
+	}
+
+	w.options.Metrics.IncrCounter("dynconf_deprecated_key_reads", "key", w.key)
+	now := w.options.Clock.Now().UnixNano()
+	lastLogTime := atomic.LoadInt64(&w.lastDeprecationLogTime)
+
+	if now-lastLogTime < int64(deprecationLogInterval) ||
+		!atomic.CompareAndSwapInt64(&w.lastDeprecationLogTime, lastLogTime, now) {
+		return
+	}
+
+	w.logger.Log(LogWarn, "dynconf_deprecated_key_read", "key", w.key, "replacement_key", w.options.ReplacementKey)
+}
+
+func (w *Watch) recordDeprecatedUpdate() {
+	if w.options.ReplacementKey == "" {
+		return
+	}
+
+	w.options.Metrics.IncrCounter("dynconf_deprecated_key_updates", "key", w.key)
+	w.logger.Log(LogWarn, "dynconf_deprecated_key_updated", "key", w.key, "replacement_key", w.options.ReplacementKey)
+}
+
+const deprecationLogInterval = time.Minute
@@ -0,0 +1,48 @@
+package dynconf_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/roy2220/dynconf"
+	"github.com/roy2220/dynconf/dynconftest"
+)
+
+func TestWithDeprecation(t *testing.T) {
+	b := new(dynconftest.Backend).Init()
+	b.Set("old", []byte("1"))
+	m := new(counters)
+	wr := dynconf.NewWatcherWithBackend(b, dynconf.WithLogger(dynconftest.Logger{T: t}), dynconf.WithMetrics(m))
+
+	w, err := wr.AddWatch(context.Background(), "old", dynconf.NewIntValue, dynconf.WithDeprecation("new", false))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer w.Remove()
+	assert.Equal(t, int64(1), w.Value().(*dynconf.IntValue).Get())
+	assert.Equal(t, 1, m.Get("dynconf_deprecated_key_reads", "key", "old"))
+
+	events := w.Events()
+	b.Set("old", []byte("2"))
+	<-events
+	assert.Equal(t, 1, m.Get("dynconf_deprecated_key_updates", "key", "old"))
+
+	w2, err := wr.AddWatch(context.Background(), "old", dynconf.NewIntValue, dynconf.WithDeprecation("new", true))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer w2.Remove()
+	assert.Equal(t, int64(2), w2.Value().(*dynconf.IntValue).Get())
+
+	events = w2.Events()
+	b.Set("new", []byte("3"))
+	<-events
+	assert.Equal(t, int64(3), w2.Value().(*dynconf.IntValue).Get())
+
+	b.Set("old", []byte("4"))
+	b.Delete("new")
+	<-events
+	assert.Equal(t, int64(4), w2.Value().(*dynconf.IntValue).Get())
+}
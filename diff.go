@@ -0,0 +1,158 @@
+package dynconf
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// WithDiff returns an option to compute the structural differences between the
+// JSON data of the old value and the new value on each update of the watch, which
+// are logged, with the values of changes omitted if the watch is redacting, and
+// are included in UpdatedEvent. The differences are not computed if either data
+// aren't in JSON.
+func WithDiff() WatchOption {
+	return func(wo *watchOptions) {
+		wo.DiffEnabled = true
+	}
+}
+
+// JSONChange represents a change of JSON data.
+type JSONChange struct {
+	// Path is the JSON pointer (RFC 6901) to the value changed.
+	Path string `json:"path"`
+
+	// OldValue is the old value, or nil if the value has been added.
+	OldValue interface{} `json:"old_value,omitempty"`
+
+	// NewValue is the new value, or nil if the value has been removed.
+	NewValue interface{} `json:"new_value,omitempty"`
+}
+
+// DiffJSON compares the given old JSON data and the given new JSON data, and then
+// returns the changes, in the order of the paths. Objects are compared key by key
+// recursively, and arrays are compared element by element recursively.
+func DiffJSON(oldData []byte, newData []byte) ([]JSONChange, error) {
+	oldObject, err := decodeJSON(oldData)
+
+	if err != nil {
+		return nil, err
+	}
+
+	newObject, err := decodeJSON(newData)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var changes []JSONChange
+	diffJSONObjects("", oldObject, newObject, &changes)
+	return changes, nil
+}
+
+func decodeJSON(data []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var object interface{}
+
+	if err := decoder.Decode(&object); err != nil {
+		return nil, err
+	}
+
+	return object, nil
+}
+
+func diffJSONObjects(path string, object1 interface{}, object2 interface{}, changes *[]JSONChange) {
+	switch value1 := object1.(type) {
+	case map[string]interface{}:
+		if value2, ok := object2.(map[string]interface{}); ok {
+			keys := make([]string, 0, len(value1)+len(value2))
+
+			for key := range value1 {
+				keys = append(keys, key)
+			}
+
+			for key := range value2 {
+				if _, ok := value1[key]; !ok {
+					keys = append(keys, key)
+				}
+			}
+
+			sort.Strings(keys)
+
+			for _, key := range keys {
+				diffJSONObjects(path+"/"+escapeJSONPointerToken(key), value1[key], value2[key], changes)
+			}
+
+			return
+		}
+	case []interface{}:
+		if value2, ok := object2.([]interface{}); ok {
+			n := len(value1)
+
+			if len(value2) > n {
+				n = len(value2)
+			}
+
+			for i := 0; i < n; i++ {
+				var element1, element2 interface{}
+
+				if i < len(value1) {
+					element1 = value1[i]
+				}
+
+				if i < len(value2) {
+					element2 = value2[i]
+				}
+
+				diffJSONObjects(path+"/"+strconv.Itoa(i), element1, element2, changes)
+			}
+
+			return
+		}
+	}
+
+	if !reflect.DeepEqual(object1, object2) {
+		*changes = append(*changes, JSONChange{path, object1, object2})
+	}
+}
+
+var jsonPointerTokenEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+func escapeJSONPointerToken(token string) string {
+	return jsonPointerTokenEscaper.Replace(token)
+}
+
+func (w *Watch) diff(oldData []byte, newData []byte) ([]JSONChange, string) {
+	if !w.options.DiffEnabled {
+		return nil, ""
+	}
+
+	changes, err := DiffJSON(oldData, newData)
+
+	if err != nil {
+		w.logger.Log(LogDebug, "dynconf_diff_failed", "key", w.key, "error", err)
+		return nil, ""
+	}
+
+	if w.redacting {
+		paths := make([]string, len(changes))
+
+		for i := range changes {
+			paths[i] = changes[i].Path
+		}
+
+		return changes, strings.Join(paths, ",")
+	}
+
+	diff, err := json.Marshal(changes)
+
+	if err != nil {
+		return changes, ""
+	}
+
+	return changes, string(diff)
+}
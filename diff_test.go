@@ -0,0 +1,48 @@
+package dynconf_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/roy2220/dynconf"
+	"github.com/roy2220/dynconf/dynconftest"
+)
+
+func TestDiffJSON(t *testing.T) {
+	changes, err := dynconf.DiffJSON(
+		[]byte(`{"a": 1, "b": {"c": [1, 2], "d/e": "x"}, "f": true}`),
+		[]byte(`{"a": 1, "b": {"c": [1, 3, 4], "d/e": "y"}, "g": null, "h": {}}`))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, []dynconf.JSONChange{
+		{Path: "/b/c/1", OldValue: json.Number("2"), NewValue: json.Number("3")},
+		{Path: "/b/c/2", NewValue: json.Number("4")},
+		{Path: "/b/d~1e", OldValue: "x", NewValue: "y"},
+		{Path: "/f", OldValue: true},
+		{Path: "/h", NewValue: map[string]interface{}{}},
+	}, changes)
+
+	_, err = dynconf.DiffJSON([]byte(`{`), []byte(`{}`))
+	assert.Error(t, err)
+}
+
+func TestWithDiff(t *testing.T) {
+	wr, b := dynconftest.NewWatcher(t)
+	b.Set("hello", []byte(`{"level": "info", "size": 1}`))
+	w, err := wr.AddWatch(context.Background(), "hello", dynconf.JSONValueFactory(func() interface{} {
+		return new(map[string]interface{})
+	}), dynconf.WithDiff())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer w.Remove()
+
+	events := w.Events()
+	b.Set("hello", []byte(`{"level": "debug", "size": 1}`))
+	e := (<-events).(*dynconf.UpdatedEvent)
+	assert.Equal(t, []dynconf.JSONChange{{Path: "/level", OldValue: "info", NewValue: "debug"}}, e.Changes)
+}
@@ -0,0 +1,83 @@
+package dynconf
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+)
+
+type diskCache struct {
+	Dir string
+}
+
+type diskCacheEntry struct {
+	Data  []byte
+	Index uint64
+	Flags uint64
+}
+
+func (dc diskCache) Load(key string) (diskCacheEntry, error) {
+	rawEntry, err := ioutil.ReadFile(dc.filePath(key))
+
+	if err != nil {
+		return diskCacheEntry{}, err
+	}
+
+	var entry diskCacheEntry
+
+	if err := json.Unmarshal(rawEntry, &entry); err != nil {
+		return diskCacheEntry{}, err
+	}
+
+	return entry, nil
+}
+
+func (dc diskCache) Save(key string, entry diskCacheEntry) error {
+	rawEntry, err := json.Marshal(entry)
+
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(dc.Dir, 0755); err != nil {
+		return err
+	}
+
+	file, err := ioutil.TempFile(dc.Dir, ".tmp-")
+
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(file.Name())
+
+	if _, err := file.Write(rawEntry); err != nil {
+		file.Close()
+		return err
+	}
+
+	if err := file.Sync(); err != nil {
+		file.Close()
+		return err
+	}
+
+	if err := file.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(file.Name(), dc.filePath(key))
+}
+
+func (dc diskCache) Delete(key string) error {
+	if err := os.Remove(dc.filePath(key)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	return nil
+}
+
+func (dc diskCache) filePath(key string) string {
+	return filepath.Join(dc.Dir, url.PathEscape(key)+".json")
+}
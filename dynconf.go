@@ -2,63 +2,442 @@
 package dynconf
 
 import (
+	"bytes"
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 	"sync"
 	"sync/atomic"
+	"time"
 
 	"github.com/hashicorp/consul/api"
-	"github.com/rs/zerolog"
 )
 
 // Watcher presents a watcher for dynamic configuration.
 type Watcher struct {
-	client *api.Client
-	logger *zerolog.Logger
+	client           *api.Client
+	backend          Backend
+	logger           Logger
+	defaultOptions   []WatchOption
+	queryRateLimiter *queryRateLimiter
+	keyContext       KeyContext
+
+	healthFailureThreshold int
+
+	mu       sync.Mutex
+	watches  map[watchLoop]struct{}
+	isClosed bool
+
+	sharedWatchesMu sync.Mutex
+	sharedWatches   map[sharedWatchKey]*sharedWatch
+
+	localOverrides        localOverrides
+	localOverridesMu      sync.Mutex
+	fileLocalOverrideKeys map[string]struct{}
+
+	freezer freezer
+}
+
+// NewWatcher creates a watcher, which watches keys in the KV store of Consul with
+// the given client, with the given options, and then returns the watcher.
+func NewWatcher(client *api.Client, options ...WatcherOption) *Watcher {
+	w := new(Watcher).Init(client, nil)
+	w.applyOptions(options)
+	return w
+}
+
+// NewWatcherWithBackend creates a watcher, which watches keys in the given backend
+// rather than the KV store of Consul, with the given options, and then returns the
+// watcher.
+func NewWatcherWithBackend(backend Backend, options ...WatcherOption) *Watcher {
+	w := new(Watcher).InitWithBackend(backend, nil)
+	w.applyOptions(options)
+	return w
 }
 
-// Init initialize the watcher and then returns the watcher.
-func (w *Watcher) Init(client *api.Client, logger *zerolog.Logger) *Watcher {
+// Init initialize the watcher and then returns the watcher. The logger defaults to
+// a no-op logger if nil. It is kept for compatibility, and NewWatcher is preferred.
+func (w *Watcher) Init(client *api.Client, logger Logger) *Watcher {
 	w.client = client
+	w.backend = client.KV()
 	w.logger = logger
+
+	if w.logger == nil {
+		w.logger = nopLogger{}
+	}
+
+	return w
+}
+
+// InitWithBackend initialize the watcher, which watches keys in the given backend
+// rather than the KV store of Consul, and then returns the watcher. The logger
+// defaults to a no-op logger if nil. It is kept for compatibility, and
+// NewWatcherWithBackend is preferred.
+func (w *Watcher) InitWithBackend(backend Backend, logger Logger) *Watcher {
+	w.backend = backend
+	w.logger = logger
+
+	if w.logger == nil {
+		w.logger = nopLogger{}
+	}
+
+	return w
+}
+
+func (w *Watcher) applyOptions(options []WatcherOption) {
+	for _, option := range options {
+		option.applyToWatcher(w)
+	}
+}
+
+// SetDefaultWatchOptions adds the given options to the defaults for watches added
+// to the watcher, e.g. WithWaitTime, WithAllowStale and WithUseCache to reduce the
+// load on the Consul servers, which are overridden by the options given to each
+// watch, and then returns the watcher. It should be called before adding watches.
+func (w *Watcher) SetDefaultWatchOptions(options ...WatchOption) *Watcher {
+	w.defaultOptions = append(w.defaultOptions, options...)
 	return w
 }
 
 // AddWatch adds a watch on the given key and then returns the watch.
-func (w *Watcher) AddWatch(ctx context.Context, key string, valueFactory ValueFactory) (*Watch, error) {
+func (w *Watcher) AddWatch(ctx context.Context, key string, valueFactory ValueFactory, options ...WatchOption) (*Watch, error) {
+	return w.doAddWatch(ctx, w.backend, key, valueFactory, options)
+}
+
+func (w *Watcher) doAddWatch(ctx context.Context, backend Backend, key string, valueFactory ValueFactory,
+	options []WatchOption) (*Watch, error) {
 	watch := Watch{
-		client:       w.client,
+		watcher:      w,
 		logger:       w.logger,
 		key:          key,
 		valueFactory: valueFactory,
+		redacting:    isRedactor(valueFactory),
+		synced:       make(chan struct{}),
+	}
+
+	watch.options.Init(w.watchOptions(options))
+	watch.redacting = watch.redacting || watch.options.Decrypter != nil
+	watch.apply = watch.makeApplyFunc()
+	backend = watch.options.withDeprecation(backend)
+	backend = watch.options.withReferences(backend, w.backend)
+	watch.backend = &overrideBackend{
+		Backend:   watch.options.withFailover(backend, w.logger, key),
+		Overrides: &w.localOverrides,
+		Clock:     watch.options.Clock,
 	}
 
 	if err := watch.populateValue(ctx); err != nil {
 		return nil, err
 	}
 
-	watch.add()
+	if err := w.addWatch(&watch); err != nil {
+		return nil, err
+	}
+
 	return &watch, nil
 }
 
+// AddLayeredWatch adds a watch on the given keys, the data of which are merged
+// with the given merge function, which defaults to MergeJSON, and then returns the
+// watch. The keys are in the order of precedence, i.e. the key with the highest
+// precedence is the first, and the watch is set on the keys joined with ",". The
+// value is re-evaluated once any of the keys has been changed.
+func (w *Watcher) AddLayeredWatch(ctx context.Context, keys []string, valueFactory ValueFactory, merge MergeFunc,
+	options ...WatchOption) (*Watch, error) {
+	if merge == nil {
+		merge = MergeJSON
+	}
+
+	backend := new(CompositeBackend).Init(keyLayers(w.backend, keys), merge)
+	return w.doAddWatch(ctx, backend, strings.Join(keys, ","), valueFactory, options)
+}
+
+// AddAliasedWatch adds a watch on the first existing key of the given candidate
+// keys, e.g. a new key followed by the legacy keys, and then returns the watch. The
+// watch is set on the keys joined with ",". The watch switches between the keys
+// once any of the keys has been changed, so that a key can be migrated without
+// downtime by creating the new key before deleting the legacy key.
+func (w *Watcher) AddAliasedWatch(ctx context.Context, keys []string, valueFactory ValueFactory,
+	options ...WatchOption) (*Watch, error) {
+	return w.AddLayeredWatch(ctx, keys, valueFactory, takeFirst, options...)
+}
+
+// keyLayers returns the layers of the given backend mapping any key to the given
+// keys respectively.
+func keyLayers(backend Backend, keys []string) []Layer {
+	layers := make([]Layer, len(keys))
+
+	for i := range keys {
+		layerKey := keys[i]
+		layers[i] = Layer{
+			Backend: backend,
+			MapKey:  func(string) string { return layerKey },
+		}
+	}
+
+	return layers
+}
+
+// AddPatchedWatch adds a watch on the given base key, with the data of the given
+// patch key, if any, applied as a JSON merge patch (RFC 7386) on top of the data of
+// the base key, and then returns the watch. The value is re-evaluated once either
+// key has been changed.
+func (w *Watcher) AddPatchedWatch(ctx context.Context, baseKey string, patchKey string, valueFactory ValueFactory,
+	options ...WatchOption) (*Watch, error) {
+	return w.AddLayeredWatch(ctx, []string{patchKey, baseKey}, valueFactory, MergeJSONPatches, options...)
+}
+
+// AddWatchWithDefault adds a watch on the given key and then returns the watch.
+// Unlike AddWatch, a missing key does not fail but makes the watch start with
+// the default value unmarshaled from the given data, until the key is created.
+func (w *Watcher) AddWatchWithDefault(ctx context.Context, key string, valueFactory ValueFactory, defaultData []byte,
+	options ...WatchOption) (*Watch, error) {
+	options = append(options[:len(options):len(options)], WithDefaultValue(defaultData))
+	return w.AddWatch(ctx, key, valueFactory, options...)
+}
+
+// WatchSpec represents a specification of a watch for AddWatches.
+type WatchSpec struct {
+	Key          string
+	ValueFactory ValueFactory
+	Options      []WatchOption
+}
+
+// AddWatches adds watches as specified by the given specifications concurrently,
+// and then returns the watches in the order of the specifications. If any watch
+// fails to be added, the watches added are removed, and then the error of the
+// first failed specification is returned.
+func (w *Watcher) AddWatches(ctx context.Context, specs []WatchSpec) ([]*Watch, error) {
+	watches := make([]*Watch, len(specs))
+	errs := make([]error, len(specs))
+	var wg sync.WaitGroup
+
+	for i := range specs {
+		i := i
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+			spec := &specs[i]
+			watches[i], errs[i] = w.AddWatch(ctx, spec.Key, spec.ValueFactory, spec.Options...)
+		}()
+	}
+
+	wg.Wait()
+
+	for _, err := range errs {
+		if err == nil {
+			continue
+		}
+
+		for _, watch := range watches {
+			if watch != nil {
+				watch.Remove()
+			}
+		}
+
+		return nil, err
+	}
+
+	return watches, nil
+}
+
+// Close removes all the watches added to the watcher and waits for them to stop
+// until the given context is done. Adding watches to a closed watcher fails with
+// ErrWatcherClosed.
+func (w *Watcher) Close(ctx context.Context) error {
+	w.mu.Lock()
+	w.isClosed = true
+	watches := w.watches
+	w.watches = nil
+	w.mu.Unlock()
+
+	for watch := range watches {
+		watch.stop()
+	}
+
+	done := make(chan struct{})
+
+	go func() {
+		for watch := range watches {
+			watch.wait()
+		}
+
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("dynconf: watcher close failed: %w", ctx.Err())
+	}
+}
+
+// WaitUntilSynced waits until all the watches added to the watcher have been
+// synced, or the given context is done.
+func (w *Watcher) WaitUntilSynced(ctx context.Context) error {
+	for _, watch := range w.watchLoops() {
+		select {
+		case <-watch.Synced():
+		case <-ctx.Done():
+			return fmt.Errorf("dynconf: wait until synced failed: %w", ctx.Err())
+		}
+	}
+
+	return nil
+}
+
+func (w *Watcher) addWatch(watch watchLoop) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.isClosed {
+		return ErrWatcherClosed
+	}
+
+	if w.watches == nil {
+		w.watches = make(map[watchLoop]struct{})
+	}
+
+	w.watches[watch] = struct{}{}
+	watch.add()
+	return nil
+}
+
+func (w *Watcher) watchLoops() []watchLoop {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	watches := make([]watchLoop, 0, len(w.watches))
+
+	for watch := range w.watches {
+		watches = append(watches, watch)
+	}
+
+	return watches
+}
+
+func (w *Watcher) watchOptions(options []WatchOption) []WatchOption {
+	if len(w.defaultOptions) == 0 {
+		return options
+	}
+
+	return append(append([]WatchOption(nil), w.defaultOptions...), options...)
+}
+
+func (w *Watcher) removeWatch(watch watchLoop) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	delete(w.watches, watch)
+}
+
+func (w *Watcher) removeWatchContext(ctx context.Context, watch watchLoop) error {
+	w.removeWatch(watch)
+	watch.stop()
+	done := make(chan struct{})
+
+	go func() {
+		watch.wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+type watchLoop interface {
+	Synced() <-chan struct{}
+
+	add()
+	stop()
+	wait()
+}
+
 // Watch presents a watch on a key.
 type Watch struct {
-	client       *api.Client
-	logger       *zerolog.Logger
+	numberOfRejectedUpdates uint64 // accessed atomically, kept 64-bit aligned
+	numberOfRecoveredPanics uint64 // accessed atomically, kept 64-bit aligned
+	lastDeprecationLogTime  int64  // accessed atomically, kept 64-bit aligned
+	usage                   usage
+
+	watcher      *Watcher
+	backend      Backend
+	logger       Logger
 	key          string
 	valueFactory ValueFactory
+	redacting    bool
+	options      watchOptions
 	value        atomic.Value
+	raw          atomic.Value
 	valueIndex   uint64
+	data         []byte
+	valueData    []byte
+	existence    int32
+	synced       chan struct{}
+	syncOnce     sync.Once
 	ctx          context.Context
 	cancel       context.CancelFunc
 	wg           sync.WaitGroup
+
+	mu               sync.Mutex
+	subscribers      map[uint64]Subscriber
+	lastSubscriberID uint64
+	events           chan Event
+	eventsClosed     bool
+
+	refreshMu      sync.Mutex
+	refreshResults []chan error
+	cancelQuery    context.CancelFunc
+
+	dataRejected        bool
+	statusMu            sync.Mutex
+	lastError           error
+	consecutiveFailures int
+	lastUpdateTime      time.Time
+	lastContactTime     time.Time
+	stale               bool
+	stalenessTimer      Timer
+	scheduleTimer       Timer
+	scheduledUpdate     int32
+	ttlTimer            Timer
+	ttlExpiry           int32
+	auditedHash         string
+	statusChanged       chan struct{}
+	freezer             freezer
+	apply               ApplyFunc
+	history             valueHistory
+}
+
+type rawData struct {
+	Data        []byte
+	ModifyIndex uint64
+	Flags       uint64
 }
 
-// Remove removes the watch.
+// Subscriber is the type of the function called once after the latest value,
+// as the old value, has been replaced with another value, as the new value.
+type Subscriber func(oldValue Value, newValue Value)
+
+// Remove removes the watch and waits for it to stop. Remove is idempotent.
 func (w *Watch) Remove() {
-	w.cancel()
-	w.wg.Wait()
+	w.watcher.removeWatch(w)
+	w.stop()
+	w.wait()
+}
+
+// RemoveContext removes the watch and waits for it to stop until the given context
+// is done, e.g. if the in-flight query is wedged. RemoveContext is idempotent.
+func (w *Watch) RemoveContext(ctx context.Context) error {
+	if err := w.watcher.removeWatchContext(ctx, w); err != nil {
+		return fmt.Errorf("dynconf: watch remove failed; key=%q: %w", w.key, err)
+	}
+
+	return nil
 }
 
 // Key returns the key on which the watch is set.
@@ -68,120 +447,695 @@ func (w *Watch) Key() string {
 
 // Value returns the latest value of the key on which the watch is set.
 func (w *Watch) Value() Value {
+	w.recordRead()
+	w.recordDeprecatedRead()
+	return w.latestValue()
+}
+
+func (w *Watch) latestValue() Value {
 	return w.value.Load().(Value)
 }
 
-func (w *Watch) populateValue(ctx context.Context) error {
-	queryOptions := (&api.QueryOptions{}).WithContext(w.ctx)
-	kvPair, _, err := w.client.KV().Get(w.key, queryOptions)
+// Raw returns the data of the latest value of the key on which the watch is set,
+// which may be the data of the default value. The returned data are read-only.
+func (w *Watch) Raw() []byte {
+	return w.raw.Load().(rawData).Data
+}
+
+// ModifyIndex returns the modify index of the key on which the watch is set, for
+// the latest value, or 0 for the default value.
+func (w *Watch) ModifyIndex() uint64 {
+	return w.raw.Load().(rawData).ModifyIndex
+}
+
+// Flags returns the flags of the key on which the watch is set, for the latest
+// value, or 0 for the default value.
+func (w *Watch) Flags() uint64 {
+	return w.raw.Load().(rawData).Flags
+}
+
+// Exists returns whether the key on which the watch is set exists currently.
+func (w *Watch) Exists() bool {
+	return atomic.LoadInt32(&w.existence) == 1
+}
+
+// Synced returns a channel which is closed once the watch has been synced, that is
+// the watch has fetched the value of the key, or has learned the key is missing,
+// from Consul at least once. A watch is not synced if the value has been loaded
+// from the disk cache, until Consul becomes reachable.
+func (w *Watch) Synced() <-chan struct{} {
+	return w.synced
+}
+
+// NumberOfRejectedUpdates returns the number of new values of the key on which
+// the watch is set, which have been rejected due to a failure of validation or
+// unmarshaling.
+func (w *Watch) NumberOfRejectedUpdates() uint64 {
+	return atomic.LoadUint64(&w.numberOfRejectedUpdates)
+}
+
+// LastError returns the error of the last failure of the watch, such as a failure
+// of a query or a rejected update, or nil if the watch has succeeded since then.
+func (w *Watch) LastError() error {
+	w.statusMu.Lock()
+	defer w.statusMu.Unlock()
+	return w.lastError
+}
+
+// ConsecutiveFailures returns the number of the failures of the watch since the
+// last success.
+func (w *Watch) ConsecutiveFailures() int {
+	w.statusMu.Lock()
+	defer w.statusMu.Unlock()
+	return w.consecutiveFailures
+}
+
+// LastUpdateTime returns the time when the latest value of the key on which the
+// watch is set has been set.
+func (w *Watch) LastUpdateTime() time.Time {
+	w.statusMu.Lock()
+	defer w.statusMu.Unlock()
+	return w.lastUpdateTime
+}
+
+// Subscribe adds the given subscriber to the watch and then returns a function
+// to remove the subscriber. Subscribers are called in an unspecified order.
+func (w *Watch) Subscribe(subscriber Subscriber) func() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.subscribers == nil {
+		w.subscribers = make(map[uint64]Subscriber)
+	}
+
+	w.lastSubscriberID++
+	subscriberID := w.lastSubscriberID
+	w.subscribers[subscriberID] = subscriber
+
+	return func() {
+		w.mu.Lock()
+		defer w.mu.Unlock()
+		delete(w.subscribers, subscriberID)
+	}
+}
+
+func (w *Watch) populateValue(ctx context.Context) (err error) {
+	ctx, span := w.options.Tracer.StartSpan(ctx, "dynconf.populate_value", "key", w.key)
+	defer func() { endSpan(span, err) }()
+	queryOptions := w.options.makeQueryOptions(ctx, w.options.QueryOptions)
+	kvPair, queryMeta, err := w.backend.Get(w.key, queryOptions)
 
 	if err != nil {
-		return fmt.Errorf("dynconf: kv get failed; key=%q: %w", w.key, err)
+		err = fmt.Errorf("dynconf: kv get failed; key=%q: %w", w.key, err)
+
+		if w.options.DiskCacheDir == "" {
+			return err
+		}
+
+		return w.populateValueFromDiskCache(err)
+	}
+
+	w.recordContact()
+
+	if kvPair != nil && w.options.ScheduleEnabled {
+		inEffect, err := w.checkSchedule(kvPair.Value, kvPair.Flags)
+
+		if err != nil {
+			return err
+		}
+
+		if !inEffect {
+			if !w.options.HasDefaultValue {
+				return fmt.Errorf("%w; key=%q", ErrNotInEffect, w.key)
+			}
+
+			// Start with the default value and a zero index, so that the first
+			// query of the watch doesn't block and the data get scheduled.
+			kvPair, queryMeta = nil, &api.QueryMeta{}
+		}
 	}
 
 	if kvPair == nil {
-		return fmt.Errorf("%w; key=%q", ErrKeyNotFound, w.key)
+		if !w.options.HasDefaultValue {
+			return fmt.Errorf("%w; key=%q", ErrKeyNotFound, w.key)
+		}
+
+		value, err := w.newValue(w.options.DefaultData)
+
+		if err != nil {
+			return fmt.Errorf("dynconf: default value unmarshal failed; key=%q data=%q: %w", w.key, w.redactData(w.options.DefaultData), err)
+		}
+
+		if err := w.validateValue(value); err != nil {
+			return fmt.Errorf("dynconf: default value validation failed; key=%q data=%q: %w", w.key, w.redactData(w.options.DefaultData), err)
+		}
+
+		w.setValue(value)
+		w.valueIndex = queryMeta.LastIndex
+		w.setData(w.options.DefaultData, w.options.DefaultData, 0, 0)
+		w.markSynced()
+		return nil
 	}
 
-	value := w.valueFactory()
+	value, data, err := w.makeValue(kvPair.Value, kvPair.Flags)
 
-	if err := value.Unmarshal(kvPair.Value); err != nil {
-		return fmt.Errorf("dynconf: value unmarshal failed; key=%q data=%q: %w", w.key, kvPair.Value, err)
+	if err != nil {
+		return err
 	}
 
 	w.setValue(value)
 	w.valueIndex = kvPair.ModifyIndex
+	w.setData(kvPair.Value, data, kvPair.ModifyIndex, kvPair.Flags)
+	w.existence = 1
+	w.markSynced()
+	w.saveToDiskCache(kvPair.Value, kvPair.ModifyIndex, kvPair.Flags)
+	return nil
+}
+
+func (w *Watch) populateValueFromDiskCache(getErr error) error {
+	entry, err := diskCache{w.options.DiskCacheDir}.Load(w.key)
+
+	if err != nil {
+		w.logger.Log(LogWarn, "dynconf_disk_cache_load_failed", "key", w.key, "error", err)
+		return getErr
+	}
+
+	value, data, err := w.makeValue(entry.Data, entry.Flags)
+
+	if err != nil {
+		return err
+	}
+
+	w.logger.Log(LogWarn, "dynconf_value_loaded_from_disk_cache",
+		"key", w.key, "new_value", w.valueString(value), "error", getErr)
+	w.setValue(value)
+	w.recordFailure(getErr)
+	w.valueIndex = entry.Index
+	w.setData(entry.Data, data, entry.Index, entry.Flags)
+	w.existence = 1
 	return nil
 }
 
+func (w *Watch) makeValue(data []byte, flags uint64) (Value, []byte, error) {
+	data, err := w.options.decodeData(w.key, data, flags)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	value, err := w.makeDecodedValue(data)
+	return value, data, err
+}
+
+// makeDecodedValue makes a value from the given data, which have been decoded.
+func (w *Watch) makeDecodedValue(data []byte) (Value, error) {
+	if err := w.options.validateData(data); err != nil {
+		return nil, fmt.Errorf("dynconf: data validation failed; key=%q data=%q: %w", w.key, w.redactData(data), err)
+	}
+
+	value, err := w.newValue(data)
+
+	if err != nil {
+		return nil, fmt.Errorf("dynconf: value unmarshal failed; key=%q data=%q: %w", w.key, w.redactData(data), err)
+	}
+
+	if err := w.validateValue(value); err != nil {
+		return nil, fmt.Errorf("dynconf: value validation failed; key=%q data=%q: %w", w.key, w.redactData(data), err)
+	}
+
+	return value, nil
+}
+
+func (w *Watch) markSynced() {
+	w.syncOnce.Do(func() { close(w.synced) })
+}
+
+func (w *Watch) isSynced() bool {
+	select {
+	case <-w.synced:
+		return true
+	default:
+		return false
+	}
+}
+
+// saveToDiskCache saves the given data as received, e.g. encrypted, in the disk
+// cache, which are decoded again when loaded.
+func (w *Watch) saveToDiskCache(data []byte, index uint64, flags uint64) {
+	if w.options.DiskCacheDir == "" {
+		return
+	}
+
+	entry := diskCacheEntry{
+		Data:  data,
+		Index: index,
+		Flags: flags,
+	}
+
+	if err := (diskCache{w.options.DiskCacheDir}).Save(w.key, entry); err != nil {
+		w.logger.Log(LogWarn, "dynconf_disk_cache_save_failed", "key", w.key, "error", err)
+	}
+}
+
+func (w *Watch) deleteFromDiskCache() {
+	if w.options.DiskCacheDir == "" {
+		return
+	}
+
+	if err := (diskCache{w.options.DiskCacheDir}).Delete(w.key); err != nil {
+		w.logger.Log(LogWarn, "dynconf_disk_cache_delete_failed", "key", w.key, "error", err)
+	}
+}
+
 func (w *Watch) add() {
 	w.ctx, w.cancel = context.WithCancel(context.Background())
 	w.wg.Add(1)
 
+	w.startStalenessTimer()
+	w.startStatusReporting()
+	w.audit()
+
+	if w.Exists() {
+		w.resetTTLTimer()
+	}
+
 	go func() {
 		w.keepValueUpToDate()
 		defer w.wg.Done()
 	}()
 }
 
+func (w *Watch) stop() {
+	w.cancel()
+}
+
+func (w *Watch) wait() {
+	w.wg.Wait()
+}
+
 func (w *Watch) keepValueUpToDate() {
 	retry := retry{
-		BackoffJitter: 0.5,
+		RetryPolicy: w.options.RetryPolicy,
+		Clock:       w.options.Clock,
 	}
 
 	for {
-		queryOptions := (&api.QueryOptions{
-			WaitIndex: w.valueIndex,
-		}).WithContext(w.ctx)
+		queryOptions := w.options.QueryOptions
+		queryCtx, cancelQuery, refreshResults := w.beginQuery()
+
+		scheduled := w.takeScheduledUpdate()
+		ttlExpired := w.takeTTLExpiry()
+
+		if w.isSynced() && len(refreshResults) == 0 && !scheduled && !ttlExpired {
+			queryOptions.WaitIndex = w.valueIndex
+			queryOptions.WaitTime = w.waitTime(queryOptions.WaitTime)
+		}
 
-		var kvPair *api.KVPair
+		var (
+			kvPair      *api.KVPair
+			queryMeta   *api.QueryMeta
+			interrupted bool
+		)
 
-		if _, err := retry.Do(w.ctx, func() bool {
+		_, err := retry.Do(w.ctx, func() bool {
 			var err error
-			kvPair, _, err = w.client.KV().Get(w.key, queryOptions)
+			kvPair, queryMeta, err = w.backend.Get(w.key, w.options.makeQueryOptions(queryCtx, queryOptions))
 
 			if err != nil {
-				w.logger.Warn().
-					Err(err).
-					Str("key", w.key).
-					Msg("dynconf_kv_get_failed")
-				return false
-			}
+				if queryCtx.Err() != nil && w.ctx.Err() == nil {
+					// The query has been interrupted by a refresh.
+					interrupted = true
+					return true
+				}
 
-			if kvPair == nil {
-				w.logger.Error().
-					Str("key", w.key).
-					Msg("dynconf_key_not_found")
+				w.logger.Log(LogWarn, "dynconf_kv_get_failed", "key", w.key, "error", err)
+				err = fmt.Errorf("dynconf: kv get failed; key=%q: %w", w.key, err)
+				w.recordFailure(err)
+				w.options.Metrics.IncrCounter("dynconf_backend_errors", "key", w.key)
+
+				if w.ctx.Err() == nil {
+					w.emitEvent(&BackendErrorEvent{Err: err})
+				}
+
+				endRefreshes(refreshResults, err)
+				refreshResults = nil
 				return false
 			}
 
+			w.recordContact()
 			return true
-		}); err != nil {
-			w.logger.Info().
-				Str("key", w.key).
-				Msg("dynconf_watch_removed")
+		})
+
+		cancelQuery()
 
-			if callback, ok := w.Value().(ValueWatchRemovedCallback); ok {
-				callback.OnWatchRemoved()
+		if err != nil {
+			w.stopStalenessTimer()
+			w.stopScheduleTimer()
+			w.stopTTLTimer()
+			w.logger.Log(LogInfo, "dynconf_watch_removed", "key", w.key)
+
+			if callback, ok := w.latestValue().(ValueWatchRemovedCallback); ok {
+				w.call("on_watch_removed", callback.OnWatchRemoved)
 			}
 
+			w.emitEvent(&WatchRemovedEvent{})
+			w.closeEvents()
 			return
 		}
 
-		if kvPair.ModifyIndex == w.valueIndex {
+		if interrupted {
+			w.resumeQuery(refreshResults, scheduled, ttlExpired)
 			continue
 		}
 
-		newValue := w.valueFactory()
+		if queryOptions.WaitIndex != 0 && queryMeta.LastIndex != queryOptions.WaitIndex {
+			kvPair, queryMeta = w.debounce(kvPair, queryMeta)
+		}
+
+		w.markSynced()
 
-		if err := newValue.Unmarshal(kvPair.Value); err == nil {
-			w.logger.Info().
-				Str("key", w.key).
-				Str("new_value", newValue.String()).
-				Msg("dynconf_value_updated")
-			oldValue := w.Value()
-			w.setValue(newValue)
+		if !w.dataRejected {
+			w.recordSuccess()
+		}
 
-			if callback, ok := oldValue.(ValueOutdatedCallback); ok {
-				callback.OnOutdated()
+		if w.IsFrozen() && w.hasUpdate(kvPair, scheduled, ttlExpired) {
+			w.holdUpdate(scheduled, ttlExpired, refreshResults)
+			continue
+		}
+
+		var index uint64
+
+		if kvPair == nil {
+			index = queryMeta.LastIndex
+
+			if w.Exists() {
+				w.handleKeyDeletion(index)
 			}
+		} else if kvPair.ModifyIndex != w.valueIndex || !w.Exists() || scheduled {
+			w.updateValue(kvPair, scheduled)
+			index = kvPair.ModifyIndex
 		} else {
-			w.logger.Err(err).
-				Str("key", w.key).
-				Bytes("data", kvPair.Value).
-				Msg("dynconf_value_unmarshal_failed")
+			if ttlExpired {
+				w.expireTTL(w.valueIndex)
+			}
+
+			index = w.valueIndex
+		}
+
+		if index < w.valueIndex {
+			index = 0
+		}
+
+		w.valueIndex = index
+		w.endRefreshes(refreshResults)
+	}
+}
+
+func (w *Watch) debounce(kvPair *api.KVPair, queryMeta *api.QueryMeta) (*api.KVPair, *api.QueryMeta) {
+	if w.options.DebounceQuietPeriod <= 0 {
+		return kvPair, queryMeta
+	}
+
+	for {
+		queryOptions := w.options.QueryOptions
+		queryOptions.WaitIndex = queryMeta.LastIndex
+		queryOptions.WaitTime = w.options.DebounceQuietPeriod
+		newKVPair, newQueryMeta, err := w.backend.Get(w.key, w.options.makeQueryOptions(w.ctx, queryOptions))
+
+		if err != nil {
+			w.logger.Log(LogWarn, "dynconf_kv_get_failed", "key", w.key, "error", err)
+			return kvPair, queryMeta
+		}
+
+		if newQueryMeta.LastIndex == queryMeta.LastIndex {
+			return kvPair, queryMeta
+		}
+
+		w.logger.Log(LogDebug, "dynconf_change_debounced", "key", w.key, "index", newQueryMeta.LastIndex)
+		kvPair, queryMeta = newKVPair, newQueryMeta
+	}
+}
+
+// updateValue updates the value with the given key-value pair, and the data are
+// applied again even if unchanged if the given scheduled flag is true.
+func (w *Watch) updateValue(kvPair *api.KVPair, scheduled bool) {
+	_, span := w.options.Tracer.StartSpan(w.ctx, "dynconf.update_value",
+		"key", w.key, "modify_index", kvPair.ModifyIndex)
+	defer span.End()
+	atomic.StoreInt32(&w.existence, 1)
+
+	if bytes.Equal(kvPair.Value, w.data) && !scheduled {
+		w.logger.Log(LogDebug, "dynconf_value_unchanged", "key", w.key)
+		w.dataRejected = false
+		w.recordSuccess()
+		w.resetTTLTimer()
+		return
+	}
+
+	w.waitForDependencies()
+	data, err := w.options.decodeData(w.key, kvPair.Value, kvPair.Flags)
+
+	if err != nil {
+		w.logger.Log(LogError, "dynconf_data_decoding_failed", "key", w.key, "error", err)
+		span.RecordError(err)
+		w.rejectUpdate(err, kvPair.Value)
+		return
+	}
+
+	if err := w.options.validateData(data); err != nil {
+		w.logger.Log(LogError, "dynconf_data_validation_failed", "key", w.key, "data", w.redactData(data), "error", err)
+		span.RecordError(err)
+		w.rejectUpdate(fmt.Errorf("dynconf: data validation failed; key=%q: %w", w.key, err), kvPair.Value)
+		return
+	}
+
+	if err := w.checkChangeLimit(data); err != nil {
+		w.logger.Log(LogError, "dynconf_change_limit_exceeded", "key", w.key, "data", w.redactData(data), "error", err)
+		span.RecordError(err)
+		w.rejectUpdate(err, kvPair.Value)
+		return
+	}
+
+	if w.options.ScheduleEnabled {
+		inEffect, err := w.applySchedule(data, kvPair.ModifyIndex, span)
+
+		if err != nil {
+			w.logger.Log(LogError, "dynconf_schedule_parse_failed", "key", w.key, "error", err)
+			span.RecordError(err)
+			w.rejectUpdate(fmt.Errorf("dynconf: schedule parse failed; key=%q: %w", w.key, err), kvPair.Value)
+			return
 		}
 
-		if kvPair.ModifyIndex < w.valueIndex {
-			kvPair.ModifyIndex = 0
+		if !inEffect {
+			return
 		}
+	}
+
+	newValue, err := w.newValue(data)
+
+	if err != nil {
+		w.logger.Log(LogError, "dynconf_value_unmarshal_failed", "key", w.key, "data", w.redactData(data), "error", err)
+		span.RecordError(err)
+		w.rejectUpdate(fmt.Errorf("dynconf: value unmarshal failed; key=%q: %w", w.key, err), kvPair.Value)
+		return
+	}
+
+	span.AddEvent("dynconf.value_unmarshaled")
+
+	if err := w.validateValue(newValue); err != nil {
+		w.logger.Log(LogError, "dynconf_value_validation_failed", "key", w.key, "data", w.redactData(data), "error", err)
+		span.RecordError(err)
+		w.rejectUpdate(fmt.Errorf("dynconf: value validation failed; key=%q: %w", w.key, err), kvPair.Value)
+		return
+	}
+
+	update := Update{
+		Key:         w.key,
+		ModifyIndex: kvPair.ModifyIndex,
+		Data:        data,
+		OldValue:    w.latestValue(),
+		NewValue:    newValue,
+		raw:         kvPair.Value,
+		flags:       kvPair.Flags,
+	}
+
+	if err := w.passUpdate(&update); err != nil {
+		w.logger.Log(LogError, "dynconf_update_vetoed", "key", w.key, "data", w.redactData(data), "error", err)
+		span.RecordError(err)
+		w.rejectUpdate(err, kvPair.Value)
+		return
+	}
+
+	if update.replaced {
+		span.AddEvent("dynconf.value_applied")
+	}
+}
+
+func (w *Watch) applyUpdate(update *Update) {
+	oldData := w.valueData
+	w.setData(update.raw, update.Data, update.ModifyIndex, update.flags)
+	w.dataRejected = false
+	w.recordSuccess()
+	w.resetTTLTimer()
+
+	if valuesEqual(w.latestValue(), update.NewValue) {
+		w.logger.Log(LogDebug, "dynconf_value_unchanged", "key", w.key)
+		w.saveToDiskCache(update.raw, update.ModifyIndex, update.flags)
+		return
+	}
+
+	w.recordDeprecatedUpdate()
+	changes, diff := w.diff(oldData, update.Data)
+	keysAndValues := []interface{}{"key", w.key, "new_value", w.valueString(update.NewValue)}
 
-		w.valueIndex = kvPair.ModifyIndex
+	if diff != "" {
+		keysAndValues = append(keysAndValues, "diff", diff)
 	}
+
+	w.logger.Log(LogInfo, "dynconf_value_updated", keysAndValues...)
+	w.replaceValue(update.NewValue, update.ModifyIndex, changes)
+	update.replaced = true
+	w.saveToDiskCache(update.raw, update.ModifyIndex, update.flags)
+}
+
+func (w *Watch) rejectUpdate(err error, data []byte) {
+	atomic.AddUint64(&w.numberOfRejectedUpdates, 1)
+	w.dataRejected = true
+	w.recordFailure(err)
+
+	if callback, ok := w.latestValue().(ValueUpdateRejectedCallback); ok {
+		w.call("on_update_rejected", func() { callback.OnUpdateRejected(err, data) })
+	}
+
+	if callback := w.options.UpdateRejectedCallback; callback != nil {
+		w.call("update_rejected_callback", func() { callback(err, data) })
+	}
+
+	w.options.Metrics.IncrCounter("dynconf_update_rejections", "key", w.key)
+	w.notifyStatusChanged()
+	w.emitEvent(&UpdateRejectedEvent{Err: err, Data: data})
+}
+
+func (w *Watch) handleKeyDeletion(index uint64) {
+	_, span := w.options.Tracer.StartSpan(w.ctx, "dynconf.handle_key_deletion", "key", w.key)
+	defer span.End()
+	atomic.StoreInt32(&w.existence, 0)
+	w.stopScheduleTimer()
+	w.stopTTLTimer()
+	w.logger.Log(LogWarn, "dynconf_key_deleted", "key", w.key)
+	w.dataRejected = false
+	w.recordSuccess()
+	w.deleteFromDiskCache()
+	oldValue := w.latestValue()
+
+	if callback, ok := oldValue.(ValueDeletedCallback); ok {
+		w.call("on_deleted", callback.OnDeleted)
+	}
+
+	w.emitEvent(&KeyDeletedEvent{Index: index})
+	w.revertToDefaultValue(index, span)
+}
+
+func (w *Watch) revertToDefaultValue(index uint64, span Span) {
+	if !w.options.HasDefaultValue || bytes.Equal(w.data, w.options.DefaultData) {
+		return
+	}
+
+	oldValue := w.latestValue()
+	newValue, err := w.newValue(w.options.DefaultData)
+
+	if err != nil {
+		w.logger.Log(LogError, "dynconf_default_value_unmarshal_failed",
+			"key", w.key, "data", w.redactData(w.options.DefaultData), "error", err)
+		span.RecordError(err)
+		w.dataRejected = true
+		w.recordFailure(fmt.Errorf("dynconf: default value unmarshal failed; key=%q: %w", w.key, err))
+		return
+	}
+
+	if err := w.validateValue(newValue); err != nil {
+		w.logger.Log(LogError, "dynconf_default_value_validation_failed",
+			"key", w.key, "data", w.redactData(w.options.DefaultData), "error", err)
+		span.RecordError(err)
+		w.dataRejected = true
+		w.recordFailure(fmt.Errorf("dynconf: default value validation failed; key=%q: %w", w.key, err))
+		return
+	}
+
+	w.setData(w.options.DefaultData, w.options.DefaultData, 0, 0)
+
+	if valuesEqual(oldValue, newValue) {
+		return
+	}
+
+	w.logger.Log(LogInfo, "dynconf_value_reverted_to_default", "key", w.key, "new_value", w.valueString(newValue))
+	w.replaceValue(newValue, index, nil)
+	span.AddEvent("dynconf.value_applied")
+}
+
+func (w *Watch) replaceValue(newValue Value, index uint64, changes []JSONChange) {
+	oldValue := w.latestValue()
+	w.setValue(newValue)
+
+	if callback, ok := oldValue.(ValueOutdatedCallback); ok {
+		w.call("on_outdated", callback.OnOutdated)
+	}
+
+	w.notifySubscribers(oldValue, newValue)
+	w.audit()
+	w.options.Metrics.IncrCounter("dynconf_value_updates", "key", w.key)
+	w.emitEvent(&UpdatedEvent{OldValue: oldValue, NewValue: newValue, Index: index, Changes: changes})
 }
 
 func (w *Watch) setValue(value Value) {
 	w.value.Store(value)
+	w.statusMu.Lock()
+	w.lastUpdateTime = w.options.Clock.Now()
+	w.statusMu.Unlock()
+}
+
+// setData sets the given raw data and the given data, decoded from the raw data,
+// of the latest value.
+func (w *Watch) setData(raw []byte, data []byte, modifyIndex uint64, flags uint64) {
+	w.data = raw
+	w.valueData = data
+	w.raw.Store(rawData{raw, modifyIndex, flags})
+	w.recordHistory(raw, modifyIndex, flags)
+	w.notifyStatusChanged()
+}
+
+func (w *Watch) recordSuccess() {
+	w.statusMu.Lock()
+	w.lastError = nil
+	w.consecutiveFailures = 0
+	w.statusMu.Unlock()
+}
+
+func (w *Watch) recordFailure(err error) {
+	w.statusMu.Lock()
+	w.lastError = err
+	w.consecutiveFailures++
+	w.statusMu.Unlock()
+}
+
+func valuesEqual(oldValue Value, newValue Value) bool {
+	equaler, ok := oldValue.(ValueEqualer)
+	return ok && equaler.Equal(newValue)
+}
+
+func (w *Watch) notifySubscribers(oldValue Value, newValue Value) {
+	w.mu.Lock()
+	subscribers := make([]Subscriber, 0, len(w.subscribers))
+
+	for _, subscriber := range w.subscribers {
+		subscribers = append(subscribers, subscriber)
+	}
+
+	w.mu.Unlock()
+
+	for _, subscriber := range subscribers {
+		w.call("subscriber", func() { subscriber(oldValue, newValue) })
+	}
 }
 
 // ValueFactory is the type of the function returning a new value.
@@ -196,6 +1150,34 @@ type Value interface {
 	String() string
 }
 
+// ValueValidator represents an optional validator to Value.
+type ValueValidator interface {
+	// Validate validates the value after it has been unmarshaled, the value
+	// is rejected if an error is returned.
+	Validate() (err error)
+}
+
+// ValueMarshaler represents an optional marshaler to Value.
+type ValueMarshaler interface {
+	// Marshal marshals the value into data.
+	Marshal() (data []byte, err error)
+}
+
+// StrictUnmarshaler represents an optional strict unmarshaler to Value, which is
+// used instead of Value.Unmarshal for watches added with WithStrictUnmarshal.
+type StrictUnmarshaler interface {
+	// UnmarshalStrict unmarshals the value from the given data, and fails if the
+	// data have fields unknown to the value.
+	UnmarshalStrict(data []byte) (err error)
+}
+
+// ValueEqualer represents an optional equality comparer to Value.
+type ValueEqualer interface {
+	// Equal reports whether the value equals the given value, the latest value
+	// is kept if the new value equals it.
+	Equal(other Value) (ok bool)
+}
+
 // ValueOutdatedCallback represents an optional callback to Value.
 type ValueOutdatedCallback interface {
 	// OnOutdated is called once after the value, as the latest value,
@@ -203,6 +1185,21 @@ type ValueOutdatedCallback interface {
 	OnOutdated()
 }
 
+// ValueDeletedCallback represents an optional callback to Value.
+type ValueDeletedCallback interface {
+	// OnDeleted is called once after the key for the value, as the latest value,
+	// has been deleted.
+	OnDeleted()
+}
+
+// ValueUpdateRejectedCallback represents an optional callback to Value.
+type ValueUpdateRejectedCallback interface {
+	// OnUpdateRejected is called once after the given data of a new value, for the
+	// key for the value as the latest value, have been rejected due to the given
+	// error of validation or unmarshaling, in which case the value is kept.
+	OnUpdateRejected(err error, data []byte)
+}
+
 // ValueWatchRemovedCallback represents an optional callback to Value.
 type ValueWatchRemovedCallback interface {
 	// OnWatchRemoved is called once after the watch has been removed,
@@ -210,5 +1207,17 @@ type ValueWatchRemovedCallback interface {
 	OnWatchRemoved()
 }
 
-// ErrKeyNotFound is returned when a key has not been found.
-var ErrKeyNotFound = errors.New("dynconf: key not found")
+var (
+	// ErrKeyNotFound is returned when a key has not been found.
+	ErrKeyNotFound = errors.New("dynconf: key not found")
+
+	// ErrWatcherClosed is returned when a watch is added to a closed watcher.
+	ErrWatcherClosed = errors.New("dynconf: watcher closed")
+
+	// ErrWatchRemoved is returned when an operation is made on a removed watch.
+	ErrWatchRemoved = errors.New("dynconf: watch removed")
+
+	// ErrDataTooLarge is returned when the data of a value exceed the maximum size
+	// set by WithMaxDataSize.
+	ErrDataTooLarge = errors.New("dynconf: data too large")
+)
@@ -4,15 +4,19 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"io/ioutil"
 	"os"
+	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
 	"github.com/hashicorp/consul/api"
-	"github.com/rs/zerolog"
 	"github.com/stretchr/testify/assert"
 
 	"github.com/roy2220/dynconf"
+	"github.com/roy2220/dynconf/dynconftest"
 )
 
 func TestWatcherAddWatcher(t *testing.T) {
@@ -83,7 +87,7 @@ func TestWatchValue(t *testing.T) {
 	}, &api.WriteOptions{})
 	assert.NoError(t, err)
 
-	time.Sleep(1 * time.Second)
+	assert.Error(t, <-cfg.UpdateRejectedEvent())
 	select {
 	case <-cfg.OutdatedEvent():
 		assert.Fail(t, "unreachable")
@@ -99,7 +103,7 @@ func TestWatchValue(t *testing.T) {
 	_, err = c.KV().Delete("hello2", &api.WriteOptions{})
 	assert.NoError(t, err)
 
-	time.Sleep(1 * time.Second)
+	<-cfg.DeletedEvent()
 	select {
 	case <-cfg.OutdatedEvent():
 		assert.Fail(t, "unreachable")
@@ -127,6 +131,191 @@ func TestWatchValue(t *testing.T) {
 	})
 }
 
+func TestWatchDeletion(t *testing.T) {
+	wr, c := makeWatcher(t)
+	_, err := c.KV().Put(&api.KVPair{
+		Key:   "hello8",
+		Value: []byte(`{"Foo": 1}`),
+	}, &api.WriteOptions{})
+	assert.NoError(t, err)
+	w, err := wr.AddWatch(context.Background(), "hello8", newValue, dynconf.WithDefaultValue([]byte(`{"Foo": -1}`)))
+	if assert.NoError(t, err) {
+		defer w.Remove()
+	}
+
+	assert.True(t, w.Exists())
+	cfg := w.Value().(*config)
+
+	_, err = c.KV().Delete("hello8", &api.WriteOptions{})
+	assert.NoError(t, err)
+
+	<-cfg.DeletedEvent()
+	<-cfg.OutdatedEvent()
+	assert.False(t, w.Exists())
+	cfg = w.Value().(*config)
+	cfg.Equals(t, &config{Foo: -1})
+
+	_, err = c.KV().Put(&api.KVPair{
+		Key:   "hello8",
+		Value: []byte(`{"Foo": 2}`),
+	}, &api.WriteOptions{})
+	assert.NoError(t, err)
+
+	<-cfg.OutdatedEvent()
+	assert.True(t, w.Exists())
+	w.Value().(*config).Equals(t, &config{Foo: 2})
+}
+
+func TestWatcherAddWatchWithDefault(t *testing.T) {
+	wr, c := makeWatcher(t)
+	_, err := wr.AddWatchWithDefault(context.Background(), "hello9", newValue, []byte(`bad json`))
+	assert.EqualError(t, err, "dynconf: default value unmarshal failed; key=\"hello9\" data=\"bad json\": invalid character 'b' looking for beginning of value")
+
+	w, err := wr.AddWatchWithDefault(context.Background(), "hello9", newValue, []byte(`{"Foo": -1}`))
+	if assert.NoError(t, err) {
+		defer w.Remove()
+	}
+
+	assert.False(t, w.Exists())
+	cfg := w.Value().(*config)
+	cfg.Equals(t, &config{Foo: -1})
+
+	_, err = c.KV().Put(&api.KVPair{
+		Key:   "hello9",
+		Value: []byte(`{"Foo": 1}`),
+	}, &api.WriteOptions{})
+	assert.NoError(t, err)
+
+	<-cfg.OutdatedEvent()
+	assert.True(t, w.Exists())
+	w.Value().(*config).Equals(t, &config{Foo: 1})
+}
+
+func TestWatchValidation(t *testing.T) {
+	wr, c := makeWatcher(t)
+	validator := func(value dynconf.Value) error {
+		if value.(*config).Foo < 0 {
+			return errors.New("negative foo")
+		}
+
+		return nil
+	}
+	_, err := c.KV().Put(&api.KVPair{
+		Key:   "hello11",
+		Value: []byte(`{"Foo": -1}`),
+	}, &api.WriteOptions{})
+	assert.NoError(t, err)
+	_, err = wr.AddWatch(context.Background(), "hello11", newValue, dynconf.WithValidator(validator))
+	assert.EqualError(t, err, "dynconf: value validation failed; key=\"hello11\" data=\"{\\\"Foo\\\": -1}\": negative foo")
+
+	_, err = c.KV().Put(&api.KVPair{
+		Key:   "hello11",
+		Value: []byte(`{"Foo": 1}`),
+	}, &api.WriteOptions{})
+	assert.NoError(t, err)
+	errs := make(chan error, 1)
+	w, err := wr.AddWatch(context.Background(), "hello11", newValue,
+		dynconf.WithValidator(validator),
+		dynconf.WithUpdateRejectedCallback(func(err error, data []byte) {
+			assert.Equal(t, `{"Foo": -2}`, string(data))
+			errs <- err
+		}),
+	)
+	if assert.NoError(t, err) {
+		defer w.Remove()
+	}
+
+	_, err = c.KV().Put(&api.KVPair{
+		Key:   "hello11",
+		Value: []byte(`{"Foo": -2}`),
+	}, &api.WriteOptions{})
+	assert.NoError(t, err)
+
+	assert.EqualError(t, <-errs, "dynconf: value validation failed; key=\"hello11\": negative foo")
+	assert.EqualError(t, <-w.Value().(*config).UpdateRejectedEvent(), "dynconf: value validation failed; key=\"hello11\": negative foo")
+	w.Value().(*config).Equals(t, &config{Foo: 1})
+	assert.Equal(t, 1, w.ConsecutiveFailures())
+	assert.EqualError(t, w.LastError(), "dynconf: value validation failed; key=\"hello11\": negative foo")
+	lastUpdateTime := w.LastUpdateTime()
+
+	cfg := w.Value().(*config)
+	_, err = c.KV().Put(&api.KVPair{
+		Key:   "hello11",
+		Value: []byte(`{"Foo": 2}`),
+	}, &api.WriteOptions{})
+	assert.NoError(t, err)
+
+	<-cfg.OutdatedEvent()
+	assert.Equal(t, 0, w.ConsecutiveFailures())
+	assert.NoError(t, w.LastError())
+	assert.True(t, w.LastUpdateTime().After(lastUpdateTime))
+}
+
+func TestWatchDiskCache(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dynconf")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	wr, c := makeWatcher(t)
+	_, err = c.KV().Put(&api.KVPair{
+		Key:   "hello12",
+		Value: []byte(`{"Foo": 1}`),
+	}, &api.WriteOptions{})
+	assert.NoError(t, err)
+	w, err := wr.AddWatch(context.Background(), "hello12", newValue, dynconf.WithDiskCache(dir))
+	if assert.NoError(t, err) {
+		w.Remove()
+	}
+
+	unreachableClient, err := api.NewClient(&api.Config{Address: "127.0.0.1:1"})
+	if !assert.NoError(t, err) {
+		return
+	}
+	wr2 := new(dynconf.Watcher).Init(unreachableClient, makeLogger(t))
+	_, err = wr2.AddWatch(context.Background(), "hello12", newValue)
+	assert.Error(t, err)
+
+	w, err = wr2.AddWatch(context.Background(), "hello12", newValue, dynconf.WithDiskCache(dir))
+	if assert.NoError(t, err) {
+		defer w.Remove()
+	}
+
+	w.Value().(*config).Equals(t, &config{Foo: 1})
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	assert.True(t, errors.Is(wr2.WaitUntilSynced(ctx), context.DeadlineExceeded))
+}
+
+func TestWatcherWaitUntilSynced(t *testing.T) {
+	wr, c := makeWatcher(t)
+	_, err := c.KV().Put(&api.KVPair{
+		Key:   "hello13",
+		Value: []byte(`{"Foo": 1}`),
+	}, &api.WriteOptions{})
+	assert.NoError(t, err)
+	w1, err := wr.AddWatch(context.Background(), "hello13", newValue)
+	if assert.NoError(t, err) {
+		defer w1.Remove()
+	}
+
+	w2, err := wr.AddWatchWithDefault(context.Background(), "hello14", newValue, []byte(`{}`))
+	if assert.NoError(t, err) {
+		defer w2.Remove()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	assert.NoError(t, wr.WaitUntilSynced(ctx))
+
+	select {
+	case <-w2.Synced():
+	default:
+		assert.Fail(t, "unreachable")
+	}
+}
+
 func TestWatchRemove(t *testing.T) {
 	wr, c := makeWatcher(t)
 	_, err := c.KV().Put(&api.KVPair{
@@ -146,12 +335,16 @@ type config struct {
 	Foo int
 	Bar string
 
-	outdatedEvent     chan struct{}
-	watchRemovedEvent chan struct{}
+	outdatedEvent       chan struct{}
+	deletedEvent        chan struct{}
+	updateRejectedEvent chan error
+	watchRemovedEvent   chan struct{}
 }
 
 func (c *config) Init() *config {
 	c.outdatedEvent = make(chan struct{})
+	c.deletedEvent = make(chan struct{})
+	c.updateRejectedEvent = make(chan error, 1)
 	c.watchRemovedEvent = make(chan struct{})
 	return c
 }
@@ -173,6 +366,22 @@ func (c *config) OutdatedEvent() <-chan struct{} {
 	return c.outdatedEvent
 }
 
+func (c *config) OnDeleted() {
+	close(c.deletedEvent)
+}
+
+func (c *config) DeletedEvent() <-chan struct{} {
+	return c.deletedEvent
+}
+
+func (c *config) OnUpdateRejected(err error, _ []byte) {
+	c.updateRejectedEvent <- err
+}
+
+func (c *config) UpdateRejectedEvent() <-chan error {
+	return c.updateRejectedEvent
+}
+
 func (c *config) OnWatchRemoved() {
 	close(c.watchRemovedEvent)
 }
@@ -204,20 +413,463 @@ func makeClient(t *testing.T) *api.Client {
 	return client
 }
 
-type tWritter struct {
-	T *testing.T
+func makeLogger(t *testing.T) dynconf.Logger {
+	return dynconftest.Logger{T: t}
 }
 
-func (tw tWritter) Write(p []byte) (int, error) {
-	tw.T.Log(string(p[:len(p)-1]))
-	return len(p), nil
+func newValue() dynconf.Value {
+	return new(config).Init()
 }
 
-func makeLogger(t *testing.T) *zerolog.Logger {
-	logger := zerolog.New(tWritter{t})
-	return &logger
+func TestWatchObjectValue(t *testing.T) {
+	wr, c := makeWatcher(t)
+	_, err := c.KV().Put(&api.KVPair{
+		Key:   "hello4",
+		Value: []byte(`{"Foo": 99, "Bar": "world"}`),
+	}, &api.WriteOptions{})
+	assert.NoError(t, err)
+	w, err := wr.AddWatch(context.Background(), "hello4", dynconf.ObjectValueFactory(func(data []byte) (interface{}, error) {
+		var cfg config
+		err := json.Unmarshal(data, &cfg)
+		return cfg, err
+	}))
+	if assert.NoError(t, err) {
+		defer w.Remove()
+	}
+
+	cfg := w.ObjectValue().(config)
+	cfg.Equals(t, &config{
+		Foo: 99,
+		Bar: "world",
+	})
 }
 
-func newValue() dynconf.Value {
-	return new(config).Init()
+func TestWatchSubscribe(t *testing.T) {
+	wr, c := makeWatcher(t)
+	_, err := c.KV().Put(&api.KVPair{
+		Key:   "hello5",
+		Value: []byte(`{"Foo": 1}`),
+	}, &api.WriteOptions{})
+	assert.NoError(t, err)
+	w, err := wr.AddWatch(context.Background(), "hello5", newValue)
+	if assert.NoError(t, err) {
+		defer w.Remove()
+	}
+
+	type update struct{ OldValue, NewValue dynconf.Value }
+	updates1 := make(chan update, 1)
+	unsubscribe1 := w.Subscribe(func(oldValue, newValue dynconf.Value) { updates1 <- update{oldValue, newValue} })
+	updates2 := make(chan update, 1)
+	unsubscribe2 := w.Subscribe(func(oldValue, newValue dynconf.Value) { updates2 <- update{oldValue, newValue} })
+	defer unsubscribe2()
+
+	_, err = c.KV().Put(&api.KVPair{
+		Key:   "hello5",
+		Value: []byte(`{"Foo": 2}`),
+	}, &api.WriteOptions{})
+	assert.NoError(t, err)
+
+	for _, updates := range []chan update{updates1, updates2} {
+		u := <-updates
+		u.OldValue.(*config).Equals(t, &config{Foo: 1})
+		u.NewValue.(*config).Equals(t, &config{Foo: 2})
+	}
+
+	unsubscribe1()
+	_, err = c.KV().Put(&api.KVPair{
+		Key:   "hello5",
+		Value: []byte(`{"Foo": 3}`),
+	}, &api.WriteOptions{})
+	assert.NoError(t, err)
+
+	u := <-updates2
+	u.NewValue.(*config).Equals(t, &config{Foo: 3})
+	select {
+	case <-updates1:
+		assert.Fail(t, "unreachable")
+	default:
+	}
+}
+
+func TestWatcherClose(t *testing.T) {
+	wr, c := makeWatcher(t)
+	_, err := c.KV().Put(&api.KVPair{
+		Key:   "hello6",
+		Value: []byte(`{}`),
+	}, &api.WriteOptions{})
+	assert.NoError(t, err)
+	w, err := wr.AddWatch(context.Background(), "hello6", newValue)
+	assert.NoError(t, err)
+	cfg := w.Value().(*config)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	assert.NoError(t, wr.Close(ctx))
+	<-cfg.WatchRemovedEvent()
+
+	_, err = wr.AddWatch(context.Background(), "hello6", newValue)
+	assert.True(t, errors.Is(err, dynconf.ErrWatcherClosed))
+}
+
+func TestWatchOptions(t *testing.T) {
+	wr, c := makeWatcher(t)
+	_, err := c.KV().Put(&api.KVPair{
+		Key:   "hello7",
+		Value: []byte(`{"Foo": 1}`),
+	}, &api.WriteOptions{})
+	assert.NoError(t, err)
+	var n int32
+	w, err := wr.AddWatch(context.Background(), "hello7", newValue,
+		dynconf.WithRetryPolicy(dynconf.ExponentialBackoff{MinBackoff: 10 * time.Millisecond}),
+		dynconf.WithWaitTime(100*time.Millisecond),
+		dynconf.WithAllowStale(true),
+		dynconf.WithTokenFunc(func() string {
+			atomic.AddInt32(&n, 1)
+			return ""
+		}),
+	)
+	if assert.NoError(t, err) {
+		defer w.Remove()
+	}
+
+	cfg := w.Value().(*config)
+	// the blocking queries time out every 100ms
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&n) >= 3 }, 5*time.Second, 10*time.Millisecond)
+	assert.Same(t, cfg, w.Value())
+
+	_, err = c.KV().Put(&api.KVPair{
+		Key:   "hello7",
+		Value: []byte(`{"Foo": 2}`),
+	}, &api.WriteOptions{})
+	assert.NoError(t, err)
+
+	<-cfg.OutdatedEvent()
+	w.Value().(*config).Equals(t, &config{Foo: 2})
+}
+
+func TestWatcherSetDefaultWatchOptions(t *testing.T) {
+	clock := dynconftest.NewClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	wr, b := dynconftest.NewWatcherWithClock(t, clock)
+	b.Set("hello", []byte("1"))
+	var n int32
+	wr.SetDefaultWatchOptions(
+		dynconf.WithWaitTime(time.Hour),
+		dynconf.WithUseCache(time.Minute),
+		dynconf.WithTokenFunc(func() string {
+			atomic.AddInt32(&n, 1)
+			return ""
+		}),
+	)
+	w, err := wr.AddWatch(context.Background(), "hello", dynconf.NewIntValue, dynconf.WithWaitTime(10*time.Millisecond))
+	if assert.NoError(t, err) {
+		defer w.Remove()
+	}
+
+	for i := 0; i < 2; i++ {
+		// wait for the blocking query, and then let it time out
+		assert.Eventually(t, func() bool { return clock.NumberOfTimers() == 1 }, time.Second, time.Millisecond)
+		clock.Advance(10 * time.Millisecond)
+	}
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&n) >= 3 }, time.Second, time.Millisecond)
+}
+
+func TestWatchRaw(t *testing.T) {
+	wr, b := dynconftest.NewWatcher(t)
+	w, err := wr.AddWatch(context.Background(), "hello", dynconf.NewIntValue, dynconf.WithDefaultValue([]byte("0")))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer w.Remove()
+
+	assert.Equal(t, []byte("0"), w.Raw())
+	assert.Equal(t, uint64(0), w.ModifyIndex())
+	assert.Equal(t, uint64(0), w.Flags())
+
+	events := w.Events()
+	b.Set("hello", []byte("1"))
+	e := (<-events).(*dynconf.UpdatedEvent)
+	assert.Equal(t, []byte("1"), w.Raw())
+	assert.Equal(t, e.Index, w.ModifyIndex())
+}
+
+func TestWatchDebounce(t *testing.T) {
+	wr, c := makeWatcher(t)
+	_, err := c.KV().Put(&api.KVPair{
+		Key:   "hello15",
+		Value: []byte(`{"Foo": 0}`),
+	}, &api.WriteOptions{})
+	assert.NoError(t, err)
+	w, err := wr.AddWatch(context.Background(), "hello15", newValue, dynconf.WithDebounce(500*time.Millisecond))
+	if assert.NoError(t, err) {
+		defer w.Remove()
+	}
+
+	var n int32
+	unsubscribe := w.Subscribe(func(_, _ dynconf.Value) { atomic.AddInt32(&n, 1) })
+	defer unsubscribe()
+	cfg := w.Value().(*config)
+
+	for i := 1; i <= 5; i++ {
+		_, err = c.KV().Put(&api.KVPair{
+			Key:   "hello15",
+			Value: []byte(fmt.Sprintf(`{"Foo": %d}`, i)),
+		}, &api.WriteOptions{})
+		assert.NoError(t, err)
+	}
+
+	<-cfg.OutdatedEvent()
+	w.Value().(*config).Equals(t, &config{Foo: 5})
+	assert.Equal(t, int32(1), atomic.LoadInt32(&n))
+}
+
+func TestWatchNoOpUpdate(t *testing.T) {
+	wr, c := makeWatcher(t)
+	_, err := c.KV().Put(&api.KVPair{
+		Key:   "hello16",
+		Value: []byte(`{"Foo": 1}`),
+	}, &api.WriteOptions{})
+	assert.NoError(t, err)
+	w, err := wr.AddWatch(context.Background(), "hello16", newValue)
+	if assert.NoError(t, err) {
+		defer w.Remove()
+	}
+
+	newValues := make(chan dynconf.Value, 2)
+	unsubscribe := w.Subscribe(func(_, newValue dynconf.Value) { newValues <- newValue })
+	defer unsubscribe()
+
+	for _, data := range []string{`{"Foo": 1}`, `{"Foo": 2}`} {
+		_, err = c.KV().Put(&api.KVPair{
+			Key:   "hello16",
+			Value: []byte(data),
+		}, &api.WriteOptions{})
+		assert.NoError(t, err)
+	}
+
+	(<-newValues).(*config).Equals(t, &config{Foo: 2})
+	assert.Len(t, newValues, 0)
+}
+
+func TestWithMaxDataSize(t *testing.T) {
+	wr, b := dynconftest.NewWatcher(t)
+	b.Set("hello", []byte("12345"))
+	_, err := wr.AddWatch(context.Background(), "hello", dynconf.NewIntValue, dynconf.WithMaxDataSize(4))
+	assert.True(t, errors.Is(err, dynconf.ErrDataTooLarge))
+
+	b.Set("hello", []byte("1"))
+	errs := make(chan error, 1)
+	w, err := wr.AddWatch(context.Background(), "hello", dynconf.NewIntValue, dynconf.WithMaxDataSize(4),
+		dynconf.WithUpdateRejectedCallback(func(err error, _ []byte) { errs <- err }))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer w.Remove()
+
+	b.Set("hello", []byte("12345"))
+	assert.True(t, errors.Is(<-errs, dynconf.ErrDataTooLarge))
+	assert.Equal(t, int64(1), w.Value().(*dynconf.IntValue).Get())
+
+	b.Set("hello", []byte("1234"))
+	assert.Eventually(t, func() bool { return w.Value().(*dynconf.IntValue).Get() == 1234 }, time.Second, 10*time.Millisecond)
+}
+
+func TestWithNamespace(t *testing.T) {
+	b := namespaceBackend{Backend: new(dynconftest.Backend).Init()}
+	b.Backend.Set("hello", []byte("1"))
+	wr := new(dynconf.Watcher).InitWithBackend(&b, dynconftest.Logger{T: t})
+	wr.SetDefaultWatchOptions(dynconf.WithNamespace("team-a"))
+	w, err := wr.AddWatch(context.Background(), "hello", dynconf.NewIntValue,
+		dynconf.WithQueryOptions(api.QueryOptions{Namespace: "default"}))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	w.Remove()
+
+	w, err = wr.AddWatch(context.Background(), "hello", dynconf.NewIntValue, dynconf.WithNamespace("team-b"))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	w.Remove()
+
+	assert.Equal(t, []string{"team-a", "team-b"}, b.Namespaces())
+}
+
+type namespaceBackend struct {
+	*dynconftest.Backend
+
+	mu         sync.Mutex
+	namespaces []string
+}
+
+func (nb *namespaceBackend) Get(key string, queryOptions *api.QueryOptions) (*api.KVPair, *api.QueryMeta, error) {
+	nb.mu.Lock()
+
+	if n := len(nb.namespaces); n == 0 || nb.namespaces[n-1] != queryOptions.Namespace {
+		nb.namespaces = append(nb.namespaces, queryOptions.Namespace)
+	}
+
+	nb.mu.Unlock()
+	return nb.Backend.Get(key, queryOptions)
+}
+
+func (nb *namespaceBackend) Namespaces() []string {
+	nb.mu.Lock()
+	defer nb.mu.Unlock()
+	return nb.namespaces
+}
+
+func TestNewWatcherWithBackend(t *testing.T) {
+	b := new(dynconftest.Backend).Init()
+	b.Set("hello", []byte("1"))
+	m := new(counters)
+	wr := dynconf.NewWatcherWithBackend(b, dynconf.WithMetrics(m), dynconf.WithNamespace("team-a"),
+		dynconf.WithDefaultRetry(dynconf.ExponentialBackoff{MinBackoff: time.Millisecond, MaxBackoff: time.Millisecond}))
+	w, err := wr.AddWatch(context.Background(), "hello", dynconf.NewIntValue)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer w.Remove()
+
+	events := w.Events()
+	b.Set("hello", []byte("x"))
+	<-events
+	b.Set("hello", []byte("2"))
+	<-events
+
+	assert.Equal(t, 1, m.Get("dynconf_update_rejections", "key", "hello"))
+	assert.Equal(t, 1, m.Get("dynconf_value_updates", "key", "hello"))
+}
+
+type counters struct {
+	mu     sync.Mutex
+	counts map[string]int
+}
+
+func (c *counters) IncrCounter(name string, labels ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.counts == nil {
+		c.counts = make(map[string]int)
+	}
+
+	c.counts[fmt.Sprint(name, labels)]++
+}
+
+func (c *counters) Get(name string, labels ...string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.counts[fmt.Sprint(name, labels)]
+}
+
+func TestWatchRemoveContext(t *testing.T) {
+	b := &wedgedBackend{Backend: new(dynconftest.Backend).Init(), release: make(chan struct{})}
+	b.Set("hello", []byte("1"))
+	wr := dynconf.NewWatcherWithBackend(b, dynconf.WithLogger(dynconftest.Logger{T: t}))
+	w, err := wr.AddWatch(context.Background(), "hello", dynconf.NewIntValue)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err = w.RemoveContext(ctx)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+
+	close(b.release)
+	w.Remove()
+	assert.NoError(t, w.RemoveContext(context.Background()))
+}
+
+type wedgedBackend struct {
+	*dynconftest.Backend
+
+	n       int32
+	release chan struct{}
+}
+
+func (wb *wedgedBackend) Get(key string, queryOptions *api.QueryOptions) (*api.KVPair, *api.QueryMeta, error) {
+	if atomic.AddInt32(&wb.n, 1) >= 2 {
+		<-wb.release
+	}
+
+	return wb.Backend.Get(key, queryOptions)
+}
+
+func TestWatchProlongedFailure(t *testing.T) {
+	clock := dynconftest.NewClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	b := &flakyBackend{Backend: new(dynconftest.Backend).InitWithClock(clock)}
+	b.Set("hello", []byte("1"))
+	wr := dynconf.NewWatcherWithBackend(b, dynconf.WithLogger(dynconftest.Logger{T: t}), dynconf.WithClock(clock))
+	w, err := wr.AddWatch(context.Background(), "hello", dynconf.NewIntValue,
+		dynconf.WithWaitTime(10*time.Second),
+		dynconf.WithRetryPolicy(dynconf.ExponentialBackoff{MinBackoff: time.Second, MaxBackoff: time.Minute}))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer w.Remove()
+
+	// wait for the blocking query
+	clock.BlockUntil(1)
+	atomic.StoreInt32(&b.Down, 1)
+	assert.Eventually(t, func() bool {
+		clock.Advance(time.Minute)
+		return w.ConsecutiveFailures() >= 100
+	}, 5*time.Second, time.Millisecond)
+	assert.Equal(t, int64(1), w.Value().(*dynconf.IntValue).Get())
+	assert.Error(t, w.LastError())
+	assert.Equal(t, dynconf.HealthFailing, wr.Health().Status)
+
+	b.Set("hello", []byte("2"))
+	atomic.StoreInt32(&b.Down, 0)
+	assert.Eventually(t, func() bool {
+		clock.Advance(time.Minute)
+		return w.Value().(*dynconf.IntValue).Get() == 2
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, 0, w.ConsecutiveFailures())
+	assert.NoError(t, w.LastError())
+	assert.Equal(t, dynconf.HealthHealthy, wr.Health().Status)
+}
+
+type flakyBackend struct {
+	*dynconftest.Backend
+
+	Down int32
+}
+
+func (fb *flakyBackend) Get(key string, queryOptions *api.QueryOptions) (*api.KVPair, *api.QueryMeta, error) {
+	if atomic.LoadInt32(&fb.Down) == 1 {
+		return nil, nil, errors.New("backend down")
+	}
+
+	return fb.Backend.Get(key, queryOptions)
+}
+
+func TestWatcherAddWatches(t *testing.T) {
+	wr, b := dynconftest.NewWatcher(t)
+	b.Set("a", []byte("1"))
+	b.Set("b", []byte("2"))
+
+	ws, err := wr.AddWatches(context.Background(), []dynconf.WatchSpec{
+		{Key: "a", ValueFactory: dynconf.NewIntValue},
+		{Key: "b", ValueFactory: dynconf.NewIntValue},
+	})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	if assert.Len(t, ws, 2) {
+		assert.Equal(t, "a", ws[0].Key())
+		assert.Equal(t, "2", ws[1].Value().String())
+	}
+	for _, w := range ws {
+		w.Remove()
+	}
+
+	_, err = wr.AddWatches(context.Background(), []dynconf.WatchSpec{
+		{Key: "a", ValueFactory: dynconf.NewIntValue},
+		{Key: "c", ValueFactory: dynconf.NewIntValue},
+		{Key: "b", ValueFactory: dynconf.NewIntValue},
+	})
+	assert.True(t, errors.Is(err, dynconf.ErrKeyNotFound))
+	assert.Len(t, wr.Health().Watches, 0)
 }
@@ -0,0 +1,194 @@
+package dynconftest
+
+import (
+	"sync"
+	"time"
+
+	"github.com/roy2220/dynconf"
+)
+
+// Clock presents a fake clock, of which the time only moves forward when Advance
+// is called, so that tests can fast-forward time deterministically rather than
+// sleep. It is to be given to dynconf.WithClock.
+type Clock struct {
+	mu     sync.Mutex
+	cond   sync.Cond
+	now    time.Time
+	timers []*fakeTimer
+}
+
+var _ dynconf.Clock = (*Clock)(nil)
+
+// NewClock returns a new fake clock starting at the given time.
+func NewClock(now time.Time) *Clock {
+	var c Clock
+	c.cond.L = &c.mu
+	c.now = now
+	return &c
+}
+
+// Now implements dynconf.Clock.Now.
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// NewTimer implements dynconf.Clock.NewTimer.
+func (c *Clock) NewTimer(d time.Duration) dynconf.Timer {
+	return c.addTimer(d, 0, nil)
+}
+
+// AfterFunc implements dynconf.Clock.AfterFunc.
+func (c *Clock) AfterFunc(d time.Duration, f func()) dynconf.Timer {
+	return c.addTimer(d, 0, f)
+}
+
+// NewTicker implements dynconf.Clock.NewTicker.
+func (c *Clock) NewTicker(d time.Duration) dynconf.Ticker {
+	if d <= 0 {
+		panic("dynconftest: non-positive interval for NewTicker")
+	}
+
+	return fakeTicker{c.addTimer(d, d, nil)}
+}
+
+// Advance moves the time forward by the given duration, and fires the timers
+// expiring in the meantime, in the order of their expiry times, as if the time
+// elapsed.
+func (c *Clock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	end := c.now.Add(d)
+
+	for {
+		var nextTimer *fakeTimer
+
+		for _, timer := range c.timers {
+			if !timer.deadline.After(end) && (nextTimer == nil || timer.deadline.Before(nextTimer.deadline)) {
+				nextTimer = timer
+			}
+		}
+
+		if nextTimer == nil {
+			break
+		}
+
+		if nextTimer.deadline.After(c.now) {
+			c.now = nextTimer.deadline
+		}
+
+		c.fire(nextTimer)
+	}
+
+	c.now = end
+}
+
+// BlockUntil blocks until at least the given number of timers and tickers are
+// active, e.g. to wait for a watch to start waiting before calling Advance.
+func (c *Clock) BlockUntil(numberOfTimers int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for len(c.timers) < numberOfTimers {
+		c.cond.Wait()
+	}
+}
+
+// NumberOfTimers returns the number of timers and tickers active.
+func (c *Clock) NumberOfTimers() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.timers)
+}
+
+func (c *Clock) addTimer(d time.Duration, period time.Duration, f func()) *fakeTimer {
+	timer := fakeTimer{
+		clock:  c,
+		period: period,
+		f:      f,
+	}
+
+	if f == nil {
+		timer.c = make(chan time.Time, 1)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	timer.deadline = c.now.Add(d)
+	c.activateTimer(&timer)
+	return &timer
+}
+
+func (c *Clock) activateTimer(timer *fakeTimer) {
+	c.timers = append(c.timers, timer)
+	c.cond.Broadcast()
+}
+
+func (c *Clock) deactivateTimer(timer *fakeTimer) bool {
+	for i, otherTimer := range c.timers {
+		if otherTimer == timer {
+			c.timers = append(c.timers[:i], c.timers[i+1:]...)
+			return true
+		}
+	}
+
+	return false
+}
+
+func (c *Clock) fire(timer *fakeTimer) {
+	if timer.period >= 1 {
+		timer.deadline = timer.deadline.Add(timer.period)
+	} else {
+		c.deactivateTimer(timer)
+	}
+
+	if timer.f != nil {
+		go timer.f()
+		return
+	}
+
+	select {
+	case timer.c <- c.now:
+	default:
+	}
+}
+
+type fakeTimer struct {
+	clock    *Clock
+	deadline time.Time
+	period   time.Duration
+	f        func()
+	c        chan time.Time
+}
+
+var _ dynconf.Timer = (*fakeTimer)(nil)
+
+func (ft *fakeTimer) C() <-chan time.Time {
+	return ft.c
+}
+
+func (ft *fakeTimer) Stop() bool {
+	ft.clock.mu.Lock()
+	defer ft.clock.mu.Unlock()
+	return ft.clock.deactivateTimer(ft)
+}
+
+func (ft *fakeTimer) Reset(d time.Duration) bool {
+	ft.clock.mu.Lock()
+	defer ft.clock.mu.Unlock()
+	active := ft.clock.deactivateTimer(ft)
+	ft.deadline = ft.clock.now.Add(d)
+	ft.clock.activateTimer(ft)
+	return active
+}
+
+type fakeTicker struct {
+	*fakeTimer
+}
+
+var _ dynconf.Ticker = fakeTicker{}
+
+func (ft fakeTicker) Stop() {
+	ft.fakeTimer.Stop()
+}
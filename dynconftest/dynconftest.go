@@ -0,0 +1,256 @@
+// Package dynconftest implements utilities for testing code using dynconf without
+// a Consul agent.
+package dynconftest
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+
+	"github.com/roy2220/dynconf"
+)
+
+// NewWatcher returns a watcher watching keys in a new in-memory backend, which
+// is returned as well, and logging with the given test. All watches should be
+// removed before the test ends.
+func NewWatcher(t testing.TB) (*dynconf.Watcher, *Backend) {
+	backend := new(Backend).Init()
+	watcher := new(dynconf.Watcher).InitWithBackend(backend, Logger{t})
+	return watcher, backend
+}
+
+// NewWatcherWithClock returns a watcher watching keys in a new in-memory backend,
+// which is returned as well, with the given clock, which drives both the watches
+// and the blocking queries of the backend, and logging with the given test. All
+// watches should be removed before the test ends.
+func NewWatcherWithClock(t testing.TB, clock dynconf.Clock) (*dynconf.Watcher, *Backend) {
+	backend := new(Backend).InitWithClock(clock)
+	watcher := dynconf.NewWatcherWithBackend(backend, dynconf.WithLogger(Logger{t}), dynconf.WithClock(clock))
+	return watcher, backend
+}
+
+// Backend presents an in-memory backend, which behaves as the KV store of Consul.
+type Backend struct {
+	mu         sync.Mutex
+	kvPairs    map[string]*api.KVPair
+	tombstones map[string]uint64
+	index      uint64
+	changed    chan struct{}
+	clock      dynconf.Clock
+}
+
+var _ dynconf.Backend = (*Backend)(nil)
+
+// Init initializes the backend and then returns the backend.
+func (b *Backend) Init() *Backend {
+	return b.InitWithClock(dynconf.SystemClock)
+}
+
+// InitWithClock initializes the backend, of which the blocking queries wait with
+// the given clock, and then returns the backend.
+func (b *Backend) InitWithClock(clock dynconf.Clock) *Backend {
+	b.clock = clock
+	b.kvPairs = make(map[string]*api.KVPair)
+	b.tombstones = make(map[string]uint64)
+	b.index = 1
+	b.changed = make(chan struct{})
+	return b
+}
+
+// Set sets the data of the given key.
+func (b *Backend) Set(key string, data []byte) {
+	b.SetWithFlags(key, data, 0)
+}
+
+// SetWithFlags sets the data and the flags of the given key.
+func (b *Backend) SetWithFlags(key string, data []byte, flags uint64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.index++
+	kvPair := api.KVPair{
+		Key:         key,
+		Value:       append([]byte(nil), data...),
+		CreateIndex: b.index,
+		ModifyIndex: b.index,
+		Flags:       flags,
+	}
+
+	if oldKVPair, ok := b.kvPairs[key]; ok {
+		kvPair.CreateIndex = oldKVPair.CreateIndex
+	}
+
+	b.kvPairs[key] = &kvPair
+	delete(b.tombstones, key)
+	b.notifyChange()
+}
+
+// Load sets the data and the flags of the keys in the given snapshot, e.g. one
+// loaded with dynconf.LoadSnapshot, so that tests can mirror the production
+// configuration and then change it.
+func (b *Backend) Load(snapshot map[string]dynconf.RawValue) {
+	for key, rawValue := range snapshot {
+		b.SetWithFlags(key, rawValue.Data, rawValue.Flags)
+	}
+}
+
+// Delete deletes the given key.
+func (b *Backend) Delete(key string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.kvPairs[key]; !ok {
+		return
+	}
+
+	b.index++
+	delete(b.kvPairs, key)
+	b.tombstones[key] = b.index
+	b.notifyChange()
+}
+
+// Get implements dynconf.Backend.Get.
+func (b *Backend) Get(key string, queryOptions *api.QueryOptions) (*api.KVPair, *api.QueryMeta, error) {
+	var kvPair *api.KVPair
+
+	queryMeta, err := b.query(queryOptions, func() uint64 {
+		kvPair = nil
+
+		if storedKVPair, ok := b.kvPairs[key]; ok {
+			kvPair = copyKVPair(storedKVPair)
+			return kvPair.ModifyIndex
+		}
+
+		if index, ok := b.tombstones[key]; ok {
+			return index
+		}
+
+		return b.index
+	})
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return kvPair, queryMeta, nil
+}
+
+// List implements dynconf.Backend.List.
+func (b *Backend) List(prefix string, queryOptions *api.QueryOptions) (api.KVPairs, *api.QueryMeta, error) {
+	var kvPairs api.KVPairs
+
+	queryMeta, err := b.query(queryOptions, func() uint64 {
+		kvPairs = nil
+		var index uint64
+
+		for key, kvPair := range b.kvPairs {
+			if !strings.HasPrefix(key, prefix) {
+				continue
+			}
+
+			kvPairs = append(kvPairs, copyKVPair(kvPair))
+
+			if kvPair.ModifyIndex > index {
+				index = kvPair.ModifyIndex
+			}
+		}
+
+		for key, tombstoneIndex := range b.tombstones {
+			if strings.HasPrefix(key, prefix) && tombstoneIndex > index {
+				index = tombstoneIndex
+			}
+		}
+
+		if index == 0 {
+			index = b.index
+		}
+
+		sort.Slice(kvPairs, func(i, j int) bool { return kvPairs[i].Key < kvPairs[j].Key })
+		return index
+	})
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return kvPairs, queryMeta, nil
+}
+
+func (b *Backend) query(queryOptions *api.QueryOptions, read func() uint64) (*api.QueryMeta, error) {
+	if queryOptions == nil {
+		queryOptions = new(api.QueryOptions)
+	}
+
+	ctx := queryOptions.Context()
+	waitTime := queryOptions.WaitTime
+
+	if waitTime <= 0 {
+		waitTime = defaultWaitTime
+	}
+
+	timer := b.clock.NewTimer(waitTime)
+	defer timer.Stop()
+
+	for {
+		b.mu.Lock()
+		index := read()
+		changed := b.changed
+		b.mu.Unlock()
+
+		if queryOptions.WaitIndex == 0 || index != queryOptions.WaitIndex {
+			return &api.QueryMeta{LastIndex: index}, nil
+		}
+
+		select {
+		case <-changed:
+		case <-timer.C():
+			return &api.QueryMeta{LastIndex: index}, nil
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func (b *Backend) notifyChange() {
+	close(b.changed)
+	b.changed = make(chan struct{})
+}
+
+func copyKVPair(kvPair *api.KVPair) *api.KVPair {
+	kvPairCopy := *kvPair
+	kvPairCopy.Value = append([]byte(nil), kvPair.Value...)
+	return &kvPairCopy
+}
+
+// Logger presents a logger logging with a test.
+type Logger struct {
+	T testing.TB
+}
+
+var _ dynconf.Logger = Logger{}
+
+// Log implements dynconf.Logger.Log.
+func (l Logger) Log(level dynconf.LogLevel, message string, keysAndValues ...interface{}) {
+	var builder strings.Builder
+	builder.WriteString(level.String())
+	builder.WriteByte(' ')
+	builder.WriteString(message)
+
+	for i := 0; i+1 < len(keysAndValues); i += 2 {
+		value := keysAndValues[i+1]
+
+		if data, ok := value.([]byte); ok {
+			value = string(data)
+		}
+
+		fmt.Fprintf(&builder, " %v=%q", keysAndValues[i], fmt.Sprint(value))
+	}
+
+	l.T.Log(builder.String())
+}
+
+const defaultWaitTime = 5 * time.Minute
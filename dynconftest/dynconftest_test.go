@@ -0,0 +1,92 @@
+package dynconftest_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/roy2220/dynconf"
+	"github.com/roy2220/dynconf/dynconftest"
+)
+
+func TestBackendWatch(t *testing.T) {
+	wr, b := dynconftest.NewWatcher(t)
+	b.Set("hello", []byte(`1`))
+	d, err := wr.AddDynInt(context.Background(), "hello")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer d.Watch().Remove()
+	assert.Equal(t, int64(1), d.Get())
+
+	b.Set("hello", []byte(`2`))
+	assert.Eventually(t, func() bool { return d.Get() == 2 }, time.Second, time.Millisecond)
+
+	b.Delete("hello")
+	assert.Eventually(t, func() bool { return !d.Watch().Exists() }, time.Second, time.Millisecond)
+
+	b.Set("hello", []byte(`3`))
+	assert.Eventually(t, func() bool { return d.Get() == 3 }, time.Second, time.Millisecond)
+}
+
+func TestBackendPrefixWatch(t *testing.T) {
+	wr, b := dynconftest.NewWatcher(t)
+	b.Set("tenants/a", []byte(`"a"`))
+	removedKeys := make(chan string, 1)
+	pw, err := wr.AddPrefixWatch(context.Background(), "tenants/", dynconf.NewStringValue, dynconf.PrefixWatchCallbacks{
+		OnKeyRemoved: func(key string, _ dynconf.Value) { removedKeys <- key },
+	})
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer pw.Remove()
+	assert.Len(t, pw.Values(), 1)
+
+	b.Set("tenants/b", []byte(`"b"`))
+	assert.Eventually(t, func() bool { return len(pw.Values()) == 2 }, time.Second, time.Millisecond)
+
+	b.Delete("tenants/a")
+	assert.Equal(t, "tenants/a", <-removedKeys)
+	assert.Len(t, pw.Values(), 1)
+}
+
+func TestClock(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	c := dynconftest.NewClock(start)
+	assert.Equal(t, start, c.Now())
+
+	timer := c.NewTimer(time.Minute)
+	fired := make(chan time.Time, 1)
+	c.AfterFunc(2*time.Minute, func() { fired <- c.Now() })
+	ticker := c.NewTicker(30 * time.Second)
+	assert.Equal(t, 3, c.NumberOfTimers())
+
+	c.Advance(59 * time.Second)
+	assert.Equal(t, start.Add(30*time.Second), <-ticker.C())
+	select {
+	case <-timer.C():
+		t.Fatal("timer fired early")
+	default:
+	}
+
+	c.Advance(time.Second)
+	assert.Equal(t, start.Add(time.Minute), <-timer.C())
+	assert.Equal(t, start.Add(time.Minute), <-ticker.C())
+	assert.False(t, timer.Stop())
+	assert.False(t, timer.Reset(time.Minute))
+
+	ticker.Stop()
+	c.Advance(time.Minute)
+	assert.Equal(t, start.Add(2*time.Minute), <-fired)
+	assert.Equal(t, start.Add(2*time.Minute), <-timer.C())
+	assert.Equal(t, 0, c.NumberOfTimers())
+
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		c.NewTimer(time.Second)
+	}()
+	c.BlockUntil(1)
+	assert.Equal(t, 1, c.NumberOfTimers())
+}
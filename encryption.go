@@ -0,0 +1,136 @@
+package dynconf
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// Decrypter represents a decrypter of data, e.g. with a key fetched from a KMS.
+// Implementations should cache keys, as data are decrypted on every update.
+type Decrypter interface {
+	// Decrypt decrypts the given ciphertext and then returns the plaintext.
+	Decrypt(ciphertext []byte) (plaintext []byte, err error)
+}
+
+// AESGCMCipher presents a cipher encrypting and decrypting data with AES-GCM, which
+// holds multiple keys identified by IDs for key rotation. Data are encrypted with
+// the primary key, and the ciphertext is prefixed with the ID of the key, so that
+// the data encrypted with any key held can be decrypted. To rotate keys, a new key
+// is added as the primary key, the data are re-encrypted, and then the old key is
+// removed.
+type AESGCMCipher struct {
+	mu           sync.RWMutex
+	aeads        map[string]cipher.AEAD
+	primaryKeyID string
+}
+
+var _ Decrypter = (*AESGCMCipher)(nil)
+
+// Init initializes the cipher and then returns the cipher.
+func (ac *AESGCMCipher) Init() *AESGCMCipher {
+	ac.aeads = make(map[string]cipher.AEAD)
+	return ac
+}
+
+// AddKey adds the given key, which must be 16, 24 or 32 bytes long, with the given
+// ID, replacing the key previously added with the ID if any. The key becomes the
+// primary key if the given primary flag is true or it is the first key added.
+func (ac *AESGCMCipher) AddKey(keyID string, key []byte, primary bool) error {
+	if len(keyID) == 0 || len(keyID) > maxAESGCMKeyIDLength {
+		return fmt.Errorf("dynconf: add key failed; key_id=%q: invalid key id", keyID)
+	}
+
+	block, err := aes.NewCipher(key)
+
+	if err != nil {
+		return fmt.Errorf("dynconf: add key failed; key_id=%q: %w", keyID, err)
+	}
+
+	aead, err := cipher.NewGCM(block)
+
+	if err != nil {
+		return fmt.Errorf("dynconf: add key failed; key_id=%q: %w", keyID, err)
+	}
+
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+	ac.aeads[keyID] = aead
+
+	if primary || ac.primaryKeyID == "" {
+		ac.primaryKeyID = keyID
+	}
+
+	return nil
+}
+
+// RemoveKey removes the key with the given ID. The primary key can't be removed.
+func (ac *AESGCMCipher) RemoveKey(keyID string) {
+	ac.mu.Lock()
+	defer ac.mu.Unlock()
+
+	if keyID != ac.primaryKeyID {
+		delete(ac.aeads, keyID)
+	}
+}
+
+// Encrypt encrypts the given plaintext with the primary key and then returns the
+// ciphertext.
+func (ac *AESGCMCipher) Encrypt(plaintext []byte) ([]byte, error) {
+	ac.mu.RLock()
+	keyID := ac.primaryKeyID
+	aead, ok := ac.aeads[keyID]
+	ac.mu.RUnlock()
+
+	if !ok {
+		return nil, errNoKey
+	}
+
+	header := append([]byte{byte(len(keyID))}, keyID...)
+	nonce := make([]byte, aead.NonceSize())
+
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+
+	ciphertext := append(header, nonce...)
+	return aead.Seal(ciphertext, nonce, plaintext, header), nil
+}
+
+// Decrypt implements Decrypter.Decrypt.
+func (ac *AESGCMCipher) Decrypt(ciphertext []byte) ([]byte, error) {
+	if len(ciphertext) < 1 || len(ciphertext) < 1+int(ciphertext[0]) {
+		return nil, errCiphertextMalformed
+	}
+
+	header := ciphertext[:1+int(ciphertext[0])]
+	keyID := string(header[1:])
+	ac.mu.RLock()
+	aead, ok := ac.aeads[keyID]
+	ac.mu.RUnlock()
+
+	if !ok {
+		return nil, fmt.Errorf("key_id=%q: %w", keyID, errKeyNotFound)
+	}
+
+	ciphertext = ciphertext[len(header):]
+
+	if len(ciphertext) < aead.NonceSize() {
+		return nil, errCiphertextMalformed
+	}
+
+	nonce := ciphertext[:aead.NonceSize()]
+	return aead.Open(nil, nonce, ciphertext[len(nonce):], header)
+}
+
+const maxAESGCMKeyIDLength = 255
+
+var (
+	errNoKey               = errors.New("no key")
+	errKeyNotFound         = errors.New("key not found")
+	errCiphertextMalformed = errors.New("ciphertext malformed")
+)
@@ -0,0 +1,99 @@
+package dynconf_test
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/roy2220/dynconf"
+	"github.com/roy2220/dynconf/dynconftest"
+)
+
+func TestWithDecrypter(t *testing.T) {
+	c := new(dynconf.AESGCMCipher).Init()
+	assert.NoError(t, c.AddKey("k1", bytes.Repeat([]byte{1}, 32), false))
+	wr, b := dynconftest.NewWatcher(t)
+	b.Set("token", encrypt(t, c, "secret1"))
+	errs := make(chan error, 1)
+	dir, err := ioutil.TempDir("", "dynconf")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer os.RemoveAll(dir)
+	w, err := wr.AddWatch(context.Background(), "token", dynconf.NewStringValue, dynconf.WithDecrypter(c),
+		dynconf.WithUpdateRejectedCallback(func(err error, _ []byte) { errs <- err }), dynconf.WithDiskCache(dir))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer w.Remove()
+
+	assert.Equal(t, "secret1", w.Value().(*dynconf.StringValue).Get())
+	fileInfos, err := ioutil.ReadDir(dir)
+	if assert.NoError(t, err) && assert.Len(t, fileInfos, 1) {
+		data, err := ioutil.ReadFile(filepath.Join(dir, fileInfos[0].Name()))
+		assert.NoError(t, err)
+		assert.NotContains(t, string(data), "secret1", "decrypted data must be kept off the disk")
+	}
+
+	// fallback to the disk cache
+	fb := &flakyBackend{Backend: b, Down: 1}
+	w2, err := dynconf.NewWatcherWithBackend(fb, dynconf.WithLogger(dynconftest.Logger{T: t})).AddWatch(context.Background(),
+		"token", dynconf.NewStringValue, dynconf.WithDecrypter(c), dynconf.WithDiskCache(dir))
+	if assert.NoError(t, err) {
+		defer w2.Remove()
+		assert.Equal(t, "secret1", w2.Value().(*dynconf.StringValue).Get())
+	}
+
+	b.Set("token", []byte("plaintext"))
+	assert.Error(t, <-errs)
+	assert.Equal(t, "secret1", w.Value().(*dynconf.StringValue).Get())
+
+	newValues := make(chan dynconf.Value, 1)
+	unsubscribe := w.Subscribe(func(_, newValue dynconf.Value) { newValues <- newValue })
+	defer unsubscribe()
+
+	// key rotation
+	assert.NoError(t, c.AddKey("k2", bytes.Repeat([]byte{2}, 32), true))
+	b.Set("token", encrypt(t, c, "secret2"))
+	assert.Equal(t, "secret2", (<-newValues).(*dynconf.StringValue).Get())
+}
+
+func TestAESGCMCipher(t *testing.T) {
+	c := new(dynconf.AESGCMCipher).Init()
+	_, err := c.Encrypt([]byte("hello"))
+	assert.Error(t, err)
+	assert.Error(t, c.AddKey("k1", []byte("short"), false))
+	assert.NoError(t, c.AddKey("k1", bytes.Repeat([]byte{1}, 16), false))
+
+	ciphertext1 := encrypt(t, c, "hello")
+	assert.NoError(t, c.AddKey("k2", bytes.Repeat([]byte{2}, 16), true))
+	ciphertext2 := encrypt(t, c, "world")
+
+	plaintext, err := c.Decrypt(ciphertext1)
+	assert.NoError(t, err)
+	assert.Equal(t, "hello", string(plaintext))
+
+	c.RemoveKey("k1")
+	_, err = c.Decrypt(ciphertext1)
+	assert.Error(t, err)
+	plaintext, err = c.Decrypt(ciphertext2)
+	assert.NoError(t, err)
+	assert.Equal(t, "world", string(plaintext))
+
+	ciphertext2[len(ciphertext2)-1] ^= 1
+	_, err = c.Decrypt(ciphertext2)
+	assert.Error(t, err)
+	_, err = c.Decrypt(nil)
+	assert.Error(t, err)
+}
+
+func encrypt(t *testing.T, c *dynconf.AESGCMCipher, s string) []byte {
+	ciphertext, err := c.Encrypt([]byte(s))
+	assert.NoError(t, err)
+	return ciphertext
+}
@@ -0,0 +1,96 @@
+package dynconf
+
+import "fmt"
+
+// Event represents an event in the lifecycle of a watch, which is one of
+// *UpdatedEvent, *UpdateRejectedEvent, *KeyDeletedEvent, *BackendErrorEvent and
+// *WatchRemovedEvent.
+type Event interface {
+	isEvent()
+}
+
+// UpdatedEvent presents an event that the latest value has been replaced with
+// another value.
+type UpdatedEvent struct {
+	OldValue Value
+	NewValue Value
+	Index    uint64
+
+	// Changes are the changes of the JSON data, which are computed only if the
+	// watch has been added with WithDiff.
+	Changes []JSONChange
+}
+
+// UpdateRejectedEvent presents an event that the data of a new value have been
+// rejected due to a failure of validation or unmarshaling.
+type UpdateRejectedEvent struct {
+	Err  error
+	Data []byte
+}
+
+// KeyDeletedEvent presents an event that the key has been deleted.
+type KeyDeletedEvent struct {
+	Index uint64
+}
+
+// BackendErrorEvent presents an event that reading the key from the backend has
+// failed, which is to be retried.
+type BackendErrorEvent struct {
+	Err error
+}
+
+// WatchRemovedEvent presents an event that the watch has been removed, which is
+// the last event.
+type WatchRemovedEvent struct{}
+
+func (*UpdatedEvent) isEvent()        {}
+func (*UpdateRejectedEvent) isEvent() {}
+func (*KeyDeletedEvent) isEvent()     {}
+func (*BackendErrorEvent) isEvent()   {}
+func (*WatchRemovedEvent) isEvent()   {}
+
+// Events returns a channel emitting the events of the watch, which is closed after
+// the watch has been removed. Events are emitted only after the first call to
+// Events, and are dropped if the channel is full, so the channel should be drained
+// promptly.
+func (w *Watch) Events() <-chan Event {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.events == nil {
+		w.events = make(chan Event, eventBufferSize)
+
+		if w.eventsClosed {
+			close(w.events)
+		}
+	}
+
+	return w.events
+}
+
+func (w *Watch) emitEvent(event Event) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.events == nil {
+		return
+	}
+
+	select {
+	case w.events <- event:
+	default:
+		w.logger.Log(LogWarn, "dynconf_event_dropped", "key", w.key, "event", fmt.Sprintf("%T", event))
+	}
+}
+
+func (w *Watch) closeEvents() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.eventsClosed = true
+
+	if w.events != nil {
+		close(w.events)
+	}
+}
+
+const eventBufferSize = 64
@@ -0,0 +1,50 @@
+package dynconf_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/roy2220/dynconf"
+	"github.com/roy2220/dynconf/dynconftest"
+)
+
+func TestWatchEvents(t *testing.T) {
+	wr, b := dynconftest.NewWatcher(t)
+	b.Set("limit", []byte("100"))
+	w, err := wr.AddWatch(context.Background(), "limit", dynconf.NewIntValue,
+		dynconf.WithValidator(func(value dynconf.Value) error {
+			if value.(*dynconf.IntValue).Get() < 0 {
+				return errors.New("negative limit")
+			}
+
+			return nil
+		}))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	events := w.Events()
+	b.Set("limit", []byte("200"))
+	e1 := (<-events).(*dynconf.UpdatedEvent)
+	assert.Equal(t, int64(100), e1.OldValue.(*dynconf.IntValue).Get())
+	assert.Equal(t, int64(200), e1.NewValue.(*dynconf.IntValue).Get())
+	assert.NotZero(t, e1.Index)
+
+	b.Set("limit", []byte("-1"))
+	e2 := (<-events).(*dynconf.UpdateRejectedEvent)
+	assert.EqualError(t, e2.Err, "dynconf: value validation failed; key=\"limit\": negative limit")
+	assert.Equal(t, []byte("-1"), e2.Data)
+
+	b.Delete("limit")
+	assert.IsType(t, (*dynconf.KeyDeletedEvent)(nil), <-events)
+
+	w.Remove()
+	assert.IsType(t, (*dynconf.WatchRemovedEvent)(nil), <-events)
+	_, ok := <-events
+	assert.False(t, ok)
+	_, ok = <-w.Events()
+	assert.False(t, ok)
+}
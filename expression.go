@@ -0,0 +1,380 @@
+package dynconf
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// AddExpressionWatch adds a watch on the value derived from the data of the given
+// input keys with the given expression, e.g. "base * multiplier", and then returns
+// the watch. The inputs map the variables of the expression to the keys, and the
+// watch is set on the expression. The value is re-evaluated once any of the keys
+// has been changed.
+//
+// The expression is in the syntax of Go expressions, and supports literals of
+// numbers, strings and booleans, the arithmetic operators + (also concatenating
+// strings), -, *, / and %, the comparison operators, the logical operators !, &&
+// and ||, field selection, e.g. "limits.max", and the functions min, max, abs,
+// round, floor, ceil and cond, e.g. "cond(enabled, limit, 0)". The data of a key
+// are parsed as JSON, or taken as a string if they are not valid JSON, and numbers
+// are float64. The result of the expression is formatted as a number, a boolean, a
+// string or JSON to create the value with the given value factory. A missing input
+// key fails the evaluation, unless all the keys are missing, in which case the
+// watch behaves as if its key is missing.
+func (w *Watcher) AddExpressionWatch(ctx context.Context, expression string, inputs map[string]string,
+	valueFactory ValueFactory, options ...WatchOption) (*Watch, error) {
+	expr, err := parser.ParseExpr(expression)
+
+	if err != nil {
+		return nil, fmt.Errorf("%w; expression=%q: %v", ErrInvalidExpression, expression, err)
+	}
+
+	names := make([]string, 0, len(inputs))
+	keys := make([]string, 0, len(inputs))
+
+	for name := range inputs {
+		if !token.IsIdentifier(name) {
+			return nil, fmt.Errorf("%w; expression=%q: invalid variable name %q", ErrInvalidExpression, expression, name)
+		}
+
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+
+	for _, name := range names {
+		keys = append(keys, inputs[name])
+	}
+
+	merge := func(datas [][]byte) ([]byte, error) {
+		if len(datas) != len(names) {
+			return nil, fmt.Errorf("dynconf: expression evaluation failed; expression=%q: missing input keys", expression)
+		}
+
+		variables := make(map[string]interface{}, len(names))
+
+		for i, name := range names {
+			variables[name] = parseExpressionInput(datas[i])
+		}
+
+		result, err := evaluateExpression(expr, variables)
+
+		if err != nil {
+			return nil, fmt.Errorf("dynconf: expression evaluation failed; expression=%q: %w", expression, err)
+		}
+
+		return formatExpressionResult(result)
+	}
+
+	backend := new(CompositeBackend).Init(keyLayers(w.backend, keys), merge)
+	return w.doAddWatch(ctx, backend, expression, valueFactory, options)
+}
+
+func parseExpressionInput(data []byte) interface{} {
+	var input interface{}
+
+	if err := json.Unmarshal(data, &input); err != nil {
+		return string(data)
+	}
+
+	return input
+}
+
+func formatExpressionResult(result interface{}) ([]byte, error) {
+	switch result := result.(type) {
+	case float64:
+		return []byte(strconv.FormatFloat(result, 'f', -1, 64)), nil
+	case bool:
+		return []byte(strconv.FormatBool(result)), nil
+	case string:
+		return []byte(result), nil
+	default:
+		return json.Marshal(result)
+	}
+}
+
+func evaluateExpression(expr ast.Expr, variables map[string]interface{}) (interface{}, error) {
+	switch expr := expr.(type) {
+	case *ast.ParenExpr:
+		return evaluateExpression(expr.X, variables)
+	case *ast.BasicLit:
+		switch expr.Kind {
+		case token.INT, token.FLOAT:
+			return strconv.ParseFloat(expr.Value, 64)
+		case token.STRING:
+			return strconv.Unquote(expr.Value)
+		default:
+			return nil, fmt.Errorf("unsupported literal %s", expr.Value)
+		}
+	case *ast.Ident:
+		switch expr.Name {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		}
+
+		value, ok := variables[expr.Name]
+
+		if !ok {
+			return nil, fmt.Errorf("undefined variable %q", expr.Name)
+		}
+
+		return value, nil
+	case *ast.SelectorExpr:
+		x, err := evaluateExpression(expr.X, variables)
+
+		if err != nil {
+			return nil, err
+		}
+
+		object, ok := x.(map[string]interface{})
+
+		if !ok {
+			return nil, fmt.Errorf("field %q of non-object", expr.Sel.Name)
+		}
+
+		value, ok := object[expr.Sel.Name]
+
+		if !ok {
+			return nil, fmt.Errorf("missing field %q", expr.Sel.Name)
+		}
+
+		return value, nil
+	case *ast.UnaryExpr:
+		x, err := evaluateExpression(expr.X, variables)
+
+		if err != nil {
+			return nil, err
+		}
+
+		switch expr.Op {
+		case token.SUB:
+			if x, ok := x.(float64); ok {
+				return -x, nil
+			}
+		case token.NOT:
+			if x, ok := x.(bool); ok {
+				return !x, nil
+			}
+		}
+
+		return nil, fmt.Errorf("invalid operation %s%v", expr.Op, x)
+	case *ast.BinaryExpr:
+		return evaluateBinaryExpression(expr, variables)
+	case *ast.CallExpr:
+		return evaluateCallExpression(expr, variables)
+	default:
+		return nil, fmt.Errorf("unsupported expression %T", expr)
+	}
+}
+
+func evaluateBinaryExpression(expr *ast.BinaryExpr, variables map[string]interface{}) (interface{}, error) {
+	x, err := evaluateExpression(expr.X, variables)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if expr.Op == token.LAND || expr.Op == token.LOR {
+		b, ok := x.(bool)
+
+		if !ok {
+			return nil, fmt.Errorf("invalid operation %v %s", x, expr.Op)
+		}
+
+		if (expr.Op == token.LAND) != b {
+			return b, nil
+		}
+
+		y, err := evaluateExpression(expr.Y, variables)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if _, ok := y.(bool); !ok {
+			return nil, fmt.Errorf("invalid operation %v %s %v", x, expr.Op, y)
+		}
+
+		return y, nil
+	}
+
+	y, err := evaluateExpression(expr.Y, variables)
+
+	if err != nil {
+		return nil, err
+	}
+
+	switch expr.Op {
+	case token.EQL:
+		return expressionValuesEqual(x, y), nil
+	case token.NEQ:
+		return !expressionValuesEqual(x, y), nil
+	}
+
+	if x, ok := x.(string); ok {
+		if y, ok := y.(string); ok {
+			switch expr.Op {
+			case token.ADD:
+				return x + y, nil
+			case token.LSS:
+				return x < y, nil
+			case token.LEQ:
+				return x <= y, nil
+			case token.GTR:
+				return x > y, nil
+			case token.GEQ:
+				return x >= y, nil
+			}
+		}
+	}
+
+	if x, ok := x.(float64); ok {
+		if y, ok := y.(float64); ok {
+			switch expr.Op {
+			case token.ADD:
+				return x + y, nil
+			case token.SUB:
+				return x - y, nil
+			case token.MUL:
+				return x * y, nil
+			case token.QUO:
+				if y == 0 {
+					return nil, errors.New("division by zero")
+				}
+
+				return x / y, nil
+			case token.REM:
+				if y == 0 {
+					return nil, errors.New("division by zero")
+				}
+
+				return math.Mod(x, y), nil
+			case token.LSS:
+				return x < y, nil
+			case token.LEQ:
+				return x <= y, nil
+			case token.GTR:
+				return x > y, nil
+			case token.GEQ:
+				return x >= y, nil
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("invalid operation %v %s %v", x, expr.Op, y)
+}
+
+func evaluateCallExpression(expr *ast.CallExpr, variables map[string]interface{}) (interface{}, error) {
+	ident, ok := expr.Fun.(*ast.Ident)
+
+	if !ok {
+		return nil, fmt.Errorf("unsupported function %T", expr.Fun)
+	}
+
+	args := make([]interface{}, len(expr.Args))
+
+	for i, arg := range expr.Args {
+		if ident.Name == "cond" && i >= 1 {
+			break
+		}
+
+		var err error
+		args[i], err = evaluateExpression(arg, variables)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	switch ident.Name {
+	case "cond":
+		if len(args) != 3 {
+			return nil, fmt.Errorf("cond: %d arguments, want 3", len(args))
+		}
+
+		b, ok := args[0].(bool)
+
+		if !ok {
+			return nil, fmt.Errorf("cond: non-boolean condition %v", args[0])
+		}
+
+		if b {
+			return evaluateExpression(expr.Args[1], variables)
+		}
+
+		return evaluateExpression(expr.Args[2], variables)
+	case "min", "max", "abs", "round", "floor", "ceil":
+		numbers := make([]float64, len(args))
+
+		for i, arg := range args {
+			number, ok := arg.(float64)
+
+			if !ok {
+				return nil, fmt.Errorf("%s: non-number argument %v", ident.Name, arg)
+			}
+
+			numbers[i] = number
+		}
+
+		switch ident.Name {
+		case "min", "max":
+			if len(numbers) == 0 {
+				return nil, fmt.Errorf("%s: no arguments", ident.Name)
+			}
+
+			result := numbers[0]
+
+			for _, number := range numbers[1:] {
+				if ident.Name == "min" {
+					result = math.Min(result, number)
+				} else {
+					result = math.Max(result, number)
+				}
+			}
+
+			return result, nil
+		default:
+			if len(numbers) != 1 {
+				return nil, fmt.Errorf("%s: %d arguments, want 1", ident.Name, len(numbers))
+			}
+
+			switch ident.Name {
+			case "abs":
+				return math.Abs(numbers[0]), nil
+			case "round":
+				return math.Round(numbers[0]), nil
+			case "floor":
+				return math.Floor(numbers[0]), nil
+			default:
+				return math.Ceil(numbers[0]), nil
+			}
+		}
+	default:
+		return nil, fmt.Errorf("undefined function %q", ident.Name)
+	}
+}
+
+func expressionValuesEqual(x interface{}, y interface{}) bool {
+	switch x.(type) {
+	case float64, bool, string, nil:
+		return x == y
+	default:
+		xData, _ := json.Marshal(x)
+		yData, _ := json.Marshal(y)
+		return bytes.Equal(xData, yData)
+	}
+}
+
+// ErrInvalidExpression is returned when an expression to derive a value is invalid.
+var ErrInvalidExpression = errors.New("dynconf: invalid expression")
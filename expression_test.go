@@ -0,0 +1,59 @@
+package dynconf_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/roy2220/dynconf"
+	"github.com/roy2220/dynconf/dynconftest"
+)
+
+func TestWatcherAddExpressionWatch(t *testing.T) {
+	wr, b := dynconftest.NewWatcher(t)
+	b.Set("base", []byte("100"))
+	b.Set("multiplier", []byte("1.5"))
+	b.Set("limits", []byte(`{"max": 120, "enabled": true}`))
+
+	w, err := wr.AddExpressionWatch(context.Background(),
+		"cond(limits.enabled, round(min(base * multiplier, limits.max)), base)",
+		map[string]string{"base": "base", "multiplier": "multiplier", "limits": "limits"},
+		dynconf.NewIntValue)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer w.Remove()
+	assert.Equal(t, int64(120), w.Value().(*dynconf.IntValue).Get())
+
+	events := w.Events()
+	b.Set("multiplier", []byte("1.1"))
+	<-events
+	assert.Equal(t, int64(110), w.Value().(*dynconf.IntValue).Get())
+
+	b.Set("limits", []byte(`{"max": 120, "enabled": false}`))
+	<-events
+	assert.Equal(t, int64(100), w.Value().(*dynconf.IntValue).Get())
+
+	w2, err := wr.AddExpressionWatch(context.Background(), `name + "-" + env == "foo-prod" && !(base < 0)`,
+		map[string]string{"name": "name", "env": "env", "base": "base"}, dynconf.NewBoolValue)
+	assert.Error(t, err)
+	assert.Nil(t, w2)
+
+	b.Set("name", []byte("foo"))
+	b.Set("env", []byte(`"prod"`))
+	w2, err = wr.AddExpressionWatch(context.Background(), `name + "-" + env == "foo-prod" && !(base < 0)`,
+		map[string]string{"name": "name", "env": "env", "base": "base"}, dynconf.NewBoolValue)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer w2.Remove()
+	assert.True(t, w2.Value().(*dynconf.BoolValue).Get())
+
+	_, err = wr.AddExpressionWatch(context.Background(), "base *", map[string]string{"base": "base"}, dynconf.NewIntValue)
+	assert.True(t, errors.Is(err, dynconf.ErrInvalidExpression))
+
+	_, err = wr.AddExpressionWatch(context.Background(), "base / 0", map[string]string{"base": "base"}, dynconf.NewIntValue)
+	assert.Error(t, err)
+}
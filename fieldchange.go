@@ -0,0 +1,100 @@
+package dynconf
+
+import (
+	"reflect"
+	"strings"
+)
+
+// FieldChangeHandler is the type of the function handling a change of a field, with
+// the old field and the new field, either of which is nil if missing.
+type FieldChangeHandler func(oldField interface{}, newField interface{})
+
+// FieldChangeDispatcher presents a dispatcher calling the handlers registered for
+// fields of values only if the fields have been changed, e.g. reinitializing a
+// connection pool only if the settings of the pool have been changed, rather than
+// whenever any setting has been changed. Objects are unwrapped from values of
+// type *ObjectValue, otherwise the values themselves are used. Fields are located
+// by paths of names, of struct fields or keys of maps, joined with ".", e.g.
+// "Database.Pool", and compared with reflect.DeepEqual.
+type FieldChangeDispatcher struct {
+	handlers []fieldChangeHandler
+}
+
+type fieldChangeHandler struct {
+	Names   []string
+	Handler FieldChangeHandler
+}
+
+// Handle registers the given handler for the field located by the given path, and
+// then returns the dispatcher. Handlers are called in the order of registration.
+// It should be called before Dispatch.
+func (fcd *FieldChangeDispatcher) Handle(path string, handler FieldChangeHandler) *FieldChangeDispatcher {
+	var names []string
+
+	if path != "" {
+		names = strings.Split(path, ".")
+	}
+
+	fcd.handlers = append(fcd.handlers, fieldChangeHandler{names, handler})
+	return fcd
+}
+
+// Dispatch compares the given old value and the given new value, and then calls the
+// handlers registered for the fields which have been changed. It is a Subscriber,
+// e.g. watch.Subscribe(dispatcher.Dispatch).
+func (fcd *FieldChangeDispatcher) Dispatch(oldValue Value, newValue Value) {
+	oldObject := reflect.ValueOf(unwrapObject(oldValue))
+	newObject := reflect.ValueOf(unwrapObject(newValue))
+
+	for _, handler := range fcd.handlers {
+		oldField := resolveField(oldObject, handler.Names)
+		newField := resolveField(newObject, handler.Names)
+
+		if !reflect.DeepEqual(oldField, newField) {
+			handler.Handler(oldField, newField)
+		}
+	}
+}
+
+func unwrapObject(value Value) interface{} {
+	if objectValue, ok := value.(*ObjectValue); ok {
+		return objectValue.Object()
+	}
+
+	return value
+}
+
+func resolveField(object reflect.Value, names []string) interface{} {
+	for _, name := range names {
+		for object.Kind() == reflect.Ptr || object.Kind() == reflect.Interface {
+			if object.IsNil() {
+				return nil
+			}
+
+			object = object.Elem()
+		}
+
+		switch object.Kind() {
+		case reflect.Struct:
+			object = object.FieldByName(name)
+		case reflect.Map:
+			if object.Type().Key().Kind() != reflect.String {
+				return nil
+			}
+
+			object = object.MapIndex(reflect.ValueOf(name).Convert(object.Type().Key()))
+		default:
+			return nil
+		}
+
+		if !object.IsValid() {
+			return nil
+		}
+	}
+
+	if !object.IsValid() || !object.CanInterface() {
+		return nil
+	}
+
+	return object.Interface()
+}
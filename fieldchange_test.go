@@ -0,0 +1,60 @@
+package dynconf_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/roy2220/dynconf"
+	"github.com/roy2220/dynconf/dynconftest"
+)
+
+func TestFieldChangeDispatcher(t *testing.T) {
+	type pool struct {
+		Size int `json:"size"`
+	}
+	type settings struct {
+		LogLevel string            `json:"log_level"`
+		Pool     *pool             `json:"pool"`
+		Labels   map[string]string `json:"labels"`
+	}
+
+	wr, b := dynconftest.NewWatcher(t)
+	b.Set("hello", []byte(`{"log_level": "info", "pool": {"size": 1}, "labels": {"a": "1"}}`))
+	w, err := wr.AddWatch(context.Background(), "hello", dynconf.JSONValueFactory(func() interface{} {
+		return new(settings)
+	}))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer w.Remove()
+
+	var changes []string
+	d := new(dynconf.FieldChangeDispatcher).
+		Handle("LogLevel", func(oldField, newField interface{}) {
+			changes = append(changes, "log_level:"+oldField.(string)+"->"+newField.(string))
+		}).
+		Handle("Pool.Size", func(oldField, newField interface{}) {
+			changes = append(changes, "pool_size")
+		}).
+		Handle("Labels.b", func(oldField, newField interface{}) {
+			assert.Nil(t, oldField)
+			changes = append(changes, "labels_b:"+newField.(string))
+		})
+	updated := make(chan struct{}, 1)
+	unsubscribe := w.Subscribe(func(oldValue, newValue dynconf.Value) {
+		d.Dispatch(oldValue, newValue)
+		updated <- struct{}{}
+	})
+	defer unsubscribe()
+
+	b.Set("hello", []byte(`{"log_level": "debug", "pool": {"size": 1}, "labels": {"a": "1"}}`))
+	<-updated
+	assert.Equal(t, []string{"log_level:info->debug"}, changes)
+
+	changes = nil
+	b.Set("hello", []byte(`{"log_level": "debug", "pool": {"size": 2}, "labels": {"a": "1", "b": "2"}}`))
+	<-updated
+	assert.Equal(t, []string{"pool_size", "labels_b:2"}, changes)
+}
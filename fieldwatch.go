@@ -0,0 +1,128 @@
+package dynconf
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/consul/api"
+)
+
+// AddFieldWatch adds a watch on the sub-document, located by the given JSON pointer
+// (RFC 6901), of the JSON data of the given key, and then returns the watch. The
+// value is only updated once the sub-document has been changed, and a missing
+// sub-document is treated as a missing key. The watch is set on the key and the
+// pointer joined with "#".
+func (w *Watcher) AddFieldWatch(ctx context.Context, key string, pointer string, valueFactory ValueFactory,
+	options ...WatchOption) (*Watch, error) {
+	tokens, err := parseJSONPointer(pointer)
+
+	if err != nil {
+		return nil, err
+	}
+
+	backend := fieldBackend{
+		Backend: w.backend,
+		Key:     key,
+		Pointer: pointer,
+		Tokens:  tokens,
+	}
+
+	return w.doAddWatch(ctx, &backend, key+"#"+pointer, valueFactory, options)
+}
+
+type fieldBackend struct {
+	Backend Backend
+	Key     string
+	Pointer string
+	Tokens  []string
+}
+
+var _ Backend = (*fieldBackend)(nil)
+
+func (fb *fieldBackend) Get(_ string, queryOptions *api.QueryOptions) (*api.KVPair, *api.QueryMeta, error) {
+	kvPair, queryMeta, err := fb.Backend.Get(fb.Key, queryOptions)
+
+	if err != nil || kvPair == nil {
+		return kvPair, queryMeta, err
+	}
+
+	data, ok, err := resolveJSONPointer(kvPair.Value, fb.Tokens)
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("dynconf: json pointer resolution failed; key=%q pointer=%q: %w", fb.Key, fb.Pointer, err)
+	}
+
+	if !ok {
+		return nil, queryMeta, nil
+	}
+
+	kvPairCopy := *kvPair
+	kvPairCopy.Value = data
+	return &kvPairCopy, queryMeta, nil
+}
+
+func (fb *fieldBackend) List(prefix string, _ *api.QueryOptions) (api.KVPairs, *api.QueryMeta, error) {
+	return nil, nil, fmt.Errorf("%w; prefix=%q", ErrListNotSupported, prefix)
+}
+
+func parseJSONPointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+
+	if pointer[0] != '/' {
+		return nil, fmt.Errorf("dynconf: invalid json pointer; pointer=%q", pointer)
+	}
+
+	tokens := strings.Split(pointer[1:], "/")
+
+	for i, token := range tokens {
+		tokens[i] = strings.NewReplacer("~1", "/", "~0", "~").Replace(token)
+	}
+
+	return tokens, nil
+}
+
+func resolveJSONPointer(data []byte, tokens []string) ([]byte, bool, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var document interface{}
+
+	if err := decoder.Decode(&document); err != nil {
+		return nil, false, err
+	}
+
+	for _, token := range tokens {
+		switch value := document.(type) {
+		case map[string]interface{}:
+			var ok bool
+			document, ok = value[token]
+
+			if !ok {
+				return nil, false, nil
+			}
+		case []interface{}:
+			i, err := strconv.Atoi(token)
+
+			if err != nil || i < 0 || i >= len(value) {
+				return nil, false, nil
+			}
+
+			document = value[i]
+		default:
+			return nil, false, nil
+		}
+	}
+
+	data, err := json.Marshal(document)
+
+	if err != nil {
+		return nil, false, err
+	}
+
+	return data, true, nil
+}
@@ -0,0 +1,35 @@
+package dynconf_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/roy2220/dynconf"
+	"github.com/roy2220/dynconf/dynconftest"
+)
+
+func TestWatcherAddFieldWatch(t *testing.T) {
+	wr, b := dynconftest.NewWatcher(t)
+	b.Set("config", []byte(`{"limits": {"max_conns": 100}, "name": "a"}`))
+	_, err := wr.AddFieldWatch(context.Background(), "config", "limits", dynconf.NewIntValue)
+	assert.EqualError(t, err, "dynconf: invalid json pointer; pointer=\"limits\"")
+
+	w, err := wr.AddFieldWatch(context.Background(), "config", "/limits/max_conns", dynconf.NewIntValue)
+	if assert.NoError(t, err) {
+		defer w.Remove()
+	}
+
+	assert.Equal(t, "config#/limits/max_conns", w.Key())
+	assert.Equal(t, int64(100), w.Value().(*dynconf.IntValue).Get())
+
+	newValues := make(chan dynconf.Value, 2)
+	unsubscribe := w.Subscribe(func(_, newValue dynconf.Value) { newValues <- newValue })
+	defer unsubscribe()
+
+	b.Set("config", []byte(`{"limits": {"max_conns": 100}, "name": "b"}`))
+	b.Set("config", []byte(`{"limits": {"max_conns": 200}, "name": "b"}`))
+	assert.Equal(t, int64(200), (<-newValues).(*dynconf.IntValue).Get())
+	assert.Len(t, newValues, 0)
+}
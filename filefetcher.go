@@ -0,0 +1,52 @@
+package dynconf
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// FileFetcher presents a fetcher reading the data of keys from files under a
+// directory, the path of the file of a key is made up of the directory and the
+// key, and the version of the data is derived from the modification time and the
+// size of the file.
+type FileFetcher struct {
+	dir string
+}
+
+var _ Fetcher = (*FileFetcher)(nil)
+
+// Init initializes the fetcher with the given directory and then returns the fetcher.
+func (ff *FileFetcher) Init(dir string) *FileFetcher {
+	ff.dir = dir
+	return ff
+}
+
+// Fetch implements Fetcher.Fetch.
+func (ff *FileFetcher) Fetch(_ context.Context, key string) ([]byte, string, bool, error) {
+	filePath := filepath.Join(ff.dir, filepath.FromSlash(key))
+	fileInfo, err := os.Stat(filePath)
+
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, "", false, nil
+		}
+
+		return nil, "", false, err
+	}
+
+	data, err := ioutil.ReadFile(filePath)
+
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, "", false, nil
+		}
+
+		return nil, "", false, err
+	}
+
+	version := strconv.FormatInt(fileInfo.ModTime().UnixNano(), 10) + "-" + strconv.FormatInt(fileInfo.Size(), 10)
+	return data, version, true, nil
+}
@@ -0,0 +1,294 @@
+// Package flags implements feature flags with targeting rules on top of dynconf
+// watches.
+package flags
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"strings"
+
+	"github.com/roy2220/dynconf"
+)
+
+// FlagSet presents a set of feature flags backed by either a key, holding the
+// flags in a JSON object keyed by flag names, or a prefix, with the keys holding
+// the flags named after the keys with the prefix trimmed.
+type FlagSet struct {
+	watch       *dynconf.Watch
+	prefixWatch *dynconf.PrefixWatch
+}
+
+// AddFlagSet adds a watch on the given key with the given watcher, and then returns
+// a flag set backed by the key.
+func AddFlagSet(ctx context.Context, watcher *dynconf.Watcher, key string, options ...dynconf.WatchOption) (*FlagSet, error) {
+	watch, err := watcher.AddWatch(ctx, key, newFlagsValue, options...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &FlagSet{watch: watch}, nil
+}
+
+// AddPrefixFlagSet adds a watch on the keys with the given prefix with the given
+// watcher, and then returns a flag set backed by the prefix.
+func AddPrefixFlagSet(ctx context.Context, watcher *dynconf.Watcher, prefix string,
+	options ...dynconf.WatchOption) (*FlagSet, error) {
+	prefixWatch, err := watcher.AddPrefixWatch(ctx, prefix, newFlagValue, dynconf.PrefixWatchCallbacks{}, options...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &FlagSet{prefixWatch: prefixWatch}, nil
+}
+
+// Remove removes the watch of the flag set.
+func (fs *FlagSet) Remove() {
+	if fs.watch != nil {
+		fs.watch.Remove()
+	} else {
+		fs.prefixWatch.Remove()
+	}
+}
+
+// Bool evaluates the flag with the given name against the given evaluation context
+// and then returns the result, or the given default value if the flag doesn't exist
+// or the result isn't a bool.
+func (fs *FlagSet) Bool(evalContext Context, name string, defaultValue bool) bool {
+	value := defaultValue
+	fs.evaluate(evalContext, name, &value)
+	return value
+}
+
+// Int evaluates the flag with the given name against the given evaluation context
+// and then returns the result, or the given default value if the flag doesn't exist
+// or the result isn't an int.
+func (fs *FlagSet) Int(evalContext Context, name string, defaultValue int64) int64 {
+	value := defaultValue
+	fs.evaluate(evalContext, name, &value)
+	return value
+}
+
+// String evaluates the flag with the given name against the given evaluation context
+// and then returns the result, or the given default value if the flag doesn't exist
+// or the result isn't a string.
+func (fs *FlagSet) String(evalContext Context, name string, defaultValue string) string {
+	value := defaultValue
+	fs.evaluate(evalContext, name, &value)
+	return value
+}
+
+func (fs *FlagSet) evaluate(evalContext Context, name string, value interface{}) {
+	flag, ok := fs.flag(name)
+
+	if !ok {
+		return
+	}
+
+	data := flag.Evaluate(evalContext, name)
+
+	if data == nil {
+		return
+	}
+
+	// Keep the default value on type mismatch.
+	_ = json.Unmarshal(data, value)
+}
+
+func (fs *FlagSet) flag(name string) (*Flag, bool) {
+	if fs.watch != nil {
+		flag, ok := fs.watch.Value().(*flagsValue).flags[name]
+		return flag, ok
+	}
+
+	value, ok := fs.prefixWatch.Value(fs.prefixWatch.Prefix() + name)
+
+	if !ok {
+		return nil, false
+	}
+
+	return &value.(*flagValue).flag, true
+}
+
+// Context represents the context which flags are evaluated against.
+type Context struct {
+	// UserID is the ID of the user, which is matched against the user IDs of rules
+	// and bucketed for the percentages of rules.
+	UserID string
+
+	// Attributes are the attributes matched against the attributes of rules.
+	Attributes map[string]string
+}
+
+// Flag represents a feature flag, which is stored in JSON.
+type Flag struct {
+	// Value is the value of the flag if no rule matches.
+	Value json.RawMessage `json:"value"`
+
+	// Rules are the targeting rules of the flag, the value of the first matching
+	// one is taken.
+	Rules []Rule `json:"rules"`
+}
+
+// Rule represents a targeting rule of a flag. A rule matches if all of its
+// conditions specified are met.
+type Rule struct {
+	// UserIDs optionally lists the user IDs matching the rule.
+	UserIDs []string `json:"user_ids"`
+
+	// Attributes optionally lists the allowed values of attributes.
+	Attributes map[string][]string `json:"attributes"`
+
+	// Percent optionally specifies the percentage of users matching the rule, the
+	// users are bucketed deterministically per flag.
+	Percent *int `json:"percent"`
+
+	// Value is the value of the flag if the rule matches.
+	Value json.RawMessage `json:"value"`
+}
+
+// Evaluate evaluates the flag with the given name against the given evaluation
+// context and then returns the value in JSON.
+func (f *Flag) Evaluate(evalContext Context, name string) json.RawMessage {
+	for i := range f.Rules {
+		rule := &f.Rules[i]
+
+		if rule.Match(evalContext, name) {
+			return rule.Value
+		}
+	}
+
+	return f.Value
+}
+
+// Validate validates the flag.
+func (f *Flag) Validate() error {
+	for i := range f.Rules {
+		if percent := f.Rules[i].Percent; percent != nil && (*percent < 0 || *percent > 100) {
+			return fmt.Errorf("rule_index=%d percent=%d: %w", i, *percent, errInvalidPercent)
+		}
+	}
+
+	return nil
+}
+
+// Match returns whether the rule of the flag with the given name matches the
+// given evaluation context.
+func (r *Rule) Match(evalContext Context, name string) bool {
+	if len(r.UserIDs) >= 1 && !containsString(r.UserIDs, evalContext.UserID) {
+		return false
+	}
+
+	for attributeName, attributeValues := range r.Attributes {
+		attributeValue, ok := evalContext.Attributes[attributeName]
+
+		if !ok || !containsString(attributeValues, attributeValue) {
+			return false
+		}
+	}
+
+	if r.Percent != nil && bucket(name, evalContext.UserID) >= *r.Percent {
+		return false
+	}
+
+	return true
+}
+
+func newFlagsValue() dynconf.Value { return new(flagsValue) }
+
+type flagsValue struct {
+	flags map[string]*Flag
+}
+
+var (
+	_ dynconf.Value          = (*flagsValue)(nil)
+	_ dynconf.ValueValidator = (*flagsValue)(nil)
+)
+
+func (fv *flagsValue) Unmarshal(data []byte) error {
+	var flags map[string]*Flag
+
+	if err := json.Unmarshal(data, &flags); err != nil {
+		return err
+	}
+
+	fv.flags = flags
+	return nil
+}
+
+func (fv *flagsValue) Validate() error {
+	for name, flag := range fv.flags {
+		if flag == nil {
+			return fmt.Errorf("flag_name=%q: %w", name, errMissingFlag)
+		}
+
+		if err := flag.Validate(); err != nil {
+			return fmt.Errorf("flag_name=%q: %w", name, err)
+		}
+	}
+
+	return nil
+}
+
+func (fv *flagsValue) String() string {
+	names := make([]string, 0, len(fv.flags))
+
+	for name := range fv.flags {
+		names = append(names, name)
+	}
+
+	sort.Strings(names)
+	return strings.Join(names, ",")
+}
+
+func newFlagValue() dynconf.Value { return new(flagValue) }
+
+type flagValue struct {
+	flag Flag
+	data []byte
+}
+
+var (
+	_ dynconf.Value          = (*flagValue)(nil)
+	_ dynconf.ValueValidator = (*flagValue)(nil)
+)
+
+func (fv *flagValue) Unmarshal(data []byte) error {
+	if err := json.Unmarshal(data, &fv.flag); err != nil {
+		return err
+	}
+
+	fv.data = data
+	return nil
+}
+
+func (fv *flagValue) Validate() error { return fv.flag.Validate() }
+func (fv *flagValue) String() string  { return string(fv.data) }
+
+func bucket(name string, userID string) int {
+	hash := fnv.New32a()
+	hash.Write([]byte(name))
+	hash.Write([]byte{0})
+	hash.Write([]byte(userID))
+	return int(hash.Sum32() % 100)
+}
+
+func containsString(ss []string, s string) bool {
+	for _, s2 := range ss {
+		if s2 == s {
+			return true
+		}
+	}
+
+	return false
+}
+
+var (
+	errInvalidPercent = errors.New("invalid percent")
+	errMissingFlag    = errors.New("missing flag")
+)
@@ -0,0 +1,66 @@
+package flags_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/roy2220/dynconf/dynconftest"
+	"github.com/roy2220/dynconf/flags"
+)
+
+func TestFlagSet(t *testing.T) {
+	wr, b := dynconftest.NewWatcher(t)
+	b.Set("flags", []byte(`{
+		"new_ui": {"value": false, "rules": [{"user_ids": ["alice"], "value": true}]},
+		"max_items": {"value": 10, "rules": [{"attributes": {"plan": ["pro"]}, "value": 100}]},
+		"theme": {"value": "light", "rules": [{"percent": 100, "value": "dark"}]},
+		"broken": {"value": "x"}
+	}`))
+	fs, err := flags.AddFlagSet(context.Background(), wr, "flags")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer fs.Remove()
+
+	alice := flags.Context{UserID: "alice"}
+	bob := flags.Context{UserID: "bob", Attributes: map[string]string{"plan": "pro"}}
+	assert.True(t, fs.Bool(alice, "new_ui", false))
+	assert.False(t, fs.Bool(bob, "new_ui", true))
+	assert.Equal(t, int64(10), fs.Int(alice, "max_items", 0))
+	assert.Equal(t, int64(100), fs.Int(bob, "max_items", 0))
+	assert.Equal(t, "dark", fs.String(alice, "theme", ""))
+	assert.Equal(t, int64(7), fs.Int(alice, "broken", 7))
+	assert.Equal(t, "y", fs.String(alice, "unknown", "y"))
+}
+
+func TestPrefixFlagSet(t *testing.T) {
+	wr, b := dynconftest.NewWatcher(t)
+	b.Set("flags/new_ui", []byte(`{"value": true, "rules": [{"percent": 0, "value": false}]}`))
+	fs, err := flags.AddPrefixFlagSet(context.Background(), wr, "flags/")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer fs.Remove()
+
+	assert.True(t, fs.Bool(flags.Context{UserID: "alice"}, "new_ui", false))
+	assert.False(t, fs.Bool(flags.Context{UserID: "alice"}, "old_ui", false))
+}
+
+func TestRuleMatch(t *testing.T) {
+	percent := 30
+	r := flags.Rule{Percent: &percent}
+	n := 0
+
+	for _, userID := range []string{"0", "1", "2", "3", "4", "5", "6", "7", "8", "9"} {
+		if r.Match(flags.Context{UserID: userID}, "flag") {
+			n++
+		}
+	}
+
+	assert.True(t, n < 10)
+	r = flags.Rule{UserIDs: []string{"alice"}, Attributes: map[string][]string{"plan": {"pro"}}}
+	assert.False(t, r.Match(flags.Context{UserID: "alice"}, "flag"))
+	assert.True(t, r.Match(flags.Context{UserID: "alice", Attributes: map[string]string{"plan": "pro"}}, "flag"))
+}
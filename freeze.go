@@ -0,0 +1,181 @@
+package dynconf
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+
+	"github.com/hashicorp/consul/api"
+)
+
+// Freeze freezes all the watches and the prefix watches added to the watcher, i.e.
+// holds the updates of the values without applying them until Unfreeze is called,
+// e.g. to prevent any configuration movement during incident response. The latest
+// data are applied once the watches have been unfrozen.
+func (w *Watcher) Freeze() {
+	if w.freezer.Freeze() {
+		w.logger.Log(LogWarn, "dynconf_watcher_frozen")
+	}
+}
+
+// Unfreeze undoes Freeze. Watches frozen on their own remain frozen.
+func (w *Watcher) Unfreeze() {
+	if w.freezer.Unfreeze() {
+		w.logger.Log(LogInfo, "dynconf_watcher_unfrozen")
+	}
+}
+
+// IsFrozen returns whether the watcher has been frozen.
+func (w *Watcher) IsFrozen() bool {
+	return w.freezer.IsFrozen()
+}
+
+// Freeze freezes the watch, i.e. holds the updates of the value without applying
+// them until Unfreeze is called. The latest data are applied once the watch has
+// been unfrozen. Refreshes of the frozen watch fail with ErrFrozen.
+func (w *Watch) Freeze() {
+	if w.freezer.Freeze() {
+		w.logger.Log(LogWarn, "dynconf_watch_frozen", "key", w.key)
+	}
+}
+
+// Unfreeze undoes Freeze. The watch remains frozen while the watcher is frozen.
+func (w *Watch) Unfreeze() {
+	if w.freezer.Unfreeze() {
+		w.logger.Log(LogInfo, "dynconf_watch_unfrozen", "key", w.key)
+	}
+}
+
+// IsFrozen returns whether the watch or the watcher has been frozen.
+func (w *Watch) IsFrozen() bool {
+	return w.freezer.IsFrozen() || w.watcher.freezer.IsFrozen()
+}
+
+// Freeze freezes the prefix watch, i.e. holds the updates of the values without
+// applying them until Unfreeze is called. The latest data are applied once the
+// prefix watch has been unfrozen.
+func (pw *PrefixWatch) Freeze() {
+	if pw.freezer.Freeze() {
+		pw.logger.Log(LogWarn, "dynconf_watch_frozen", "prefix", pw.prefix)
+	}
+}
+
+// Unfreeze undoes Freeze. The prefix watch remains frozen while the watcher is
+// frozen.
+func (pw *PrefixWatch) Unfreeze() {
+	if pw.freezer.Unfreeze() {
+		pw.logger.Log(LogInfo, "dynconf_watch_unfrozen", "prefix", pw.prefix)
+	}
+}
+
+// IsFrozen returns whether the prefix watch or the watcher has been frozen.
+func (pw *PrefixWatch) IsFrozen() bool {
+	return pw.freezer.IsFrozen() || pw.watcher.freezer.IsFrozen()
+}
+
+func (w *Watch) hasUpdate(kvPair *api.KVPair, scheduled bool, ttlExpired bool) bool {
+	if kvPair == nil {
+		return w.Exists()
+	}
+
+	return kvPair.ModifyIndex != w.valueIndex || !w.Exists() || scheduled || ttlExpired
+}
+
+// holdUpdate holds the update while the watch is frozen, the update is made again
+// once the watch has been unfrozen.
+func (w *Watch) holdUpdate(scheduled bool, ttlExpired bool, refreshResults []chan error) {
+	if scheduled {
+		atomic.StoreInt32(&w.scheduledUpdate, 1)
+	}
+
+	if ttlExpired {
+		atomic.StoreInt32(&w.ttlExpiry, 1)
+	}
+
+	w.logger.Log(LogInfo, "dynconf_update_frozen", "key", w.key)
+	w.options.Metrics.IncrCounter("dynconf_frozen_updates", "key", w.key)
+	endRefreshes(refreshResults, fmt.Errorf("%w; key=%q", ErrFrozen, w.key))
+	waitUntilUnfrozen(w.ctx.Done(), &w.freezer, &w.watcher.freezer)
+}
+
+func (pw *PrefixWatch) holdUpdate() {
+	pw.logger.Log(LogInfo, "dynconf_update_frozen", "prefix", pw.prefix)
+	pw.options.Metrics.IncrCounter("dynconf_frozen_updates", "prefix", pw.prefix)
+	waitUntilUnfrozen(pw.ctx.Done(), &pw.freezer, &pw.watcher.freezer)
+}
+
+func waitUntilUnfrozen(done <-chan struct{}, freezers ...*freezer) {
+	for {
+		frozen := false
+
+		for _, freezer := range freezers {
+			if !freezer.IsFrozen() {
+				continue
+			}
+
+			frozen = true
+
+			select {
+			case <-freezer.Unfrozen():
+			case <-done:
+				return
+			}
+		}
+
+		if !frozen {
+			return
+		}
+	}
+}
+
+type freezer struct {
+	mu       sync.Mutex
+	unfrozen chan struct{}
+}
+
+func (f *freezer) Freeze() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.unfrozen != nil {
+		return false
+	}
+
+	f.unfrozen = make(chan struct{})
+	return true
+}
+
+func (f *freezer) Unfreeze() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.unfrozen == nil {
+		return false
+	}
+
+	close(f.unfrozen)
+	f.unfrozen = nil
+	return true
+}
+
+func (f *freezer) IsFrozen() bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.unfrozen != nil
+}
+
+// Unfrozen returns a channel which is closed once the freezer has been unfrozen.
+func (f *freezer) Unfrozen() <-chan struct{} {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.unfrozen == nil {
+		return syncedChannel
+	}
+
+	return f.unfrozen
+}
+
+// ErrFrozen is returned when refreshing a frozen watch.
+var ErrFrozen = errors.New("dynconf: frozen")
@@ -0,0 +1,67 @@
+package dynconf_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/roy2220/dynconf"
+	"github.com/roy2220/dynconf/dynconftest"
+)
+
+func TestWatcherFreeze(t *testing.T) {
+	b := new(dynconftest.Backend).Init()
+	b.Set("hello", []byte("1"))
+	b.Set("dir/a", []byte("1"))
+	m := new(counters)
+	wr := dynconf.NewWatcherWithBackend(b, dynconf.WithLogger(dynconftest.Logger{T: t}), dynconf.WithMetrics(m))
+	defer wr.Close(context.Background())
+
+	w, err := wr.AddWatch(context.Background(), "hello", dynconf.NewIntValue)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	pw, err := wr.AddPrefixWatch(context.Background(), "dir/", dynconf.NewIntValue, dynconf.PrefixWatchCallbacks{})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	prefixValue := func() int64 {
+		v, _ := pw.Value("dir/a")
+		return v.(*dynconf.IntValue).Get()
+	}
+
+	wr.Freeze()
+	assert.True(t, wr.IsFrozen())
+	assert.True(t, w.IsFrozen())
+	assert.True(t, pw.IsFrozen())
+	b.Set("hello", []byte("2"))
+	b.Set("hello", []byte("3"))
+	b.Set("dir/a", []byte("2"))
+	assert.Eventually(t, func() bool {
+		return m.Get("dynconf_frozen_updates", "key", "hello") == 1 &&
+			m.Get("dynconf_frozen_updates", "prefix", "dir/") == 1
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, int64(1), w.Value().(*dynconf.IntValue).Get())
+	assert.Equal(t, int64(1), prefixValue())
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.True(t, errors.Is(w.Refresh(ctx), dynconf.ErrFrozen))
+
+	w.Freeze()
+	wr.Unfreeze()
+	assert.True(t, w.IsFrozen())
+	assert.False(t, pw.IsFrozen())
+	assert.Eventually(t, func() bool { return prefixValue() == 2 }, time.Second, 10*time.Millisecond)
+	assert.True(t, errors.Is(w.Refresh(ctx), dynconf.ErrFrozen))
+	assert.Equal(t, int64(1), w.Value().(*dynconf.IntValue).Get())
+	assert.Equal(t, 1, m.Get("dynconf_frozen_updates", "key", "hello"))
+
+	w.Unfreeze()
+	assert.False(t, w.IsFrozen())
+	assert.Eventually(t, func() bool { return w.Value().(*dynconf.IntValue).Get() == 3 }, time.Second, 10*time.Millisecond)
+	assert.NoError(t, w.Refresh(ctx))
+}
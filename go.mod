@@ -3,8 +3,14 @@ module github.com/roy2220/dynconf
 go 1.13
 
 require (
+	github.com/BurntSushi/toml v0.4.1
+	github.com/go-redis/redis/v8 v8.11.4
 	github.com/hashicorp/consul/api v1.4.0
-	github.com/rs/zerolog v1.18.0
-	github.com/stretchr/testify v1.6.0
+	github.com/hashicorp/hcl v1.0.0
+	github.com/stretchr/testify v1.7.0
+	github.com/xeipuuv/gojsonschema v1.2.0
 	golang.org/x/lint v0.0.0-20200302205851-738671d3881b // indirect
+	golang.org/x/time v0.0.0-20210220033141-f8bda1e9f3ba
+	google.golang.org/protobuf v1.27.1
+	gopkg.in/yaml.v3 v3.0.0-20200313102051-9f266ea9e77c
 )
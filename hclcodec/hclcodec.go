@@ -0,0 +1,34 @@
+// Package hclcodec registers the codec for format "hcl" once imported. The codec
+// only unmarshals, and marshaling fails with dynconf.ErrMarshalNotSupported.
+package hclcodec
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/hcl"
+
+	"github.com/roy2220/dynconf"
+)
+
+// Format is the format for which the codec is registered.
+const Format = "hcl"
+
+// ValueFactory returns a value factory creating values of type *dynconf.ObjectValue,
+// which unmarshal objects, created by the given function, from HCL data.
+func ValueFactory(newObject func() interface{}) dynconf.ValueFactory {
+	return dynconf.CodecValueFactory(Format, newObject)
+}
+
+type codec struct{}
+
+func (codec) Marshal(interface{}) ([]byte, error) {
+	return nil, fmt.Errorf("%w; format=%q", dynconf.ErrMarshalNotSupported, Format)
+}
+
+func (codec) Unmarshal(data []byte, object interface{}) error {
+	return hcl.Unmarshal(data, object)
+}
+
+func init() {
+	dynconf.RegisterCodec(Format, codec{})
+}
@@ -0,0 +1,29 @@
+package hclcodec_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/roy2220/dynconf"
+	"github.com/roy2220/dynconf/hclcodec"
+)
+
+type config struct {
+	Foo int    `hcl:"foo"`
+	Bar string `hcl:"bar"`
+}
+
+func TestValueFactory(t *testing.T) {
+	v := hclcodec.ValueFactory(func() interface{} { return new(config) })()
+	assert.NoError(t, v.Unmarshal([]byte("foo = 1\nbar = \"a\"\n")))
+	assert.Equal(t, &config{Foo: 1, Bar: "a"}, v.(*dynconf.ObjectValue).Object())
+
+	assert.Error(t, v.Unmarshal([]byte("foo = {")))
+	assert.Error(t, v.Unmarshal([]byte("foo = \"a\"")))
+
+	_, err := v.(*dynconf.ObjectValue).Marshal()
+	assert.True(t, errors.Is(err, dynconf.ErrMarshalNotSupported))
+	assert.EqualError(t, err, "dynconf: marshal not supported; format=\"hcl\"")
+}
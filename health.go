@@ -0,0 +1,192 @@
+package dynconf
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sort"
+	"time"
+)
+
+// WithHealthFailureThreshold returns an option to set the number of consecutive
+// failures, from which a watch is considered failing rather than degraded by
+// Watcher.Health, defaults to 5.
+func WithHealthFailureThreshold(healthFailureThreshold int) WatcherOption {
+	return watcherOption(func(w *Watcher) {
+		w.healthFailureThreshold = healthFailureThreshold
+	})
+}
+
+// Health returns a report summarizing the health of the watches added to the
+// watcher. A watch is failing if it hasn't been synced, or if it has failed
+// consecutively for at least the threshold set by WithHealthFailureThreshold, it
+// is degraded if its last query or update has failed, or if its value is stale
+// according to WithMaxStaleness, otherwise it is healthy.
+// The watcher is as healthy as its least healthy watch.
+func (w *Watcher) Health() HealthReport {
+	healthFailureThreshold := w.healthFailureThreshold
+
+	if healthFailureThreshold < 1 {
+		healthFailureThreshold = defaultHealthFailureThreshold
+	}
+
+	var healthReport HealthReport
+
+	for _, watch := range w.watchLoops() {
+		var watchHealth WatchHealth
+
+		switch watch := watch.(type) {
+		case *Watch:
+			watchHealth = WatchHealth{
+				Key:                 watch.Key(),
+				LastError:           watch.LastError(),
+				ConsecutiveFailures: watch.ConsecutiveFailures(),
+				LastUpdateTime:      watch.LastUpdateTime(),
+				Stale:               watch.IsStale(),
+			}
+		case *PrefixWatch:
+			watchHealth = WatchHealth{
+				Key:                 watch.Prefix(),
+				LastError:           watch.LastError(),
+				ConsecutiveFailures: watch.ConsecutiveFailures(),
+			}
+		default:
+			continue
+		}
+
+		select {
+		case <-watch.Synced():
+			switch {
+			case watchHealth.ConsecutiveFailures >= healthFailureThreshold:
+				watchHealth.Status = HealthFailing
+			case watchHealth.LastError != nil || watchHealth.Stale:
+				watchHealth.Status = HealthDegraded
+			default:
+				watchHealth.Status = HealthHealthy
+			}
+		default:
+			watchHealth.Status = HealthFailing
+		}
+
+		if watchHealth.Status > healthReport.Status {
+			healthReport.Status = watchHealth.Status
+		}
+
+		healthReport.Watches = append(healthReport.Watches, watchHealth)
+	}
+
+	sort.Slice(healthReport.Watches, func(i, j int) bool {
+		return healthReport.Watches[i].Key < healthReport.Watches[j].Key
+	})
+
+	return healthReport
+}
+
+// ReportHealth calls the given function with the health report of the watcher
+// immediately and then every given interval, until the given context is done.
+// It helps integrate with health services, e.g. the gRPC health service:
+//
+//	go w.ReportHealth(ctx, time.Second, func(healthReport dynconf.HealthReport) {
+//		servingStatus := healthpb.HealthCheckResponse_SERVING
+//		if healthReport.Status == dynconf.HealthFailing {
+//			servingStatus = healthpb.HealthCheckResponse_NOT_SERVING
+//		}
+//		healthServer.SetServingStatus("", servingStatus)
+//	})
+func (w *Watcher) ReportHealth(ctx context.Context, interval time.Duration, report func(healthReport HealthReport)) {
+	ticker := w.clock().NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		report(w.Health())
+
+		select {
+		case <-ticker.C():
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// HealthHandler returns an HTTP handler, e.g. for "/healthz", reporting the health
+// of the watcher in JSON, with the status code 503 if the watcher is failing, or
+// 200 otherwise.
+func (w *Watcher) HealthHandler() http.Handler {
+	return http.HandlerFunc(func(responseWriter http.ResponseWriter, request *http.Request) {
+		healthReport := w.Health()
+		statusCode := http.StatusOK
+
+		if healthReport.Status == HealthFailing {
+			statusCode = http.StatusServiceUnavailable
+		}
+
+		responseWriter.Header().Set("Content-Type", "application/json")
+		responseWriter.WriteHeader(statusCode)
+		encoder := json.NewEncoder(responseWriter)
+		encoder.SetIndent("", "  ")
+		encoder.Encode(&healthReport)
+	})
+}
+
+// HealthReport represents a report summarizing the health of a watcher.
+type HealthReport struct {
+	Status  HealthStatus  `json:"status"`
+	Watches []WatchHealth `json:"watches"`
+}
+
+// WatchHealth represents the health of a watch.
+type WatchHealth struct {
+	// Key is the key, or the prefix, on which the watch is set.
+	Key                 string       `json:"key"`
+	Status              HealthStatus `json:"status"`
+	LastError           error        `json:"-"`
+	ConsecutiveFailures int          `json:"consecutive_failures"`
+	LastUpdateTime      time.Time    `json:"last_update_time"`
+	Stale               bool         `json:"stale,omitempty"`
+}
+
+// MarshalJSON implements json.Marshaler.
+func (wh WatchHealth) MarshalJSON() ([]byte, error) {
+	type watchHealth WatchHealth
+	var lastError string
+
+	if wh.LastError != nil {
+		lastError = wh.LastError.Error()
+	}
+
+	return json.Marshal(struct {
+		watchHealth
+		LastError string `json:"last_error,omitempty"`
+	}{watchHealth(wh), lastError})
+}
+
+// HealthStatus represents a status of health.
+type HealthStatus int
+
+// The statuses of health, in the order of severity.
+const (
+	HealthHealthy HealthStatus = iota
+	HealthDegraded
+	HealthFailing
+)
+
+// String returns a string representing the status of health.
+func (hs HealthStatus) String() string {
+	switch hs {
+	case HealthHealthy:
+		return "healthy"
+	case HealthDegraded:
+		return "degraded"
+	case HealthFailing:
+		return "failing"
+	default:
+		return "unknown"
+	}
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (hs HealthStatus) MarshalText() ([]byte, error) {
+	return []byte(hs.String()), nil
+}
+
+const defaultHealthFailureThreshold = 5
@@ -0,0 +1,80 @@
+package dynconf_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/roy2220/dynconf"
+	"github.com/roy2220/dynconf/dynconftest"
+)
+
+func TestWatcherHealth(t *testing.T) {
+	b := new(dynconftest.Backend).Init()
+	b.Set("hello", []byte("1"))
+	b.Set("world/a", []byte("2"))
+	wr := dynconf.NewWatcherWithBackend(b, dynconf.WithLogger(dynconftest.Logger{T: t}),
+		dynconf.WithHealthFailureThreshold(2))
+	w, err := wr.AddWatch(context.Background(), "hello", dynconf.NewIntValue)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer w.Remove()
+	pw, err := wr.AddPrefixWatch(context.Background(), "world/", dynconf.NewIntValue, dynconf.PrefixWatchCallbacks{})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer pw.Remove()
+
+	hr := wr.Health()
+	assert.Equal(t, dynconf.HealthHealthy, hr.Status)
+	if assert.Len(t, hr.Watches, 2) {
+		assert.Equal(t, "hello", hr.Watches[0].Key)
+		assert.Equal(t, "world/", hr.Watches[1].Key)
+	}
+
+	events := w.Events()
+	b.Set("hello", []byte("x"))
+	<-events
+	hr = wr.Health()
+	assert.Equal(t, dynconf.HealthDegraded, hr.Status)
+	assert.Equal(t, dynconf.HealthDegraded, hr.Watches[0].Status)
+	assert.Error(t, hr.Watches[0].LastError)
+
+	b.Set("hello", []byte("y"))
+	<-events
+	assert.Equal(t, dynconf.HealthFailing, wr.Health().Status)
+
+	server := httptest.NewServer(wr.HealthHandler())
+	defer server.Close()
+	response, err := http.Get(server.URL)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer response.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, response.StatusCode)
+	var body struct {
+		Status  string `json:"status"`
+		Watches []struct {
+			Key       string `json:"key"`
+			Status    string `json:"status"`
+			LastError string `json:"last_error"`
+		} `json:"watches"`
+	}
+	if assert.NoError(t, json.NewDecoder(response.Body).Decode(&body)) {
+		assert.Equal(t, "failing", body.Status)
+		if assert.Len(t, body.Watches, 2) {
+			assert.Equal(t, "failing", body.Watches[0].Status)
+			assert.NotEmpty(t, body.Watches[0].LastError)
+			assert.Equal(t, "healthy", body.Watches[1].Status)
+		}
+	}
+
+	b.Set("hello", []byte("2"))
+	<-events
+	assert.Equal(t, dynconf.HealthHealthy, wr.Health().Status)
+}
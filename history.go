@@ -0,0 +1,101 @@
+package dynconf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// WithHistory returns an option to keep the raw data of the last given number of
+// values applied by the watch in memory, which are returned by Watch.History and
+// can be restored with Watch.Rollback. Default values aren't kept. It takes effect
+// only for watches on keys.
+func WithHistory(maxEntries int) WatchOption {
+	return func(wo *watchOptions) {
+		wo.HistorySize = maxEntries
+	}
+}
+
+// History returns the raw data of the values applied by the watch, kept if the watch
+// has been added with WithHistory, from the newest to the oldest, i.e. the first one
+// is that of the latest value if the key exists.
+func (w *Watch) History() []RawValue {
+	return w.history.Entries()
+}
+
+// Rollback writes the raw data and the flags of the value the given number of steps
+// back in the history of the watch, e.g. 1 for the previous value, back to the key
+// with the given publisher, with check-and-set on the modify index of the latest
+// value, and then returns. The watch picks up the data written as usual. Rollback
+// fails with ErrHistoryEntryNotFound if there is no such a value in the history,
+// and with ErrPublishConflict if the key has been modified since the latest value,
+// e.g. by others or by a rollback not yet picked up by the watch.
+func (w *Watch) Rollback(ctx context.Context, publisher *Publisher, steps int) error {
+	entries := w.History()
+
+	if steps < 1 || steps >= len(entries) || !w.Exists() {
+		return fmt.Errorf("%w; key=%q steps=%d number_of_entries=%d", ErrHistoryEntryNotFound, w.key, steps, len(entries))
+	}
+
+	latestEntry := entries[0]
+	entry := entries[steps]
+	queryOptions := w.options.makeQueryOptions(ctx, w.options.QueryOptions)
+	w.logger.Log(LogInfo, "dynconf_rollback_requested", "key", w.key, "steps", steps,
+		"modify_index", latestEntry.ModifyIndex, "target_modify_index", entry.ModifyIndex)
+	return publisher.publishData(w.key, entry.Data, entry.Flags, latestEntry.ModifyIndex, queryOptions, nil, nil)
+}
+
+func (w *Watch) recordHistory(raw []byte, modifyIndex uint64, flags uint64) {
+	if w.options.HistorySize < 1 || modifyIndex == 0 {
+		return
+	}
+
+	w.history.Add(w.options.HistorySize, RawValue{
+		Data:        raw,
+		ModifyIndex: modifyIndex,
+		Flags:       flags,
+		UpdateTime:  w.options.Clock.Now(),
+	})
+}
+
+// valueHistory presents a ring buffer of raw values.
+type valueHistory struct {
+	mu      sync.Mutex
+	entries []RawValue
+	next    int
+}
+
+func (vh *valueHistory) Add(size int, entry RawValue) {
+	vh.mu.Lock()
+	defer vh.mu.Unlock()
+
+	if n := len(vh.entries); n >= 1 && vh.entries[(vh.next+n-1)%n].ModifyIndex == entry.ModifyIndex {
+		return
+	}
+
+	if len(vh.entries) < size {
+		vh.entries = append(vh.entries, entry)
+		return
+	}
+
+	vh.entries[vh.next] = entry
+	vh.next = (vh.next + 1) % len(vh.entries)
+}
+
+func (vh *valueHistory) Entries() []RawValue {
+	vh.mu.Lock()
+	defer vh.mu.Unlock()
+	n := len(vh.entries)
+	entries := make([]RawValue, n)
+
+	for i := range entries {
+		entries[i] = vh.entries[(vh.next+n-1-i)%n]
+	}
+
+	return entries
+}
+
+// ErrHistoryEntryNotFound is returned when rolling back a watch to a value not in
+// the history of the watch.
+var ErrHistoryEntryNotFound = errors.New("dynconf: history entry not found")
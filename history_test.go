@@ -0,0 +1,86 @@
+package dynconf_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/roy2220/dynconf"
+	"github.com/roy2220/dynconf/dynconftest"
+)
+
+func TestWithHistory(t *testing.T) {
+	clock := dynconftest.NewClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	wr, b := dynconftest.NewWatcherWithClock(t, clock)
+	b.Set("hello", []byte("1"))
+	w, err := wr.AddWatch(context.Background(), "hello", dynconf.NewIntValue, dynconf.WithHistory(3),
+		dynconf.WithDefaultValue([]byte("0")))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer w.Remove()
+
+	historyData := func() []string {
+		var data []string
+		for _, entry := range w.History() {
+			data = append(data, string(entry.Data))
+		}
+		return data
+	}
+	assert.Equal(t, []string{"1"}, historyData())
+
+	events := w.Events()
+
+	for _, data := range []string{"2", "x", "3", "4"} {
+		clock.Advance(time.Minute)
+		b.Set("hello", []byte(data))
+		<-events
+	}
+	assert.Equal(t, []string{"4", "3", "2"}, historyData())
+	assert.Equal(t, clock.Now(), w.History()[0].UpdateTime)
+	assert.Equal(t, clock.Now().Add(-3*time.Minute), w.History()[2].UpdateTime)
+
+	b.Delete("hello")
+	assert.IsType(t, (*dynconf.KeyDeletedEvent)(nil), <-events)
+	<-events
+	assert.Equal(t, int64(0), w.Value().(*dynconf.IntValue).Get())
+	assert.Equal(t, []string{"4", "3", "2"}, historyData())
+
+	err = w.Rollback(context.Background(), nil, 1)
+	assert.True(t, errors.Is(err, dynconf.ErrHistoryEntryNotFound))
+}
+
+func TestWatchRollback(t *testing.T) {
+	wr, c := makeWatcher(t)
+	_, err := c.KV().Put(&api.KVPair{Key: "hello27", Value: []byte("1"), Flags: 1}, &api.WriteOptions{})
+	assert.NoError(t, err)
+	w, err := wr.AddWatch(context.Background(), "hello27", dynconf.NewIntValue, dynconf.WithHistory(10))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer w.Remove()
+	events := w.Events()
+	_, err = c.KV().Put(&api.KVPair{Key: "hello27", Value: []byte("2")}, &api.WriteOptions{})
+	assert.NoError(t, err)
+	<-events
+
+	p := new(dynconf.Publisher).Init(c)
+	err = w.Rollback(context.Background(), p, 2)
+	assert.True(t, errors.Is(err, dynconf.ErrHistoryEntryNotFound))
+
+	assert.NoError(t, w.Rollback(context.Background(), p, 1))
+	<-events
+	assert.Equal(t, int64(1), w.Value().(*dynconf.IntValue).Get())
+	assert.Equal(t, uint64(1), w.Flags())
+
+	w.Freeze()
+	_, err = c.KV().Put(&api.KVPair{Key: "hello27", Value: []byte("3")}, &api.WriteOptions{})
+	assert.NoError(t, err)
+	err = w.Rollback(context.Background(), p, 1)
+	assert.True(t, errors.Is(err, dynconf.ErrPublishConflict))
+	w.Unfreeze()
+}
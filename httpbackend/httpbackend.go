@@ -0,0 +1,139 @@
+// Package httpbackend implements a fetcher reading configurations from an HTTP
+// server, to be used with dynconf.PollingBackend.
+package httpbackend
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/roy2220/dynconf"
+)
+
+// Fetcher presents a fetcher reading configurations from an HTTP server, the data
+// of a key is the body of the response to the GET request for the URL made up of
+// the base URL and the key. Conditional requests are made with the ETag and the
+// Last-Modified headers of previous responses, if any, so that unchanged data are
+// not transferred again.
+type Fetcher struct {
+	httpClient *http.Client
+	baseURL    string
+	header     http.Header
+
+	mu      sync.Mutex
+	entries map[string]cacheEntry
+}
+
+var _ dynconf.Fetcher = (*Fetcher)(nil)
+
+// Init initializes the fetcher with the given HTTP client, which defaults to
+// http.DefaultClient, the given base URL, and the given header added to each
+// request, e.g. for authorization, and then returns the fetcher.
+func (f *Fetcher) Init(httpClient *http.Client, baseURL string, header http.Header) *Fetcher {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	f.httpClient = httpClient
+	f.baseURL = strings.TrimSuffix(baseURL, "/")
+	f.header = header
+	f.entries = make(map[string]cacheEntry)
+	return f
+}
+
+// Fetch implements dynconf.Fetcher.Fetch.
+func (f *Fetcher) Fetch(ctx context.Context, key string) ([]byte, string, bool, error) {
+	url := f.baseURL + "/" + strings.TrimPrefix(key, "/")
+	request, err := http.NewRequest(http.MethodGet, url, nil)
+
+	if err != nil {
+		return nil, "", false, fmt.Errorf("httpbackend: request creation failed; url=%q: %w", url, err)
+	}
+
+	for name, values := range f.header {
+		request.Header[name] = values
+	}
+
+	f.mu.Lock()
+	entry, entryOK := f.entries[key]
+	f.mu.Unlock()
+
+	if entryOK {
+		if entry.ETag != "" {
+			request.Header.Set("If-None-Match", entry.ETag)
+		}
+
+		if entry.LastModified != "" {
+			request.Header.Set("If-Modified-Since", entry.LastModified)
+		}
+	}
+
+	response, err := f.httpClient.Do(request.WithContext(ctx))
+
+	if err != nil {
+		return nil, "", false, fmt.Errorf("httpbackend: request failed; url=%q: %w", url, err)
+	}
+
+	defer response.Body.Close()
+	data, err := ioutil.ReadAll(response.Body)
+
+	if err != nil {
+		return nil, "", false, fmt.Errorf("httpbackend: response read failed; url=%q: %w", url, err)
+	}
+
+	switch response.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotModified:
+		if entryOK {
+			return entry.Data, entry.Version, true, nil
+		}
+
+		return nil, "", false, fmt.Errorf("httpbackend: unexpected response; url=%q status_code=%d", url, response.StatusCode)
+	case http.StatusNotFound:
+		f.mu.Lock()
+		delete(f.entries, key)
+		f.mu.Unlock()
+		return nil, "", false, nil
+	default:
+		return nil, "", false, fmt.Errorf("httpbackend: unexpected response; url=%q status_code=%d body=%q",
+			url, response.StatusCode, data)
+	}
+
+	entry = makeCacheEntry(data, response.Header)
+	f.mu.Lock()
+	f.entries[key] = entry
+	f.mu.Unlock()
+	return entry.Data, entry.Version, true, nil
+}
+
+type cacheEntry struct {
+	Data         []byte
+	Version      string
+	ETag         string
+	LastModified string
+}
+
+func makeCacheEntry(data []byte, header http.Header) cacheEntry {
+	entry := cacheEntry{
+		Data:         data,
+		ETag:         header.Get("ETag"),
+		LastModified: header.Get("Last-Modified"),
+	}
+
+	switch {
+	case entry.ETag != "":
+		entry.Version = entry.ETag
+	case entry.LastModified != "":
+		entry.Version = entry.LastModified
+	default:
+		checksum := sha256.Sum256(data)
+		entry.Version = hex.EncodeToString(checksum[:])
+	}
+
+	return entry
+}
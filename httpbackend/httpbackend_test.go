@@ -0,0 +1,50 @@
+package httpbackend_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/roy2220/dynconf/httpbackend"
+)
+
+func TestFetcherFetch(t *testing.T) {
+	var notModifiedCount int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer t0ken", r.Header.Get("Authorization"))
+
+		if r.URL.Path != "/configs/app" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModifiedCount++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte(`{"foo": 1}`))
+	}))
+	defer server.Close()
+
+	f := new(httpbackend.Fetcher).Init(nil, server.URL+"/configs", http.Header{"Authorization": {"Bearer t0ken"}})
+
+	for i := 0; i < 2; i++ {
+		data, version, ok, err := f.Fetch(context.Background(), "app")
+		if assert.NoError(t, err) && assert.True(t, ok) {
+			assert.Equal(t, `{"foo": 1}`, string(data))
+			assert.Equal(t, `"v1"`, version)
+		}
+	}
+
+	assert.Equal(t, 1, notModifiedCount)
+
+	_, _, ok, err := f.Fetch(context.Background(), "other")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
@@ -0,0 +1,216 @@
+package dynconf
+
+import (
+	"bytes"
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/hashicorp/consul/api"
+)
+
+// AddVerifiedWatch adds a watch on the given key, the data of which must be verified
+// with the given verifier against the proof, e.g. a checksum or a signature, stored
+// in the key suffixed with the proof key suffix of the verifier, and then returns the
+// watch. The data and the proof are re-read once either of the keys has been changed,
+// so the proof should be written after the data. Data failing verification are never
+// unmarshaled, and the failure is returned from the query, which is retried with the
+// retry policy of the watch, in the meanwhile the latest value is kept.
+func (w *Watcher) AddVerifiedWatch(ctx context.Context, key string, verifier Verifier, valueFactory ValueFactory,
+	options ...WatchOption) (*Watch, error) {
+	backend := verifiedBackend{
+		Backend:  w.backend,
+		Key:      key,
+		Verifier: verifier,
+	}
+
+	return w.doAddWatch(ctx, &backend, key, valueFactory, options)
+}
+
+// Verifier represents a verifier of data against proofs.
+type Verifier interface {
+	// ProofKeySuffix returns the suffix of the key holding the proof of the
+	// data of a key.
+	ProofKeySuffix() string
+
+	// Verify verifies the given data against the given proof.
+	Verify(data []byte, proof []byte) error
+}
+
+// SHA256Verifier presents a verifier verifying data against the SHA-256 checksums
+// in hex, which are stored in keys suffixed with ".sha256".
+type SHA256Verifier struct{}
+
+var _ Verifier = SHA256Verifier{}
+
+// ProofKeySuffix implements Verifier.ProofKeySuffix.
+func (SHA256Verifier) ProofKeySuffix() string { return ".sha256" }
+
+// Verify implements Verifier.Verify.
+func (SHA256Verifier) Verify(data []byte, proof []byte) error {
+	checksum := sha256.Sum256(data)
+	expectedChecksum, err := hex.DecodeString(string(bytes.TrimSpace(proof)))
+
+	if err != nil {
+		return fmt.Errorf("checksum decode failed: %w", err)
+	}
+
+	if !bytes.Equal(checksum[:], expectedChecksum) {
+		return errChecksumMismatch
+	}
+
+	return nil
+}
+
+// Ed25519Verifier presents a verifier verifying data against the Ed25519 signatures
+// in base64, which are stored in keys suffixed with ".sig". A signature is valid if
+// it is signed by any of the public keys, so that the keys can be rotated.
+type Ed25519Verifier struct {
+	PublicKeys []ed25519.PublicKey
+}
+
+var _ Verifier = Ed25519Verifier{}
+
+// ProofKeySuffix implements Verifier.ProofKeySuffix.
+func (Ed25519Verifier) ProofKeySuffix() string { return ".sig" }
+
+// Verify implements Verifier.Verify.
+func (ev Ed25519Verifier) Verify(data []byte, proof []byte) error {
+	signature, err := base64.StdEncoding.DecodeString(string(bytes.TrimSpace(proof)))
+
+	if err != nil {
+		return fmt.Errorf("signature decode failed: %w", err)
+	}
+
+	for _, publicKey := range ev.PublicKeys {
+		if ed25519.Verify(publicKey, data, signature) {
+			return nil
+		}
+	}
+
+	return errSignatureInvalid
+}
+
+// ErrVerificationFailed is returned when data fail to be verified.
+var ErrVerificationFailed = errors.New("dynconf: verification failed")
+
+var (
+	errChecksumMismatch = errors.New("checksum mismatch")
+	errSignatureInvalid = errors.New("signature invalid")
+	errProofNotFound    = errors.New("proof not found")
+)
+
+type verifiedBackend struct {
+	Backend  Backend
+	Key      string
+	Verifier Verifier
+
+	mu        sync.Mutex
+	entry     compositeEntry
+	lastIndex uint64
+}
+
+var _ Backend = (*verifiedBackend)(nil)
+
+func (vb *verifiedBackend) Get(key string, queryOptions *api.QueryOptions) (*api.KVPair, *api.QueryMeta, error) {
+	if queryOptions == nil {
+		queryOptions = new(api.QueryOptions)
+	}
+
+	vb.mu.Lock()
+	entry := vb.entry
+	vb.mu.Unlock()
+
+	if entry.Index != 0 && queryOptions.WaitIndex != 0 && queryOptions.WaitIndex == entry.Index {
+		keys := vb.keys()
+		queries := make([]query, len(keys))
+
+		for i := range keys {
+			key := keys[i]
+			queries[i] = func(queryOptions *api.QueryOptions) (*api.QueryMeta, error) {
+				_, queryMeta, err := vb.Backend.Get(key, queryOptions)
+				return queryMeta, err
+			}
+		}
+
+		changed, err := waitForAnyChange(queries, entry.LayerIndexes, queryOptions)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if !changed {
+			return entry.Result()
+		}
+	}
+
+	return vb.refresh(key, queryOptions)
+}
+
+func (vb *verifiedBackend) List(prefix string, _ *api.QueryOptions) (api.KVPairs, *api.QueryMeta, error) {
+	return nil, nil, fmt.Errorf("%w; prefix=%q", ErrListNotSupported, prefix)
+}
+
+func (vb *verifiedBackend) keys() []string {
+	return []string{vb.Key, vb.Key + vb.Verifier.ProofKeySuffix()}
+}
+
+func (vb *verifiedBackend) refresh(key string, queryOptions *api.QueryOptions) (*api.KVPair, *api.QueryMeta, error) {
+	queryOptions = queryOptions.WithContext(queryOptions.Context())
+	queryOptions.WaitIndex = 0
+	keys := vb.keys()
+	kvPairs := make([]*api.KVPair, len(keys))
+	waitIndexes := make([]uint64, len(keys))
+
+	for i, key := range keys {
+		kvPair, queryMeta, err := vb.Backend.Get(key, queryOptions)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		kvPairs[i] = kvPair
+		waitIndexes[i] = queryMeta.LastIndex
+	}
+
+	kvPair, proofKVPair := kvPairs[0], kvPairs[1]
+
+	if kvPair != nil {
+		err := errProofNotFound
+
+		if proofKVPair != nil {
+			err = vb.Verifier.Verify(kvPair.Value, proofKVPair.Value)
+		}
+
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w; key=%q proof_key=%q: %v", ErrVerificationFailed, vb.Key, keys[1], err)
+		}
+	}
+
+	vb.mu.Lock()
+	entry := vb.entry
+
+	if entry.Index == 0 || !uint64sEqual(entry.LayerIndexes, waitIndexes) {
+		vb.lastIndex++
+		entry = compositeEntry{
+			Key:          key,
+			LayerIndexes: waitIndexes,
+			Index:        vb.lastIndex,
+			Exists:       kvPair != nil,
+		}
+
+		if kvPair != nil {
+			entry.Data = kvPair.Value
+		}
+
+		vb.entry = entry
+	}
+
+	vb.mu.Unlock()
+	return entry.Result()
+}
@@ -0,0 +1,70 @@
+package dynconf_test
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/roy2220/dynconf"
+	"github.com/roy2220/dynconf/dynconftest"
+)
+
+func TestWatcherAddVerifiedWatch(t *testing.T) {
+	wr, b := dynconftest.NewWatcher(t)
+	b.Set("limit", []byte("100"))
+	b.Set("limit.sha256", []byte("0000"))
+	_, err := wr.AddVerifiedWatch(context.Background(), "limit", dynconf.SHA256Verifier{}, dynconf.NewIntValue)
+	assert.True(t, errors.Is(err, dynconf.ErrVerificationFailed))
+
+	b.Set("limit.sha256", sha256Hex("100"))
+	w, err := wr.AddVerifiedWatch(context.Background(), "limit", dynconf.SHA256Verifier{}, dynconf.NewIntValue)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer w.Remove()
+
+	assert.Equal(t, int64(100), w.Value().(*dynconf.IntValue).Get())
+
+	newValues := make(chan dynconf.Value, 2)
+	unsubscribe := w.Subscribe(func(_, newValue dynconf.Value) { newValues <- newValue })
+	defer unsubscribe()
+
+	// tampered
+	b.Set("limit", []byte("999"))
+	b.Set("limit", []byte("200"))
+	b.Set("limit.sha256", sha256Hex("200"))
+	assert.Equal(t, int64(200), (<-newValues).(*dynconf.IntValue).Get())
+	assert.Len(t, newValues, 0)
+}
+
+func TestEd25519Verifier(t *testing.T) {
+	oldPublicKey, oldPrivateKey, err := ed25519.GenerateKey(nil)
+	assert.NoError(t, err)
+	newPublicKey, newPrivateKey, err := ed25519.GenerateKey(nil)
+	assert.NoError(t, err)
+	_, otherPrivateKey, err := ed25519.GenerateKey(nil)
+	assert.NoError(t, err)
+	v := dynconf.Ed25519Verifier{PublicKeys: []ed25519.PublicKey{oldPublicKey, newPublicKey}}
+	assert.Equal(t, ".sig", v.ProofKeySuffix())
+
+	data := []byte("hello")
+	sign := func(privateKey ed25519.PrivateKey) []byte {
+		return []byte(base64.StdEncoding.EncodeToString(ed25519.Sign(privateKey, data)))
+	}
+	assert.NoError(t, v.Verify(data, sign(oldPrivateKey)))
+	assert.NoError(t, v.Verify(data, sign(newPrivateKey)))
+	assert.Error(t, v.Verify(data, sign(otherPrivateKey)))
+	assert.Error(t, v.Verify([]byte("world"), sign(newPrivateKey)))
+	assert.Error(t, v.Verify(data, []byte("!")))
+}
+
+func sha256Hex(s string) []byte {
+	checksum := sha256.Sum256([]byte(s))
+	return []byte(hex.EncodeToString(checksum[:]))
+}
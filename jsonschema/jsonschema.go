@@ -0,0 +1,58 @@
+// Package jsonschema validates data of values against JSON schemas.
+package jsonschema
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/xeipuuv/gojsonschema"
+
+	"github.com/roy2220/dynconf"
+)
+
+// Validator presents a validator validating data against a JSON schema.
+type Validator struct {
+	schema *gojsonschema.Schema
+}
+
+// NewValidator returns a new validator validating data against the given JSON schema.
+func NewValidator(schema []byte) (*Validator, error) {
+	compiledSchema, err := gojsonschema.NewSchema(gojsonschema.NewBytesLoader(schema))
+
+	if err != nil {
+		return nil, fmt.Errorf("jsonschema: schema compilation failed: %w", err)
+	}
+
+	return &Validator{compiledSchema}, nil
+}
+
+// Validate validates the given data against the JSON schema.
+func (v *Validator) Validate(data []byte) error {
+	result, err := v.schema.Validate(gojsonschema.NewBytesLoader(data))
+
+	if err != nil {
+		return fmt.Errorf("jsonschema: data load failed: %w", err)
+	}
+
+	if result.Valid() {
+		return nil
+	}
+
+	violations := make([]string, len(result.Errors()))
+
+	for i, resultError := range result.Errors() {
+		violations[i] = resultError.String()
+	}
+
+	return fmt.Errorf("%w: %s", ErrSchemaViolated, strings.Join(violations, "; "))
+}
+
+// WatchOption returns a dynconf watch option to validate data of new values of
+// the watch against the JSON schema.
+func (v *Validator) WatchOption() dynconf.WatchOption {
+	return dynconf.WithDataValidator(v.Validate)
+}
+
+// ErrSchemaViolated is returned when data violates a JSON schema.
+var ErrSchemaViolated = errors.New("jsonschema: schema violated")
@@ -0,0 +1,37 @@
+package jsonschema_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/roy2220/dynconf/jsonschema"
+)
+
+func TestValidatorValidate(t *testing.T) {
+	_, err := jsonschema.NewValidator([]byte(`{"type": 1}`))
+	assert.Error(t, err)
+
+	v, err := jsonschema.NewValidator([]byte(`{
+		"type": "object",
+		"properties": {"Foo": {"type": "integer", "minimum": 0}},
+		"required": ["Foo"]
+	}`))
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	assert.NoError(t, v.Validate([]byte(`{"Foo": 1}`)))
+
+	err = v.Validate([]byte(`{"Foo": -1}`))
+	assert.True(t, errors.Is(err, jsonschema.ErrSchemaViolated))
+	assert.EqualError(t, err, "jsonschema: schema violated: Foo: Must be greater than or equal to 0")
+
+	err = v.Validate([]byte(`{}`))
+	assert.True(t, errors.Is(err, jsonschema.ErrSchemaViolated))
+
+	err = v.Validate([]byte(`bad json`))
+	assert.False(t, errors.Is(err, jsonschema.ErrSchemaViolated))
+	assert.Error(t, err)
+}
@@ -0,0 +1,146 @@
+package dynconf
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/consul/api"
+)
+
+// Diff compares the keys with the given prefix in the KV store of Consul with the
+// given client A and those with the same prefix with the given client B, e.g. of
+// the production cluster and the staging cluster respectively, and then returns the
+// differences, in the order of the keys, which turn the keys of A into those of B.
+// The given options, e.g. WithNamespace and WithTokenFunc, set the options for the
+// queries to both clusters.
+func Diff(ctx context.Context, clientA *api.Client, clientB *api.Client, prefix string,
+	options ...WatchOption) ([]KeyDiff, error) {
+	return DiffPrefixes(ctx, clientA.KV(), prefix, options, clientB.KV(), prefix, options)
+}
+
+// DiffPrefixes compares the keys with the given prefix A in the given backend A and
+// the keys with the given prefix B in the given backend B, which may be the same,
+// and then returns the differences, in the order of the keys, which turn the keys
+// of A into those of B. The keys are compared with the prefixes trimmed. The given
+// options A and B, e.g. WithNamespace and WithTokenFunc, set the options for the
+// queries to backend A and backend B respectively.
+func DiffPrefixes(ctx context.Context, backendA Backend, prefixA string, optionsA []WatchOption,
+	backendB Backend, prefixB string, optionsB []WatchOption) ([]KeyDiff, error) {
+	kvPairsA, err := listKVPairs(ctx, backendA, prefixA, optionsA)
+
+	if err != nil {
+		return nil, err
+	}
+
+	kvPairsB, err := listKVPairs(ctx, backendB, prefixB, optionsB)
+
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(kvPairsA)+len(kvPairsB))
+
+	for key := range kvPairsA {
+		keys = append(keys, key)
+	}
+
+	for key := range kvPairsB {
+		if _, ok := kvPairsA[key]; !ok {
+			keys = append(keys, key)
+		}
+	}
+
+	sort.Strings(keys)
+	var keyDiffs []KeyDiff
+
+	for _, key := range keys {
+		kvPairA, kvPairB := kvPairsA[key], kvPairsB[key]
+		keyDiff := KeyDiff{Key: key}
+
+		switch {
+		case kvPairA == nil:
+			keyDiff.Type = KeyAdded
+		case kvPairB == nil:
+			keyDiff.Type = KeyRemoved
+		case !bytes.Equal(kvPairA.Value, kvPairB.Value) || kvPairA.Flags != kvPairB.Flags:
+			keyDiff.Type = KeyModified
+			keyDiff.Changes, _ = DiffJSON(kvPairA.Value, kvPairB.Value)
+		default:
+			continue
+		}
+
+		if kvPairA != nil {
+			keyDiff.OldData = kvPairA.Value
+			keyDiff.OldFlags = kvPairA.Flags
+		}
+
+		if kvPairB != nil {
+			keyDiff.NewData = kvPairB.Value
+			keyDiff.NewFlags = kvPairB.Flags
+		}
+
+		keyDiffs = append(keyDiffs, keyDiff)
+	}
+
+	return keyDiffs, nil
+}
+
+func listKVPairs(ctx context.Context, backend Backend, prefix string,
+	options []WatchOption) (map[string]*api.KVPair, error) {
+	var watchOptions watchOptions
+	watchOptions.Init(options)
+	queryOptions := watchOptions.makeQueryOptions(ctx, watchOptions.QueryOptions)
+	kvPairs, _, err := backend.List(prefix, queryOptions)
+
+	if err != nil {
+		return nil, fmt.Errorf("dynconf: kv list failed; prefix=%q: %w", prefix, err)
+	}
+
+	kvPairsByKey := make(map[string]*api.KVPair, len(kvPairs))
+
+	for _, kvPair := range kvPairs {
+		kvPairsByKey[strings.TrimPrefix(kvPair.Key, prefix)] = kvPair
+	}
+
+	return kvPairsByKey, nil
+}
+
+// KeyDiff represents a difference of a key between two sets of keys.
+type KeyDiff struct {
+	// Key is the key with the prefix trimmed.
+	Key string
+
+	// Type is the type of the difference.
+	Type KeyDiffType
+
+	// OldData and OldFlags are the data and the flags of the key in the old set,
+	// or nil and zero if the key has been added.
+	OldData  []byte
+	OldFlags uint64
+
+	// NewData and NewFlags are the data and the flags of the key in the new set,
+	// or nil and zero if the key has been removed.
+	NewData  []byte
+	NewFlags uint64
+
+	// Changes are the changes of the data if the key has been modified and both
+	// the old data and the new data are in JSON.
+	Changes []JSONChange
+}
+
+// KeyDiffType represents a type of difference of a key.
+type KeyDiffType string
+
+const (
+	// KeyAdded indicates the key is only in the new set.
+	KeyAdded = KeyDiffType("added")
+
+	// KeyRemoved indicates the key is only in the old set.
+	KeyRemoved = KeyDiffType("removed")
+
+	// KeyModified indicates the data or the flags of the key are different.
+	KeyModified = KeyDiffType("modified")
+)
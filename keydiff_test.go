@@ -0,0 +1,103 @@
+package dynconf_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/roy2220/dynconf"
+	"github.com/roy2220/dynconf/dynconftest"
+)
+
+func TestDiffPrefixes(t *testing.T) {
+	prod := new(dynconftest.Backend).Init()
+	prod.Set("app/same", []byte("1"))
+	prod.Set("app/removed", []byte("2"))
+	prod.Set("app/config", []byte(`{"Foo": 1, "Bar": "x"}`))
+	prod.Set("app/text", []byte("a"))
+	prod.SetWithFlags("app/flagged", []byte("3"), 1)
+	prod.Set("other/ignored", []byte("4"))
+
+	staging := new(dynconftest.Backend).Init()
+	staging.Set("staging/app/same", []byte("1"))
+	staging.Set("staging/app/added", []byte("5"))
+	staging.Set("staging/app/config", []byte(`{"Foo": 2, "Bar": "x"}`))
+	staging.Set("staging/app/text", []byte("b"))
+	staging.SetWithFlags("staging/app/flagged", []byte("3"), 2)
+
+	keyDiffs, err := dynconf.DiffPrefixes(context.Background(), prod, "app/", nil, staging, "staging/app/", nil)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, []dynconf.KeyDiff{
+		{Key: "added", Type: dynconf.KeyAdded, NewData: []byte("5")},
+		{
+			Key:     "config",
+			Type:    dynconf.KeyModified,
+			OldData: []byte(`{"Foo": 1, "Bar": "x"}`),
+			NewData: []byte(`{"Foo": 2, "Bar": "x"}`),
+			Changes: []dynconf.JSONChange{{Path: "/Foo", OldValue: json.Number("1"), NewValue: json.Number("2")}},
+		},
+		{Key: "flagged", Type: dynconf.KeyModified, OldData: []byte("3"), OldFlags: 1, NewData: []byte("3"), NewFlags: 2},
+		{Key: "removed", Type: dynconf.KeyRemoved, OldData: []byte("2")},
+		{Key: "text", Type: dynconf.KeyModified, OldData: []byte("a"), NewData: []byte("b")},
+	}, keyDiffs)
+
+	keyDiffs, err = dynconf.DiffPrefixes(context.Background(), prod, "app/same", nil, staging, "staging/app/same", nil)
+	assert.NoError(t, err)
+	assert.Empty(t, keyDiffs)
+}
+
+func TestDiffPrefixesWithOptions(t *testing.T) {
+	b := &namespacedBackends{Backends: map[string]*dynconftest.Backend{
+		"prod":    new(dynconftest.Backend).Init(),
+		"staging": new(dynconftest.Backend).Init(),
+	}}
+	b.Backends["prod"].Set("app/a", []byte("x"))
+	b.Backends["staging"].Set("app/a", []byte("y"))
+
+	prodOptions := []dynconf.WatchOption{dynconf.WithNamespace("prod")}
+	stagingOptions := []dynconf.WatchOption{dynconf.WithNamespace("staging")}
+	keyDiffs, err := dynconf.DiffPrefixes(context.Background(), b, "app/", prodOptions, b, "app/", stagingOptions)
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, []dynconf.KeyDiff{
+		{Key: "a", Type: dynconf.KeyModified, OldData: []byte("x"), NewData: []byte("y")},
+	}, keyDiffs)
+
+	_, err = dynconf.DiffPrefixes(context.Background(), b, "app/", prodOptions, b, "app/", nil)
+	assert.EqualError(t, err, `dynconf: kv list failed; prefix="app/": unknown namespace`)
+}
+
+type namespacedBackends struct {
+	dynconf.Backend
+
+	Backends map[string]*dynconftest.Backend
+}
+
+func (nbs *namespacedBackends) List(prefix string, queryOptions *api.QueryOptions) (api.KVPairs, *api.QueryMeta, error) {
+	backend, ok := nbs.Backends[queryOptions.Namespace]
+
+	if !ok {
+		return nil, nil, errors.New("unknown namespace")
+	}
+
+	return backend.List(prefix, queryOptions)
+}
+
+func TestDiff(t *testing.T) {
+	c := makeClient(t)
+	_, err := c.KV().DeleteTree("hello26/", nil)
+	assert.NoError(t, err)
+	_, err = c.KV().Put(&api.KVPair{Key: "hello26/a", Value: []byte("1")}, nil)
+	assert.NoError(t, err)
+
+	keyDiffs, err := dynconf.Diff(context.Background(), c, c, "hello26/")
+	assert.NoError(t, err)
+	assert.Empty(t, keyDiffs)
+}
@@ -0,0 +1,129 @@
+package dynconf
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/hashicorp/consul/api"
+)
+
+// KeyContext represents a context of key templates, which maps the names of the
+// placeholders in key templates, e.g. "service" and "env" in the key template
+// "config/{{service}}/{{env}}/limits", to their values.
+type KeyContext map[string]string
+
+// Resolve replaces the placeholders in the given key template with their values,
+// and then returns the key. It fails with ErrUnresolvedPlaceholder if any
+// placeholder has no value.
+func (kc KeyContext) Resolve(keyTemplate string) (string, error) {
+	if !strings.Contains(keyTemplate, "{{") {
+		return keyTemplate, nil
+	}
+
+	var builder strings.Builder
+	rest := keyTemplate
+
+	for {
+		i := strings.Index(rest, "{{")
+
+		if i < 0 {
+			builder.WriteString(rest)
+			return builder.String(), nil
+		}
+
+		builder.WriteString(rest[:i])
+		rest = rest[i+2:]
+		j := strings.Index(rest, "}}")
+
+		if j < 0 {
+			return "", fmt.Errorf("dynconf: invalid key template; key_template=%q: unterminated placeholder", keyTemplate)
+		}
+
+		name := strings.TrimSpace(rest[:j])
+		value, ok := kc[name]
+
+		if !ok {
+			return "", fmt.Errorf("%w; key_template=%q placeholder=%q", ErrUnresolvedPlaceholder, keyTemplate, name)
+		}
+
+		builder.WriteString(value)
+		rest = rest[j+2:]
+	}
+}
+
+// WithKeyContext returns an option to resolve the key templates, i.e. the keys and
+// prefixes containing placeholders like "{{service}}", read by the watches added
+// to the watcher with the given context. The watches are set on the key templates,
+// and adding a watch fails with ErrUnresolvedPlaceholder if any placeholder has no
+// value in the context. It takes effect for the watches reading through the backend
+// of the watcher, and should be given before the other options wrapping the
+// backend, e.g. SetQueryRateLimit.
+func WithKeyContext(keyContext KeyContext) WatcherOption {
+	return watcherOption(func(w *Watcher) {
+		w.keyContext = keyContext
+		w.backend = &keyTemplateBackend{
+			Backend:    w.backend,
+			KeyContext: keyContext,
+		}
+	})
+}
+
+// ResolveKey resolves the given key template with the context set by
+// WithKeyContext, and then returns the key.
+func (w *Watcher) ResolveKey(keyTemplate string) (string, error) {
+	return w.keyContext.Resolve(keyTemplate)
+}
+
+type keyTemplateBackend struct {
+	Backend    Backend
+	KeyContext KeyContext
+}
+
+var _ Backend = (*keyTemplateBackend)(nil)
+
+func (ktb *keyTemplateBackend) Get(key string, queryOptions *api.QueryOptions) (*api.KVPair, *api.QueryMeta, error) {
+	resolvedKey, err := ktb.KeyContext.Resolve(key)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	kvPair, queryMeta, err := ktb.Backend.Get(resolvedKey, queryOptions)
+
+	if err != nil || kvPair == nil || resolvedKey == key {
+		return kvPair, queryMeta, err
+	}
+
+	kvPairCopy := *kvPair
+	kvPairCopy.Key = key
+	return &kvPairCopy, queryMeta, nil
+}
+
+func (ktb *keyTemplateBackend) List(prefix string, queryOptions *api.QueryOptions) (api.KVPairs, *api.QueryMeta, error) {
+	resolvedPrefix, err := ktb.KeyContext.Resolve(prefix)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	kvPairs, queryMeta, err := ktb.Backend.List(resolvedPrefix, queryOptions)
+
+	if err != nil || resolvedPrefix == prefix {
+		return kvPairs, queryMeta, err
+	}
+
+	kvPairsCopy := make(api.KVPairs, len(kvPairs))
+
+	for i, kvPair := range kvPairs {
+		kvPairCopy := *kvPair
+		kvPairCopy.Key = prefix + strings.TrimPrefix(kvPair.Key, resolvedPrefix)
+		kvPairsCopy[i] = &kvPairCopy
+	}
+
+	return kvPairsCopy, queryMeta, nil
+}
+
+// ErrUnresolvedPlaceholder is returned when a placeholder in a key template has no
+// value in the key context.
+var ErrUnresolvedPlaceholder = errors.New("dynconf: unresolved placeholder")
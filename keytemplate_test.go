@@ -0,0 +1,69 @@
+package dynconf_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/roy2220/dynconf"
+	"github.com/roy2220/dynconf/dynconftest"
+)
+
+func TestKeyContextResolve(t *testing.T) {
+	kc := dynconf.KeyContext{"service": "foo", "env": "prod"}
+
+	key, err := kc.Resolve("config/{{service}}/{{ env }}/limits")
+	assert.NoError(t, err)
+	assert.Equal(t, "config/foo/prod/limits", key)
+
+	key, err = kc.Resolve("config/limits")
+	assert.NoError(t, err)
+	assert.Equal(t, "config/limits", key)
+
+	_, err = kc.Resolve("config/{{service}}/{{region}}/limits")
+	assert.True(t, errors.Is(err, dynconf.ErrUnresolvedPlaceholder))
+
+	_, err = kc.Resolve("config/{{service")
+	assert.Error(t, err)
+}
+
+func TestWithKeyContext(t *testing.T) {
+	b := new(dynconftest.Backend).Init()
+	b.Set("config/foo/prod/limits", []byte("1"))
+	b.Set("config/foo/prod/dir/a", []byte("2"))
+	wr := dynconf.NewWatcherWithBackend(b,
+		dynconf.WithLogger(dynconftest.Logger{T: t}),
+		dynconf.WithKeyContext(dynconf.KeyContext{"service": "foo", "env": "prod"}),
+	)
+
+	key, err := wr.ResolveKey("config/{{service}}/{{env}}/limits")
+	assert.NoError(t, err)
+	assert.Equal(t, "config/foo/prod/limits", key)
+
+	w, err := wr.AddWatch(context.Background(), "config/{{service}}/{{env}}/limits", dynconf.NewIntValue)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer w.Remove()
+	assert.Equal(t, int64(1), w.Value().(*dynconf.IntValue).Get())
+
+	events := w.Events()
+	b.Set("config/foo/prod/limits", []byte("2"))
+	<-events
+	assert.Equal(t, int64(2), w.Value().(*dynconf.IntValue).Get())
+
+	pw, err := wr.AddPrefixWatch(context.Background(), "config/{{service}}/{{env}}/dir/", dynconf.NewIntValue,
+		dynconf.PrefixWatchCallbacks{})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer pw.Remove()
+	if v, ok := pw.Value("config/{{service}}/{{env}}/dir/a"); assert.True(t, ok) {
+		assert.Equal(t, int64(2), v.(*dynconf.IntValue).Get())
+	}
+
+	_, err = wr.AddWatch(context.Background(), "config/{{service}}/{{region}}/limits", dynconf.NewIntValue)
+	assert.True(t, errors.Is(err, dynconf.ErrUnresolvedPlaceholder))
+}
@@ -0,0 +1,125 @@
+package dynconf
+
+import (
+	"context"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+)
+
+// AddKillSwitch adds a watch on the given key holding a bool, which is true if the
+// switch is engaged, i.e. the feature guarded is killed, and then returns the kill
+// switch. The state of the switch is unknown while the key is missing or the
+// backend has been unreachable for longer than the max staleness of the given
+// policy, during which the switch fails safe according to the policy. If the
+// backend is unreachable when the switch is added, the switch starts in the state
+// of the policy as well, and keeps watching the key until the backend is reachable.
+// Flips of the switch are logged and counted with the counter
+// "dynconf_kill_switch_flips", and fallbacks due to the backend being unreachable
+// with the counter "dynconf_kill_switch_fallbacks".
+func (w *Watcher) AddKillSwitch(ctx context.Context, key string, policy KillSwitchPolicy,
+	options ...WatchOption) (*KillSwitch, error) {
+	policy.normalize()
+	var watchOptions watchOptions
+	metrics := watchOptions.Init(w.watchOptions(options)).Metrics
+	logger := w.logger
+	backend := killSwitchBackend{
+		Backend: w.backend,
+		OnUnreachable: func(err error) {
+			logger.Log(LogWarn, "dynconf_kill_switch_fallen_back", "key", key, "engaged", policy.FailClosed,
+				"error", err)
+			metrics.IncrCounter("dynconf_kill_switch_fallbacks", "key", key)
+		},
+	}
+
+	options = append(options[:len(options):len(options)],
+		WithDefaultValue([]byte(strconv.FormatBool(policy.FailClosed))),
+		WithMaxStaleness(policy.MaxStaleness, func() {
+			logger.Log(LogWarn, "dynconf_kill_switch_fallen_back", "key", key, "engaged", policy.FailClosed)
+			metrics.IncrCounter("dynconf_kill_switch_fallbacks", "key", key)
+		}))
+	watch, err := w.doAddWatch(ctx, &backend, key, NewBoolValue, options)
+
+	if err != nil {
+		return nil, err
+	}
+
+	watch.Subscribe(func(_ Value, newValue Value) {
+		engaged := newValue.(*BoolValue).Get()
+		logger.Log(LogInfo, "dynconf_kill_switch_flipped", "key", key, "engaged", engaged)
+		metrics.IncrCounter("dynconf_kill_switch_flips", "key", key, "engaged", strconv.FormatBool(engaged))
+	})
+
+	return &KillSwitch{
+		watch:  watch,
+		policy: policy,
+	}, nil
+}
+
+// killSwitchBackend presents a backend of a kill switch, which makes the first
+// query find the key missing if the backend is unreachable, so that the switch
+// starts with the default value, i.e. the state of the policy, rather than fails.
+type killSwitchBackend struct {
+	Backend
+
+	OnUnreachable func(err error)
+	queried       int32
+}
+
+func (ksb *killSwitchBackend) Get(key string, queryOptions *api.QueryOptions) (*api.KVPair, *api.QueryMeta, error) {
+	kvPair, queryMeta, err := ksb.Backend.Get(key, queryOptions)
+
+	if err != nil && atomic.CompareAndSwapInt32(&ksb.queried, 0, 1) {
+		ksb.OnUnreachable(err)
+		return nil, &api.QueryMeta{}, nil
+	}
+
+	atomic.StoreInt32(&ksb.queried, 1)
+	return kvPair, queryMeta, err
+}
+
+// KillSwitchPolicy represents the policy of a kill switch when its state is unknown.
+type KillSwitchPolicy struct {
+	// FailClosed indicates whether the switch is considered engaged, killing the
+	// feature guarded, while its state is unknown. Otherwise the switch fails open,
+	// i.e. the switch is considered disengaged.
+	FailClosed bool
+
+	// MaxStaleness is the max duration for which the backend may be unreachable
+	// before the state of the switch is considered unknown, which defaults to
+	// DefaultKillSwitchMaxStaleness.
+	MaxStaleness time.Duration
+}
+
+func (ksp *KillSwitchPolicy) normalize() {
+	if ksp.MaxStaleness <= 0 {
+		ksp.MaxStaleness = DefaultKillSwitchMaxStaleness
+	}
+}
+
+// DefaultKillSwitchMaxStaleness is the default value of KillSwitchPolicy.MaxStaleness.
+const DefaultKillSwitchMaxStaleness = time.Minute
+
+// KillSwitch presents a kill switch with fail-safe semantics.
+type KillSwitch struct {
+	watch  *Watch
+	policy KillSwitchPolicy
+}
+
+// Engaged returns whether the switch is engaged, i.e. the feature guarded should be
+// killed, according to the latest state, or the policy if the state is unknown. It
+// is safe for concurrent use.
+func (ks *KillSwitch) Engaged() bool {
+	if ks.watch.IsStale() {
+		return ks.policy.FailClosed
+	}
+
+	return ks.watch.Value().(*BoolValue).Get()
+}
+
+// Watch returns the watch with which the kill switch is kept up to date.
+func (ks *KillSwitch) Watch() *Watch {
+	return ks.watch
+}
@@ -0,0 +1,118 @@
+package dynconf_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/roy2220/dynconf"
+	"github.com/roy2220/dynconf/dynconftest"
+)
+
+func TestKillSwitch(t *testing.T) {
+	clock := dynconftest.NewClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	b := &flakyBackend{Backend: new(dynconftest.Backend).InitWithClock(clock)}
+	m := new(counters)
+	wr := dynconf.NewWatcherWithBackend(b, dynconf.WithLogger(dynconftest.Logger{T: t}), dynconf.WithMetrics(m),
+		dynconf.WithClock(clock), dynconf.WithWaitTime(10*time.Second),
+		dynconf.WithRetryPolicy(dynconf.ConstantBackoff{Interval: 10 * time.Second}))
+
+	ks, err := wr.AddKillSwitch(context.Background(), "kill", dynconf.KillSwitchPolicy{
+		FailClosed:   true,
+		MaxStaleness: time.Minute,
+	})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer ks.Watch().Remove()
+	assert.True(t, ks.Engaged())
+
+	events := ks.Watch().Events()
+	b.Set("kill", []byte("false"))
+	<-events
+	assert.False(t, ks.Engaged())
+	assert.Equal(t, 1, m.Get("dynconf_kill_switch_flips", "key", "kill", "engaged", "false"))
+
+	// wait for the staleness timer and the blocking query
+	clock.BlockUntil(2)
+	atomic.StoreInt32(&b.Down, 1)
+	assert.Eventually(t, func() bool {
+		clock.Advance(10 * time.Second)
+		return ks.Engaged()
+	}, time.Second, time.Millisecond)
+	assert.Eventually(t, func() bool {
+		return m.Get("dynconf_kill_switch_fallbacks", "key", "kill") == 1
+	}, time.Second, time.Millisecond)
+
+	atomic.StoreInt32(&b.Down, 0)
+	assert.Eventually(t, func() bool {
+		clock.Advance(10 * time.Second)
+		return !ks.Engaged()
+	}, time.Second, time.Millisecond)
+
+	ks2, err := wr.AddKillSwitch(context.Background(), "kill2", dynconf.KillSwitchPolicy{})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer ks2.Watch().Remove()
+	assert.False(t, ks2.Engaged())
+}
+
+func TestKillSwitchWithBackendDown(t *testing.T) {
+	clock := dynconftest.NewClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	b := &flakyBackend{Backend: new(dynconftest.Backend).InitWithClock(clock), Down: 1}
+	m := new(counters)
+	wr := dynconf.NewWatcherWithBackend(b, dynconf.WithLogger(dynconftest.Logger{T: t}), dynconf.WithMetrics(m),
+		dynconf.WithClock(clock), dynconf.WithRetryPolicy(dynconf.ConstantBackoff{Interval: 10 * time.Second}))
+
+	ks, err := wr.AddKillSwitch(context.Background(), "kill", dynconf.KillSwitchPolicy{FailClosed: true})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer ks.Watch().Remove()
+	assert.True(t, ks.Engaged())
+	assert.Equal(t, 1, m.Get("dynconf_kill_switch_fallbacks", "key", "kill"))
+
+	ks2, err := wr.AddKillSwitch(context.Background(), "kill2", dynconf.KillSwitchPolicy{FailClosed: false})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer ks2.Watch().Remove()
+	assert.False(t, ks2.Engaged())
+
+	b.Set("kill", []byte("false"))
+	b.Set("kill2", []byte("true"))
+	atomic.StoreInt32(&b.Down, 0)
+	assert.Eventually(t, func() bool {
+		clock.Advance(10 * time.Second)
+		return !ks.Engaged() && ks2.Engaged()
+	}, time.Second, time.Millisecond)
+}
+
+func TestKillSwitchWithDefaults(t *testing.T) {
+	clock := dynconftest.NewClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	wr, b := dynconftest.NewWatcherWithClock(t, clock)
+	b.Set("kill", []byte("false"))
+
+	ks, err := wr.AddKillSwitch(context.Background(), "kill", dynconf.KillSwitchPolicy{FailClosed: true})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer ks.Watch().Remove()
+
+	// waitForQuery waits for the staleness timer and the blocking query
+	waitForQuery := func() {
+		assert.Eventually(t, func() bool { return clock.NumberOfTimers() == 2 }, time.Second, time.Millisecond)
+	}
+
+	for i := 0; i < 10; i++ {
+		waitForQuery()
+		clock.Advance(dynconf.DefaultKillSwitchMaxStaleness/2 + time.Second)
+	}
+	waitForQuery()
+	assert.False(t, ks.Watch().IsStale())
+	assert.False(t, ks.Engaged())
+}
@@ -0,0 +1,230 @@
+// Package limits implements rate limiters and circuit breaker thresholds kept up
+// to date with dynconf watches.
+package limits
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"github.com/roy2220/dynconf"
+)
+
+// RateLimiter presents a rate limiter whose limit and burst are kept up to date
+// with a watch on a key, which holds a JSON object in the form of
+// {"limit": 100, "burst": 10}, where the limit is in events per second or "inf".
+type RateLimiter struct {
+	watch       *dynconf.Watch
+	unsubscribe func()
+	limiter     *rate.Limiter
+}
+
+// AddRateLimiter adds a watch on the given key with the given watcher, and then
+// returns a rate limiter kept up to date with the watch.
+func AddRateLimiter(ctx context.Context, watcher *dynconf.Watcher, key string,
+	options ...dynconf.WatchOption) (*RateLimiter, error) {
+	watch, err := watcher.AddWatch(ctx, key, NewRateLimitValue, options...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var rl RateLimiter
+	rl.watch = watch
+	rl.limiter = rate.NewLimiter(0, 0)
+	rl.unsubscribe = watch.Subscribe(func(_, newValue dynconf.Value) { rl.update(newValue.(*RateLimitValue)) })
+	// The value may have been updated before subscribing.
+	rl.update(watch.Value().(*RateLimitValue))
+	return &rl, nil
+}
+
+// Remove removes the watch of the rate limiter, the rate limiter is no longer
+// updated after that.
+func (rl *RateLimiter) Remove() {
+	rl.unsubscribe()
+	rl.watch.Remove()
+}
+
+// Limiter returns the rate limiter, which is the same one across updates.
+func (rl *RateLimiter) Limiter() *rate.Limiter {
+	return rl.limiter
+}
+
+func (rl *RateLimiter) update(value *RateLimitValue) {
+	now := time.Now()
+	rl.limiter.SetLimitAt(now, value.Limit)
+	rl.limiter.SetBurstAt(now, value.Burst)
+}
+
+// NewRateLimitValue returns a new value of type *RateLimitValue, it is a ValueFactory.
+func NewRateLimitValue() dynconf.Value {
+	return new(RateLimitValue)
+}
+
+// RateLimitValue presents a value holding a limit and a burst of a rate limiter.
+type RateLimitValue struct {
+	Limit rate.Limit
+	Burst int
+}
+
+var (
+	_ dynconf.Value          = (*RateLimitValue)(nil)
+	_ dynconf.ValueValidator = (*RateLimitValue)(nil)
+)
+
+// Unmarshal implements dynconf.Value.Unmarshal.
+func (rlv *RateLimitValue) Unmarshal(data []byte) error {
+	var rawValue struct {
+		Limit json.RawMessage `json:"limit"`
+		Burst int             `json:"burst"`
+	}
+
+	if err := json.Unmarshal(data, &rawValue); err != nil {
+		return err
+	}
+
+	if bytes.Equal(rawValue.Limit, []byte(`"inf"`)) {
+		rlv.Limit = rate.Inf
+	} else {
+		var limit float64
+
+		if err := json.Unmarshal(rawValue.Limit, &limit); err != nil {
+			return fmt.Errorf("limit=%s: %w", rawValue.Limit, err)
+		}
+
+		rlv.Limit = rate.Limit(limit)
+	}
+
+	rlv.Burst = rawValue.Burst
+	return nil
+}
+
+// Validate implements dynconf.ValueValidator.Validate.
+func (rlv *RateLimitValue) Validate() error {
+	if rlv.Limit < 0 {
+		return fmt.Errorf("limit=%v: %w", rlv.Limit, errNegativeLimit)
+	}
+
+	if rlv.Burst < 0 {
+		return fmt.Errorf("burst=%v: %w", rlv.Burst, errNegativeBurst)
+	}
+
+	return nil
+}
+
+// String implements dynconf.Value.String.
+func (rlv *RateLimitValue) String() string {
+	return fmt.Sprintf("limit=%v burst=%v", rlv.Limit, rlv.Burst)
+}
+
+// Breaker presents thresholds of a circuit breaker kept up to date with a watch on
+// a key, which holds a JSON object in the form of
+// {"failure_ratio": 0.5, "min_requests": 20, "open_timeout": "30s"}.
+type Breaker struct {
+	watch *dynconf.Watch
+}
+
+// AddBreaker adds a watch on the given key with the given watcher, and then returns
+// circuit breaker thresholds kept up to date with the watch.
+func AddBreaker(ctx context.Context, watcher *dynconf.Watcher, key string, options ...dynconf.WatchOption) (*Breaker, error) {
+	watch, err := watcher.AddWatch(ctx, key, NewBreakerThresholds, options...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &Breaker{watch}, nil
+}
+
+// Remove removes the watch of the circuit breaker thresholds.
+func (b *Breaker) Remove() {
+	b.watch.Remove()
+}
+
+// Thresholds returns the latest circuit breaker thresholds, which are replaced
+// atomically on change. The returned thresholds are read-only.
+func (b *Breaker) Thresholds() *BreakerThresholds {
+	return b.watch.Value().(*BreakerThresholds)
+}
+
+// NewBreakerThresholds returns a new value of type *BreakerThresholds, it is a ValueFactory.
+func NewBreakerThresholds() dynconf.Value {
+	return new(BreakerThresholds)
+}
+
+// BreakerThresholds presents a value holding thresholds of a circuit breaker.
+type BreakerThresholds struct {
+	// FailureRatio is the ratio of failed requests above which the breaker opens.
+	FailureRatio float64
+
+	// MinRequests is the minimum number of requests before the breaker may open.
+	MinRequests int
+
+	// OpenTimeout is the duration for which the breaker stays open before trying
+	// to close.
+	OpenTimeout time.Duration
+}
+
+var (
+	_ dynconf.Value          = (*BreakerThresholds)(nil)
+	_ dynconf.ValueValidator = (*BreakerThresholds)(nil)
+)
+
+// Unmarshal implements dynconf.Value.Unmarshal.
+func (bt *BreakerThresholds) Unmarshal(data []byte) error {
+	var rawValue struct {
+		FailureRatio float64 `json:"failure_ratio"`
+		MinRequests  int     `json:"min_requests"`
+		OpenTimeout  string  `json:"open_timeout"`
+	}
+
+	if err := json.Unmarshal(data, &rawValue); err != nil {
+		return err
+	}
+
+	openTimeout, err := time.ParseDuration(rawValue.OpenTimeout)
+
+	if err != nil {
+		return fmt.Errorf("open_timeout=%q: %w", rawValue.OpenTimeout, err)
+	}
+
+	bt.FailureRatio = rawValue.FailureRatio
+	bt.MinRequests = rawValue.MinRequests
+	bt.OpenTimeout = openTimeout
+	return nil
+}
+
+// Validate implements dynconf.ValueValidator.Validate.
+func (bt *BreakerThresholds) Validate() error {
+	if bt.FailureRatio < 0 || bt.FailureRatio > 1 {
+		return fmt.Errorf("failure_ratio=%v: %w", bt.FailureRatio, errInvalidFailureRatio)
+	}
+
+	if bt.MinRequests < 0 {
+		return fmt.Errorf("min_requests=%v: %w", bt.MinRequests, errNegativeMinRequests)
+	}
+
+	if bt.OpenTimeout <= 0 {
+		return fmt.Errorf("open_timeout=%v: %w", bt.OpenTimeout, errNonPositiveOpenTimeout)
+	}
+
+	return nil
+}
+
+// String implements dynconf.Value.String.
+func (bt *BreakerThresholds) String() string {
+	return fmt.Sprintf("failure_ratio=%v min_requests=%v open_timeout=%v", bt.FailureRatio, bt.MinRequests, bt.OpenTimeout)
+}
+
+var (
+	errNegativeLimit          = errors.New("negative limit")
+	errNegativeBurst          = errors.New("negative burst")
+	errInvalidFailureRatio    = errors.New("invalid failure ratio")
+	errNegativeMinRequests    = errors.New("negative min requests")
+	errNonPositiveOpenTimeout = errors.New("non-positive open timeout")
+)
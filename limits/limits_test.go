@@ -0,0 +1,47 @@
+package limits_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/time/rate"
+
+	"github.com/roy2220/dynconf/dynconftest"
+	"github.com/roy2220/dynconf/limits"
+)
+
+func TestRateLimiter(t *testing.T) {
+	wr, b := dynconftest.NewWatcher(t)
+	b.Set("rate_limit", []byte(`{"limit": 10, "burst": 5}`))
+	rl, err := limits.AddRateLimiter(context.Background(), wr, "rate_limit")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer rl.Remove()
+
+	l := rl.Limiter()
+	assert.Equal(t, rate.Limit(10), l.Limit())
+	assert.Equal(t, 5, l.Burst())
+
+	b.Set("rate_limit", []byte(`{"limit": -1, "burst": 5}`))
+	b.Set("rate_limit", []byte(`{"limit": "inf", "burst": 1}`))
+	assert.Eventually(t, func() bool { return l.Limit() == rate.Inf && l.Burst() == 1 }, time.Second, 10*time.Millisecond)
+	assert.Same(t, l, rl.Limiter())
+}
+
+func TestBreaker(t *testing.T) {
+	wr, b := dynconftest.NewWatcher(t)
+	b.Set("breaker", []byte(`{"failure_ratio": 0.5, "min_requests": 20, "open_timeout": "30s"}`))
+	br, err := limits.AddBreaker(context.Background(), wr, "breaker")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer br.Remove()
+
+	assert.Equal(t, limits.BreakerThresholds{FailureRatio: 0.5, MinRequests: 20, OpenTimeout: 30 * time.Second}, *br.Thresholds())
+
+	b.Set("breaker", []byte(`{"failure_ratio": 0.1, "min_requests": 10, "open_timeout": "1m"}`))
+	assert.Eventually(t, func() bool { return br.Thresholds().OpenTimeout == time.Minute }, time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,267 @@
+package dynconf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+)
+
+// SetLocalOverride sets the given data as the local override of the given key,
+// which takes precedence over the data in the backend for the watches on the key,
+// as a break-glass mechanism, until it is cleared with ClearLocalOverride. The
+// backend is not queried for the key while overridden. Prefix watches are not
+// affected by local overrides.
+func (w *Watcher) SetLocalOverride(key string, data []byte) {
+	w.logger.Log(LogWarn, "dynconf_local_override_set", "key", key)
+	w.localOverrides.Set(key, data)
+}
+
+// ClearLocalOverride clears the local override of the given key, if any, and the
+// watches on the key revert to the data in the backend.
+func (w *Watcher) ClearLocalOverride(key string) {
+	if w.localOverrides.Clear(key) {
+		w.logger.Log(LogWarn, "dynconf_local_override_cleared", "key", key)
+	}
+}
+
+// LoadLocalOverrides sets the data of the files under the given directory as the
+// local overrides of the keys, which are the paths of the files relative to the
+// directory, and clears the local overrides of the keys loaded last time but whose
+// files no longer exist. It can be called again to reload the files.
+func (w *Watcher) LoadLocalOverrides(dir string) error {
+	overrides := make(map[string][]byte)
+
+	if err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			return nil
+		}
+
+		relativePath, err := filepath.Rel(dir, path)
+
+		if err != nil {
+			return err
+		}
+
+		data, err := ioutil.ReadFile(path)
+
+		if err != nil {
+			return err
+		}
+
+		overrides[filepath.ToSlash(relativePath)] = data
+		return nil
+	}); err != nil {
+		return fmt.Errorf("dynconf: local overrides load failed; dir=%q: %w", dir, err)
+	}
+
+	w.localOverridesMu.Lock()
+	defer w.localOverridesMu.Unlock()
+
+	for key := range w.fileLocalOverrideKeys {
+		if _, ok := overrides[key]; !ok {
+			w.ClearLocalOverride(key)
+		}
+	}
+
+	for key, data := range overrides {
+		w.SetLocalOverride(key, data)
+	}
+
+	w.fileLocalOverrideKeys = make(map[string]struct{}, len(overrides))
+
+	for key := range overrides {
+		w.fileLocalOverrideKeys[key] = struct{}{}
+	}
+
+	return nil
+}
+
+type localOverrides struct {
+	mu        sync.Mutex
+	entries   map[string]localOverride
+	lastIndex uint64
+	changes   map[string]chan struct{}
+}
+
+type localOverride struct {
+	Data  []byte
+	Index uint64
+}
+
+func (lo *localOverrides) Set(key string, data []byte) {
+	lo.mu.Lock()
+	defer lo.mu.Unlock()
+	lo.init()
+	lo.entries[key] = localOverride{
+		Data:  append([]byte(nil), data...),
+		Index: lo.nextIndex(),
+	}
+	lo.notifyChange(key)
+}
+
+func (lo *localOverrides) Clear(key string) bool {
+	lo.mu.Lock()
+	defer lo.mu.Unlock()
+	lo.init()
+
+	if _, ok := lo.entries[key]; !ok {
+		return false
+	}
+
+	delete(lo.entries, key)
+	lo.notifyChange(key)
+	return true
+}
+
+// Get returns the local override of the given key, if any, and a channel which is
+// closed once the local override of the key has been changed.
+func (lo *localOverrides) Get(key string) (localOverride, bool, <-chan struct{}) {
+	lo.mu.Lock()
+	defer lo.mu.Unlock()
+	lo.init()
+	entry, ok := lo.entries[key]
+	changed := lo.changes[key]
+
+	if changed == nil {
+		changed = make(chan struct{})
+		lo.changes[key] = changed
+	}
+
+	return entry, ok, changed
+}
+
+func (lo *localOverrides) init() {
+	if lo.entries == nil {
+		lo.entries = make(map[string]localOverride)
+		lo.changes = make(map[string]chan struct{})
+	}
+}
+
+func (lo *localOverrides) nextIndex() uint64 {
+	// Indexes of local overrides are derived from the time, so that they hardly
+	// collide with indexes in the backend.
+	index := uint64(time.Now().UnixNano())
+
+	if index <= lo.lastIndex {
+		index = lo.lastIndex + 1
+	}
+
+	lo.lastIndex = index
+	return index
+}
+
+// notifyChange wakes up only the watches on the given key, so that changing the
+// local override of a key doesn't interrupt the queries of the other keys.
+func (lo *localOverrides) notifyChange(key string) {
+	if changed, ok := lo.changes[key]; ok {
+		close(changed)
+		delete(lo.changes, key)
+	}
+}
+
+// overrideBackend is set up for each watch and only used by the watch loop.
+type overrideBackend struct {
+	Backend   Backend
+	Overrides *localOverrides
+	Clock     Clock
+
+	lastOverrideIndex uint64
+}
+
+var _ Backend = (*overrideBackend)(nil)
+
+func (ob *overrideBackend) Get(key string, queryOptions *api.QueryOptions) (*api.KVPair, *api.QueryMeta, error) {
+	if queryOptions == nil {
+		queryOptions = new(api.QueryOptions)
+	}
+
+	ctx := queryOptions.Context()
+	var timer Timer
+
+	for {
+		override, ok, changed := ob.Overrides.Get(key)
+
+		if ok {
+			ob.lastOverrideIndex = override.Index
+
+			if queryOptions.WaitIndex == 0 || queryOptions.WaitIndex != override.Index {
+				return &api.KVPair{Key: key, Value: override.Data, ModifyIndex: override.Index},
+					&api.QueryMeta{LastIndex: override.Index}, nil
+			}
+
+			if timer == nil {
+				waitTime := queryOptions.WaitTime
+
+				if waitTime <= 0 {
+					waitTime = defaultWaitTime
+				}
+
+				timer = ob.Clock.NewTimer(waitTime)
+				defer timer.Stop()
+			}
+
+			select {
+			case <-changed:
+				continue
+			case <-timer.C():
+				return &api.KVPair{Key: key, Value: override.Data, ModifyIndex: override.Index},
+					&api.QueryMeta{LastIndex: override.Index}, nil
+			case <-ctx.Done():
+				return nil, nil, ctx.Err()
+			}
+		}
+
+		if queryOptions.WaitIndex != 0 && queryOptions.WaitIndex == ob.lastOverrideIndex {
+			// The local override has been cleared, re-read the backend immediately.
+			queryOptions = queryOptions.WithContext(ctx)
+			queryOptions.WaitIndex = 0
+		}
+
+		kvPair, queryMeta, err := ob.getFromBackend(key, queryOptions, changed)
+
+		if err == errLocalOverridesChanged {
+			continue
+		}
+
+		return kvPair, queryMeta, err
+	}
+}
+
+func (ob *overrideBackend) List(prefix string, queryOptions *api.QueryOptions) (api.KVPairs, *api.QueryMeta, error) {
+	return ob.Backend.List(prefix, queryOptions)
+}
+
+func (ob *overrideBackend) getFromBackend(key string, queryOptions *api.QueryOptions,
+	changed <-chan struct{}) (*api.KVPair, *api.QueryMeta, error) {
+	ctx, cancel := context.WithCancel(queryOptions.Context())
+	defer cancel()
+
+	go func() {
+		select {
+		case <-changed:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	kvPair, queryMeta, err := ob.Backend.Get(key, queryOptions.WithContext(ctx))
+
+	if err != nil && ctx.Err() != nil && queryOptions.Context().Err() == nil {
+		return nil, nil, errLocalOverridesChanged
+	}
+
+	return kvPair, queryMeta, err
+}
+
+var errLocalOverridesChanged = errors.New("local overrides changed")
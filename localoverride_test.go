@@ -0,0 +1,86 @@
+package dynconf_test
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/roy2220/dynconf"
+	"github.com/roy2220/dynconf/dynconftest"
+)
+
+func TestWatcherSetLocalOverride(t *testing.T) {
+	wr, b := dynconftest.NewWatcher(t)
+	b.Set("limit", []byte("1"))
+	w, err := wr.AddWatch(context.Background(), "limit", dynconf.NewIntValue, dynconf.WithWaitTime(time.Hour))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer w.Remove()
+
+	newValues := make(chan dynconf.Value, 3)
+	unsubscribe := w.Subscribe(func(_, newValue dynconf.Value) { newValues <- newValue })
+	defer unsubscribe()
+
+	wr.SetLocalOverride("limit", []byte("100"))
+	assert.Equal(t, int64(100), (<-newValues).(*dynconf.IntValue).Get())
+
+	b.Set("limit", []byte("2"))
+	wr.SetLocalOverride("limit", []byte("200"))
+	assert.Equal(t, int64(200), (<-newValues).(*dynconf.IntValue).Get())
+
+	wr.ClearLocalOverride("limit")
+	assert.Equal(t, int64(2), (<-newValues).(*dynconf.IntValue).Get())
+	assert.Len(t, newValues, 0)
+}
+
+func TestWatcherLoadLocalOverrides(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dynconf")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer os.RemoveAll(dir)
+
+	assert.NoError(t, os.MkdirAll(filepath.Join(dir, "app"), 0755))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "app", "limit"), []byte("100"), 0644))
+
+	wr, b := dynconftest.NewWatcher(t)
+	b.Set("app/limit", []byte("1"))
+	assert.NoError(t, wr.LoadLocalOverrides(dir))
+	w, err := wr.AddWatch(context.Background(), "app/limit", dynconf.NewIntValue)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer w.Remove()
+
+	assert.Equal(t, int64(100), w.Value().(*dynconf.IntValue).Get())
+
+	events := w.Events()
+	assert.NoError(t, os.Remove(filepath.Join(dir, "app", "limit")))
+	assert.NoError(t, wr.LoadLocalOverrides(dir))
+	assert.Equal(t, int64(1), (<-events).(*dynconf.UpdatedEvent).NewValue.(*dynconf.IntValue).Get())
+}
+
+func TestWatcherSetLocalOverrideOfOtherKey(t *testing.T) {
+	b := &countingBackend{Backend: new(dynconftest.Backend).Init()}
+	b.Set("limit", []byte("1"))
+	wr := dynconf.NewWatcherWithBackend(b, dynconf.WithLogger(dynconftest.Logger{T: t}))
+	defer wr.Close(context.Background())
+	w, err := wr.AddWatch(context.Background(), "limit", dynconf.NewIntValue, dynconf.WithWaitTime(time.Hour))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&b.NumberOfGets) == 2 }, time.Second, time.Millisecond)
+
+	// the blocking query of the key isn't interrupted
+	wr.SetLocalOverride("limit2", []byte("100"))
+	wr.ClearLocalOverride("limit2")
+	assert.Never(t, func() bool { return atomic.LoadInt32(&b.NumberOfGets) != 2 }, 100*time.Millisecond, time.Millisecond)
+	assert.Equal(t, int64(1), w.Value().(*dynconf.IntValue).Get())
+}
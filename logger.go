@@ -0,0 +1,42 @@
+package dynconf
+
+// Logger represents a logger for watches.
+type Logger interface {
+	// Log logs the given message with the given fields, as alternating keys and
+	// values, at the given level. Keys are strings and values are strings, byte
+	// slices or errors.
+	Log(level LogLevel, message string, keysAndValues ...interface{})
+}
+
+// LogLevel represents a level of logging.
+type LogLevel int
+
+// The log levels.
+const (
+	LogDebug LogLevel = iota
+	LogInfo
+	LogWarn
+	LogError
+)
+
+// String returns a string representing the log level.
+func (ll LogLevel) String() string {
+	switch ll {
+	case LogDebug:
+		return "debug"
+	case LogInfo:
+		return "info"
+	case LogWarn:
+		return "warn"
+	case LogError:
+		return "error"
+	default:
+		return "unknown"
+	}
+}
+
+type nopLogger struct{}
+
+var _ Logger = nopLogger{}
+
+func (nopLogger) Log(LogLevel, string, ...interface{}) {}
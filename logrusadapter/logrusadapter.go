@@ -0,0 +1,50 @@
+// Package logrusadapter adapts logrus loggers to dynconf loggers.
+package logrusadapter
+
+import (
+	"github.com/sirupsen/logrus"
+
+	"github.com/roy2220/dynconf"
+)
+
+// Logger presents a dynconf logger backed by a logrus logger.
+type Logger struct {
+	logger logrus.FieldLogger
+}
+
+var _ dynconf.Logger = (*Logger)(nil)
+
+// Init initializes the logger with the given logrus logger and then returns the logger.
+func (l *Logger) Init(logger logrus.FieldLogger) *Logger {
+	l.logger = logger
+	return l
+}
+
+// Log implements dynconf.Logger.Log.
+func (l *Logger) Log(level dynconf.LogLevel, message string, keysAndValues ...interface{}) {
+	fields := make(logrus.Fields, len(keysAndValues)/2)
+
+	for i := 0; i+1 < len(keysAndValues); i += 2 {
+		key, _ := keysAndValues[i].(string)
+
+		switch value := keysAndValues[i+1].(type) {
+		case []byte:
+			fields[key] = string(value)
+		default:
+			fields[key] = value
+		}
+	}
+
+	entry := l.logger.WithFields(fields)
+
+	switch level {
+	case dynconf.LogDebug:
+		entry.Debug(message)
+	case dynconf.LogInfo:
+		entry.Info(message)
+	case dynconf.LogWarn:
+		entry.Warn(message)
+	default:
+		entry.Error(message)
+	}
+}
@@ -0,0 +1,47 @@
+package logrusadapter_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/sirupsen/logrus/hooks/test"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/roy2220/dynconf"
+	"github.com/roy2220/dynconf/logrusadapter"
+)
+
+func TestLogger(t *testing.T) {
+	ll, hook := test.NewNullLogger()
+	ll.SetLevel(logrus.DebugLevel)
+	l := new(logrusadapter.Logger).Init(ll)
+
+	for _, tc := range []struct {
+		Level         dynconf.LogLevel
+		ExpectedLevel logrus.Level
+	}{
+		{dynconf.LogDebug, logrus.DebugLevel},
+		{dynconf.LogInfo, logrus.InfoLevel},
+		{dynconf.LogWarn, logrus.WarnLevel},
+		{dynconf.LogError, logrus.ErrorLevel},
+	} {
+		err := errors.New("oops")
+		l.Log(tc.Level, "dynconf_test", "key", "hello", "data", []byte("x"), "err", err, "index", 1)
+
+		entry := hook.LastEntry()
+		if !assert.NotNil(t, entry) {
+			t.FailNow()
+		}
+		assert.Equal(t, tc.ExpectedLevel, entry.Level)
+		assert.Equal(t, "dynconf_test", entry.Message)
+		assert.Equal(t, logrus.Fields{
+			"key":   "hello",
+			"data":  "x",
+			"err":   err,
+			"index": 1,
+		}, entry.Data)
+	}
+
+	assert.Len(t, hook.AllEntries(), 4)
+}
@@ -0,0 +1,96 @@
+package dynconf
+
+import (
+	"bytes"
+	"encoding/json"
+)
+
+// MergeJSON is a merge function deep-merging the JSON data of a key in multiple
+// layers: objects are merged recursively, and other values of a layer with higher
+// precedence replace the ones of a layer with lower precedence.
+func MergeJSON(datas [][]byte) ([]byte, error) {
+	var mergedObject interface{}
+
+	for i := len(datas) - 1; i >= 0; i-- {
+		decoder := json.NewDecoder(bytes.NewReader(datas[i]))
+		decoder.UseNumber()
+		var object interface{}
+
+		if err := decoder.Decode(&object); err != nil {
+			return nil, err
+		}
+
+		mergedObject = mergeJSONObjects(mergedObject, object)
+	}
+
+	return json.Marshal(mergedObject)
+}
+
+func mergeJSONObjects(object1 interface{}, object2 interface{}) interface{} {
+	map1, ok1 := object1.(map[string]interface{})
+	map2, ok2 := object2.(map[string]interface{})
+
+	if !ok1 || !ok2 {
+		return object2
+	}
+
+	for key, value2 := range map2 {
+		if value1, ok := map1[key]; ok {
+			map1[key] = mergeJSONObjects(value1, value2)
+		} else {
+			map1[key] = value2
+		}
+	}
+
+	return map1
+}
+
+// MergeJSONPatches is a merge function applying the JSON data of a key in multiple
+// layers, except the layer with the lowest precedence, as JSON merge patches
+// (RFC 7386) on top of the JSON data of the layer with the lowest precedence, in
+// the order of precedence from low to high.
+func MergeJSONPatches(datas [][]byte) ([]byte, error) {
+	var document interface{}
+
+	for i := len(datas) - 1; i >= 0; i-- {
+		decoder := json.NewDecoder(bytes.NewReader(datas[i]))
+		decoder.UseNumber()
+		var object interface{}
+
+		if err := decoder.Decode(&object); err != nil {
+			return nil, err
+		}
+
+		if i == len(datas)-1 {
+			document = object
+		} else {
+			document = applyJSONMergePatch(document, object)
+		}
+	}
+
+	return json.Marshal(document)
+}
+
+func applyJSONMergePatch(target interface{}, patch interface{}) interface{} {
+	patchMap, ok := patch.(map[string]interface{})
+
+	if !ok {
+		return patch
+	}
+
+	targetMap, ok := target.(map[string]interface{})
+
+	if !ok {
+		targetMap = make(map[string]interface{}, len(patchMap))
+	}
+
+	for key, value := range patchMap {
+		if value == nil {
+			delete(targetMap, key)
+		} else {
+			targetMap[key] = applyJSONMergePatch(targetMap[key], value)
+		}
+	}
+
+	return targetMap
+}
@@ -0,0 +1,21 @@
+package dynconf
+
+// Metrics represents a sink of metrics for watches, e.g. backed by Prometheus
+// counters. The counters incremented are:
+//
+//   - dynconf_value_updates, labeled with "key", once a value has been replaced.
+//   - dynconf_update_rejections, labeled with "key", once the data of a new value
+//     have been rejected.
+//   - dynconf_backend_errors, labeled with "key" or "prefix", once reading from the
+//     backend has failed.
+type Metrics interface {
+	// IncrCounter increments by one the counter with the given name and labels, as
+	// alternating keys and values.
+	IncrCounter(name string, labels ...string)
+}
+
+type nopMetrics struct{}
+
+var _ Metrics = nopMetrics{}
+
+func (nopMetrics) IncrCounter(string, ...string) {}
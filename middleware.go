@@ -0,0 +1,88 @@
+package dynconf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Update represents an update of the value of a watch, which is passed through the
+// apply middlewares of the watch.
+type Update struct {
+	// Key is the key on which the watch is set.
+	Key string
+
+	// ModifyIndex is the modify index of the key for the new value.
+	ModifyIndex uint64
+
+	// Data is the data of the new value, after decryption and decompression.
+	Data []byte
+
+	// OldValue is the current value.
+	OldValue Value
+
+	// NewValue is the new value, which has passed the validation and which may be
+	// replaced by the middlewares.
+	NewValue Value
+
+	raw      []byte
+	flags    uint64
+	applied  bool
+	replaced bool
+}
+
+// ApplyFunc is the type of the function applying the given update.
+type ApplyFunc func(ctx context.Context, update *Update) (err error)
+
+// ApplyMiddleware is the type of the function wrapping the given function applying
+// updates, in order to add cross-cutting concerns, e.g. metrics, veto rules or
+// canary gating, to the path applying updates.
+type ApplyMiddleware func(next ApplyFunc) ApplyFunc
+
+// WithApplyMiddlewares returns an option to pass the updates of the value of the
+// watch, which have passed the validation, through the given middlewares before
+// they are applied. The first middleware is the outermost. An update is vetoed and
+// rejected if a middleware returns, with the error as the reason if any, without
+// calling the next function. The initial value and the reversions to the default
+// value are not passed through the middlewares.
+func WithApplyMiddlewares(middlewares ...ApplyMiddleware) WatchOption {
+	return func(wo *watchOptions) {
+		wo.ApplyMiddlewares = append(wo.ApplyMiddlewares, middlewares...)
+	}
+}
+
+func (w *Watch) makeApplyFunc() ApplyFunc {
+	apply := func(_ context.Context, update *Update) error {
+		update.applied = true
+		w.applyUpdate(update)
+		return nil
+	}
+
+	for i := len(w.options.ApplyMiddlewares) - 1; i >= 0; i-- {
+		apply = w.options.ApplyMiddlewares[i](apply)
+	}
+
+	return apply
+}
+
+// passUpdate passes the given update through the apply middlewares, and then
+// returns the error vetoing the update, if any.
+func (w *Watch) passUpdate(update *Update) error {
+	err := w.protect("apply", func() error { return w.apply(w.ctx, update) })
+
+	if update.applied {
+		if err != nil {
+			w.logger.Log(LogWarn, "dynconf_apply_middleware_failed", "key", w.key, "error", err)
+		}
+
+		return nil
+	}
+
+	if err == nil {
+		err = errUpdateDropped
+	}
+
+	return fmt.Errorf("dynconf: update vetoed; key=%q: %w", w.key, err)
+}
+
+var errUpdateDropped = errors.New("update dropped by middleware")
@@ -0,0 +1,57 @@
+package dynconf_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/roy2220/dynconf"
+	"github.com/roy2220/dynconf/dynconftest"
+)
+
+func TestWithApplyMiddlewares(t *testing.T) {
+	wr, b := dynconftest.NewWatcher(t)
+	b.Set("limit", []byte("10"))
+
+	var trace []string
+	logging := func(next dynconf.ApplyFunc) dynconf.ApplyFunc {
+		return func(ctx context.Context, update *dynconf.Update) error {
+			trace = append(trace, "log:"+update.OldValue.String()+"->"+update.NewValue.String())
+			return next(ctx, update)
+		}
+	}
+	veto := func(next dynconf.ApplyFunc) dynconf.ApplyFunc {
+		return func(ctx context.Context, update *dynconf.Update) error {
+			if update.NewValue.(*dynconf.IntValue).Get() > 100 {
+				return errors.New("limit too high")
+			}
+
+			return next(ctx, update)
+		}
+	}
+	w, err := wr.AddWatch(context.Background(), "limit", dynconf.NewIntValue, dynconf.WithApplyMiddlewares(logging, veto))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer w.Remove()
+	assert.Empty(t, trace)
+
+	events := w.Events()
+	b.Set("limit", []byte("20"))
+	<-events
+	assert.Equal(t, int64(20), w.Value().(*dynconf.IntValue).Get())
+	assert.Equal(t, []string{"log:10->20"}, trace)
+
+	b.Set("limit", []byte("200"))
+	<-events
+	assert.Equal(t, int64(20), w.Value().(*dynconf.IntValue).Get())
+	assert.EqualError(t, w.LastError(), "dynconf: update vetoed; key=\"limit\": limit too high")
+	assert.Equal(t, []string{"log:10->20", "log:20->200"}, trace)
+
+	b.Set("limit", []byte("30"))
+	<-events
+	assert.Equal(t, int64(30), w.Value().(*dynconf.IntValue).Get())
+	assert.NoError(t, w.LastError())
+}
@@ -0,0 +1,128 @@
+package dynconf
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// WithMigrations returns an option to migrate the JSON data of the watch with the
+// given migrations before unmarshaling.
+func WithMigrations(migrations *Migrations) WatchOption {
+	return func(wo *watchOptions) {
+		wo.Migrations = migrations
+	}
+}
+
+// MigrationFunc is the type of the function migrating the given JSON object from
+// a version to the next version in place.
+type MigrationFunc func(object map[string]interface{}) error
+
+// Migrations presents migrations of JSON objects following the convention of the
+// field "version", an integer defaulting to 0 if missing, which is the version of
+// the schema of an object. Objects of versions older than the current version are
+// migrated to the current version step by step, while objects of versions newer
+// than the current version, which the binary doesn't understand, are rejected with
+// ErrVersionTooNew, so that the values are kept unchanged.
+type Migrations struct {
+	currentVersion int
+	migrations     map[int]MigrationFunc
+}
+
+// Init initializes the migrations with the given current version and then returns
+// the migrations.
+func (m *Migrations) Init(currentVersion int) *Migrations {
+	m.currentVersion = currentVersion
+	m.migrations = make(map[int]MigrationFunc)
+	return m
+}
+
+// Register registers the given function migrating objects from the given version
+// to the next version, and then returns the migrations. It should be called before
+// the migrations are used.
+func (m *Migrations) Register(fromVersion int, migrate MigrationFunc) *Migrations {
+	m.migrations[fromVersion] = migrate
+	return m
+}
+
+// Migrate migrates the given JSON data to the current version and then returns the
+// migrated data. The data are returned as is if they're of the current version.
+func (m *Migrations) Migrate(data []byte) ([]byte, error) {
+	object, ok := decodeJSONObject(data)
+
+	if !ok {
+		return nil, errJSONObjectRequired
+	}
+
+	version, err := objectVersion(object)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if version > m.currentVersion {
+		return nil, fmt.Errorf("%w; version=%d current_version=%d", ErrVersionTooNew, version, m.currentVersion)
+	}
+
+	if version == m.currentVersion {
+		return data, nil
+	}
+
+	for ; version < m.currentVersion; version++ {
+		migrate, ok := m.migrations[version]
+
+		if !ok {
+			return nil, fmt.Errorf("%w; from_version=%d", ErrMigrationNotFound, version)
+		}
+
+		if err := migrate(object); err != nil {
+			return nil, fmt.Errorf("dynconf: migration failed; from_version=%d: %w", version, err)
+		}
+
+		object[versionFieldName] = version + 1
+	}
+
+	return json.Marshal(object)
+}
+
+func decodeJSONObject(data []byte) (map[string]interface{}, bool) {
+	object, err := decodeJSON(data)
+
+	if err != nil {
+		return nil, false
+	}
+
+	objectMap, ok := object.(map[string]interface{})
+	return objectMap, ok
+}
+
+func objectVersion(object map[string]interface{}) (int, error) {
+	rawVersion, ok := object[versionFieldName]
+
+	if !ok {
+		return 0, nil
+	}
+
+	if number, ok := rawVersion.(json.Number); ok {
+		if version, err := strconv.Atoi(number.String()); err == nil {
+			return version, nil
+		}
+	}
+
+	return 0, fmt.Errorf("dynconf: invalid version; version=%v", rawVersion)
+}
+
+const versionFieldName = "version"
+
+var (
+	// ErrVersionTooNew is returned when the version of data is newer than the
+	// current version of migrations.
+	ErrVersionTooNew = errors.New("dynconf: version too new")
+
+	// ErrMigrationNotFound is returned when no migration has been registered to
+	// migrate data from a version.
+	ErrMigrationNotFound = errors.New("dynconf: migration not found")
+)
+
+var errJSONObjectRequired = errors.New("json object required")
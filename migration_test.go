@@ -0,0 +1,72 @@
+package dynconf_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/roy2220/dynconf"
+	"github.com/roy2220/dynconf/dynconftest"
+)
+
+func TestMigrations(t *testing.T) {
+	m := new(dynconf.Migrations).Init(2).
+		Register(0, func(object map[string]interface{}) error {
+			object["timeout_ms"] = object["timeout"]
+			delete(object, "timeout")
+			return nil
+		}).
+		Register(1, func(object map[string]interface{}) error {
+			object["enabled"] = true
+			return nil
+		})
+
+	data, err := m.Migrate([]byte(`{"timeout": 100}`))
+	if assert.NoError(t, err) {
+		assert.JSONEq(t, `{"version": 2, "timeout_ms": 100, "enabled": true}`, string(data))
+	}
+
+	data, err = m.Migrate([]byte(`{"version": 2, "timeout_ms": 200}`))
+	if assert.NoError(t, err) {
+		assert.JSONEq(t, `{"version": 2, "timeout_ms": 200}`, string(data))
+	}
+
+	_, err = m.Migrate([]byte(`{"version": 3}`))
+	assert.True(t, errors.Is(err, dynconf.ErrVersionTooNew))
+
+	_, err = new(dynconf.Migrations).Init(1).Migrate([]byte(`{}`))
+	assert.True(t, errors.Is(err, dynconf.ErrMigrationNotFound))
+
+	_, err = m.Migrate([]byte(`{"version": "x"}`))
+	assert.Error(t, err)
+}
+
+func TestWithMigrations(t *testing.T) {
+	type config struct {
+		Version   int `json:"version"`
+		TimeoutMS int `json:"timeout_ms"`
+	}
+	m := new(dynconf.Migrations).Init(1).Register(0, func(object map[string]interface{}) error {
+		object["timeout_ms"] = object["timeout"]
+		return nil
+	})
+
+	wr, b := dynconftest.NewWatcher(t)
+	b.Set("hello", []byte(`{"timeout": 100}`))
+	w, err := wr.AddWatch(context.Background(), "hello", dynconf.JSONValueFactory(func() interface{} {
+		return new(config)
+	}), dynconf.WithMigrations(m))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer w.Remove()
+	assert.Equal(t, &config{Version: 1, TimeoutMS: 100}, w.Value().(*dynconf.ObjectValue).Object())
+
+	events := w.Events()
+	b.Set("hello", []byte(`{"version": 2, "timeout_ms": 200}`))
+	e := (<-events).(*dynconf.UpdateRejectedEvent)
+	assert.True(t, errors.Is(e.Err, dynconf.ErrVersionTooNew))
+	assert.Equal(t, &config{Version: 1, TimeoutMS: 100}, w.Value().(*dynconf.ObjectValue).Object())
+}
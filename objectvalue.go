@@ -0,0 +1,88 @@
+package dynconf
+
+import "fmt"
+
+// UnmarshalFunc is the type of the function unmarshaling an object from the given data.
+type UnmarshalFunc func(data []byte) (object interface{}, err error)
+
+// ObjectValueFactory returns a value factory creating values of type *ObjectValue,
+// which unmarshal objects with the given function. With Go 1.18 or later, AddWatchT
+// adds a watch of which the values are typed instead.
+func ObjectValueFactory(unmarshal UnmarshalFunc) ValueFactory {
+	return func() Value {
+		return &ObjectValue{unmarshal: unmarshal}
+	}
+}
+
+// MarshalFunc is the type of the function marshaling the given object into data.
+type MarshalFunc func(object interface{}) (data []byte, err error)
+
+// ObjectValue presents a value holding an object unmarshaled with a function,
+// which saves the need to implement Value for every type of object.
+type ObjectValue struct {
+	unmarshal       UnmarshalFunc
+	unmarshalStrict UnmarshalFunc
+	marshal         MarshalFunc
+	object          interface{}
+}
+
+var (
+	_ Value             = (*ObjectValue)(nil)
+	_ ValueMarshaler    = (*ObjectValue)(nil)
+	_ StrictUnmarshaler = (*ObjectValue)(nil)
+)
+
+// Unmarshal implements Value.Unmarshal.
+func (ov *ObjectValue) Unmarshal(data []byte) error {
+	object, err := ov.unmarshal(data)
+
+	if err != nil {
+		return err
+	}
+
+	ov.object = object
+	return nil
+}
+
+// UnmarshalStrict implements StrictUnmarshaler.UnmarshalStrict, it falls back to
+// Unmarshal if the value has no function to unmarshal the object in strict mode.
+func (ov *ObjectValue) UnmarshalStrict(data []byte) error {
+	if ov.unmarshalStrict == nil {
+		return ov.Unmarshal(data)
+	}
+
+	object, err := ov.unmarshalStrict(data)
+
+	if err != nil {
+		return err
+	}
+
+	ov.object = object
+	return nil
+}
+
+// Marshal implements ValueMarshaler.Marshal, it returns ErrMarshalNotSupported
+// if the value has no function to marshal the object.
+func (ov *ObjectValue) Marshal() ([]byte, error) {
+	if ov.marshal == nil {
+		return nil, ErrMarshalNotSupported
+	}
+
+	return ov.marshal(ov.object)
+}
+
+// String implements Value.String.
+func (ov *ObjectValue) String() string {
+	return fmt.Sprintf("%+v", ov.object)
+}
+
+// Object returns the object unmarshaled.
+func (ov *ObjectValue) Object() interface{} {
+	return ov.object
+}
+
+// ObjectValue returns the object of the latest value of the key on which the watch
+// is set, the watch must be created with the value factory returned by ObjectValueFactory.
+func (w *Watch) ObjectValue() interface{} {
+	return w.Value().(*ObjectValue).Object()
+}
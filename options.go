@@ -0,0 +1,371 @@
+package dynconf
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+)
+
+// WatchOption represents an option for adding a watch.
+type WatchOption func(*watchOptions)
+
+// WithRetryPolicy returns an option to set the policy for retrying failed queries
+// of the watch. Failed queries are retried indefinitely, with the backoffs capped
+// by the policy, while the watch keeps the latest value and reports the failures
+// with LastError and ConsecutiveFailures, so that a watch never removes itself,
+// even after a prolonged failure, e.g. a network partition, and catches up once
+// the backend is reachable again.
+func WithRetryPolicy(retryPolicy RetryPolicy) WatchOption {
+	return func(wo *watchOptions) {
+		wo.RetryPolicy = retryPolicy
+	}
+}
+
+// WithWaitTime returns an option to set the maximum duration for blocking queries
+// of the watch.
+func WithWaitTime(waitTime time.Duration) WatchOption {
+	return func(wo *watchOptions) {
+		wo.QueryOptions.WaitTime = waitTime
+	}
+}
+
+// WithAllowStale returns an option to allow any Consul server, rather than only
+// the leader, to serve queries of the watch.
+func WithAllowStale(allowStale bool) WatchOption {
+	return func(wo *watchOptions) {
+		wo.QueryOptions.AllowStale = allowStale
+	}
+}
+
+// WithUseCache returns an option to allow the Consul agent to serve queries of the
+// watch from its local cache, with cached results older than the given maximum age,
+// if non-zero, being re-fetched. This only takes effect for endpoints supported by
+// the agent cache.
+func WithUseCache(maxAge time.Duration) WatchOption {
+	return func(wo *watchOptions) {
+		wo.QueryOptions.UseCache = true
+		wo.QueryOptions.MaxAge = maxAge
+	}
+}
+
+// WithQueryOptions returns an option to set the base options for queries of the
+// watch, the wait index and the context of which are always overridden, and so
+// is the token if WithTokenFunc is used.
+func WithQueryOptions(queryOptions api.QueryOptions) WatchOption {
+	return func(wo *watchOptions) {
+		wo.QueryOptions = queryOptions
+	}
+}
+
+// WithDefaultValue returns an option to set the data of the default value, with
+// which the watch starts if the key on which the watch is set is missing, and to
+// which the watch reverts once the key has been deleted.
+func WithDefaultValue(data []byte) WatchOption {
+	return func(wo *watchOptions) {
+		wo.HasDefaultValue = true
+		wo.DefaultData = data
+	}
+}
+
+// WithTracer returns an option to set the tracer tracing the propagation of
+// values of the watch.
+func WithTracer(tracer Tracer) WatchOption {
+	return func(wo *watchOptions) {
+		wo.Tracer = tracer
+	}
+}
+
+// WithMetrics returns an option to set the sink of metrics of the watch.
+func WithMetrics(metrics Metrics) WatchOption {
+	return func(wo *watchOptions) {
+		wo.Metrics = metrics
+	}
+}
+
+// WithNamespace returns an option to set the namespace of Consul Enterprise, in
+// which the key of the watch is, overriding the namespace set by WithQueryOptions
+// and the default one of the client. Watches on keys in different namespaces can
+// be added to the same watcher, and SetDefaultWatchOptions can be used to set the
+// namespace for all watches of the watcher.
+func WithNamespace(namespace string) WatchOption {
+	return func(wo *watchOptions) {
+		wo.Namespace = namespace
+	}
+}
+
+// WithDataValidator returns an option to set the validator, which validates the
+// data of new values of the watch before the values are unmarshaled. Data failing
+// validation are rejected.
+func WithDataValidator(validator func(data []byte) error) WatchOption {
+	return func(wo *watchOptions) {
+		wo.DataValidator = validator
+	}
+}
+
+// WithValidator returns an option to set the validator, which validates new values
+// of the watch, in addition to ValueValidator. Values failing validation are rejected.
+func WithValidator(validator func(value Value) error) WatchOption {
+	return func(wo *watchOptions) {
+		wo.Validator = validator
+	}
+}
+
+// WithUpdateRejectedCallback returns an option to set the callback, which is
+// called once after the data of a new value of the watch has been rejected due
+// to a failure of validation or unmarshaling, in which case the latest value is
+// kept.
+func WithUpdateRejectedCallback(callback func(err error, data []byte)) WatchOption {
+	return func(wo *watchOptions) {
+		wo.UpdateRejectedCallback = callback
+	}
+}
+
+// WithMaxDataSize returns an option to set the maximum size of the data of values
+// of the watch in bytes. Data exceeding the size are rejected with ErrDataTooLarge
+// without being unmarshaled, and never appear in logs.
+func WithMaxDataSize(maxDataSize int) WatchOption {
+	return func(wo *watchOptions) {
+		wo.MaxDataSize = maxDataSize
+	}
+}
+
+// WithDecompression returns an option to decompress the data of values of the watch
+// before the values are unmarshaled, of which the compression formats are detected
+// by the magic numbers registered with RegisterCompression. If the given compression
+// flag is non-zero, only the data of keys with the flag set in the flags of the keys
+// are decompressed, and the data must be compressed. The maximum size set by
+// WithMaxDataSize applies to both the compressed and decompressed data.
+func WithDecompression(compressionFlag uint64) WatchOption {
+	return func(wo *watchOptions) {
+		wo.DecompressionEnabled = true
+		wo.CompressionFlag = compressionFlag
+	}
+}
+
+// WithDecrypter returns an option to decrypt the data of values of the watch with
+// the given decrypter before the values are decompressed and unmarshaled. Data
+// failing decryption are rejected, and the decrypted data are never logged. The
+// disk cache set by WithDiskCache keeps the data encrypted, as received, which are
+// decrypted again when loaded.
+func WithDecrypter(decrypter Decrypter) WatchOption {
+	return func(wo *watchOptions) {
+		wo.Decrypter = decrypter
+	}
+}
+
+// WithDiskCache returns an option to cache the data of values applied by the watch
+// in files under the given directory, the cached data are used as the last known
+// good value if Consul is unreachable when the watch is being added.
+func WithDiskCache(dir string) WatchOption {
+	return func(wo *watchOptions) {
+		wo.DiskCacheDir = dir
+	}
+}
+
+// WithDebounce returns an option to coalesce a burst of changes to the watch, so
+// that new values are applied only once the watch has seen no further changes for
+// the given quiet period.
+func WithDebounce(quietPeriod time.Duration) WatchOption {
+	return func(wo *watchOptions) {
+		wo.DebounceQuietPeriod = quietPeriod
+	}
+}
+
+// WithTokenFunc returns an option to set the function returning the ACL token for
+// queries of the watch, which is called before each query, so that failed queries,
+// e.g. due to an expired token, are retried with the latest token.
+func WithTokenFunc(tokenFunc func() (token string)) WatchOption {
+	return func(wo *watchOptions) {
+		wo.TokenFunc = tokenFunc
+	}
+}
+
+// WatcherOption represents an option for creating a watcher. Any WatchOption is
+// also a WatcherOption, which is added to the defaults for watches added to the
+// watcher, e.g. NewWatcher(client, WithNamespace("team-a"), WithMetrics(metrics)).
+type WatcherOption interface {
+	applyToWatcher(w *Watcher)
+}
+
+type watcherOption func(*Watcher)
+
+func (o watcherOption) applyToWatcher(w *Watcher) {
+	o(w)
+}
+
+func (o WatchOption) applyToWatcher(w *Watcher) {
+	w.defaultOptions = append(w.defaultOptions, o)
+}
+
+// WithLogger returns an option to set the logger of the watcher, which defaults to
+// a no-op logger.
+func WithLogger(logger Logger) WatcherOption {
+	return watcherOption(func(w *Watcher) {
+		if logger != nil {
+			w.logger = logger
+		}
+	})
+}
+
+// WithDefaultRetry returns an option to set the policy for retrying failed queries
+// of watches added to the watcher, which is overridden by WithRetryPolicy given to
+// each watch.
+func WithDefaultRetry(retryPolicy RetryPolicy) WatcherOption {
+	return WithRetryPolicy(retryPolicy)
+}
+
+type watchOptions struct {
+	RetryPolicy         RetryPolicy
+	QueryOptions        api.QueryOptions
+	HasDefaultValue     bool
+	DefaultData         []byte
+	Tracer              Tracer
+	Metrics             Metrics
+	DiskCacheDir        string
+	DebounceQuietPeriod time.Duration
+	TokenFunc           func() string
+	Namespace           string
+	Datacenter          string
+	FailoverDatacenter  string
+	FailoverThreshold   time.Duration
+	MaxDataSize         int
+	MaxStaleness        time.Duration
+	StaleCallback       func()
+	Dependencies        []*Watch
+	DiffEnabled         bool
+	Migrations          *Migrations
+	ScheduleEnabled     bool
+	TTL                 time.Duration
+	AuditSink           AuditSink
+	InstanceID          string
+	StatusInterval      time.Duration
+	UsageTracking       bool
+	ReplacementKey      string
+	DualRead            bool
+	References          bool
+	TemplatingEnabled   bool
+	TemplateMetadata    map[string]string
+	TemplateEnvVars     []string
+	StrictUnmarshal     bool
+	StructValidator     StructValidator
+	ApplyMiddlewares    []ApplyMiddleware
+	MaxChangeRatio      float64
+	ChangeLimitPointers []string
+	HistorySize         int
+	Clock               Clock
+
+	DecompressionEnabled bool
+	CompressionFlag      uint64
+	Decrypter            Decrypter
+
+	DataValidator          func([]byte) error
+	Validator              func(Value) error
+	UpdateRejectedCallback func(error, []byte)
+}
+
+func (wo *watchOptions) Init(options []WatchOption) *watchOptions {
+	wo.RetryPolicy = DefaultRetryPolicy
+	wo.Tracer = nopTracer{}
+	wo.Metrics = nopMetrics{}
+	wo.Clock = SystemClock
+
+	for _, option := range options {
+		option(wo)
+	}
+
+	return wo
+}
+
+func (wo *watchOptions) makeQueryOptions(ctx context.Context, queryOptions api.QueryOptions) *api.QueryOptions {
+	if wo.TokenFunc != nil {
+		queryOptions.Token = wo.TokenFunc()
+	}
+
+	if wo.Namespace != "" {
+		queryOptions.Namespace = wo.Namespace
+	}
+
+	if wo.Datacenter != "" {
+		queryOptions.Datacenter = wo.Datacenter
+	}
+
+	return queryOptions.WithContext(ctx)
+}
+
+func (wo *watchOptions) validateValue(value Value) error {
+	if validator, ok := value.(ValueValidator); ok {
+		if err := validator.Validate(); err != nil {
+			return err
+		}
+	}
+
+	if err := wo.validateStruct(value); err != nil {
+		return err
+	}
+
+	if wo.Validator != nil {
+		return wo.Validator(value)
+	}
+
+	return nil
+}
+
+func (wo *watchOptions) checkDataSize(key string, data []byte) error {
+	if wo.MaxDataSize >= 1 && len(data) > wo.MaxDataSize {
+		return fmt.Errorf("%w; key=%q data_size=%d max_data_size=%d", ErrDataTooLarge, key, len(data), wo.MaxDataSize)
+	}
+
+	return nil
+}
+
+// decodeData checks the size of the given data, decrypts, decompresses, renders and
+// migrates the data if needed, and then returns the data to be unmarshaled.
+func (wo *watchOptions) decodeData(key string, data []byte, flags uint64) ([]byte, error) {
+	if err := wo.checkDataSize(key, data); err != nil {
+		return nil, err
+	}
+
+	if wo.Decrypter != nil {
+		plaintext, err := wo.Decrypter.Decrypt(data)
+
+		if err != nil {
+			return nil, fmt.Errorf("dynconf: data decryption failed; key=%q: %w", key, err)
+		}
+
+		data = plaintext
+	}
+
+	data, err := wo.decompressData(key, data, flags)
+
+	if err != nil {
+		return nil, err
+	}
+
+	data, err = wo.renderData(key, data)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if wo.Migrations != nil {
+		migratedData, err := wo.Migrations.Migrate(data)
+
+		if err != nil {
+			return nil, fmt.Errorf("dynconf: data migration failed; key=%q: %w", key, err)
+		}
+
+		data = migratedData
+	}
+
+	return data, nil
+}
+
+func (wo *watchOptions) validateData(data []byte) error {
+	if wo.DataValidator != nil {
+		return wo.DataValidator(data)
+	}
+
+	return nil
+}
@@ -0,0 +1,13 @@
+module github.com/roy2220/dynconf/oteladapter
+
+go 1.16
+
+require (
+	github.com/roy2220/dynconf v0.0.0
+	github.com/stretchr/testify v1.7.1
+	go.opentelemetry.io/otel v1.7.0
+	go.opentelemetry.io/otel/sdk v1.7.0
+	go.opentelemetry.io/otel/trace v1.7.0
+)
+
+replace github.com/roy2220/dynconf => ../
@@ -0,0 +1,76 @@
+// Package oteladapter adapts OpenTelemetry tracers to dynconf tracers. It is a
+// module of its own, so that the module github.com/roy2220/dynconf doesn't depend
+// on OpenTelemetry.
+package oteladapter
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/roy2220/dynconf"
+)
+
+// Tracer presents a dynconf tracer backed by an OpenTelemetry tracer.
+type Tracer struct {
+	tracer trace.Tracer
+}
+
+var _ dynconf.Tracer = (*Tracer)(nil)
+
+// Init initializes the tracer with the given OpenTelemetry tracer and then returns the tracer.
+func (t *Tracer) Init(tracer trace.Tracer) *Tracer {
+	t.tracer = tracer
+	return t
+}
+
+// StartSpan implements dynconf.Tracer.StartSpan.
+func (t *Tracer) StartSpan(ctx context.Context, name string, keysAndValues ...interface{}) (context.Context, dynconf.Span) {
+	ctx, span := t.tracer.Start(ctx, name, trace.WithAttributes(makeAttributes(keysAndValues)...))
+	return ctx, spanAdapter{span}
+}
+
+type spanAdapter struct {
+	span trace.Span
+}
+
+func (sa spanAdapter) AddEvent(name string, keysAndValues ...interface{}) {
+	sa.span.AddEvent(name, trace.WithAttributes(makeAttributes(keysAndValues)...))
+}
+
+func (sa spanAdapter) RecordError(err error) {
+	sa.span.RecordError(err)
+	sa.span.SetStatus(codes.Error, err.Error())
+}
+
+func (sa spanAdapter) End() {
+	sa.span.End()
+}
+
+func makeAttributes(keysAndValues []interface{}) []attribute.KeyValue {
+	attributes := make([]attribute.KeyValue, 0, len(keysAndValues)/2)
+
+	for i := 0; i+1 < len(keysAndValues); i += 2 {
+		key, _ := keysAndValues[i].(string)
+
+		switch value := keysAndValues[i+1].(type) {
+		case string:
+			attributes = append(attributes, attribute.String(key, value))
+		case int:
+			attributes = append(attributes, attribute.Int(key, value))
+		case uint64:
+			attributes = append(attributes, attribute.Int64(key, int64(value)))
+		case []byte:
+			attributes = append(attributes, attribute.String(key, string(value)))
+		case error:
+			attributes = append(attributes, attribute.String(key, value.Error()))
+		default:
+			attributes = append(attributes, attribute.String(key, fmt.Sprint(value)))
+		}
+	}
+
+	return attributes
+}
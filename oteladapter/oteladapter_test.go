@@ -0,0 +1,77 @@
+package oteladapter_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	"github.com/roy2220/dynconf"
+	"github.com/roy2220/dynconf/dynconftest"
+	"github.com/roy2220/dynconf/oteladapter"
+)
+
+func TestTracer(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	tr := new(oteladapter.Tracer).Init(tp.Tracer("dynconf"))
+
+	ctx, span := tr.StartSpan(context.Background(), "parent", "key", "hello", "index", 1, "modify_index", uint64(2),
+		"data", []byte("x"), "error", errors.New("oops"), "other", 1.5)
+	_, childSpan := tr.StartSpan(ctx, "child")
+	childSpan.AddEvent("event", "key", "hello")
+	childSpan.RecordError(errors.New("failure"))
+	childSpan.End()
+	span.End()
+
+	spans := recorder.Ended()
+	if !assert.Len(t, spans, 2) {
+		t.FailNow()
+	}
+
+	assert.Equal(t, "child", spans[0].Name())
+	assert.Equal(t, spans[1].SpanContext().SpanID(), spans[0].Parent().SpanID())
+	assert.Equal(t, codes.Error, spans[0].Status().Code)
+	assert.Equal(t, "failure", spans[0].Status().Description)
+	if events := spans[0].Events(); assert.Len(t, events, 2) {
+		assert.Equal(t, "event", events[0].Name)
+		assert.Equal(t, []attribute.KeyValue{attribute.String("key", "hello")}, events[0].Attributes)
+		assert.Equal(t, "exception", events[1].Name)
+	}
+
+	assert.Equal(t, "parent", spans[1].Name())
+	assert.Equal(t, []attribute.KeyValue{
+		attribute.String("key", "hello"),
+		attribute.Int("index", 1),
+		attribute.Int64("modify_index", 2),
+		attribute.String("data", "x"),
+		attribute.String("error", "oops"),
+		attribute.String("other", "1.5"),
+	}, spans[1].Attributes())
+	assert.Equal(t, codes.Unset, spans[1].Status().Code)
+}
+
+func TestTracerWithWatch(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	wr, b := dynconftest.NewWatcher(t)
+	b.Set("hello", []byte("1"))
+	w, err := wr.AddWatch(context.Background(), "hello", dynconf.NewIntValue,
+		dynconf.WithTracer(new(oteladapter.Tracer).Init(tp.Tracer("dynconf"))))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer w.Remove()
+
+	spans := recorder.Ended()
+	if assert.Len(t, spans, 1) {
+		assert.Equal(t, "dynconf.populate_value", spans[0].Name())
+		assert.Equal(t, []attribute.KeyValue{attribute.String("key", "hello")}, spans[0].Attributes())
+		assert.Equal(t, codes.Unset, spans[0].Status().Code)
+	}
+}
@@ -0,0 +1,95 @@
+package dynconf
+
+import (
+	"errors"
+	"fmt"
+	"runtime/debug"
+	"sync/atomic"
+)
+
+// ErrPanicked is returned when a value factory, a value or a callback has panicked,
+// in which case the panic is recovered and the latest value is kept.
+var ErrPanicked = errors.New("dynconf: panicked")
+
+// NumberOfRecoveredPanics returns the number of panics, from the value factory,
+// the values or the callbacks of the watch, which have been recovered.
+func (w *Watch) NumberOfRecoveredPanics() uint64 {
+	return atomic.LoadUint64(&w.numberOfRecoveredPanics)
+}
+
+// NumberOfRecoveredPanics returns the number of panics, from the value factory,
+// the values or the callbacks of the watch, which have been recovered.
+func (pw *PrefixWatch) NumberOfRecoveredPanics() uint64 {
+	return atomic.LoadUint64(&pw.numberOfRecoveredPanics)
+}
+
+func (w *Watch) protect(what string, f func() error) error {
+	return protect(w.logger, w.key, what, &w.numberOfRecoveredPanics, f)
+}
+
+func (w *Watch) call(what string, f func()) {
+	w.protect(what, func() error { f(); return nil })
+}
+
+func (w *Watch) newValue(data []byte) (value Value, err error) {
+	err = w.protect("unmarshal", func() error {
+		value = w.valueFactory()
+		return w.options.unmarshalValue(value, data)
+	})
+
+	return
+}
+
+func (w *Watch) validateValue(value Value) error {
+	return w.protect("validate", func() error { return w.options.validateValue(value) })
+}
+
+func (w *Watch) valueString(value Value) (s string) {
+	if err := w.protect("string", func() error { s = stringValue(value); return nil }); err != nil {
+		return "<panicked>"
+	}
+
+	return
+}
+
+func (pw *PrefixWatch) protect(key string, what string, f func() error) error {
+	return protect(pw.logger, key, what, &pw.numberOfRecoveredPanics, f)
+}
+
+func (pw *PrefixWatch) call(key string, what string, f func()) {
+	pw.protect(key, what, func() error { f(); return nil })
+}
+
+func (pw *PrefixWatch) newValue(key string, data []byte) (value Value, err error) {
+	err = pw.protect(key, "unmarshal", func() error {
+		value = pw.valueFactory()
+		return pw.options.unmarshalValue(value, data)
+	})
+
+	return
+}
+
+func (pw *PrefixWatch) validateValue(key string, value Value) error {
+	return pw.protect(key, "validate", func() error { return pw.options.validateValue(value) })
+}
+
+func (pw *PrefixWatch) valueString(key string, value Value) (s string) {
+	if err := pw.protect(key, "string", func() error { s = stringValue(value); return nil }); err != nil {
+		return "<panicked>"
+	}
+
+	return
+}
+
+func protect(logger Logger, key string, what string, numberOfPanics *uint64, f func() error) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			atomic.AddUint64(numberOfPanics, 1)
+			logger.Log(LogError, "dynconf_panic_recovered",
+				"key", key, "what", what, "panic", r, "stack", debug.Stack())
+			err = fmt.Errorf("%w; what=%q panic=%v", ErrPanicked, what, r)
+		}
+	}()
+
+	return f()
+}
@@ -0,0 +1,55 @@
+package dynconf_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/roy2220/dynconf"
+	"github.com/roy2220/dynconf/dynconftest"
+)
+
+func TestWatchPanicRecovery(t *testing.T) {
+	wr, b := dynconftest.NewWatcher(t)
+	b.Set("panicky", []byte("1"))
+	errs := make(chan error, 1)
+	w, err := wr.AddWatch(context.Background(), "panicky", newPanickyValue,
+		dynconf.WithUpdateRejectedCallback(func(err error, _ []byte) { errs <- err }))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer w.Remove()
+
+	newValues := make(chan dynconf.Value, 1)
+	w.Subscribe(func(_, _ dynconf.Value) { panic("subscriber panic") })
+	w.Subscribe(func(_, newValue dynconf.Value) { newValues <- newValue })
+
+	b.Set("panicky", []byte("panic"))
+	err = <-errs
+	assert.True(t, errors.Is(err, dynconf.ErrPanicked))
+	assert.Equal(t, "1", w.Value().String())
+
+	b.Set("panicky", []byte("2"))
+	assert.Equal(t, "2", (<-newValues).String())
+	assert.Equal(t, "2", w.Value().String())
+	assert.Equal(t, uint64(2), w.NumberOfRecoveredPanics())
+}
+
+type panickyValue struct {
+	data string
+}
+
+func newPanickyValue() dynconf.Value { return new(panickyValue) }
+
+func (pv *panickyValue) Unmarshal(data []byte) error {
+	if string(data) == "panic" {
+		panic("unmarshal panic")
+	}
+
+	pv.data = string(data)
+	return nil
+}
+
+func (pv *panickyValue) String() string { return pv.data }
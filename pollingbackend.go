@@ -0,0 +1,142 @@
+package dynconf
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+)
+
+// Fetcher represents a source of data of keys, which can only be polled.
+type Fetcher interface {
+	// Fetch fetches the data of the given key along with the version of the data,
+	// which changes each time the data has been changed. ok is false if the key is
+	// missing.
+	Fetch(ctx context.Context, key string) (data []byte, version string, ok bool, err error)
+}
+
+// PollingBackend presents a backend which polls a fetcher periodically, turning
+// the fetcher into a backend supporting blocking queries.
+type PollingBackend struct {
+	fetcher      Fetcher
+	pollInterval time.Duration
+	clock        Clock
+
+	mu        sync.Mutex
+	entries   map[string]pollingEntry
+	lastIndex uint64
+}
+
+var _ Backend = (*PollingBackend)(nil)
+
+// Init initializes the backend with the given fetcher and the given interval for
+// polling, which defaults to 10s, and then returns the backend.
+func (pb *PollingBackend) Init(fetcher Fetcher, pollInterval time.Duration) *PollingBackend {
+	return pb.InitWithClock(fetcher, pollInterval, SystemClock)
+}
+
+// InitWithClock initializes the backend like Init, of which the polling and the
+// blocking queries wait with the given clock, and then returns the backend.
+func (pb *PollingBackend) InitWithClock(fetcher Fetcher, pollInterval time.Duration, clock Clock) *PollingBackend {
+	if pollInterval <= 0 {
+		pollInterval = defaultPollInterval
+	}
+
+	pb.fetcher = fetcher
+	pb.pollInterval = pollInterval
+	pb.clock = clock
+	pb.entries = make(map[string]pollingEntry)
+	return pb
+}
+
+// Get implements Backend.Get.
+func (pb *PollingBackend) Get(key string, queryOptions *api.QueryOptions) (*api.KVPair, *api.QueryMeta, error) {
+	if queryOptions == nil {
+		queryOptions = new(api.QueryOptions)
+	}
+
+	ctx := queryOptions.Context()
+	var deadline time.Time
+
+	if queryOptions.WaitIndex != 0 {
+		waitTime := queryOptions.WaitTime
+
+		if waitTime <= 0 {
+			waitTime = defaultWaitTime
+		}
+
+		deadline = pb.clock.Now().Add(waitTime)
+	}
+
+	for {
+		data, version, ok, err := pb.fetcher.Fetch(ctx, key)
+
+		if err != nil {
+			return nil, nil, fmt.Errorf("dynconf: fetch failed; key=%q: %w", key, err)
+		}
+
+		index := pb.updateEntry(key, version, ok)
+
+		if queryOptions.WaitIndex == 0 || index != queryOptions.WaitIndex || !pb.clock.Now().Before(deadline) {
+			queryMeta := api.QueryMeta{LastIndex: index}
+
+			if !ok {
+				return nil, &queryMeta, nil
+			}
+
+			return &api.KVPair{Key: key, Value: data, ModifyIndex: index}, &queryMeta, nil
+		}
+
+		pollInterval := pb.pollInterval
+
+		if remainingWaitTime := deadline.Sub(pb.clock.Now()); pollInterval > remainingWaitTime {
+			pollInterval = remainingWaitTime
+		}
+
+		timer := pb.clock.NewTimer(pollInterval)
+
+		select {
+		case <-timer.C():
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, nil, ctx.Err()
+		}
+	}
+}
+
+// List implements Backend.List, it always returns ErrListNotSupported.
+func (pb *PollingBackend) List(prefix string, queryOptions *api.QueryOptions) (api.KVPairs, *api.QueryMeta, error) {
+	return nil, nil, fmt.Errorf("%w; prefix=%q", ErrListNotSupported, prefix)
+}
+
+func (pb *PollingBackend) updateEntry(key string, version string, ok bool) uint64 {
+	pb.mu.Lock()
+	defer pb.mu.Unlock()
+	entry, entryOK := pb.entries[key]
+
+	if entryOK && entry.Version == version && entry.OK == ok {
+		return entry.Index
+	}
+
+	pb.lastIndex++
+	pb.entries[key] = pollingEntry{
+		Version: version,
+		OK:      ok,
+		Index:   pb.lastIndex,
+	}
+
+	return pb.lastIndex
+}
+
+type pollingEntry struct {
+	Version string
+	OK      bool
+	Index   uint64
+}
+
+const (
+	defaultPollInterval = 10 * time.Second
+	defaultWaitTime     = 5 * time.Minute
+)
@@ -0,0 +1,112 @@
+package dynconf_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/roy2220/dynconf"
+	"github.com/roy2220/dynconf/dynconftest"
+)
+
+func TestPollingBackend(t *testing.T) {
+	f := &fetcher{data: map[string]string{"hello": `{"Foo": 1}`}}
+	b := new(dynconf.PollingBackend).Init(f, 10*time.Millisecond)
+	wr := new(dynconf.Watcher).InitWithBackend(b, makeLogger(t))
+	w, err := wr.AddWatch(context.Background(), "hello", newValue)
+	if assert.NoError(t, err) {
+		defer w.Remove()
+	}
+
+	cfg := w.Value().(*config)
+	cfg.Equals(t, &config{Foo: 1})
+
+	f.Set("hello", `{"Foo": 2}`)
+	<-cfg.OutdatedEvent()
+	cfg = w.Value().(*config)
+	cfg.Equals(t, &config{Foo: 2})
+
+	f.Delete("hello")
+	<-cfg.DeletedEvent()
+	assert.False(t, w.Exists())
+
+	_, err = wr.AddPrefixWatch(context.Background(), "hel", newValue, dynconf.PrefixWatchCallbacks{})
+	assert.True(t, errors.Is(err, dynconf.ErrListNotSupported))
+}
+
+func TestPollingBackendWithClock(t *testing.T) {
+	clock := dynconftest.NewClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	f := &fetcher{data: map[string]string{"hello": "1"}}
+	b := new(dynconf.PollingBackend).InitWithClock(f, 10*time.Second, clock)
+	wr := dynconf.NewWatcherWithBackend(b, dynconf.WithLogger(dynconftest.Logger{T: t}), dynconf.WithClock(clock))
+	w, err := wr.AddWatch(context.Background(), "hello", dynconf.NewIntValue)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer w.Remove()
+
+	events := w.Events()
+	// wait for the blocking query to poll
+	clock.BlockUntil(1)
+	f.Set("hello", "2")
+	assert.Equal(t, int64(1), w.Value().(*dynconf.IntValue).Get())
+	clock.Advance(10 * time.Second)
+	e := (<-events).(*dynconf.UpdatedEvent)
+	assert.Equal(t, int64(2), e.NewValue.(*dynconf.IntValue).Get())
+}
+
+type fetcher struct {
+	mu      sync.Mutex
+	data    map[string]string
+	version int
+}
+
+func (f *fetcher) Fetch(_ context.Context, key string) ([]byte, string, bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	data, ok := f.data[key]
+	return []byte(data), strconv.Itoa(f.version), ok, nil
+}
+
+func (f *fetcher) Set(key string, data string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.data[key] = data
+	f.version++
+}
+
+func (f *fetcher) Delete(key string) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	delete(f.data, key)
+	f.version++
+}
+
+func TestFileFetcher(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dynconf")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer os.RemoveAll(dir)
+
+	f := new(dynconf.FileFetcher).Init(dir)
+	_, _, ok, err := f.Fetch(context.Background(), "app/hello")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	assert.NoError(t, os.Mkdir(filepath.Join(dir, "app"), 0755))
+	assert.NoError(t, ioutil.WriteFile(filepath.Join(dir, "app", "hello"), []byte(`{"Foo": 1}`), 0644))
+	data, version, ok, err := f.Fetch(context.Background(), "app/hello")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, `{"Foo": 1}`, string(data))
+	assert.NotEmpty(t, version)
+}
@@ -0,0 +1,480 @@
+package dynconf
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/hashicorp/consul/api"
+)
+
+// AddPrefixWatch adds a watch on the keys with the given prefix and then returns the watch.
+// The keys whose data fail to be decoded, validated or unmarshaled are rejected
+// and left out of the values of the watch, until their data are fixed.
+func (w *Watcher) AddPrefixWatch(ctx context.Context, prefix string, valueFactory ValueFactory,
+	callbacks PrefixWatchCallbacks, options ...WatchOption) (*PrefixWatch, error) {
+	prefixWatch := PrefixWatch{
+		watcher:      w,
+		logger:       w.logger,
+		prefix:       prefix,
+		valueFactory: valueFactory,
+		redacting:    isRedactor(valueFactory),
+		callbacks:    callbacks,
+	}
+
+	prefixWatch.options.Init(w.watchOptions(options))
+	prefixWatch.redacting = prefixWatch.redacting || prefixWatch.options.Decrypter != nil
+	prefixWatch.backend = prefixWatch.options.withFailover(w.backend, w.logger, prefix)
+
+	if err := prefixWatch.populateValues(ctx); err != nil {
+		return nil, err
+	}
+
+	if err := w.addWatch(&prefixWatch); err != nil {
+		return nil, err
+	}
+
+	return &prefixWatch, nil
+}
+
+// PrefixWatch presents a watch on the keys with a prefix.
+type PrefixWatch struct {
+	numberOfRejectedUpdates uint64 // accessed atomically, kept 64-bit aligned
+	numberOfRecoveredPanics uint64 // accessed atomically, kept 64-bit aligned
+
+	watcher      *Watcher
+	backend      Backend
+	logger       Logger
+	prefix       string
+	valueFactory ValueFactory
+	redacting    bool
+	callbacks    PrefixWatchCallbacks
+	options      watchOptions
+	values       atomic.Value
+	dataIndexes  map[string]uint64
+	rawData      map[string][]byte
+	rawValues    atomic.Value
+	index        uint64
+	ctx          context.Context
+	cancel       context.CancelFunc
+	wg           sync.WaitGroup
+
+	statusMu            sync.Mutex
+	lastError           error
+	consecutiveFailures int
+
+	freezer freezer
+}
+
+// PrefixWatchCallbacks represents the optional callbacks to PrefixWatch.
+type PrefixWatchCallbacks struct {
+	// OnKeyAdded is called once after the value of a new key has been added.
+	OnKeyAdded func(key string, value Value)
+
+	// OnKeyUpdated is called once after the value of an existing key has been
+	// replaced with another value.
+	OnKeyUpdated func(key string, oldValue Value, newValue Value)
+
+	// OnKeyRemoved is called once after the value of an existing key has been
+	// removed due to the deletion of the key.
+	OnKeyRemoved func(key string, oldValue Value)
+}
+
+// Remove removes the watch and waits for it to stop. Remove is idempotent.
+func (pw *PrefixWatch) Remove() {
+	pw.watcher.removeWatch(pw)
+	pw.stop()
+	pw.wait()
+}
+
+// RemoveContext removes the watch and waits for it to stop until the given context
+// is done. RemoveContext is idempotent.
+func (pw *PrefixWatch) RemoveContext(ctx context.Context) error {
+	if err := pw.watcher.removeWatchContext(ctx, pw); err != nil {
+		return fmt.Errorf("dynconf: watch remove failed; prefix=%q: %w", pw.prefix, err)
+	}
+
+	return nil
+}
+
+// Prefix returns the prefix on which the watch is set.
+func (pw *PrefixWatch) Prefix() string {
+	return pw.prefix
+}
+
+// Values returns the latest values of the keys with the prefix on which the watch
+// is set. The returned map is read-only.
+func (pw *PrefixWatch) Values() map[string]Value {
+	return pw.values.Load().(map[string]Value)
+}
+
+// Value returns the latest value of the given key with the prefix on which the
+// watch is set.
+func (pw *PrefixWatch) Value(key string) (Value, bool) {
+	value, ok := pw.Values()[key]
+	return value, ok
+}
+
+// LastError returns the error of the last failed query of the watch, or nil if a
+// query has succeeded since then.
+func (pw *PrefixWatch) LastError() error {
+	pw.statusMu.Lock()
+	defer pw.statusMu.Unlock()
+	return pw.lastError
+}
+
+// ConsecutiveFailures returns the number of the failed queries of the watch since
+// the last successful query.
+func (pw *PrefixWatch) ConsecutiveFailures() int {
+	pw.statusMu.Lock()
+	defer pw.statusMu.Unlock()
+	return pw.consecutiveFailures
+}
+
+// Synced returns a channel which is closed once the watch has been synced. A prefix
+// watch is always synced as it is populated from Consul.
+func (pw *PrefixWatch) Synced() <-chan struct{} {
+	return syncedChannel
+}
+
+// NumberOfRejectedUpdates returns the number of new values of the keys with the
+// prefix on which the watch is set, which have been rejected due to a failure of
+// validation or unmarshaling.
+func (pw *PrefixWatch) NumberOfRejectedUpdates() uint64 {
+	return atomic.LoadUint64(&pw.numberOfRejectedUpdates)
+}
+
+func (pw *PrefixWatch) populateValues(ctx context.Context) (err error) {
+	ctx, span := pw.options.Tracer.StartSpan(ctx, "dynconf.populate_values", "prefix", pw.prefix)
+	defer func() { endSpan(span, err) }()
+	queryOptions := pw.options.makeQueryOptions(ctx, pw.options.QueryOptions)
+	kvPairs, queryMeta, err := pw.backend.List(pw.prefix, queryOptions)
+
+	if err != nil {
+		return fmt.Errorf("dynconf: kv list failed; prefix=%q: %w", pw.prefix, err)
+	}
+
+	values := make(map[string]Value, len(kvPairs))
+	pw.dataIndexes = make(map[string]uint64, len(kvPairs))
+	pw.rawData = make(map[string][]byte, len(kvPairs))
+
+	for _, kvPair := range kvPairs {
+		if isFolder(kvPair) {
+			continue
+		}
+
+		pw.dataIndexes[kvPair.Key] = kvPair.ModifyIndex
+		value, err := pw.makeValue(kvPair.Key, kvPair.Value, kvPair.Flags)
+
+		if err != nil {
+			pw.logger.Log(LogError, "dynconf_update_rejected", "key", kvPair.Key, "error", err)
+			span.RecordError(err)
+			pw.rejectUpdate(kvPair.Key, err, kvPair.Value, nil)
+			continue
+		}
+
+		values[kvPair.Key] = value
+		pw.rawData[kvPair.Key] = kvPair.Value
+	}
+
+	pw.setValues(values)
+	pw.setRawValues()
+	pw.index = queryMeta.LastIndex
+	return nil
+}
+
+func (pw *PrefixWatch) add() {
+	pw.ctx, pw.cancel = context.WithCancel(context.Background())
+	pw.wg.Add(1)
+
+	go func() {
+		defer pw.wg.Done()
+		pw.keepValuesUpToDate()
+	}()
+}
+
+func (pw *PrefixWatch) stop() {
+	pw.cancel()
+}
+
+func (pw *PrefixWatch) wait() {
+	pw.wg.Wait()
+}
+
+func (pw *PrefixWatch) keepValuesUpToDate() {
+	retry := retry{
+		RetryPolicy: pw.options.RetryPolicy,
+		Clock:       pw.options.Clock,
+	}
+
+	for {
+		queryOptions := pw.options.QueryOptions
+		queryOptions.WaitIndex = pw.index
+
+		var (
+			kvPairs   api.KVPairs
+			queryMeta *api.QueryMeta
+		)
+
+		if _, err := retry.Do(pw.ctx, func() bool {
+			var err error
+			kvPairs, queryMeta, err = pw.backend.List(pw.prefix, pw.options.makeQueryOptions(pw.ctx, queryOptions))
+
+			if err != nil {
+				pw.logger.Log(LogWarn, "dynconf_kv_list_failed", "prefix", pw.prefix, "error", err)
+				pw.options.Metrics.IncrCounter("dynconf_backend_errors", "prefix", pw.prefix)
+				pw.recordFailure(fmt.Errorf("dynconf: kv list failed; prefix=%q: %w", pw.prefix, err))
+				return false
+			}
+
+			pw.recordSuccess()
+			return true
+		}); err != nil {
+			pw.logger.Log(LogInfo, "dynconf_watch_removed", "prefix", pw.prefix)
+
+			for key, value := range pw.Values() {
+				if callback, ok := value.(ValueWatchRemovedCallback); ok {
+					pw.call(key, "on_watch_removed", callback.OnWatchRemoved)
+				}
+			}
+
+			return
+		}
+
+		if queryMeta.LastIndex == pw.index {
+			continue
+		}
+
+		if pw.IsFrozen() {
+			pw.holdUpdate()
+			continue
+		}
+
+		kvPairs, queryMeta = pw.debounce(kvPairs, queryMeta)
+
+		pw.updateValues(kvPairs, queryMeta.LastIndex)
+
+		if queryMeta.LastIndex < pw.index {
+			queryMeta.LastIndex = 0
+		}
+
+		pw.index = queryMeta.LastIndex
+	}
+}
+
+func (pw *PrefixWatch) debounce(kvPairs api.KVPairs, queryMeta *api.QueryMeta) (api.KVPairs, *api.QueryMeta) {
+	if pw.options.DebounceQuietPeriod <= 0 {
+		return kvPairs, queryMeta
+	}
+
+	for {
+		queryOptions := pw.options.QueryOptions
+		queryOptions.WaitIndex = queryMeta.LastIndex
+		queryOptions.WaitTime = pw.options.DebounceQuietPeriod
+		newKVPairs, newQueryMeta, err := pw.backend.List(pw.prefix, pw.options.makeQueryOptions(pw.ctx, queryOptions))
+
+		if err != nil {
+			pw.logger.Log(LogWarn, "dynconf_kv_list_failed", "prefix", pw.prefix, "error", err)
+			return kvPairs, queryMeta
+		}
+
+		if newQueryMeta.LastIndex == queryMeta.LastIndex {
+			return kvPairs, queryMeta
+		}
+
+		pw.logger.Log(LogDebug, "dynconf_change_debounced", "prefix", pw.prefix, "index", newQueryMeta.LastIndex)
+		kvPairs, queryMeta = newKVPairs, newQueryMeta
+	}
+}
+
+func (pw *PrefixWatch) updateValues(kvPairs api.KVPairs, index uint64) {
+	_, span := pw.options.Tracer.StartSpan(pw.ctx, "dynconf.update_values", "prefix", pw.prefix, "index", index)
+	defer span.End()
+	oldValues := pw.Values()
+	newValues := make(map[string]Value, len(kvPairs))
+	dataIndexes := make(map[string]uint64, len(kvPairs))
+	rawData := make(map[string][]byte, len(kvPairs))
+	var addedKeys, updatedKeys []string
+
+	for _, kvPair := range kvPairs {
+		if isFolder(kvPair) {
+			continue
+		}
+
+		oldValue, ok := oldValues[kvPair.Key]
+		dataIndex, dataIndexOK := pw.dataIndexes[kvPair.Key]
+		dataIndexes[kvPair.Key] = kvPair.ModifyIndex
+		oldData, oldDataOK := pw.rawData[kvPair.Key]
+
+		if oldDataOK {
+			rawData[kvPair.Key] = oldData
+		}
+
+		if dataIndexOK && kvPair.ModifyIndex == dataIndex {
+			if ok {
+				newValues[kvPair.Key] = oldValue
+			}
+
+			continue
+		}
+
+		if ok && oldDataOK && bytes.Equal(kvPair.Value, oldData) {
+			pw.logger.Log(LogDebug, "dynconf_value_unchanged", "key", kvPair.Key)
+			newValues[kvPair.Key] = oldValue
+			continue
+		}
+
+		newValue, err := pw.makeValue(kvPair.Key, kvPair.Value, kvPair.Flags)
+
+		if err != nil {
+			pw.logger.Log(LogError, "dynconf_update_rejected", "key", kvPair.Key, "error", err)
+			span.RecordError(err)
+			pw.rejectUpdate(kvPair.Key, err, kvPair.Value, oldValue)
+
+			if ok {
+				newValues[kvPair.Key] = oldValue
+			}
+
+			continue
+		}
+
+		rawData[kvPair.Key] = kvPair.Value
+
+		if ok && valuesEqual(oldValue, newValue) {
+			pw.logger.Log(LogDebug, "dynconf_value_unchanged", "key", kvPair.Key)
+			newValues[kvPair.Key] = oldValue
+			continue
+		}
+
+		newValues[kvPair.Key] = newValue
+
+		if ok {
+			pw.logger.Log(LogInfo, "dynconf_value_updated", "key", kvPair.Key, "new_value", pw.valueString(kvPair.Key, newValue))
+			updatedKeys = append(updatedKeys, kvPair.Key)
+		} else {
+			pw.logger.Log(LogInfo, "dynconf_value_added", "key", kvPair.Key, "new_value", pw.valueString(kvPair.Key, newValue))
+			addedKeys = append(addedKeys, kvPair.Key)
+		}
+	}
+
+	var removedKeys []string
+
+	for key := range oldValues {
+		if _, ok := newValues[key]; !ok {
+			pw.logger.Log(LogInfo, "dynconf_value_removed", "key", key)
+			removedKeys = append(removedKeys, key)
+		}
+	}
+
+	pw.setValues(newValues)
+	pw.dataIndexes = dataIndexes
+	pw.rawData = rawData
+	pw.setRawValues()
+	span.AddEvent("dynconf.values_replaced",
+		"added_keys", len(addedKeys), "updated_keys", len(updatedKeys), "removed_keys", len(removedKeys))
+
+	for _, key := range addedKeys {
+		pw.options.Metrics.IncrCounter("dynconf_value_updates", "key", key)
+
+		if pw.callbacks.OnKeyAdded != nil {
+			pw.call(key, "on_key_added", func() { pw.callbacks.OnKeyAdded(key, newValues[key]) })
+		}
+	}
+
+	for _, key := range updatedKeys {
+		pw.options.Metrics.IncrCounter("dynconf_value_updates", "key", key)
+		oldValue := oldValues[key]
+
+		if callback, ok := oldValue.(ValueOutdatedCallback); ok {
+			pw.call(key, "on_outdated", callback.OnOutdated)
+		}
+
+		if pw.callbacks.OnKeyUpdated != nil {
+			pw.call(key, "on_key_updated", func() { pw.callbacks.OnKeyUpdated(key, oldValue, newValues[key]) })
+		}
+	}
+
+	for _, key := range removedKeys {
+		oldValue := oldValues[key]
+
+		if callback, ok := oldValue.(ValueOutdatedCallback); ok {
+			pw.call(key, "on_outdated", callback.OnOutdated)
+		}
+
+		if pw.callbacks.OnKeyRemoved != nil {
+			pw.call(key, "on_key_removed", func() { pw.callbacks.OnKeyRemoved(key, oldValue) })
+		}
+	}
+
+	span.AddEvent("dynconf.values_applied")
+}
+
+// makeValue makes a value of the given key from the given data, which are decoded,
+// validated and unmarshaled, and then returns the value.
+func (pw *PrefixWatch) makeValue(key string, data []byte, flags uint64) (Value, error) {
+	data, err := pw.options.decodeData(key, data, flags)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := pw.options.validateData(data); err != nil {
+		return nil, fmt.Errorf("dynconf: data validation failed; key=%q data=%q: %w", key, pw.redactData(data), err)
+	}
+
+	value, err := pw.newValue(key, data)
+
+	if err != nil {
+		return nil, fmt.Errorf("dynconf: value unmarshal failed; key=%q data=%q: %w", key, pw.redactData(data), err)
+	}
+
+	if err := pw.validateValue(key, value); err != nil {
+		return nil, fmt.Errorf("dynconf: value validation failed; key=%q data=%q: %w", key, pw.redactData(data), err)
+	}
+
+	return value, nil
+}
+
+func (pw *PrefixWatch) rejectUpdate(key string, err error, data []byte, oldValue Value) {
+	atomic.AddUint64(&pw.numberOfRejectedUpdates, 1)
+	pw.options.Metrics.IncrCounter("dynconf_update_rejections", "key", key)
+
+	if callback, ok := oldValue.(ValueUpdateRejectedCallback); ok {
+		pw.call(key, "on_update_rejected", func() { callback.OnUpdateRejected(err, data) })
+	}
+
+	if callback := pw.options.UpdateRejectedCallback; callback != nil {
+		pw.call(key, "update_rejected_callback", func() { callback(err, data) })
+	}
+}
+
+func (pw *PrefixWatch) recordSuccess() {
+	pw.statusMu.Lock()
+	pw.lastError = nil
+	pw.consecutiveFailures = 0
+	pw.statusMu.Unlock()
+}
+
+func (pw *PrefixWatch) recordFailure(err error) {
+	pw.statusMu.Lock()
+	pw.lastError = err
+	pw.consecutiveFailures++
+	pw.statusMu.Unlock()
+}
+
+func (pw *PrefixWatch) setValues(values map[string]Value) {
+	pw.values.Store(values)
+}
+
+var syncedChannel = func() chan struct{} {
+	c := make(chan struct{})
+	close(c)
+	return c
+}()
+
+func isFolder(kvPair *api.KVPair) bool {
+	return strings.HasSuffix(kvPair.Key, "/") && len(kvPair.Value) == 0
+}
@@ -0,0 +1,111 @@
+package dynconf_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/roy2220/dynconf"
+	"github.com/roy2220/dynconf/dynconftest"
+)
+
+func TestPrefixWatchValues(t *testing.T) {
+	wr, c := makeWatcher(t)
+	_, err := c.KV().Put(&api.KVPair{
+		Key:   "tenants/a",
+		Value: []byte(`{"Foo": 1, "Bar": "a"}`),
+	}, &api.WriteOptions{})
+	assert.NoError(t, err)
+
+	addedKeys := make(chan string, 1)
+	updatedKeys := make(chan string, 1)
+	removedKeys := make(chan string, 1)
+	pw, err := wr.AddPrefixWatch(context.Background(), "tenants/", newValue, dynconf.PrefixWatchCallbacks{
+		OnKeyAdded:   func(key string, _ dynconf.Value) { addedKeys <- key },
+		OnKeyUpdated: func(key string, _, _ dynconf.Value) { updatedKeys <- key },
+		OnKeyRemoved: func(key string, _ dynconf.Value) { removedKeys <- key },
+	})
+	if assert.NoError(t, err) {
+		defer pw.Remove()
+	}
+
+	assert.Equal(t, "tenants/", pw.Prefix())
+	assert.Len(t, pw.Values(), 1)
+	v, ok := pw.Value("tenants/a")
+	if assert.True(t, ok) {
+		v.(*config).Equals(t, &config{
+			Foo: 1,
+			Bar: "a",
+		})
+	}
+
+	_, err = c.KV().Put(&api.KVPair{
+		Key:   "tenants/b",
+		Value: []byte(`{"Foo": 2, "Bar": "b"}`),
+	}, &api.WriteOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "tenants/b", <-addedKeys)
+	assert.Len(t, pw.Values(), 2)
+
+	_, err = c.KV().Put(&api.KVPair{
+		Key:   "tenants/a",
+		Value: []byte(`{"Foo": 3, "Bar": "aa"}`),
+	}, &api.WriteOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "tenants/a", <-updatedKeys)
+	v, _ = pw.Value("tenants/a")
+	v.(*config).Equals(t, &config{
+		Foo: 3,
+		Bar: "aa",
+	})
+
+	_, err = c.KV().Delete("tenants/b", &api.WriteOptions{})
+	assert.NoError(t, err)
+	assert.Equal(t, "tenants/b", <-removedKeys)
+	assert.Len(t, pw.Values(), 1)
+}
+
+func TestPrefixWatchRejectedKeys(t *testing.T) {
+	wr, b := dynconftest.NewWatcher(t)
+	defer wr.Close(context.Background())
+	b.Set("tenants/a", []byte(`{"Foo": 1, "Bar": "a"}`))
+	b.Set("tenants/b", []byte(`{"Foo": "oops"}`))
+	b.Set("tenants/c", []byte(`{"Foo": 3, "Bar": "c"}`))
+
+	var rejectedData []string
+	addedKeys := make(chan string, 1)
+	pw, err := wr.AddPrefixWatch(context.Background(), "tenants/", newValue, dynconf.PrefixWatchCallbacks{
+		OnKeyAdded: func(key string, _ dynconf.Value) { addedKeys <- key },
+	}, dynconf.WithUpdateRejectedCallback(func(_ error, data []byte) {
+		rejectedData = append(rejectedData, string(data))
+	}))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	assert.Len(t, pw.Values(), 2)
+	_, ok := pw.Value("tenants/b")
+	assert.False(t, ok)
+	assert.Equal(t, uint64(1), pw.NumberOfRejectedUpdates())
+	assert.Equal(t, []string{`{"Foo": "oops"}`}, rejectedData)
+
+	b.Set("tenants/b", []byte(`{"Foo": 2, "Bar": "b"}`))
+	select {
+	case key := <-addedKeys:
+		assert.Equal(t, "tenants/b", key)
+	case <-time.After(time.Second):
+		t.Fatal("key not added")
+	}
+	v, ok := pw.Value("tenants/b")
+	if assert.True(t, ok) {
+		v.(*config).Equals(t, &config{
+			Foo: 2,
+			Bar: "b",
+		})
+	}
+	assert.Len(t, pw.Values(), 3)
+	assert.Equal(t, uint64(1), pw.NumberOfRejectedUpdates())
+}
@@ -0,0 +1,307 @@
+package dynconf
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// NewIntValue returns a new value of type *IntValue, it is a ValueFactory.
+func NewIntValue() Value {
+	return new(IntValue)
+}
+
+// IntValue presents a value holding an int64.
+type IntValue struct {
+	value int64
+}
+
+var _ Value = (*IntValue)(nil)
+
+// Unmarshal implements Value.Unmarshal.
+func (iv *IntValue) Unmarshal(data []byte) error {
+	value, err := strconv.ParseInt(strings.TrimSpace(string(data)), 10, 64)
+
+	if err != nil {
+		return err
+	}
+
+	iv.value = value
+	return nil
+}
+
+// String implements Value.String.
+func (iv *IntValue) String() string {
+	return strconv.FormatInt(iv.value, 10)
+}
+
+// Get returns the int64 held.
+func (iv *IntValue) Get() int64 {
+	return iv.value
+}
+
+// AddDynInt adds a watch on the given key holding an int64 and then returns
+// the dynamic int64 kept up to date with the watch.
+func (w *Watcher) AddDynInt(ctx context.Context, key string, options ...WatchOption) (*DynInt, error) {
+	watch, err := w.AddWatch(ctx, key, NewIntValue, options...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &DynInt{watch}, nil
+}
+
+// DynInt presents a dynamic int64.
+type DynInt struct {
+	watch *Watch
+}
+
+// Get returns the latest int64, it is safe for concurrent use.
+func (di *DynInt) Get() int64 {
+	return di.watch.Value().(*IntValue).Get()
+}
+
+// Watch returns the watch with which the dynamic int64 is kept up to date.
+func (di *DynInt) Watch() *Watch {
+	return di.watch
+}
+
+// NewFloatValue returns a new value of type *FloatValue, it is a ValueFactory.
+func NewFloatValue() Value {
+	return new(FloatValue)
+}
+
+// FloatValue presents a value holding a float64.
+type FloatValue struct {
+	value float64
+}
+
+var _ Value = (*FloatValue)(nil)
+
+// Unmarshal implements Value.Unmarshal.
+func (fv *FloatValue) Unmarshal(data []byte) error {
+	value, err := strconv.ParseFloat(strings.TrimSpace(string(data)), 64)
+
+	if err != nil {
+		return err
+	}
+
+	fv.value = value
+	return nil
+}
+
+// String implements Value.String.
+func (fv *FloatValue) String() string {
+	return strconv.FormatFloat(fv.value, 'g', -1, 64)
+}
+
+// Get returns the float64 held.
+func (fv *FloatValue) Get() float64 {
+	return fv.value
+}
+
+// AddDynFloat adds a watch on the given key holding a float64 and then returns
+// the dynamic float64 kept up to date with the watch.
+func (w *Watcher) AddDynFloat(ctx context.Context, key string, options ...WatchOption) (*DynFloat, error) {
+	watch, err := w.AddWatch(ctx, key, NewFloatValue, options...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &DynFloat{watch}, nil
+}
+
+// DynFloat presents a dynamic float64.
+type DynFloat struct {
+	watch *Watch
+}
+
+// Get returns the latest float64, it is safe for concurrent use.
+func (df *DynFloat) Get() float64 {
+	return df.watch.Value().(*FloatValue).Get()
+}
+
+// Watch returns the watch with which the dynamic float64 is kept up to date.
+func (df *DynFloat) Watch() *Watch {
+	return df.watch
+}
+
+// NewBoolValue returns a new value of type *BoolValue, it is a ValueFactory.
+func NewBoolValue() Value {
+	return new(BoolValue)
+}
+
+// BoolValue presents a value holding a bool.
+type BoolValue struct {
+	value bool
+}
+
+var _ Value = (*BoolValue)(nil)
+
+// Unmarshal implements Value.Unmarshal.
+func (bv *BoolValue) Unmarshal(data []byte) error {
+	value, err := strconv.ParseBool(strings.TrimSpace(string(data)))
+
+	if err != nil {
+		return err
+	}
+
+	bv.value = value
+	return nil
+}
+
+// String implements Value.String.
+func (bv *BoolValue) String() string {
+	return strconv.FormatBool(bv.value)
+}
+
+// Get returns the bool held.
+func (bv *BoolValue) Get() bool {
+	return bv.value
+}
+
+// AddDynBool adds a watch on the given key holding a bool and then returns
+// the dynamic bool kept up to date with the watch.
+func (w *Watcher) AddDynBool(ctx context.Context, key string, options ...WatchOption) (*DynBool, error) {
+	watch, err := w.AddWatch(ctx, key, NewBoolValue, options...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &DynBool{watch}, nil
+}
+
+// DynBool presents a dynamic bool.
+type DynBool struct {
+	watch *Watch
+}
+
+// Get returns the latest bool, it is safe for concurrent use.
+func (db *DynBool) Get() bool {
+	return db.watch.Value().(*BoolValue).Get()
+}
+
+// Watch returns the watch with which the dynamic bool is kept up to date.
+func (db *DynBool) Watch() *Watch {
+	return db.watch
+}
+
+// NewStringValue returns a new value of type *StringValue, it is a ValueFactory.
+func NewStringValue() Value {
+	return new(StringValue)
+}
+
+// StringValue presents a value holding a string.
+type StringValue struct {
+	value string
+}
+
+var _ Value = (*StringValue)(nil)
+
+// Unmarshal implements Value.Unmarshal.
+func (sv *StringValue) Unmarshal(data []byte) error {
+	sv.value = string(data)
+	return nil
+}
+
+// String implements Value.String.
+func (sv *StringValue) String() string {
+	return sv.value
+}
+
+// Get returns the string held.
+func (sv *StringValue) Get() string {
+	return sv.value
+}
+
+// AddDynString adds a watch on the given key holding a string and then returns
+// the dynamic string kept up to date with the watch.
+func (w *Watcher) AddDynString(ctx context.Context, key string, options ...WatchOption) (*DynString, error) {
+	watch, err := w.AddWatch(ctx, key, NewStringValue, options...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &DynString{watch}, nil
+}
+
+// DynString presents a dynamic string.
+type DynString struct {
+	watch *Watch
+}
+
+// Get returns the latest string, it is safe for concurrent use.
+func (ds *DynString) Get() string {
+	return ds.watch.Value().(*StringValue).Get()
+}
+
+// Watch returns the watch with which the dynamic string is kept up to date.
+func (ds *DynString) Watch() *Watch {
+	return ds.watch
+}
+
+// NewDurationValue returns a new value of type *DurationValue, it is a ValueFactory.
+func NewDurationValue() Value {
+	return new(DurationValue)
+}
+
+// DurationValue presents a value holding a time.Duration.
+type DurationValue struct {
+	value time.Duration
+}
+
+var _ Value = (*DurationValue)(nil)
+
+// Unmarshal implements Value.Unmarshal.
+func (dv *DurationValue) Unmarshal(data []byte) error {
+	value, err := time.ParseDuration(strings.TrimSpace(string(data)))
+
+	if err != nil {
+		return err
+	}
+
+	dv.value = value
+	return nil
+}
+
+// String implements Value.String.
+func (dv *DurationValue) String() string {
+	return dv.value.String()
+}
+
+// Get returns the time.Duration held.
+func (dv *DurationValue) Get() time.Duration {
+	return dv.value
+}
+
+// AddDynDuration adds a watch on the given key holding a time.Duration and then returns
+// the dynamic time.Duration kept up to date with the watch.
+func (w *Watcher) AddDynDuration(ctx context.Context, key string, options ...WatchOption) (*DynDuration, error) {
+	watch, err := w.AddWatch(ctx, key, NewDurationValue, options...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &DynDuration{watch}, nil
+}
+
+// DynDuration presents a dynamic time.Duration.
+type DynDuration struct {
+	watch *Watch
+}
+
+// Get returns the latest time.Duration, it is safe for concurrent use.
+func (dd *DynDuration) Get() time.Duration {
+	return dd.watch.Value().(*DurationValue).Get()
+}
+
+// Watch returns the watch with which the dynamic time.Duration is kept up to date.
+func (dd *DynDuration) Watch() *Watch {
+	return dd.watch
+}
@@ -0,0 +1,38 @@
+package dynconf_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/roy2220/dynconf"
+)
+
+func TestPrimitiveValues(t *testing.T) {
+	iv := dynconf.NewIntValue().(*dynconf.IntValue)
+	assert.NoError(t, iv.Unmarshal([]byte(" 233\n")))
+	assert.Equal(t, int64(233), iv.Get())
+	assert.Equal(t, "233", iv.String())
+	assert.Error(t, iv.Unmarshal([]byte("2.5")))
+
+	fv := dynconf.NewFloatValue().(*dynconf.FloatValue)
+	assert.NoError(t, fv.Unmarshal([]byte("2.5")))
+	assert.Equal(t, 2.5, fv.Get())
+	assert.Equal(t, "2.5", fv.String())
+
+	bv := dynconf.NewBoolValue().(*dynconf.BoolValue)
+	assert.NoError(t, bv.Unmarshal([]byte("true")))
+	assert.True(t, bv.Get())
+	assert.Error(t, bv.Unmarshal([]byte("yes")))
+
+	sv := dynconf.NewStringValue().(*dynconf.StringValue)
+	assert.NoError(t, sv.Unmarshal([]byte(" hello ")))
+	assert.Equal(t, " hello ", sv.Get())
+
+	dv := dynconf.NewDurationValue().(*dynconf.DurationValue)
+	assert.NoError(t, dv.Unmarshal([]byte("500ms")))
+	assert.Equal(t, 500*time.Millisecond, dv.Get())
+	assert.Equal(t, "500ms", dv.String())
+	assert.Error(t, dv.Unmarshal([]byte("500")))
+}
@@ -0,0 +1,47 @@
+// Package protovalue implements values holding protobuf messages.
+package protovalue
+
+import (
+	"encoding/json"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/roy2220/dynconf"
+)
+
+// ValueFactory returns a value factory creating values of type *Value, which
+// unmarshal messages created by the given function.
+func ValueFactory(newMessage func() proto.Message) dynconf.ValueFactory {
+	return func() dynconf.Value {
+		return &Value{message: newMessage()}
+	}
+}
+
+// Value presents a value holding a protobuf message. The message is unmarshaled
+// from protojson data if the data is valid JSON, otherwise from binary protobuf
+// data.
+type Value struct {
+	message proto.Message
+}
+
+var _ dynconf.Value = (*Value)(nil)
+
+// Unmarshal implements dynconf.Value.Unmarshal.
+func (v *Value) Unmarshal(data []byte) error {
+	if json.Valid(data) {
+		return protojson.Unmarshal(data, v.message)
+	}
+
+	return proto.Unmarshal(data, v.message)
+}
+
+// String implements dynconf.Value.String.
+func (v *Value) String() string {
+	return protojson.Format(v.message)
+}
+
+// Message returns the message held.
+func (v *Value) Message() proto.Message {
+	return v.message
+}
@@ -0,0 +1,30 @@
+package protovalue_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	"github.com/roy2220/dynconf/protovalue"
+)
+
+func TestValueUnmarshal(t *testing.T) {
+	valueFactory := protovalue.ValueFactory(func() proto.Message { return new(structpb.Struct) })
+
+	v := valueFactory().(*protovalue.Value)
+	assert.NoError(t, v.Unmarshal([]byte(`{"foo": 99, "bar": "world"}`)))
+	s := v.Message().(*structpb.Struct)
+	assert.Equal(t, 99.0, s.Fields["foo"].GetNumberValue())
+	assert.Equal(t, "world", s.Fields["bar"].GetStringValue())
+
+	data, err := proto.Marshal(s)
+	assert.NoError(t, err)
+	v = valueFactory().(*protovalue.Value)
+	assert.NoError(t, v.Unmarshal(data))
+	assert.True(t, proto.Equal(s, v.Message()))
+
+	v = valueFactory().(*protovalue.Value)
+	assert.Error(t, v.Unmarshal([]byte{0xff, 0xff}))
+}
@@ -0,0 +1,152 @@
+package dynconf
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/hashicorp/consul/api"
+)
+
+// Publisher presents a publisher writing values of keys to the KV store of Consul.
+type Publisher struct {
+	client *api.Client
+
+	mu            sync.Mutex
+	modifyIndexes map[string]uint64
+}
+
+// Init initializes the publisher with the given client and then returns the publisher.
+func (p *Publisher) Init(client *api.Client) *Publisher {
+	p.client = client
+	p.modifyIndexes = make(map[string]uint64)
+	return p
+}
+
+// Publish marshals the given value, which must implement ValueMarshaler, validates
+// the value and the data with the validators set by the given options, e.g. the
+// same options for watches on the key, and then writes the data to the given key
+// with check-and-set on the modify index observed at the last publication to the
+// key. Publish fails with ErrModifyIndexUnknown if there is no such a publication,
+// in which case PublishCAS should be called instead, and with ErrPublishConflict if
+// the key has been modified since then, in which case the latest value should be
+// re-read before publishing again with PublishCAS.
+func (p *Publisher) Publish(ctx context.Context, key string, value Value, options ...WatchOption) error {
+	return p.publish(ctx, key, value, options, nil, nil, nil)
+}
+
+// PublishCAS publishes the given value to the given key like Publish, but with
+// check-and-set on the given modify index, which should be the one of the value
+// the given value is based on, e.g. returned by Watch.ModifyIndex, or 0 if the key
+// must be missing. PublishCAS fails with ErrPublishConflict if the key has been
+// modified since then.
+func (p *Publisher) PublishCAS(ctx context.Context, key string, value Value, modifyIndex uint64,
+	options ...WatchOption) error {
+	return p.publish(ctx, key, value, options, &modifyIndex, nil, nil)
+}
+
+// publish publishes the given value to the given key with check-and-set on the
+// given modify index, or the one observed at the last publication to the key if
+// nil, with the given operations checking the preconditions of the publication in
+// the same transaction, which fails with the given error if any of the
+// preconditions isn't met.
+func (p *Publisher) publish(ctx context.Context, key string, value Value, options []WatchOption, modifyIndex *uint64,
+	preconditions api.TxnOps, errPreconditionFailed error) error {
+	marshaler, ok := value.(ValueMarshaler)
+
+	if !ok {
+		return fmt.Errorf("dynconf: publish failed; key=%q type=%T: value marshaler required", key, value)
+	}
+
+	data, err := marshaler.Marshal()
+
+	if err != nil {
+		return fmt.Errorf("dynconf: value marshal failed; key=%q: %w", key, err)
+	}
+
+	var watchOptions watchOptions
+	watchOptions.Init(options)
+
+	if err := watchOptions.checkDataSize(key, data); err != nil {
+		return err
+	}
+
+	if err := watchOptions.validateValue(value); err != nil {
+		return fmt.Errorf("dynconf: value validation failed; key=%q data=%q: %w", key, data, err)
+	}
+
+	if err := watchOptions.validateData(data); err != nil {
+		return fmt.Errorf("dynconf: data validation failed; key=%q data=%q: %w", key, data, err)
+	}
+
+	if modifyIndex == nil {
+		p.mu.Lock()
+		lastModifyIndex, ok := p.modifyIndexes[key]
+		p.mu.Unlock()
+
+		if !ok {
+			return fmt.Errorf("%w; key=%q", ErrModifyIndexUnknown, key)
+		}
+
+		modifyIndex = &lastModifyIndex
+	}
+
+	queryOptions := watchOptions.makeQueryOptions(ctx, watchOptions.QueryOptions)
+	return p.publishData(key, data, 0, *modifyIndex, queryOptions, preconditions, errPreconditionFailed)
+}
+
+// publishData writes the given data and the given flags to the given key with
+// check-and-set on the given modify index, with the given operations checking the
+// preconditions of the publication in the same transaction.
+func (p *Publisher) publishData(key string, data []byte, flags uint64, modifyIndex uint64, queryOptions *api.QueryOptions,
+	preconditions api.TxnOps, errPreconditionFailed error) error {
+	txnOps := append(preconditions[:len(preconditions):len(preconditions)], &api.TxnOp{
+		KV: &api.KVTxnOp{
+			Verb:      api.KVCAS,
+			Key:       key,
+			Value:     data,
+			Flags:     flags,
+			Index:     modifyIndex,
+			Namespace: queryOptions.Namespace,
+		},
+	})
+	ok, response, _, err := p.client.Txn().Txn(txnOps, queryOptions)
+
+	if err != nil {
+		return fmt.Errorf("dynconf: kv cas failed; key=%q: %w", key, err)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if !ok {
+		for _, txnError := range response.Errors {
+			if txnError.OpIndex < len(preconditions) {
+				return fmt.Errorf("%w; key=%q: %s", errPreconditionFailed, key, txnError.What)
+			}
+		}
+
+		delete(p.modifyIndexes, key)
+		return fmt.Errorf("%w; key=%q modify_index=%d", ErrPublishConflict, key, modifyIndex)
+	}
+
+	delete(p.modifyIndexes, key)
+
+	for _, result := range response.Results {
+		if result.KV != nil && result.KV.Key == key {
+			p.modifyIndexes[key] = result.KV.ModifyIndex
+		}
+	}
+
+	return nil
+}
+
+// ErrPublishConflict is returned when a key has been modified by others since
+// the last known modify index of the key.
+var ErrPublishConflict = errors.New("dynconf: publish conflict")
+
+// ErrModifyIndexUnknown is returned when a publisher publishes to a key without a
+// modify index to check-and-set on, i.e. before any publication to the key or after
+// a conflict.
+var ErrModifyIndexUnknown = errors.New("dynconf: modify index unknown")
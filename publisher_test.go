@@ -0,0 +1,58 @@
+package dynconf_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/roy2220/dynconf"
+)
+
+func TestPublisherPublish(t *testing.T) {
+	c := makeClient(t)
+	_, err := c.KV().Delete("hello17", &api.WriteOptions{})
+	assert.NoError(t, err)
+	p := new(dynconf.Publisher).Init(c)
+	validator := dynconf.WithValidator(func(value dynconf.Value) error {
+		if value.(*dynconf.ObjectValue).Object().(*config).Foo < 0 {
+			return errors.New("negative foo")
+		}
+
+		return nil
+	})
+
+	err = p.Publish(context.Background(), "hello17", dynconf.NewCodecValue("json", &config{Foo: -1}), validator)
+	assert.EqualError(t, err, "dynconf: value validation failed; key=\"hello17\" data=\"{\\\"Foo\\\":-1,\\\"Bar\\\":\\\"\\\"}\": negative foo")
+
+	err = p.Publish(context.Background(), "hello17", dynconf.NewCodecValue("json", &config{Foo: 1}), validator)
+	assert.True(t, errors.Is(err, dynconf.ErrModifyIndexUnknown))
+
+	assert.NoError(t, p.PublishCAS(context.Background(), "hello17", dynconf.NewCodecValue("json", &config{Foo: 1}), 0, validator))
+	assert.NoError(t, p.Publish(context.Background(), "hello17", dynconf.NewCodecValue("json", &config{Foo: 2}), validator))
+
+	_, err = c.KV().Put(&api.KVPair{
+		Key:   "hello17",
+		Value: []byte(`{"Foo": 3}`),
+	}, &api.WriteOptions{})
+	assert.NoError(t, err)
+	err = p.Publish(context.Background(), "hello17", dynconf.NewCodecValue("json", &config{Foo: 4}), validator)
+	assert.True(t, errors.Is(err, dynconf.ErrPublishConflict))
+
+	err = p.Publish(context.Background(), "hello17", dynconf.NewCodecValue("json", &config{Foo: 4}), validator)
+	assert.True(t, errors.Is(err, dynconf.ErrModifyIndexUnknown))
+
+	kvPair, _, err := c.KV().Get("hello17", &api.QueryOptions{})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, `{"Foo": 3}`, string(kvPair.Value))
+	err = p.PublishCAS(context.Background(), "hello17", dynconf.NewCodecValue("json", &config{Foo: 4}), kvPair.ModifyIndex-1, validator)
+	assert.True(t, errors.Is(err, dynconf.ErrPublishConflict))
+	assert.NoError(t, p.PublishCAS(context.Background(), "hello17", dynconf.NewCodecValue("json", &config{Foo: 4}), kvPair.ModifyIndex, validator))
+
+	err = p.Publish(context.Background(), "hello17", new(config).Init())
+	assert.EqualError(t, err, "dynconf: publish failed; key=\"hello17\" type=*dynconf_test.config: value marshaler required")
+}
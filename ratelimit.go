@@ -0,0 +1,126 @@
+package dynconf
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+
+	"github.com/hashicorp/consul/api"
+	"golang.org/x/time/rate"
+)
+
+// SetQueryRateLimit limits the rate of queries, across all the watches added to
+// the watcher, to the given limit in queries per second with the given burst, so
+// that watches re-established simultaneously can not flood the backend, and then
+// returns the watcher. Queries exceeding the rate are queued until allowed, or
+// dropped if their contexts are done in the meantime, in which case they are
+// retried as failed. It should be called before adding watches.
+func (w *Watcher) SetQueryRateLimit(limit rate.Limit, burst int) *Watcher {
+	w.queryRateLimiter = &queryRateLimiter{
+		Limiter: rate.NewLimiter(limit, burst),
+	}
+
+	w.backend = w.limitQueryRate(w.backend)
+	return w
+}
+
+// QueryRateLimitStats returns the statistics of the query rate limit set by
+// SetQueryRateLimit.
+func (w *Watcher) QueryRateLimitStats() QueryRateLimitStats {
+	qrl := w.queryRateLimiter
+
+	if qrl == nil {
+		return QueryRateLimitStats{}
+	}
+
+	return QueryRateLimitStats{
+		NumberOfQueuedQueries:  atomic.LoadUint64(&qrl.numberOfQueuedQueries),
+		NumberOfDroppedQueries: atomic.LoadUint64(&qrl.numberOfDroppedQueries),
+	}
+}
+
+// QueryRateLimitStats represents the statistics of a query rate limit.
+type QueryRateLimitStats struct {
+	// NumberOfQueuedQueries is the number of queries which have been queued due to
+	// exceeding the rate.
+	NumberOfQueuedQueries uint64
+
+	// NumberOfDroppedQueries is the number of queued queries which have been dropped
+	// due to their contexts being done.
+	NumberOfDroppedQueries uint64
+}
+
+// limitQueryRate returns the given backend with its queries limited by the query
+// rate limit set by SetQueryRateLimit, if any, e.g. for the backends reading the
+// catalog of Consul rather than its KV store.
+func (w *Watcher) limitQueryRate(backend Backend) Backend {
+	if w.queryRateLimiter == nil {
+		return backend
+	}
+
+	return &rateLimitedBackend{
+		Backend:     backend,
+		RateLimiter: w.queryRateLimiter,
+	}
+}
+
+// waitForQueryRate waits until a query with the given options is allowed by the
+// query rate limit set by SetQueryRateLimit, if any, e.g. for the queries which
+// aren't made through backends.
+func (w *Watcher) waitForQueryRate(queryOptions *api.QueryOptions) error {
+	if w.queryRateLimiter == nil {
+		return nil
+	}
+
+	return w.queryRateLimiter.Wait(queryOptions)
+}
+
+type queryRateLimiter struct {
+	numberOfQueuedQueries  uint64 // accessed atomically, kept 64-bit aligned
+	numberOfDroppedQueries uint64 // accessed atomically, kept 64-bit aligned
+
+	Limiter *rate.Limiter
+}
+
+func (qrl *queryRateLimiter) Wait(queryOptions *api.QueryOptions) error {
+	if qrl.Limiter.Allow() {
+		return nil
+	}
+
+	atomic.AddUint64(&qrl.numberOfQueuedQueries, 1)
+	ctx := context.Background()
+
+	if queryOptions != nil {
+		ctx = queryOptions.Context()
+	}
+
+	if err := qrl.Limiter.Wait(ctx); err != nil {
+		atomic.AddUint64(&qrl.numberOfDroppedQueries, 1)
+		return err
+	}
+
+	return nil
+}
+
+type rateLimitedBackend struct {
+	Backend     Backend
+	RateLimiter *queryRateLimiter
+}
+
+var _ Backend = (*rateLimitedBackend)(nil)
+
+func (rlb *rateLimitedBackend) Get(key string, queryOptions *api.QueryOptions) (*api.KVPair, *api.QueryMeta, error) {
+	if err := rlb.RateLimiter.Wait(queryOptions); err != nil {
+		return nil, nil, fmt.Errorf("dynconf: query dropped; key=%q: %w", key, err)
+	}
+
+	return rlb.Backend.Get(key, queryOptions)
+}
+
+func (rlb *rateLimitedBackend) List(prefix string, queryOptions *api.QueryOptions) (api.KVPairs, *api.QueryMeta, error) {
+	if err := rlb.RateLimiter.Wait(queryOptions); err != nil {
+		return nil, nil, fmt.Errorf("dynconf: query dropped; prefix=%q: %w", prefix, err)
+	}
+
+	return rlb.Backend.List(prefix, queryOptions)
+}
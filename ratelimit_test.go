@@ -0,0 +1,58 @@
+package dynconf_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/time/rate"
+
+	"github.com/roy2220/dynconf"
+	"github.com/roy2220/dynconf/dynconftest"
+)
+
+func TestWatcherSetQueryRateLimit(t *testing.T) {
+	wr, b := dynconftest.NewWatcher(t)
+	wr.SetQueryRateLimit(rate.Every(10*time.Millisecond), 2)
+	startTime := time.Now()
+
+	for i := 0; i < 5; i++ {
+		key := fmt.Sprintf("hello%d", i)
+		b.Set(key, []byte("1"))
+		w, err := wr.AddWatch(context.Background(), key, dynconf.NewIntValue)
+		if assert.NoError(t, err) {
+			defer w.Remove()
+		}
+	}
+
+	assert.NoError(t, wr.WaitUntilSynced(context.Background()))
+	assert.True(t, time.Since(startTime) >= 30*time.Millisecond)
+	assert.True(t, wr.QueryRateLimitStats().NumberOfQueuedQueries >= 3)
+}
+
+func TestWatcherSetQueryRateLimitWithClient(t *testing.T) {
+	wr := new(dynconf.Watcher).Init(makeClient(t), makeLogger(t))
+	defer wr.Close(context.Background())
+	wr.SetQueryRateLimit(rate.Every(time.Hour), 1)
+
+	// the burst is taken by the first query, whether it succeeds or not
+	if w, err := wr.AddServiceWatch(context.Background(), "hello", ""); err == nil {
+		defer w.Remove()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err := wr.AddServiceWatch(ctx, "world", "")
+	assert.Error(t, err)
+	_, err = wr.AddConfigEntryWatch(ctx, api.ServiceDefaults, "hello",
+		dynconf.JSONValueFactory(func() interface{} { return new(api.ServiceConfigEntry) }))
+	assert.Error(t, err)
+	_, err = wr.AddIntentionsWatch(ctx)
+	assert.Error(t, err)
+	_, err = wr.AddUserEventWatch(ctx, "flush-caches", dynconf.NewStringValue)
+	assert.Error(t, err)
+	assert.True(t, wr.QueryRateLimitStats().NumberOfDroppedQueries >= 4)
+}
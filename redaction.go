@@ -0,0 +1,47 @@
+package dynconf
+
+// ValueRedactor represents an optional redactor to Value, for values holding
+// secrets.
+type ValueRedactor interface {
+	// RedactedString returns a string representing the value with secrets
+	// redacted, which is logged instead of the one returned by String. The data
+	// of values implementing ValueRedactor are never logged.
+	RedactedString() string
+}
+
+func isRedactor(valueFactory ValueFactory) (ok bool) {
+	defer func() {
+		if recover() != nil {
+			ok = false
+		}
+	}()
+
+	_, ok = valueFactory().(ValueRedactor)
+	return
+}
+
+func (w *Watch) redactData(data []byte) []byte {
+	if w.redacting {
+		return redactedData
+	}
+
+	return data
+}
+
+func (pw *PrefixWatch) redactData(data []byte) []byte {
+	if pw.redacting {
+		return redactedData
+	}
+
+	return data
+}
+
+func stringValue(value Value) string {
+	if redactor, ok := value.(ValueRedactor); ok {
+		return redactor.RedactedString()
+	}
+
+	return value.String()
+}
+
+var redactedData = []byte("<redacted>")
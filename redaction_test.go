@@ -0,0 +1,86 @@
+package dynconf_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/roy2220/dynconf"
+	"github.com/roy2220/dynconf/dynconftest"
+)
+
+func TestValueRedactor(t *testing.T) {
+	b := new(dynconftest.Backend).Init()
+	var l recordingLogger
+	wr := new(dynconf.Watcher).InitWithBackend(b, &l)
+	b.Set("password", []byte("secret1"))
+	w, err := wr.AddWatch(context.Background(), "password", newPasswordValue)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer w.Remove()
+
+	events := w.Events()
+	b.Set("password", []byte("bad secret2"))
+	<-events
+	b.Set("password", []byte("secret3"))
+	<-events
+
+	_, err = wr.AddWatch(context.Background(), "password", newPasswordValue,
+		dynconf.WithDataValidator(func([]byte) error { return fmt.Errorf("invalid") }))
+	assert.EqualError(t, err, "dynconf: data validation failed; key=\"password\" data=\"<redacted>\": invalid")
+
+	logs := l.String()
+	assert.Contains(t, logs, "dynconf_value_updated")
+	assert.Contains(t, logs, "dynconf_value_unmarshal_failed")
+	assert.NotContains(t, logs, "secret")
+}
+
+type passwordValue struct {
+	password string
+}
+
+func newPasswordValue() dynconf.Value { return new(passwordValue) }
+
+func (pv *passwordValue) Unmarshal(data []byte) error {
+	if strings.Contains(string(data), " ") {
+		return fmt.Errorf("invalid password")
+	}
+
+	pv.password = string(data)
+	return nil
+}
+
+func (pv *passwordValue) String() string         { return pv.password }
+func (pv *passwordValue) RedactedString() string { return "***" }
+
+type recordingLogger struct {
+	mu      sync.Mutex
+	builder strings.Builder
+}
+
+func (rl *recordingLogger) Log(level dynconf.LogLevel, message string, keysAndValues ...interface{}) {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	fmt.Fprintf(&rl.builder, "%v %s", level, message)
+
+	for _, keyOrValue := range keysAndValues {
+		if data, ok := keyOrValue.([]byte); ok {
+			keyOrValue = string(data)
+		}
+
+		fmt.Fprintf(&rl.builder, " %v", keyOrValue)
+	}
+
+	rl.builder.WriteByte('\n')
+}
+
+func (rl *recordingLogger) String() string {
+	rl.mu.Lock()
+	defer rl.mu.Unlock()
+	return rl.builder.String()
+}
@@ -0,0 +1,228 @@
+// Package redisbackend implements a backend reading keys from Redis.
+package redisbackend
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/hashicorp/consul/api"
+
+	"github.com/roy2220/dynconf"
+)
+
+// Backend presents a backend reading keys from Redis. Changes to keys are detected
+// with keyspace notifications, which requires the notify-keyspace-events option of
+// Redis to include "K$g", and with polling as a fallback.
+type Backend struct {
+	client        *redis.Client
+	pollInterval  time.Duration
+	channelPrefix string
+
+	mu             sync.Mutex
+	entries        map[string]keyEntry
+	lastIndex      uint64
+	pubSub         *redis.PubSub
+	subscribedKeys map[string]struct{}
+	waiters        map[string]map[chan struct{}]struct{}
+	isClosed       bool
+}
+
+var _ dynconf.Backend = (*Backend)(nil)
+
+// Init initializes the backend with the given client and the given interval for
+// polling, which defaults to 1m, and then returns the backend.
+func (b *Backend) Init(client *redis.Client, pollInterval time.Duration) *Backend {
+	if pollInterval <= 0 {
+		pollInterval = defaultPollInterval
+	}
+
+	b.client = client
+	b.pollInterval = pollInterval
+	b.channelPrefix = fmt.Sprintf("__keyspace@%d__:", client.Options().DB)
+	b.entries = make(map[string]keyEntry)
+	b.subscribedKeys = make(map[string]struct{})
+	b.waiters = make(map[string]map[chan struct{}]struct{})
+	return b
+}
+
+// Close closes the subscription to keyspace notifications.
+func (b *Backend) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.isClosed = true
+
+	if b.pubSub == nil {
+		return nil
+	}
+
+	return b.pubSub.Close()
+}
+
+// Get implements dynconf.Backend.Get.
+func (b *Backend) Get(key string, queryOptions *api.QueryOptions) (*api.KVPair, *api.QueryMeta, error) {
+	if queryOptions == nil {
+		queryOptions = new(api.QueryOptions)
+	}
+
+	ctx := queryOptions.Context()
+	var (
+		deadline time.Time
+		notified chan struct{}
+	)
+
+	if queryOptions.WaitIndex != 0 {
+		waitTime := queryOptions.WaitTime
+
+		if waitTime <= 0 {
+			waitTime = defaultWaitTime
+		}
+
+		deadline = time.Now().Add(waitTime)
+		notified = b.addWaiter(ctx, key)
+		defer b.removeWaiter(key, notified)
+	}
+
+	for {
+		data, err := b.client.Get(ctx, key).Bytes()
+		ok := true
+
+		if err != nil {
+			if err != redis.Nil {
+				return nil, nil, fmt.Errorf("redisbackend: get failed; key=%q: %w", key, err)
+			}
+
+			ok = false
+		}
+
+		index := b.updateEntry(key, data, ok)
+
+		if queryOptions.WaitIndex == 0 || index != queryOptions.WaitIndex || !time.Now().Before(deadline) {
+			queryMeta := api.QueryMeta{LastIndex: index}
+
+			if !ok {
+				return nil, &queryMeta, nil
+			}
+
+			return &api.KVPair{Key: key, Value: data, ModifyIndex: index}, &queryMeta, nil
+		}
+
+		pollInterval := b.pollInterval
+
+		if remainingWaitTime := time.Until(deadline); pollInterval > remainingWaitTime {
+			pollInterval = remainingWaitTime
+		}
+
+		timer := time.NewTimer(pollInterval)
+
+		select {
+		case <-notified:
+			timer.Stop()
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, nil, ctx.Err()
+		}
+	}
+}
+
+// List implements dynconf.Backend.List, it always returns dynconf.ErrListNotSupported.
+func (b *Backend) List(prefix string, queryOptions *api.QueryOptions) (api.KVPairs, *api.QueryMeta, error) {
+	return nil, nil, fmt.Errorf("%w; prefix=%q", dynconf.ErrListNotSupported, prefix)
+}
+
+func (b *Backend) updateEntry(key string, data []byte, ok bool) uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	entry, entryOK := b.entries[key]
+
+	if entryOK && entry.OK == ok && bytes.Equal(entry.Data, data) {
+		return entry.Index
+	}
+
+	b.lastIndex++
+	b.entries[key] = keyEntry{
+		Data:  data,
+		OK:    ok,
+		Index: b.lastIndex,
+	}
+
+	return b.lastIndex
+}
+
+func (b *Backend) addWaiter(ctx context.Context, key string) chan struct{} {
+	notified := make(chan struct{}, 1)
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	waiters, ok := b.waiters[key]
+
+	if !ok {
+		waiters = make(map[chan struct{}]struct{})
+		b.waiters[key] = waiters
+	}
+
+	waiters[notified] = struct{}{}
+
+	if b.isClosed {
+		return notified
+	}
+
+	if _, ok := b.subscribedKeys[key]; ok {
+		return notified
+	}
+
+	channel := b.channelPrefix + key
+
+	if b.pubSub == nil {
+		b.pubSub = b.client.Subscribe(ctx, channel)
+		go b.dispatchNotifications(b.pubSub.Channel())
+	} else if err := b.pubSub.Subscribe(ctx, channel); err != nil {
+		// fall back to polling
+		return notified
+	}
+
+	b.subscribedKeys[key] = struct{}{}
+	return notified
+}
+
+func (b *Backend) removeWaiter(key string, notified chan struct{}) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	waiters := b.waiters[key]
+	delete(waiters, notified)
+
+	if len(waiters) == 0 {
+		delete(b.waiters, key)
+	}
+}
+
+func (b *Backend) dispatchNotifications(messages <-chan *redis.Message) {
+	for message := range messages {
+		key := strings.TrimPrefix(message.Channel, b.channelPrefix)
+		b.mu.Lock()
+
+		for notified := range b.waiters[key] {
+			select {
+			case notified <- struct{}{}:
+			default:
+			}
+		}
+
+		b.mu.Unlock()
+	}
+}
+
+type keyEntry struct {
+	Data  []byte
+	OK    bool
+	Index uint64
+}
+
+const (
+	defaultPollInterval = time.Minute
+	defaultWaitTime     = 5 * time.Minute
+)
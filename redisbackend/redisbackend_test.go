@@ -0,0 +1,39 @@
+package redisbackend_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/go-redis/redis/v8"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/roy2220/dynconf"
+	"github.com/roy2220/dynconf/dynconftest"
+	"github.com/roy2220/dynconf/redisbackend"
+)
+
+func TestBackend(t *testing.T) {
+	client := redis.NewClient(&redis.Options{Addr: os.Getenv("TEST_REDIS_ADDRESS")})
+	defer client.Close()
+	ctx := context.Background()
+	assert.NoError(t, client.ConfigSet(ctx, "notify-keyspace-events", "K$g").Err())
+	assert.NoError(t, client.Set(ctx, "hello", "1", 0).Err())
+
+	b := new(redisbackend.Backend).Init(client, time.Hour)
+	defer b.Close()
+	wr := new(dynconf.Watcher).InitWithBackend(b, dynconftest.Logger{T: t})
+	w, err := wr.AddDynInt(ctx, "hello")
+	if !assert.NoError(t, err) {
+		return
+	}
+	defer w.Watch().Remove()
+	assert.Equal(t, int64(1), w.Get())
+
+	assert.NoError(t, client.Set(ctx, "hello", "2", 0).Err())
+	assert.Eventually(t, func() bool { return w.Get() == 2 }, 5*time.Second, 10*time.Millisecond)
+
+	assert.NoError(t, client.Del(ctx, "hello").Err())
+	assert.Eventually(t, func() bool { return !w.Watch().Exists() }, 5*time.Second, 10*time.Millisecond)
+}
@@ -0,0 +1,204 @@
+package dynconf
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sync"
+
+	"github.com/hashicorp/consul/api"
+)
+
+// WithReferences returns an option to resolve the references to other keys, in the
+// form of "${consul:other/key}", inside the data of the watch, e.g. the JSON value
+// {"endpoint": "${consul:shared/endpoint}"}, so that data shared by multiple keys
+// can be kept in one key. Each reference is replaced with the data of the referenced
+// key, escaped as the content of a JSON string, and the value is re-evaluated once
+// the key of the watch or any referenced key has been changed. References inside the
+// data of referenced keys are not resolved, and a missing referenced key fails the
+// query with ErrUnresolvedReference. It takes effect only for watches on keys.
+func WithReferences() WatchOption {
+	return func(wo *watchOptions) {
+		wo.References = true
+	}
+}
+
+func (wo *watchOptions) withReferences(backend Backend, referenceBackend Backend) Backend {
+	if !wo.References {
+		return backend
+	}
+
+	return &referenceResolvingBackend{
+		Backend:          backend,
+		ReferenceBackend: referenceBackend,
+	}
+}
+
+type referenceResolvingBackend struct {
+	Backend          Backend
+	ReferenceBackend Backend
+
+	mu        sync.Mutex
+	entry     *referenceEntry
+	lastIndex uint64
+}
+
+var _ Backend = (*referenceResolvingBackend)(nil)
+
+func (rrb *referenceResolvingBackend) Get(key string, queryOptions *api.QueryOptions) (*api.KVPair, *api.QueryMeta, error) {
+	if queryOptions == nil {
+		queryOptions = new(api.QueryOptions)
+	}
+
+	rrb.mu.Lock()
+	entry := rrb.entry
+	rrb.mu.Unlock()
+
+	if entry != nil && entry.Key == key && queryOptions.WaitIndex != 0 && queryOptions.WaitIndex == entry.Index {
+		changed, err := rrb.waitForChange(entry, queryOptions)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if !changed {
+			return entry.Result()
+		}
+	}
+
+	return rrb.refresh(key, queryOptions)
+}
+
+func (rrb *referenceResolvingBackend) List(prefix string, queryOptions *api.QueryOptions) (api.KVPairs, *api.QueryMeta, error) {
+	return nil, nil, fmt.Errorf("%w; prefix=%q", ErrListNotSupported, prefix)
+}
+
+func (rrb *referenceResolvingBackend) waitForChange(entry *referenceEntry, queryOptions *api.QueryOptions) (bool, error) {
+	queries := make([]query, 1+len(entry.ReferencedKeys))
+	queries[0] = func(queryOptions *api.QueryOptions) (*api.QueryMeta, error) {
+		_, queryMeta, err := rrb.Backend.Get(entry.Key, queryOptions)
+		return queryMeta, err
+	}
+
+	for i := range entry.ReferencedKeys {
+		referencedKey := entry.ReferencedKeys[i]
+		queries[1+i] = func(queryOptions *api.QueryOptions) (*api.QueryMeta, error) {
+			_, queryMeta, err := rrb.ReferenceBackend.Get(referencedKey, queryOptions)
+			return queryMeta, err
+		}
+	}
+
+	return waitForAnyChange(queries, entry.KeyIndexes, queryOptions)
+}
+
+func (rrb *referenceResolvingBackend) refresh(key string, queryOptions *api.QueryOptions) (*api.KVPair, *api.QueryMeta, error) {
+	keyQueryOptions := queryOptions.WithContext(queryOptions.Context())
+	keyQueryOptions.WaitIndex = 0
+	kvPair, queryMeta, err := rrb.Backend.Get(key, keyQueryOptions)
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	newEntry := referenceEntry{
+		Key:        key,
+		KeyIndexes: []uint64{queryMeta.LastIndex},
+		Exists:     kvPair != nil,
+	}
+
+	if kvPair != nil {
+		newEntry.ReferencedKeys = findReferences(kvPair.Value)
+		referencedDatas := make(map[string][]byte, len(newEntry.ReferencedKeys))
+
+		for _, referencedKey := range newEntry.ReferencedKeys {
+			referencedKVPair, queryMeta, err := rrb.ReferenceBackend.Get(referencedKey, keyQueryOptions)
+
+			if err != nil {
+				return nil, nil, err
+			}
+
+			if referencedKVPair == nil {
+				return nil, nil, fmt.Errorf("%w; key=%q referenced_key=%q", ErrUnresolvedReference, key, referencedKey)
+			}
+
+			newEntry.KeyIndexes = append(newEntry.KeyIndexes, queryMeta.LastIndex)
+			referencedDatas[referencedKey] = referencedKVPair.Value
+		}
+
+		newEntry.Data = resolveReferences(kvPair.Value, referencedDatas)
+	}
+
+	rrb.mu.Lock()
+	entry := rrb.entry
+
+	if entry == nil || entry.Key != key || !uint64sEqual(entry.KeyIndexes, newEntry.KeyIndexes) {
+		rrb.lastIndex++
+		newEntry.Index = rrb.lastIndex
+		entry = &newEntry
+		rrb.entry = entry
+	}
+
+	rrb.mu.Unlock()
+	return entry.Result()
+}
+
+type referenceEntry struct {
+	Key            string
+	ReferencedKeys []string
+	KeyIndexes     []uint64
+	Index          uint64
+	Exists         bool
+	Data           []byte
+}
+
+func (re *referenceEntry) Result() (*api.KVPair, *api.QueryMeta, error) {
+	queryMeta := api.QueryMeta{LastIndex: re.Index}
+
+	if !re.Exists {
+		return nil, &queryMeta, nil
+	}
+
+	return &api.KVPair{Key: re.Key, Value: re.Data, ModifyIndex: re.Index}, &queryMeta, nil
+}
+
+func findReferences(data []byte) []string {
+	var referencedKeys []string
+	seen := make(map[string]struct{})
+
+	for _, match := range referencePattern.FindAllSubmatch(data, -1) {
+		referencedKey := string(match[1])
+
+		if _, ok := seen[referencedKey]; ok {
+			continue
+		}
+
+		seen[referencedKey] = struct{}{}
+		referencedKeys = append(referencedKeys, referencedKey)
+	}
+
+	return referencedKeys
+}
+
+func resolveReferences(data []byte, referencedDatas map[string][]byte) []byte {
+	return referencePattern.ReplaceAllFunc(data, func(reference []byte) []byte {
+		referencedKey := string(referencePattern.FindSubmatch(reference)[1])
+		return escapeJSONString(referencedDatas[referencedKey])
+	})
+}
+
+func escapeJSONString(data []byte) []byte {
+	var buffer bytes.Buffer
+	encoder := json.NewEncoder(&buffer)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(string(data))
+	escapedData := bytes.TrimSuffix(buffer.Bytes(), []byte("\n"))
+	return escapedData[1 : len(escapedData)-1]
+}
+
+var referencePattern = regexp.MustCompile(`\$\{consul:([^}]+)\}`)
+
+// ErrUnresolvedReference is returned when a key referenced inside the data of a
+// watch is missing.
+var ErrUnresolvedReference = errors.New("dynconf: unresolved reference")
@@ -0,0 +1,46 @@
+package dynconf_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/roy2220/dynconf"
+	"github.com/roy2220/dynconf/dynconftest"
+)
+
+func TestWithReferences(t *testing.T) {
+	wr, b := dynconftest.NewWatcher(t)
+	b.Set("shared/endpoint", []byte(`http://a/"x"`))
+	b.Set("shared/port", []byte("80"))
+	b.Set("hello", []byte(`{"endpoint": "${consul:shared/endpoint}", "port": "${consul:shared/port}", `+
+		`"again": "${consul:shared/endpoint}"}`))
+
+	w, err := wr.AddWatch(context.Background(), "hello", dynconf.NewStringValue, dynconf.WithReferences())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer w.Remove()
+	assert.Equal(t, `{"endpoint": "http://a/\"x\"", "port": "80", "again": "http://a/\"x\""}`,
+		w.Value().(*dynconf.StringValue).Get())
+
+	events := w.Events()
+	b.Set("shared/endpoint", []byte("http://b"))
+	<-events
+	assert.Equal(t, `{"endpoint": "http://b", "port": "80", "again": "http://b"}`,
+		w.Value().(*dynconf.StringValue).Get())
+
+	b.Set("hello", []byte(`{"port": "${consul:shared/port}"}`))
+	<-events
+	assert.Equal(t, `{"port": "80"}`, w.Value().(*dynconf.StringValue).Get())
+
+	b.Set("shared/port", []byte("8080"))
+	<-events
+	assert.Equal(t, `{"port": "8080"}`, w.Value().(*dynconf.StringValue).Get())
+
+	b.Set("world", []byte(`{"endpoint": "${consul:shared/missing}"}`))
+	_, err = wr.AddWatch(context.Background(), "world", dynconf.NewStringValue, dynconf.WithReferences())
+	assert.True(t, errors.Is(err, dynconf.ErrUnresolvedReference))
+}
@@ -0,0 +1,175 @@
+package dynconf
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"sync/atomic"
+	"syscall"
+)
+
+// Refresh makes the watch re-read the key immediately, interrupting the outstanding
+// blocking query, and waits until the result has been applied as usual. Refresh
+// returns the error of reading the key or rejecting the data, if any, and fails
+// with ErrFrozen if the watch has been frozen.
+func (w *Watch) Refresh(ctx context.Context) error {
+	if w.IsFrozen() {
+		return fmt.Errorf("%w; key=%q", ErrFrozen, w.key)
+	}
+
+	result := w.requestRefresh()
+
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return fmt.Errorf("dynconf: refresh failed; key=%q: %w", w.key, ctx.Err())
+	case <-w.ctx.Done():
+		return fmt.Errorf("%w; key=%q", ErrWatchRemoved, w.key)
+	}
+}
+
+// RefreshAll makes all the watches added to the watcher re-read their keys
+// immediately, as Watch.Refresh does, and waits until the results have been applied.
+// RefreshAll returns the first error of the watches, if any. Prefix watches are not
+// affected.
+func (w *Watcher) RefreshAll(ctx context.Context) error {
+	var watches []*Watch
+
+	for _, watch := range w.watchLoops() {
+		if watch, ok := watch.(*Watch); ok {
+			watches = append(watches, watch)
+		}
+	}
+
+	errs := make(chan error, len(watches))
+	var wg sync.WaitGroup
+
+	for _, watch := range watches {
+		watch := watch
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+			errs <- watch.Refresh(ctx)
+		}()
+	}
+
+	wg.Wait()
+	close(errs)
+	var firstErr error
+	numberOfFailures := 0
+
+	for err := range errs {
+		if err == nil {
+			continue
+		}
+
+		if firstErr == nil {
+			firstErr = err
+		}
+
+		numberOfFailures++
+	}
+
+	if firstErr != nil {
+		return fmt.Errorf("dynconf: refresh all failed; number_of_failures=%d: %w", numberOfFailures, firstErr)
+	}
+
+	return nil
+}
+
+// RefreshOnSignals makes the watcher call RefreshAll each time any of the given
+// signals, which default to SIGHUP, has been received, so that sending SIGHUP to
+// the process forces the watches to re-read their keys, and then returns a function
+// to stop it.
+func (w *Watcher) RefreshOnSignals(signals ...os.Signal) (stop func()) {
+	if len(signals) == 0 {
+		signals = []os.Signal{syscall.SIGHUP}
+	}
+
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, signals...)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+
+	go func() {
+		defer close(done)
+
+		for {
+			select {
+			case sig := <-c:
+				w.logger.Log(LogInfo, "dynconf_refresh_signaled", "signal", sig.String())
+
+				if err := w.RefreshAll(ctx); err != nil {
+					w.logger.Log(LogWarn, "dynconf_refresh_failed", "error", err)
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return func() {
+		signal.Stop(c)
+		cancel()
+		<-done
+	}
+}
+
+func (w *Watch) requestRefresh() chan error {
+	result := make(chan error, 1)
+	w.refreshMu.Lock()
+	defer w.refreshMu.Unlock()
+	w.refreshResults = append(w.refreshResults, result)
+
+	if w.cancelQuery != nil {
+		w.cancelQuery()
+	}
+
+	return result
+}
+
+func (w *Watch) beginQuery() (context.Context, context.CancelFunc, []chan error) {
+	queryCtx, cancelQuery := context.WithCancel(w.ctx)
+	w.refreshMu.Lock()
+	refreshResults := w.refreshResults
+	w.refreshResults = nil
+	w.cancelQuery = cancelQuery
+	w.refreshMu.Unlock()
+	return queryCtx, cancelQuery, refreshResults
+}
+
+// resumeQuery puts back the given refreshes and pending updates taken by the query
+// interrupted, so that the next query handles them rather than drops them.
+func (w *Watch) resumeQuery(refreshResults []chan error, scheduled bool, ttlExpired bool) {
+	if scheduled {
+		atomic.StoreInt32(&w.scheduledUpdate, 1)
+	}
+
+	if ttlExpired {
+		atomic.StoreInt32(&w.ttlExpiry, 1)
+	}
+
+	w.refreshMu.Lock()
+	w.refreshResults = append(refreshResults, w.refreshResults...)
+	w.refreshMu.Unlock()
+}
+
+func (w *Watch) endRefreshes(refreshResults []chan error) {
+	var err error
+
+	if w.dataRejected {
+		err = w.LastError()
+	}
+
+	endRefreshes(refreshResults, err)
+}
+
+func endRefreshes(refreshResults []chan error, err error) {
+	for _, refreshResult := range refreshResults {
+		refreshResult <- err
+	}
+}
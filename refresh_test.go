@@ -0,0 +1,163 @@
+package dynconf_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"sync/atomic"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/roy2220/dynconf"
+	"github.com/roy2220/dynconf/dynconftest"
+)
+
+func TestWatchRefresh(t *testing.T) {
+	wr, b := dynconftest.NewWatcher(t)
+	b.Set("limit", []byte("1"))
+	w, err := wr.AddWatch(context.Background(), "limit", dynconf.NewIntValue,
+		dynconf.WithWaitTime(time.Hour),
+		dynconf.WithValidator(func(value dynconf.Value) error {
+			if value.(*dynconf.IntValue).Get() < 0 {
+				return errors.New("negative limit")
+			}
+
+			return nil
+		}))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.NoError(t, w.Refresh(ctx))
+
+	b.Set("limit", []byte("-1"))
+	assert.EqualError(t, w.Refresh(ctx), "dynconf: value validation failed; key=\"limit\": negative limit")
+	assert.EqualError(t, w.Refresh(ctx), "dynconf: value validation failed; key=\"limit\": negative limit")
+
+	b.Set("limit", []byte("2"))
+	assert.NoError(t, w.Refresh(ctx))
+	assert.Equal(t, int64(2), w.Value().(*dynconf.IntValue).Get())
+
+	w.Remove()
+	assert.True(t, errors.Is(w.Refresh(ctx), dynconf.ErrWatchRemoved))
+}
+
+func TestWatchConcurrentRefreshes(t *testing.T) {
+	b := &stallingBackend{Backend: new(dynconftest.Backend).Init(), Release: make(chan struct{})}
+	b.Set("limit", []byte("1"))
+	wr := dynconf.NewWatcherWithBackend(b, dynconf.WithLogger(dynconftest.Logger{T: t}))
+	w, err := wr.AddWatch(context.Background(), "limit", dynconf.NewIntValue, dynconf.WithWaitTime(time.Hour))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer w.Remove()
+
+	atomic.StoreInt32(&b.Stall, 1)
+	errs := make(chan error, 2)
+	go func() { errs <- w.Refresh(context.Background()) }()
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&b.NumberOfStalls) == 1 }, time.Second, time.Millisecond)
+
+	// the second refresh interrupts the query of the first one
+	go func() { errs <- w.Refresh(context.Background()) }()
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&b.NumberOfStalls) == 2 }, time.Second, time.Millisecond)
+
+	b.Set("limit", []byte("2"))
+	close(b.Release)
+
+	for i := 0; i < 2; i++ {
+		select {
+		case err := <-errs:
+			assert.NoError(t, err)
+		case <-time.After(time.Second):
+			t.Fatal("refresh not ended")
+		}
+	}
+	assert.Equal(t, int64(2), w.Value().(*dynconf.IntValue).Get())
+}
+
+// stallingBackend presents a backend which, once Stall has been set, stalls the
+// non-blocking queries until Release has been closed.
+type stallingBackend struct {
+	*dynconftest.Backend
+
+	Stall          int32
+	Release        chan struct{}
+	NumberOfStalls int32
+}
+
+func (sb *stallingBackend) Get(key string, queryOptions *api.QueryOptions) (*api.KVPair, *api.QueryMeta, error) {
+	if queryOptions.WaitIndex == 0 && atomic.LoadInt32(&sb.Stall) == 1 {
+		atomic.AddInt32(&sb.NumberOfStalls, 1)
+		ctx := queryOptions.Context()
+
+		select {
+		case <-sb.Release:
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		}
+	}
+
+	return sb.Backend.Get(key, queryOptions)
+}
+
+type countingBackend struct {
+	*dynconftest.Backend
+
+	NumberOfGets int32
+}
+
+func (cb *countingBackend) Get(key string, queryOptions *api.QueryOptions) (*api.KVPair, *api.QueryMeta, error) {
+	atomic.AddInt32(&cb.NumberOfGets, 1)
+	return cb.Backend.Get(key, queryOptions)
+}
+
+func TestWatcherRefreshAll(t *testing.T) {
+	clock := dynconftest.NewClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	b := &countingBackend{Backend: new(dynconftest.Backend).InitWithClock(clock)}
+	b.Set("limit", []byte("1"))
+	b.Set("limit2", []byte("2"))
+	wr := dynconf.NewWatcherWithBackend(b, dynconf.WithLogger(dynconftest.Logger{T: t}), dynconf.WithClock(clock))
+	defer wr.Close(context.Background())
+	validator := dynconf.WithValidator(func(value dynconf.Value) error {
+		if value.(*dynconf.IntValue).Get() < 0 {
+			return errors.New("negative limit")
+		}
+
+		return nil
+	})
+
+	for _, key := range []string{"limit", "limit2"} {
+		_, err := wr.AddWatch(context.Background(), key, dynconf.NewIntValue, dynconf.WithWaitTime(time.Hour), validator)
+		if !assert.NoError(t, err) {
+			t.FailNow()
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	assert.NoError(t, wr.RefreshAll(ctx))
+
+	b.Set("limit2", []byte("-2"))
+	err := wr.RefreshAll(ctx)
+	if assert.Error(t, err) {
+		assert.True(t, strings.HasPrefix(err.Error(), "dynconf: refresh all failed; number_of_failures=1: "))
+	}
+
+	stop := wr.RefreshOnSignals()
+	defer stop()
+	// wait for the blocking queries of both watches
+	clock.BlockUntil(2)
+	numberOfGets := atomic.LoadInt32(&b.NumberOfGets)
+	p, _ := os.FindProcess(os.Getpid())
+	assert.NoError(t, p.Signal(syscall.SIGHUP))
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(&b.NumberOfGets) >= numberOfGets+2
+	}, time.Second, 10*time.Millisecond)
+}
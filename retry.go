@@ -7,21 +7,163 @@ import (
 	"time"
 )
 
+// RetryPolicy represents a policy for retrying failed queries with backoffs, which
+// is one of ExponentialBackoff, DecorrelatedJitterBackoff, ConstantBackoff and
+// FibonacciBackoff, or a custom implementation.
+type RetryPolicy interface {
+	// Backoff returns the backoff before the retry following the given number of
+	// consecutive failed attempts, which is at least 1, with the given previous
+	// backoff, which is 0 before the first retry.
+	Backoff(numberOfAttempts int, previousBackoff time.Duration) time.Duration
+}
+
+// DefaultRetryPolicy is the retry policy used when none is given.
+var DefaultRetryPolicy RetryPolicy = ExponentialBackoff{
+	BackoffJitter: 0.5,
+}
+
+// ExponentialBackoff presents a retry policy with exponentially growing backoffs.
+type ExponentialBackoff struct {
+	// MinBackoff is the backoff before the first retry, defaults to 100ms.
+	MinBackoff time.Duration
+
+	// MaxBackoff is the upper limit of backoffs, defaults to 300s.
+	MaxBackoff time.Duration
+
+	// BackoffFactor is the factor by which backoffs grow, defaults to 2.0.
+	BackoffFactor float64
+
+	// BackoffJitter is the relative range, in [0.0, 1.0], by which backoffs
+	// are randomized.
+	BackoffJitter float64
+}
+
+var _ RetryPolicy = ExponentialBackoff{}
+
+// Backoff implements RetryPolicy.Backoff.
+func (eb ExponentialBackoff) Backoff(numberOfAttempts int, _ time.Duration) time.Duration {
+	minBackoff, maxBackoff := normalizeBackoffRange(eb.MinBackoff, eb.MaxBackoff)
+	backoffFactor := eb.BackoffFactor
+
+	if backoffFactor < 1.0 {
+		backoffFactor = 2.0
+	}
+
+	// The backoff isn't derived from the previous one, which is jittered.
+	backoff := minBackoff
+
+	for i := 1; i < numberOfAttempts && backoff < maxBackoff; i++ {
+		backoff = time.Duration(float64(backoff) * backoffFactor)
+	}
+
+	if backoff > maxBackoff {
+		backoff = maxBackoff
+	}
+
+	return jitter(backoff, eb.BackoffJitter)
+}
+
+// DecorrelatedJitterBackoff presents a retry policy with backoffs randomly picked
+// between the minimum backoff and three times the previous backoff, which spreads
+// retries of many clients better than jittered exponential backoffs.
+type DecorrelatedJitterBackoff struct {
+	// MinBackoff is the lower limit of backoffs, defaults to 100ms.
+	MinBackoff time.Duration
+
+	// MaxBackoff is the upper limit of backoffs, defaults to 300s.
+	MaxBackoff time.Duration
+}
+
+var _ RetryPolicy = DecorrelatedJitterBackoff{}
+
+// Backoff implements RetryPolicy.Backoff.
+func (djb DecorrelatedJitterBackoff) Backoff(_ int, previousBackoff time.Duration) time.Duration {
+	minBackoff, maxBackoff := normalizeBackoffRange(djb.MinBackoff, djb.MaxBackoff)
+
+	if previousBackoff < minBackoff {
+		previousBackoff = minBackoff
+	}
+
+	backoff := minBackoff + time.Duration(randFloat64()*float64(3*previousBackoff-minBackoff))
+
+	if backoff > maxBackoff {
+		backoff = maxBackoff
+	}
+
+	return backoff
+}
+
+// ConstantBackoff presents a retry policy with a constant backoff.
+type ConstantBackoff struct {
+	// Interval is the backoff before each retry, defaults to 1s.
+	Interval time.Duration
+
+	// BackoffJitter is the relative range, in [0.0, 1.0], by which backoffs
+	// are randomized.
+	BackoffJitter float64
+}
+
+var _ RetryPolicy = ConstantBackoff{}
+
+// Backoff implements RetryPolicy.Backoff.
+func (cb ConstantBackoff) Backoff(int, time.Duration) time.Duration {
+	backoff := cb.Interval
+
+	if backoff < 1 {
+		backoff = time.Second
+	}
+
+	return jitter(backoff, cb.BackoffJitter)
+}
+
+// FibonacciBackoff presents a retry policy with backoffs growing as the Fibonacci
+// sequence, i.e. 1, 1, 2, 3, 5, ... times the minimum backoff, which grow slower
+// than exponential backoffs.
+type FibonacciBackoff struct {
+	// MinBackoff is the backoff before the first retry, defaults to 100ms.
+	MinBackoff time.Duration
+
+	// MaxBackoff is the upper limit of backoffs, defaults to 300s.
+	MaxBackoff time.Duration
+
+	// BackoffJitter is the relative range, in [0.0, 1.0], by which backoffs
+	// are randomized.
+	BackoffJitter float64
+}
+
+var _ RetryPolicy = FibonacciBackoff{}
+
+// Backoff implements RetryPolicy.Backoff.
+func (fb FibonacciBackoff) Backoff(numberOfAttempts int, _ time.Duration) time.Duration {
+	minBackoff, maxBackoff := normalizeBackoffRange(fb.MinBackoff, fb.MaxBackoff)
+	backoff, nextBackoff := minBackoff, minBackoff
+
+	for i := 1; i < numberOfAttempts && backoff < maxBackoff; i++ {
+		backoff, nextBackoff = nextBackoff, backoff+nextBackoff
+	}
+
+	if backoff > maxBackoff {
+		backoff = maxBackoff
+	}
+
+	return jitter(backoff, fb.BackoffJitter)
+}
+
 type retry struct {
+	RetryPolicy         RetryPolicy
 	MaxNumberOfAttempts int
-	MinBackoff          time.Duration
-	MaxBackoff          time.Duration
-	BackoffFactor       float64
-	BackoffJitter       float64
-
-	normalizeOnce sync.Once
+	Clock               Clock
 }
 
 func (r *retry) Do(ctx context.Context, callback func() bool) (bool, error) {
-	r.normalize()
+	retryPolicy := r.RetryPolicy
+
+	if retryPolicy == nil {
+		retryPolicy = DefaultRetryPolicy
+	}
+
 	attemptCount := 0
 	backoff := time.Duration(0)
-	rand := rand.New(rand.NewSource(time.Now().UnixNano()))
 
 	for {
 		if callback() {
@@ -34,21 +176,11 @@ func (r *retry) Do(ctx context.Context, callback func() bool) (bool, error) {
 			return false, nil
 		}
 
-		if backoff == 0 {
-			backoff = r.MinBackoff
-		} else {
-			backoff = time.Duration(float64(backoff) * r.BackoffFactor)
-
-			if backoff > r.MaxBackoff {
-				backoff = r.MaxBackoff
-			}
-		}
-
-		p := (1.0 - r.BackoffJitter) + (2*r.BackoffJitter)*rand.Float64()
-		timer := time.NewTimer(time.Duration(float64(backoff) * p))
+		backoff = retryPolicy.Backoff(attemptCount, backoff)
+		timer := r.clock().NewTimer(backoff)
 
 		select {
-		case <-timer.C:
+		case <-timer.C():
 		case <-ctx.Done():
 			timer.Stop()
 			return false, ctx.Err()
@@ -56,30 +188,50 @@ func (r *retry) Do(ctx context.Context, callback func() bool) (bool, error) {
 	}
 }
 
-func (r *retry) normalize() {
-	r.normalizeOnce.Do(func() {
-		if r.MinBackoff < 1 {
-			r.MinBackoff = 100 * time.Millisecond
-		}
+func (r *retry) clock() Clock {
+	if r.Clock == nil {
+		return SystemClock
+	}
 
-		if r.MaxBackoff < 1 {
-			r.MaxBackoff = 300 * time.Second
-		}
+	return r.Clock
+}
 
-		if r.MaxBackoff < r.MinBackoff {
-			r.MaxBackoff = r.MinBackoff
-		}
+func normalizeBackoffRange(minBackoff time.Duration, maxBackoff time.Duration) (time.Duration, time.Duration) {
+	if minBackoff < 1 {
+		minBackoff = 100 * time.Millisecond
+	}
 
-		if r.BackoffFactor < 1.0 {
-			r.BackoffFactor = 2.0
-		}
+	if maxBackoff < 1 {
+		maxBackoff = 300 * time.Second
+	}
 
-		if r.BackoffJitter < 0.0 {
-			r.BackoffJitter = 0.0
-		}
+	if maxBackoff < minBackoff {
+		maxBackoff = minBackoff
+	}
 
-		if r.BackoffJitter > 1.0 {
-			r.BackoffJitter = 1.0
-		}
-	})
+	return minBackoff, maxBackoff
+}
+
+func jitter(backoff time.Duration, backoffJitter float64) time.Duration {
+	if backoffJitter < 0.0 {
+		backoffJitter = 0.0
+	}
+
+	if backoffJitter > 1.0 {
+		backoffJitter = 1.0
+	}
+
+	p := (1.0 - backoffJitter) + (2*backoffJitter)*randFloat64()
+	return time.Duration(float64(backoff) * p)
+}
+
+var (
+	randMu sync.Mutex
+	random = rand.New(rand.NewSource(time.Now().UnixNano()))
+)
+
+func randFloat64() float64 {
+	randMu.Lock()
+	defer randMu.Unlock()
+	return random.Float64()
 }
@@ -0,0 +1,58 @@
+package dynconf_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/roy2220/dynconf"
+)
+
+func TestRetryPolicies(t *testing.T) {
+	type testCase struct {
+		Name        string
+		RetryPolicy dynconf.RetryPolicy
+		Backoffs    []time.Duration
+	}
+	testCases := []testCase{
+		{
+			Name:        "exponential",
+			RetryPolicy: dynconf.ExponentialBackoff{MinBackoff: time.Second, MaxBackoff: 10 * time.Second},
+			Backoffs:    []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second},
+		},
+		{
+			Name:        "constant",
+			RetryPolicy: dynconf.ConstantBackoff{Interval: 3 * time.Second},
+			Backoffs:    []time.Duration{3 * time.Second, 3 * time.Second, 3 * time.Second},
+		},
+		{
+			Name:        "fibonacci",
+			RetryPolicy: dynconf.FibonacciBackoff{MinBackoff: time.Second, MaxBackoff: 6 * time.Second},
+			Backoffs:    []time.Duration{time.Second, time.Second, 2 * time.Second, 3 * time.Second, 5 * time.Second, 6 * time.Second},
+		},
+	}
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.Name, func(t *testing.T) {
+			backoff := time.Duration(0)
+			for i, expectedBackoff := range tc.Backoffs {
+				backoff = tc.RetryPolicy.Backoff(i+1, backoff)
+				assert.Equal(t, expectedBackoff, backoff, "attempt %d", i+1)
+			}
+		})
+	}
+}
+
+func TestDecorrelatedJitterBackoff(t *testing.T) {
+	rp := dynconf.DecorrelatedJitterBackoff{MinBackoff: time.Second, MaxBackoff: 10 * time.Second}
+	backoff := time.Duration(0)
+	for i := 1; i <= 100; i++ {
+		previousBackoff := backoff
+		backoff = rp.Backoff(i, previousBackoff)
+		assert.True(t, backoff >= time.Second && backoff <= 10*time.Second, backoff)
+		if previousBackoff >= time.Second {
+			assert.True(t, backoff <= 3*previousBackoff, backoff)
+		}
+	}
+}
@@ -0,0 +1,157 @@
+package dynconf
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+)
+
+// AddRolloutWatch adds a watch on the given key holding a rollout payload, which
+// is in the form of {"rollout_percent": N, "old": ..., "new": ...}, and then returns
+// the watch. The given instance, identified by e.g. the hostname, is bucketed
+// deterministically, and the new data are used if the bucket of the instance falls
+// in the rollout percentage, otherwise the old data are used. The value of the watch
+// is of type *RolloutValue, holding the value created with the given value factory.
+func (w *Watcher) AddRolloutWatch(ctx context.Context, key string, instanceID string, valueFactory ValueFactory,
+	options ...WatchOption) (*Watch, error) {
+	return w.AddWatch(ctx, key, func() Value {
+		return &RolloutValue{
+			key:          key,
+			instanceID:   instanceID,
+			valueFactory: valueFactory,
+		}
+	}, options...)
+}
+
+// RolloutValue presents a value holding either the old or the new value of a
+// rollout payload.
+type RolloutValue struct {
+	key          string
+	instanceID   string
+	valueFactory ValueFactory
+
+	value Value
+	isNew bool
+}
+
+var (
+	_ Value                       = (*RolloutValue)(nil)
+	_ ValueValidator              = (*RolloutValue)(nil)
+	_ ValueOutdatedCallback       = (*RolloutValue)(nil)
+	_ ValueDeletedCallback        = (*RolloutValue)(nil)
+	_ ValueUpdateRejectedCallback = (*RolloutValue)(nil)
+	_ ValueWatchRemovedCallback   = (*RolloutValue)(nil)
+)
+
+// Unmarshal implements Value.Unmarshal.
+func (rv *RolloutValue) Unmarshal(data []byte) error {
+	var payload struct {
+		RolloutPercent int             `json:"rollout_percent"`
+		Old            json.RawMessage `json:"old"`
+		New            json.RawMessage `json:"new"`
+	}
+
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return err
+	}
+
+	if payload.RolloutPercent < 0 || payload.RolloutPercent > 100 {
+		return fmt.Errorf("rollout_percent=%d: %w", payload.RolloutPercent, errInvalidRolloutPercent)
+	}
+
+	isNew := rolloutBucket(rv.key, rv.instanceID) < payload.RolloutPercent
+	selectedData := payload.Old
+
+	if isNew {
+		selectedData = payload.New
+	}
+
+	if selectedData == nil {
+		return errMissingRolloutData
+	}
+
+	value := rv.valueFactory()
+
+	if err := value.Unmarshal(selectedData); err != nil {
+		return err
+	}
+
+	rv.value = value
+	rv.isNew = isNew
+	return nil
+}
+
+// Validate implements ValueValidator.Validate, it validates the value held if
+// the value implements ValueValidator.
+func (rv *RolloutValue) Validate() error {
+	if validator, ok := rv.value.(ValueValidator); ok {
+		return validator.Validate()
+	}
+
+	return nil
+}
+
+// OnOutdated implements ValueOutdatedCallback.OnOutdated, it calls OnOutdated of
+// the value held if the value implements ValueOutdatedCallback.
+func (rv *RolloutValue) OnOutdated() {
+	if callback, ok := rv.value.(ValueOutdatedCallback); ok {
+		callback.OnOutdated()
+	}
+}
+
+// OnDeleted implements ValueDeletedCallback.OnDeleted, it calls OnDeleted of the
+// value held if the value implements ValueDeletedCallback.
+func (rv *RolloutValue) OnDeleted() {
+	if callback, ok := rv.value.(ValueDeletedCallback); ok {
+		callback.OnDeleted()
+	}
+}
+
+// OnUpdateRejected implements ValueUpdateRejectedCallback.OnUpdateRejected, it
+// calls OnUpdateRejected of the value held if the value implements
+// ValueUpdateRejectedCallback.
+func (rv *RolloutValue) OnUpdateRejected(err error, data []byte) {
+	if callback, ok := rv.value.(ValueUpdateRejectedCallback); ok {
+		callback.OnUpdateRejected(err, data)
+	}
+}
+
+// OnWatchRemoved implements ValueWatchRemovedCallback.OnWatchRemoved, it calls
+// OnWatchRemoved of the value held if the value implements ValueWatchRemovedCallback.
+func (rv *RolloutValue) OnWatchRemoved() {
+	if callback, ok := rv.value.(ValueWatchRemovedCallback); ok {
+		callback.OnWatchRemoved()
+	}
+}
+
+// String implements Value.String.
+func (rv *RolloutValue) String() string {
+	if rv.isNew {
+		return "new:" + rv.value.String()
+	}
+
+	return "old:" + rv.value.String()
+}
+
+// Value returns the value held.
+func (rv *RolloutValue) Value() Value { return rv.value }
+
+// IsNew returns whether the value held is the new one.
+func (rv *RolloutValue) IsNew() bool { return rv.isNew }
+
+// rolloutBucket deterministically buckets the given instance into one of 100
+// buckets for the given key.
+func rolloutBucket(key string, instanceID string) int {
+	hash := fnv.New32a()
+	hash.Write([]byte(key))
+	hash.Write([]byte{0})
+	hash.Write([]byte(instanceID))
+	return int(hash.Sum32() % 100)
+}
+
+var (
+	errInvalidRolloutPercent = errors.New("invalid rollout percent")
+	errMissingRolloutData    = errors.New("missing rollout data")
+)
@@ -0,0 +1,35 @@
+package dynconf_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/roy2220/dynconf"
+	"github.com/roy2220/dynconf/dynconftest"
+)
+
+func TestWatcherAddRolloutWatch(t *testing.T) {
+	wr, b := dynconftest.NewWatcher(t)
+	b.Set("limit", []byte(`{"rollout_percent": 0, "old": 100, "new": 200}`))
+	w, err := wr.AddRolloutWatch(context.Background(), "limit", "instance-1", dynconf.NewIntValue)
+	if assert.NoError(t, err) {
+		defer w.Remove()
+	}
+
+	v := w.Value().(*dynconf.RolloutValue)
+	assert.False(t, v.IsNew())
+	assert.Equal(t, int64(100), v.Value().(*dynconf.IntValue).Get())
+	assert.Equal(t, "old:100", v.String())
+
+	newValues := make(chan dynconf.Value, 2)
+	unsubscribe := w.Subscribe(func(_, newValue dynconf.Value) { newValues <- newValue })
+	defer unsubscribe()
+
+	b.Set("limit", []byte(`{"rollout_percent": 101, "old": 100, "new": 200}`))
+	b.Set("limit", []byte(`{"rollout_percent": 100, "old": 100, "new": 200}`))
+	v = (<-newValues).(*dynconf.RolloutValue)
+	assert.True(t, v.IsNew())
+	assert.Equal(t, int64(200), v.Value().(*dynconf.IntValue).Get())
+}
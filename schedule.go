@@ -0,0 +1,140 @@
+package dynconf
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"time"
+)
+
+// WithSchedule returns an option to schedule the activation of the JSON data of the
+// watch with the top-level fields "effective_at" and "expires_at", both optional
+// and in RFC 3339, so that changes can be staged in advance. Data not yet in effect
+// are applied at the effective time, until which the value is kept unchanged, and
+// once the data have expired, the value reverts to the default one set by
+// WithDefaultValue if any. If the data aren't in effect when the watch is added,
+// the watch starts with the default value, or fails with ErrNotInEffect if there's
+// none. Data with invalid timestamps are rejected. It takes effect only for watches
+// on keys.
+func WithSchedule() WatchOption {
+	return func(wo *watchOptions) {
+		wo.ScheduleEnabled = true
+	}
+}
+
+// checkSchedule returns whether the given data are in effect.
+func (w *Watch) checkSchedule(data []byte, flags uint64) (bool, error) {
+	data, err := w.options.decodeData(w.key, data, flags)
+
+	if err != nil {
+		return false, err
+	}
+
+	effectiveAt, expiresAt, err := parseSchedule(data)
+
+	if err != nil {
+		return false, fmt.Errorf("dynconf: schedule parse failed; key=%q: %w", w.key, err)
+	}
+
+	now := w.options.Clock.Now()
+	return !now.Before(effectiveAt) && (expiresAt.IsZero() || now.Before(expiresAt)), nil
+}
+
+// applySchedule schedules the given decoded data, and then returns whether the data
+// are in effect and should be applied.
+func (w *Watch) applySchedule(data []byte, index uint64, span Span) (bool, error) {
+	w.stopScheduleTimer()
+	effectiveAt, expiresAt, err := parseSchedule(data)
+
+	if err != nil {
+		return false, err
+	}
+
+	now := w.options.Clock.Now()
+
+	if now.Before(effectiveAt) {
+		w.dataRejected = false
+		w.recordSuccess()
+		w.logger.Log(LogInfo, "dynconf_update_scheduled", "key", w.key, "effective_at", effectiveAt.String())
+		w.startScheduleTimer(effectiveAt.Sub(now))
+		return false, nil
+	}
+
+	if expiresAt.IsZero() {
+		return true, nil
+	}
+
+	if now.Before(expiresAt) {
+		w.startScheduleTimer(expiresAt.Sub(now))
+		return true, nil
+	}
+
+	w.dataRejected = false
+	w.recordSuccess()
+	w.logger.Log(LogInfo, "dynconf_update_expired", "key", w.key, "expires_at", expiresAt.String())
+	w.revertToDefaultValue(index, span)
+	return false, nil
+}
+
+func (w *Watch) startScheduleTimer(delay time.Duration) {
+	w.scheduleTimer = w.options.Clock.AfterFunc(delay, func() {
+		atomic.StoreInt32(&w.scheduledUpdate, 1)
+		w.requestRefresh()
+	})
+}
+
+func (w *Watch) stopScheduleTimer() {
+	if w.scheduleTimer != nil {
+		w.scheduleTimer.Stop()
+		w.scheduleTimer = nil
+	}
+}
+
+// takeScheduledUpdate returns whether a scheduled time has come, since the last
+// call, so that the data should be applied again.
+func (w *Watch) takeScheduledUpdate() bool {
+	return atomic.SwapInt32(&w.scheduledUpdate, 0) == 1
+}
+
+func parseSchedule(data []byte) (effectiveAt time.Time, expiresAt time.Time, err error) {
+	object, ok := decodeJSONObject(data)
+
+	if !ok {
+		return time.Time{}, time.Time{}, nil
+	}
+
+	if effectiveAt, err = parseScheduleTime(object, effectiveAtFieldName); err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	if expiresAt, err = parseScheduleTime(object, expiresAtFieldName); err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+
+	return effectiveAt, expiresAt, nil
+}
+
+func parseScheduleTime(object map[string]interface{}, fieldName string) (time.Time, error) {
+	rawTime, ok := object[fieldName]
+
+	if !ok || rawTime == nil {
+		return time.Time{}, nil
+	}
+
+	if s, ok := rawTime.(string); ok {
+		if t, err := time.Parse(time.RFC3339, s); err == nil {
+			return t, nil
+		}
+	}
+
+	return time.Time{}, fmt.Errorf("dynconf: invalid time; %s=%v", fieldName, rawTime)
+}
+
+const (
+	effectiveAtFieldName = "effective_at"
+	expiresAtFieldName   = "expires_at"
+)
+
+// ErrNotInEffect is returned when a watch with WithSchedule but without a default
+// value is added while the data of the key aren't in effect.
+var ErrNotInEffect = errors.New("dynconf: not in effect")
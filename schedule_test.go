@@ -0,0 +1,59 @@
+package dynconf_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/roy2220/dynconf"
+	"github.com/roy2220/dynconf/dynconftest"
+)
+
+func TestWithSchedule(t *testing.T) {
+	type config struct {
+		Level string `json:"level"`
+	}
+
+	level := func(w *dynconf.Watch) string {
+		return w.Value().(*dynconf.ObjectValue).Object().(*config).Level
+	}
+	valueFactory := dynconf.JSONValueFactory(func() interface{} { return new(config) })
+	clock := dynconftest.NewClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	data := []byte(fmt.Sprintf(`{"level": "debug", "effective_at": %q, "expires_at": %q}`,
+		clock.Now().Add(time.Hour).Format(time.RFC3339), clock.Now().Add(2*time.Hour).Format(time.RFC3339)))
+
+	wr, b := dynconftest.NewWatcherWithClock(t, clock)
+	b.Set("hello", data)
+
+	_, err := wr.AddWatch(context.Background(), "hello", valueFactory, dynconf.WithSchedule())
+	assert.True(t, errors.Is(err, dynconf.ErrNotInEffect))
+
+	w, err := wr.AddWatch(context.Background(), "hello", valueFactory, dynconf.WithSchedule(),
+		dynconf.WithDefaultValue([]byte(`{"level": "info"}`)))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer w.Remove()
+
+	events := w.Events()
+	assert.Equal(t, "info", level(w))
+
+	clock.Advance(59 * time.Minute)
+	assert.Equal(t, "info", level(w))
+
+	clock.Advance(time.Minute)
+	<-events
+	assert.Equal(t, "debug", level(w))
+
+	clock.Advance(time.Hour)
+	<-events
+	assert.Equal(t, "info", level(w))
+
+	b.Set("hello", []byte(`{"level": "warn", "effective_at": "tomorrow"}`))
+	assert.Error(t, w.Refresh(context.Background()))
+	assert.Equal(t, "info", level(w))
+}
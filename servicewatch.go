@@ -0,0 +1,166 @@
+package dynconf
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/consul/api"
+)
+
+// AddServiceWatch adds a watch on the healthy instances of the given service with
+// the given tag, which may be empty, in the service catalog of Consul, and then
+// returns the watch. The instances are read with blocking queries on the health
+// endpoint, and the value of the watch is of type *ServiceValue, the watch is set
+// on the key "service:<service>" or "service:<service>:<tag>". The watcher must be
+// initialized with a client.
+func (w *Watcher) AddServiceWatch(ctx context.Context, service string, tag string,
+	options ...WatchOption) (*ServiceWatch, error) {
+	if w.client == nil {
+		return nil, fmt.Errorf("dynconf: service watch failed; service=%q: %w", service, errClientRequired)
+	}
+
+	backend := serviceBackend{
+		Health:  w.client.Health(),
+		Service: service,
+		Tag:     tag,
+	}
+	key := "service:" + service
+
+	if tag != "" {
+		key += ":" + tag
+	}
+
+	watch, err := w.doAddWatch(ctx, w.limitQueryRate(&backend), key, NewServiceValue, options)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &ServiceWatch{watch}, nil
+}
+
+// ServiceWatch presents a watch on the healthy instances of a service.
+type ServiceWatch struct {
+	*Watch
+}
+
+// Endpoints returns the endpoints of the latest healthy instances. The returned
+// slice is read-only.
+func (sw *ServiceWatch) Endpoints() []ServiceEndpoint {
+	return sw.Value().(*ServiceValue).Get()
+}
+
+// ServiceEndpoint represents an endpoint of an instance of a service.
+type ServiceEndpoint struct {
+	ID      string            `json:"id"`
+	Node    string            `json:"node"`
+	Address string            `json:"address"`
+	Port    int               `json:"port"`
+	Tags    []string          `json:"tags,omitempty"`
+	Meta    map[string]string `json:"meta,omitempty"`
+}
+
+// HostPort returns the address and the port of the endpoint joined by ":".
+func (se *ServiceEndpoint) HostPort() string {
+	return net.JoinHostPort(se.Address, strconv.Itoa(se.Port))
+}
+
+// NewServiceValue returns a new value of type *ServiceValue, it is a ValueFactory.
+func NewServiceValue() Value {
+	return new(ServiceValue)
+}
+
+// ServiceValue presents a value holding the endpoints of the instances of a service,
+// which are in JSON.
+type ServiceValue struct {
+	endpoints []ServiceEndpoint
+}
+
+var _ Value = (*ServiceValue)(nil)
+
+// Unmarshal implements Value.Unmarshal.
+func (sv *ServiceValue) Unmarshal(data []byte) error {
+	var endpoints []ServiceEndpoint
+
+	if err := json.Unmarshal(data, &endpoints); err != nil {
+		return err
+	}
+
+	sv.endpoints = endpoints
+	return nil
+}
+
+// String implements Value.String.
+func (sv *ServiceValue) String() string {
+	hostPorts := make([]string, len(sv.endpoints))
+
+	for i := range sv.endpoints {
+		hostPorts[i] = sv.endpoints[i].HostPort()
+	}
+
+	return strings.Join(hostPorts, ",")
+}
+
+// Get returns the endpoints held. The returned slice is read-only.
+func (sv *ServiceValue) Get() []ServiceEndpoint {
+	return sv.endpoints
+}
+
+type serviceBackend struct {
+	Health  *api.Health
+	Service string
+	Tag     string
+}
+
+var _ Backend = (*serviceBackend)(nil)
+
+func (sb *serviceBackend) Get(key string, queryOptions *api.QueryOptions) (*api.KVPair, *api.QueryMeta, error) {
+	serviceEntries, queryMeta, err := sb.Health.Service(sb.Service, sb.Tag, true, queryOptions)
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("dynconf: health service failed; service=%q tag=%q: %w", sb.Service, sb.Tag, err)
+	}
+
+	endpoints := make([]ServiceEndpoint, len(serviceEntries))
+
+	for i, serviceEntry := range serviceEntries {
+		endpoints[i] = ServiceEndpoint{
+			ID:      serviceEntry.Service.ID,
+			Node:    serviceEntry.Node.Node,
+			Address: serviceEntry.Service.Address,
+			Port:    serviceEntry.Service.Port,
+			Tags:    serviceEntry.Service.Tags,
+			Meta:    serviceEntry.Service.Meta,
+		}
+
+		if endpoints[i].Address == "" {
+			endpoints[i].Address = serviceEntry.Node.Address
+		}
+	}
+
+	// Sort the endpoints so that the data remain unchanged if the instances do.
+	sort.Slice(endpoints, func(i, j int) bool {
+		if endpoints[i].Node != endpoints[j].Node {
+			return endpoints[i].Node < endpoints[j].Node
+		}
+
+		return endpoints[i].ID < endpoints[j].ID
+	})
+
+	data, err := json.Marshal(endpoints)
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("dynconf: endpoints marshal failed; service=%q: %w", sb.Service, err)
+	}
+
+	return &api.KVPair{Key: key, Value: data, ModifyIndex: queryMeta.LastIndex}, queryMeta, nil
+}
+
+func (sb *serviceBackend) List(prefix string, _ *api.QueryOptions) (api.KVPairs, *api.QueryMeta, error) {
+	return nil, nil, fmt.Errorf("%w; prefix=%q", ErrListNotSupported, prefix)
+}
@@ -0,0 +1,53 @@
+package dynconf_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/roy2220/dynconf"
+)
+
+func TestWatcherAddServiceWatch(t *testing.T) {
+	wr, c := makeWatcher(t)
+	err := c.Agent().ServiceRegister(&api.AgentServiceRegistration{
+		ID:      "hello-1",
+		Name:    "hello",
+		Tags:    []string{"v1"},
+		Address: "10.0.0.1",
+		Port:    8080,
+	})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer c.Agent().ServiceDeregister("hello-1")
+
+	w, err := wr.AddServiceWatch(context.Background(), "hello", "v1")
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer w.Remove()
+
+	endpoints := w.Endpoints()
+	if assert.Len(t, endpoints, 1) {
+		assert.Equal(t, "10.0.0.1:8080", endpoints[0].HostPort())
+	}
+
+	newValues := make(chan dynconf.Value, 1)
+	unsubscribe := w.Subscribe(func(_, newValue dynconf.Value) { newValues <- newValue })
+	defer unsubscribe()
+
+	assert.NoError(t, c.Agent().ServiceDeregister("hello-1"))
+	assert.Len(t, (<-newValues).(*dynconf.ServiceValue).Get(), 0)
+}
+
+func TestServiceValue(t *testing.T) {
+	v := dynconf.NewServiceValue()
+	err := v.Unmarshal([]byte(`[{"id": "a", "address": "10.0.0.1", "port": 80}, {"id": "b", "address": "::1", "port": 81}]`))
+	if assert.NoError(t, err) {
+		assert.Len(t, v.(*dynconf.ServiceValue).Get(), 2)
+		assert.Equal(t, "10.0.0.1:80,[::1]:81", v.String())
+	}
+}
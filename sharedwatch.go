@@ -0,0 +1,137 @@
+package dynconf
+
+import (
+	"context"
+	"sync"
+)
+
+// AddSharedWatch adds a watch on the given key, which is shared by the callers
+// adding shared watches on the same key with the same share ID, and then returns
+// a handle to the watch. The share ID identifies the value factory, which can't
+// be told apart from others reliably, e.g. closures of the same function literal,
+// so callers must give the same share ID only along with the same value factory.
+// The value factory and the options given by the first caller take effect. The
+// watch is removed once all the handles have been removed.
+func (w *Watcher) AddSharedWatch(ctx context.Context, key string, shareID string, valueFactory ValueFactory,
+	options ...WatchOption) (*SharedWatch, error) {
+	var watchOptions watchOptions
+	watchOptions.Init(w.watchOptions(options))
+	entryKey := sharedWatchKey{
+		Namespace: watchOptions.Namespace,
+		Key:       key,
+		ShareID:   shareID,
+	}
+
+	for {
+		w.sharedWatchesMu.Lock()
+		entry, ok := w.sharedWatches[entryKey]
+
+		if ok {
+			if entry.watch != nil {
+				entry.refCount++
+				w.sharedWatchesMu.Unlock()
+				return &SharedWatch{Watch: entry.watch, sharedWatch: entry}, nil
+			}
+
+			w.sharedWatchesMu.Unlock()
+
+			// Wait for the watch being added by another caller.
+			select {
+			case <-entry.added:
+				continue
+			case <-ctx.Done():
+				return nil, ctx.Err()
+			}
+		}
+
+		if w.sharedWatches == nil {
+			w.sharedWatches = make(map[sharedWatchKey]*sharedWatch)
+		}
+
+		entry = &sharedWatch{
+			watcher: w,
+			key:     entryKey,
+			added:   make(chan struct{}),
+		}
+
+		w.sharedWatches[entryKey] = entry
+		w.sharedWatchesMu.Unlock()
+		watch, err := w.AddWatch(ctx, key, valueFactory, options...)
+		w.sharedWatchesMu.Lock()
+		close(entry.added)
+
+		if err != nil {
+			delete(w.sharedWatches, entryKey)
+			w.sharedWatchesMu.Unlock()
+			return nil, err
+		}
+
+		entry.watch = watch
+		entry.refCount = 1
+		w.sharedWatchesMu.Unlock()
+		return &SharedWatch{Watch: watch, sharedWatch: entry}, nil
+	}
+}
+
+// SharedWatch presents a handle to a watch shared by multiple callers.
+type SharedWatch struct {
+	*Watch
+
+	sharedWatch *sharedWatch
+	removeOnce  sync.Once
+}
+
+// Remove removes the handle, and the watch as well if it is the last handle to
+// the watch. Remove is idempotent.
+func (sw *SharedWatch) Remove() {
+	sw.removeOnce.Do(func() {
+		if sw.sharedWatch.release() {
+			sw.Watch.Remove()
+		}
+	})
+}
+
+// RemoveContext removes the handle, and the watch as well if it is the last handle
+// to the watch, waiting for the watch to stop until the given context is done.
+// RemoveContext is idempotent.
+func (sw *SharedWatch) RemoveContext(ctx context.Context) error {
+	var err error
+
+	sw.removeOnce.Do(func() {
+		if sw.sharedWatch.release() {
+			err = sw.Watch.RemoveContext(ctx)
+		}
+	})
+
+	return err
+}
+
+type sharedWatchKey struct {
+	Namespace string
+	Key       string
+	ShareID   string
+}
+
+type sharedWatch struct {
+	watcher  *Watcher
+	key      sharedWatchKey
+	added    chan struct{}
+	watch    *Watch
+	refCount int
+}
+
+// release releases a reference to the watch, and then returns whether the watch
+// should be removed.
+func (sw *sharedWatch) release() bool {
+	w := sw.watcher
+	w.sharedWatchesMu.Lock()
+	defer w.sharedWatchesMu.Unlock()
+	sw.refCount--
+
+	if sw.refCount >= 1 {
+		return false
+	}
+
+	delete(w.sharedWatches, sw.key)
+	return true
+}
@@ -0,0 +1,75 @@
+package dynconf_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/roy2220/dynconf"
+	"github.com/roy2220/dynconf/dynconftest"
+)
+
+func TestWatcherAddSharedWatch(t *testing.T) {
+	wr, b := dynconftest.NewWatcher(t)
+	b.Set("hello", []byte("1"))
+	sw1, err := wr.AddSharedWatch(context.Background(), "hello", "int", dynconf.NewIntValue)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	sw2, err := wr.AddSharedWatch(context.Background(), "hello", "int", dynconf.NewIntValue)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	sw3, err := wr.AddSharedWatch(context.Background(), "hello", "float", dynconf.NewFloatValue)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer sw3.Remove()
+
+	assert.Same(t, sw1.Watch, sw2.Watch)
+	assert.NotSame(t, sw1.Watch, sw3.Watch)
+	events := sw1.Events()
+
+	sw1.Remove()
+	sw1.Remove()
+	b.Set("hello", []byte("2"))
+	assert.IsType(t, (*dynconf.UpdatedEvent)(nil), <-events)
+	assert.Equal(t, int64(2), sw2.Value().(*dynconf.IntValue).Get())
+
+	sw2.Remove()
+	assert.IsType(t, (*dynconf.WatchRemovedEvent)(nil), <-events)
+
+	sw4, err := wr.AddSharedWatch(context.Background(), "hello", "int", dynconf.NewIntValue)
+	if assert.NoError(t, err) {
+		defer sw4.Remove()
+	}
+
+	assert.NotSame(t, sw2.Watch, sw4.Watch)
+}
+
+func TestWatcherAddSharedWatchWithClosures(t *testing.T) {
+	type a struct{ Foo int }
+	type b struct{ Foo int }
+	wr, be := dynconftest.NewWatcher(t)
+	be.Set("hello", []byte(`{"Foo": 1}`))
+	swA, err := wr.AddSharedWatch(context.Background(), "hello", "a",
+		dynconf.JSONValueFactory(func() interface{} { return new(a) }))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer swA.Remove()
+
+	swB, err := wr.AddSharedWatch(context.Background(), "hello", "b",
+		dynconf.JSONValueFactory(func() interface{} { return new(b) }))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer swB.Remove()
+
+	assert.NotSame(t, swA.Watch, swB.Watch)
+	assert.Equal(t, &a{Foo: 1}, swA.ObjectValue())
+	assert.Equal(t, &b{Foo: 1}, swB.ObjectValue())
+}
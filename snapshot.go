@@ -0,0 +1,140 @@
+package dynconf
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"time"
+)
+
+// RawValue represents the raw data of a key watched.
+type RawValue struct {
+	// Data is the raw data of the key, before decryption and decompression.
+	Data []byte `json:"data"`
+
+	// ModifyIndex is the modify index of the key.
+	ModifyIndex uint64 `json:"modify_index"`
+
+	// Flags is the flags of the key, which is always 0 for keys watched by prefix
+	// watches.
+	Flags uint64 `json:"flags,omitempty"`
+
+	// UpdateTime is the time when the raw data were taken by the watch.
+	UpdateTime time.Time `json:"update_time"`
+}
+
+// Snapshot returns the raw data of every existing key watched by the watches and
+// the prefix watches of the watcher, keyed by the keys, e.g. for attaching to crash
+// reports. The raw data of each key are consistent with its modify index. Keys
+// missing, i.e. watches with default values, are left out, and so are the keys
+// watched by redacting watches, i.e. the ones with values implementing
+// ValueRedactor or with decrypters, so that secrets never leak into snapshots.
+func (w *Watcher) Snapshot() map[string]RawValue {
+	snapshot := make(map[string]RawValue)
+
+	for _, watch := range w.watchLoops() {
+		switch watch := watch.(type) {
+		case *Watch:
+			if !watch.Exists() || watch.redacting {
+				continue
+			}
+
+			raw := watch.raw.Load().(rawData)
+			snapshot[watch.Key()] = RawValue{
+				Data:        raw.Data,
+				ModifyIndex: raw.ModifyIndex,
+				Flags:       raw.Flags,
+				UpdateTime:  watch.LastUpdateTime(),
+			}
+		case *PrefixWatch:
+			if watch.redacting {
+				continue
+			}
+
+			for key, rawValue := range watch.loadRawValues() {
+				snapshot[key] = rawValue
+			}
+		}
+	}
+
+	return snapshot
+}
+
+// ExportJSON writes the snapshot of the watcher, returned by Snapshot, in JSON to
+// the given writer, e.g. for attaching to support bundles. The snapshot can be
+// loaded with LoadSnapshot.
+func (w *Watcher) ExportJSON(writer io.Writer) error {
+	encoder := json.NewEncoder(writer)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(snapshotFile{
+		Time:   w.clock().Now(),
+		Values: w.Snapshot(),
+	})
+}
+
+// redactedKeyMatcher returns a function reporting whether a given key is watched
+// by any redacting watch of the watcher.
+func (w *Watcher) redactedKeyMatcher() func(key string) bool {
+	keys := make(map[string]struct{})
+	var prefixes []string
+
+	for _, watch := range w.watchLoops() {
+		switch watch := watch.(type) {
+		case *Watch:
+			if watch.redacting {
+				keys[watch.Key()] = struct{}{}
+			}
+		case *PrefixWatch:
+			if watch.redacting {
+				prefixes = append(prefixes, watch.Prefix())
+			}
+		}
+	}
+
+	return func(key string) bool {
+		if _, ok := keys[key]; ok {
+			return true
+		}
+
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(key, prefix) {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+type snapshotFile struct {
+	Time   time.Time           `json:"time"`
+	Values map[string]RawValue `json:"values"`
+}
+
+func (pw *PrefixWatch) setRawValues() {
+	oldRawValues := pw.loadRawValues()
+	newRawValues := make(map[string]RawValue, len(pw.rawData))
+	now := pw.options.Clock.Now()
+
+	for key, data := range pw.rawData {
+		// the raw data of rejected updates are not taken
+		if oldRawValue, ok := oldRawValues[key]; ok && bytes.Equal(oldRawValue.Data, data) {
+			newRawValues[key] = oldRawValue
+			continue
+		}
+
+		newRawValues[key] = RawValue{
+			Data:        data,
+			ModifyIndex: pw.dataIndexes[key],
+			UpdateTime:  now,
+		}
+	}
+
+	pw.rawValues.Store(newRawValues)
+}
+
+func (pw *PrefixWatch) loadRawValues() map[string]RawValue {
+	rawValues, _ := pw.rawValues.Load().(map[string]RawValue)
+	return rawValues
+}
@@ -0,0 +1,139 @@
+package dynconf_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/roy2220/dynconf"
+	"github.com/roy2220/dynconf/dynconftest"
+)
+
+func TestWatcherSnapshot(t *testing.T) {
+	wr, b := dynconftest.NewWatcher(t)
+	b.Set("hello", []byte("1"))
+	b.Set("dir/a", []byte("2"))
+	b.Set("dir/b", []byte("3"))
+
+	w, err := wr.AddWatch(context.Background(), "hello", dynconf.NewIntValue)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer w.Remove()
+	w2, err := wr.AddWatch(context.Background(), "world", dynconf.NewIntValue, dynconf.WithDefaultValue([]byte("0")))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer w2.Remove()
+	pw, err := wr.AddPrefixWatch(context.Background(), "dir/", dynconf.NewIntValue, dynconf.PrefixWatchCallbacks{})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer pw.Remove()
+
+	snapshot := wr.Snapshot()
+	if assert.Len(t, snapshot, 3) {
+		assert.Equal(t, []byte("1"), snapshot["hello"].Data)
+		assert.Equal(t, w.ModifyIndex(), snapshot["hello"].ModifyIndex)
+		assert.Equal(t, w.LastUpdateTime(), snapshot["hello"].UpdateTime)
+		assert.Equal(t, []byte("2"), snapshot["dir/a"].Data)
+		assert.Equal(t, []byte("3"), snapshot["dir/b"].Data)
+		assert.False(t, snapshot["dir/b"].UpdateTime.IsZero())
+	}
+
+	var buffer bytes.Buffer
+	assert.NoError(t, wr.ExportJSON(&buffer))
+	var file struct {
+		Values map[string]dynconf.RawValue `json:"values"`
+	}
+	assert.NoError(t, json.Unmarshal(buffer.Bytes(), &file))
+	assert.Equal(t, snapshot["hello"].Data, file.Values["hello"].Data)
+	assert.Equal(t, snapshot["dir/a"].ModifyIndex, file.Values["dir/a"].ModifyIndex)
+}
+
+func TestWatcherSnapshotRedaction(t *testing.T) {
+	wr, b := dynconftest.NewWatcher(t)
+	defer wr.Close(context.Background())
+	b.Set("hello", []byte("1"))
+	b.Set("password", []byte("secret1"))
+	b.Set("passwords/a", []byte("secret2"))
+
+	_, err := wr.AddWatch(context.Background(), "hello", dynconf.NewIntValue)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	_, err = wr.AddWatch(context.Background(), "password", newPasswordValue)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	_, err = wr.AddPrefixWatch(context.Background(), "passwords/", newPasswordValue, dynconf.PrefixWatchCallbacks{})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	snapshot := wr.Snapshot()
+	if assert.Len(t, snapshot, 1) {
+		assert.Equal(t, []byte("1"), snapshot["hello"].Data)
+	}
+
+	var buffer bytes.Buffer
+	assert.NoError(t, wr.ExportJSON(&buffer))
+	assert.NotContains(t, buffer.String(), "password")
+}
+
+func TestSnapshotBackend(t *testing.T) {
+	wr, b := dynconftest.NewWatcher(t)
+	b.Set("hello", []byte("1"))
+	b.Set("dir/a", []byte("2"))
+
+	w, err := wr.AddWatch(context.Background(), "hello", dynconf.NewIntValue)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	pw, err := wr.AddPrefixWatch(context.Background(), "dir/", dynconf.NewIntValue, dynconf.PrefixWatchCallbacks{})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	var buffer bytes.Buffer
+	assert.NoError(t, wr.ExportJSON(&buffer))
+	w.Remove()
+	pw.Remove()
+
+	snapshot, err := dynconf.LoadSnapshot(&buffer)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	wr2 := dynconf.NewWatcherWithBackend(new(dynconf.SnapshotBackend).Init(snapshot),
+		dynconf.WithLogger(dynconftest.Logger{T: t}))
+	defer wr2.Close(context.Background())
+
+	w, err = wr2.AddWatch(context.Background(), "hello", dynconf.NewIntValue)
+	if assert.NoError(t, err) {
+		assert.Equal(t, int64(1), w.Value().(*dynconf.IntValue).Get())
+		w.Remove()
+	}
+	pw, err = wr2.AddPrefixWatch(context.Background(), "dir/", dynconf.NewIntValue, dynconf.PrefixWatchCallbacks{})
+	if assert.NoError(t, err) {
+		if v, ok := pw.Value("dir/a"); assert.True(t, ok) {
+			assert.Equal(t, int64(2), v.(*dynconf.IntValue).Get())
+		}
+		pw.Remove()
+	}
+	_, err = wr2.AddWatch(context.Background(), "world", dynconf.NewIntValue)
+	assert.True(t, errors.Is(err, dynconf.ErrKeyNotFound))
+
+	wr3, b3 := dynconftest.NewWatcher(t)
+	b3.Load(snapshot)
+	w, err = wr3.AddWatch(context.Background(), "dir/a", dynconf.NewIntValue)
+	if assert.NoError(t, err) {
+		assert.Equal(t, int64(2), w.Value().(*dynconf.IntValue).Get())
+		w.Remove()
+	}
+
+	_, err = dynconf.LoadSnapshot(bytes.NewReader([]byte("{")))
+	assert.Error(t, err)
+}
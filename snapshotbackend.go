@@ -0,0 +1,148 @@
+package dynconf
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/hashicorp/consul/api"
+)
+
+// LoadSnapshot reads a snapshot written by Watcher.ExportJSON from the given reader,
+// and then returns the snapshot, which can be served by SnapshotBackend.
+func LoadSnapshot(reader io.Reader) (map[string]RawValue, error) {
+	var snapshotFile snapshotFile
+
+	if err := json.NewDecoder(reader).Decode(&snapshotFile); err != nil {
+		return nil, fmt.Errorf("dynconf: snapshot load failed: %w", err)
+	}
+
+	if snapshotFile.Values == nil {
+		snapshotFile.Values = make(map[string]RawValue)
+	}
+
+	return snapshotFile.Values, nil
+}
+
+// SnapshotBackend presents a read-only backend serving the raw data of keys in a
+// snapshot, e.g. one loaded with LoadSnapshot, so that a process can run entirely
+// from a snapshot captured in production, without Consul, for reproducing issues
+// or for integration tests. The keys never change, so blocking queries just block
+// until the wait time elapses.
+type SnapshotBackend struct {
+	snapshot  map[string]RawValue
+	lastIndex uint64
+	clock     Clock
+}
+
+var _ Backend = (*SnapshotBackend)(nil)
+
+// Init initializes the backend with the given snapshot and then returns the backend.
+func (sb *SnapshotBackend) Init(snapshot map[string]RawValue) *SnapshotBackend {
+	return sb.InitWithClock(snapshot, SystemClock)
+}
+
+// InitWithClock initializes the backend like Init, of which the blocking queries
+// wait with the given clock, and then returns the backend.
+func (sb *SnapshotBackend) InitWithClock(snapshot map[string]RawValue, clock Clock) *SnapshotBackend {
+	sb.clock = clock
+	sb.snapshot = make(map[string]RawValue, len(snapshot))
+	sb.lastIndex = 1
+
+	for key, rawValue := range snapshot {
+		if rawValue.ModifyIndex == 0 {
+			rawValue.ModifyIndex = 1
+		}
+
+		if rawValue.ModifyIndex > sb.lastIndex {
+			sb.lastIndex = rawValue.ModifyIndex
+		}
+
+		sb.snapshot[key] = rawValue
+	}
+
+	return sb
+}
+
+// Get implements Backend.Get.
+func (sb *SnapshotBackend) Get(key string, queryOptions *api.QueryOptions) (*api.KVPair, *api.QueryMeta, error) {
+	var kvPair *api.KVPair
+	index := sb.lastIndex
+
+	if rawValue, ok := sb.snapshot[key]; ok {
+		kvPair = rawValue.kvPair(key)
+		index = kvPair.ModifyIndex
+	}
+
+	if err := sb.wait(index, queryOptions); err != nil {
+		return nil, nil, err
+	}
+
+	return kvPair, &api.QueryMeta{LastIndex: index}, nil
+}
+
+// List implements Backend.List.
+func (sb *SnapshotBackend) List(prefix string, queryOptions *api.QueryOptions) (api.KVPairs, *api.QueryMeta, error) {
+	var kvPairs api.KVPairs
+	var index uint64
+
+	for key, rawValue := range sb.snapshot {
+		if !strings.HasPrefix(key, prefix) {
+			continue
+		}
+
+		kvPair := rawValue.kvPair(key)
+		kvPairs = append(kvPairs, kvPair)
+
+		if kvPair.ModifyIndex > index {
+			index = kvPair.ModifyIndex
+		}
+	}
+
+	sort.Slice(kvPairs, func(i, j int) bool { return kvPairs[i].Key < kvPairs[j].Key })
+
+	if index == 0 {
+		index = sb.lastIndex
+	}
+
+	if err := sb.wait(index, queryOptions); err != nil {
+		return nil, nil, err
+	}
+
+	return kvPairs, &api.QueryMeta{LastIndex: index}, nil
+}
+
+func (sb *SnapshotBackend) wait(index uint64, queryOptions *api.QueryOptions) error {
+	if queryOptions == nil || queryOptions.WaitIndex == 0 || queryOptions.WaitIndex != index {
+		return nil
+	}
+
+	waitTime := queryOptions.WaitTime
+
+	if waitTime <= 0 {
+		waitTime = defaultWaitTime
+	}
+
+	timer := sb.clock.NewTimer(waitTime)
+	defer timer.Stop()
+	ctx := queryOptions.Context()
+
+	select {
+	case <-timer.C():
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (rv RawValue) kvPair(key string) *api.KVPair {
+	return &api.KVPair{
+		Key:         key,
+		Value:       append([]byte(nil), rv.Data...),
+		CreateIndex: rv.ModifyIndex,
+		ModifyIndex: rv.ModifyIndex,
+		Flags:       rv.Flags,
+	}
+}
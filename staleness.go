@@ -0,0 +1,101 @@
+package dynconf
+
+import (
+	"time"
+)
+
+// WithMaxStaleness returns an option to consider the value of the watch stale once
+// the watch hasn't been able to contact the backend for longer than the given
+// duration, which means the value may have been changed without the watch knowing.
+// Once the value has become stale, the given callback, if not nil, is called, and
+// the watch is reported degraded by Watcher.Health, until the backend is contacted
+// again. The wait time of blocking queries, set by WithWaitTime, is capped to half
+// of the given duration, so that an unchanged key doesn't make the value stale. It
+// takes effect only for watches on keys.
+func WithMaxStaleness(maxStaleness time.Duration, onStale func()) WatchOption {
+	return func(wo *watchOptions) {
+		wo.MaxStaleness = maxStaleness
+		wo.StaleCallback = onStale
+	}
+}
+
+// IsStale returns whether the value of the watch is stale, according to the
+// duration set by WithMaxStaleness.
+func (w *Watch) IsStale() bool {
+	w.statusMu.Lock()
+	defer w.statusMu.Unlock()
+	return w.stale
+}
+
+// LastContactTime returns the time when the watch contacted the backend
+// successfully last time.
+func (w *Watch) LastContactTime() time.Time {
+	w.statusMu.Lock()
+	defer w.statusMu.Unlock()
+	return w.lastContactTime
+}
+
+// waitTime returns the given wait time of a blocking query capped to half of the max
+// staleness, so that the backend, which holds the query open until the wait time
+// elapses if the key is unchanged, is contacted before the value becomes stale.
+func (w *Watch) waitTime(waitTime time.Duration) time.Duration {
+	if w.options.MaxStaleness <= 0 {
+		return waitTime
+	}
+
+	maxWaitTime := w.options.MaxStaleness / 2
+
+	if waitTime <= 0 || waitTime > maxWaitTime {
+		return maxWaitTime
+	}
+
+	return waitTime
+}
+
+func (w *Watch) startStalenessTimer() {
+	if w.options.MaxStaleness <= 0 {
+		return
+	}
+
+	w.statusMu.Lock()
+	defer w.statusMu.Unlock()
+	w.stalenessTimer = w.options.Clock.AfterFunc(w.options.MaxStaleness, w.markStale)
+}
+
+func (w *Watch) stopStalenessTimer() {
+	w.statusMu.Lock()
+	defer w.statusMu.Unlock()
+
+	if w.stalenessTimer != nil {
+		w.stalenessTimer.Stop()
+	}
+}
+
+func (w *Watch) recordContact() {
+	w.statusMu.Lock()
+	w.lastContactTime = w.options.Clock.Now()
+	wasStale := w.stale
+	w.stale = false
+
+	if w.stalenessTimer != nil {
+		w.stalenessTimer.Reset(w.options.MaxStaleness)
+	}
+
+	w.statusMu.Unlock()
+
+	if wasStale {
+		w.logger.Log(LogInfo, "dynconf_value_refreshed", "key", w.key)
+	}
+}
+
+func (w *Watch) markStale() {
+	w.statusMu.Lock()
+	lastContactTime := w.lastContactTime
+	w.stale = true
+	w.statusMu.Unlock()
+	w.logger.Log(LogWarn, "dynconf_value_stale", "key", w.key, "last_contact_time", lastContactTime.String())
+
+	if callback := w.options.StaleCallback; callback != nil {
+		w.call("stale_callback", callback)
+	}
+}
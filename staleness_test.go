@@ -0,0 +1,77 @@
+package dynconf_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/roy2220/dynconf"
+	"github.com/roy2220/dynconf/dynconftest"
+)
+
+func TestWithMaxStaleness(t *testing.T) {
+	clock := dynconftest.NewClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	b := &flakyBackend{Backend: new(dynconftest.Backend).InitWithClock(clock)}
+	b.Set("hello", []byte("1"))
+	wr := dynconf.NewWatcherWithBackend(b, dynconf.WithLogger(dynconftest.Logger{T: t}), dynconf.WithClock(clock),
+		dynconf.WithHealthFailureThreshold(1000))
+	var numberOfStales int32
+	w, err := wr.AddWatch(context.Background(), "hello", dynconf.NewIntValue,
+		dynconf.WithWaitTime(10*time.Second),
+		dynconf.WithRetryPolicy(dynconf.ConstantBackoff{Interval: 10 * time.Second}),
+		dynconf.WithMaxStaleness(time.Minute, func() { atomic.AddInt32(&numberOfStales, 1) }))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer w.Remove()
+	assert.False(t, w.IsStale())
+	assert.Equal(t, clock.Now(), w.LastContactTime())
+
+	// wait for the staleness timer and the blocking query
+	clock.BlockUntil(2)
+	atomic.StoreInt32(&b.Down, 1)
+	assert.Eventually(t, func() bool {
+		clock.Advance(10 * time.Second)
+		return w.IsStale()
+	}, time.Second, time.Millisecond)
+	assert.Eventually(t, func() bool { return atomic.LoadInt32(&numberOfStales) == 1 }, time.Second, time.Millisecond)
+	assert.True(t, w.LastContactTime().After(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)))
+	assert.Equal(t, dynconf.HealthDegraded, wr.Health().Status)
+
+	atomic.StoreInt32(&b.Down, 0)
+	assert.Eventually(t, func() bool {
+		clock.Advance(10 * time.Second)
+		return !w.IsStale()
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&numberOfStales))
+}
+
+func TestWithMaxStalenessAndDefaultWaitTime(t *testing.T) {
+	clock := dynconftest.NewClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	wr, b := dynconftest.NewWatcherWithClock(t, clock)
+	b.Set("hello", []byte("1"))
+	var numberOfStales int32
+	w, err := wr.AddWatch(context.Background(), "hello", dynconf.NewIntValue,
+		dynconf.WithMaxStaleness(time.Minute, func() { atomic.AddInt32(&numberOfStales, 1) }))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer w.Remove()
+
+	// waitForQuery waits for the staleness timer and the blocking query
+	waitForQuery := func() {
+		assert.Eventually(t, func() bool { return clock.NumberOfTimers() == 2 }, time.Second, time.Millisecond)
+	}
+
+	for i := 0; i < 10; i++ {
+		waitForQuery()
+		clock.Advance(30 * time.Second)
+	}
+	waitForQuery()
+	assert.False(t, w.IsStale())
+	assert.Equal(t, int32(0), atomic.LoadInt32(&numberOfStales))
+	assert.Equal(t, dynconf.HealthHealthy, wr.Health().Status)
+}
@@ -0,0 +1,290 @@
+package dynconf
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+)
+
+// WithStatusReporting returns an option to report the status of the watch, i.e.
+// the modify index of the value applied, to the KV store of Consul under the key
+// "status/<key>/<instance id>" as a StatusRecord in JSON, once the data have been
+// changed and at the given interval, so that the convergence of the instances can
+// be verified with QueryConvergence. The status key is acquired with a session,
+// which is renewed on each report, so that the key is deleted once the instance
+// has gone away or the watch has been removed. The watcher must be initialized
+// with a client. It takes effect only for watches on keys.
+func WithStatusReporting(instanceID string, interval time.Duration) WatchOption {
+	return func(wo *watchOptions) {
+		wo.InstanceID = instanceID
+		wo.StatusInterval = interval
+	}
+}
+
+// StatusPrefix is the prefix of the keys of the statuses reported by watches.
+const StatusPrefix = "status/"
+
+// StatusRecord represents a status of a watch reported by an instance.
+type StatusRecord struct {
+	// InstanceID is the ID of the instance.
+	InstanceID string `json:"instance_id"`
+
+	// ModifyIndex is the modify index of the data of the value applied, which is
+	// zero for the default value.
+	ModifyIndex uint64 `json:"modify_index"`
+
+	// Hash is the SHA-256 hash, in hex, of the data of the value applied.
+	Hash string `json:"hash"`
+
+	// LastError is the last error of the watch, if the latest data have been
+	// rejected or the backend is failing.
+	LastError string `json:"last_error,omitempty"`
+
+	// ReportTime is the time when the status was reported.
+	ReportTime time.Time `json:"report_time"`
+}
+
+func statusKey(key string, instanceID string) string {
+	return StatusPrefix + key + "/" + instanceID
+}
+
+func (w *Watch) startStatusReporting() {
+	if w.options.StatusInterval <= 0 {
+		return
+	}
+
+	client := w.watcher.client
+
+	if client == nil {
+		w.logger.Log(LogWarn, "dynconf_status_reporting_disabled", "key", w.key, "error", errClientRequired)
+		return
+	}
+
+	w.statusChanged = make(chan struct{}, 1)
+	w.wg.Add(1)
+
+	go func() {
+		defer w.wg.Done()
+		w.reportStatus(client)
+	}()
+}
+
+func (w *Watch) notifyStatusChanged() {
+	if w.statusChanged == nil {
+		return
+	}
+
+	select {
+	case w.statusChanged <- struct{}{}:
+	default:
+	}
+}
+
+func (w *Watch) reportStatus(client *api.Client) {
+	ticker := w.options.Clock.NewTicker(w.options.StatusInterval)
+	defer ticker.Stop()
+	var sessionID string
+
+	for {
+		var err error
+
+		if sessionID, err = w.writeStatus(client, sessionID); err != nil {
+			w.logger.Log(LogWarn, "dynconf_status_report_failed", "key", w.key, "error", err)
+		}
+
+		select {
+		case <-w.ctx.Done():
+			if sessionID != "" {
+				w.deleteStatus(client, sessionID)
+			}
+
+			return
+		case <-ticker.C():
+		case <-w.statusChanged:
+		}
+	}
+}
+
+// writeStatus writes the status with the given session, which is created if empty
+// or expired, and then returns the session.
+func (w *Watch) writeStatus(client *api.Client, sessionID string) (string, error) {
+	writeOptions := w.options.makeWriteOptions(w.ctx)
+
+	if sessionID != "" {
+		sessionEntry, _, err := client.Session().Renew(sessionID, writeOptions)
+
+		if err != nil {
+			return sessionID, fmt.Errorf("dynconf: session renew failed; session_id=%q: %w", sessionID, err)
+		}
+
+		if sessionEntry == nil {
+			sessionID = ""
+		}
+	}
+
+	if sessionID == "" {
+		var err error
+		sessionID, _, err = client.Session().Create(&api.SessionEntry{
+			Name:     "dynconf-status: " + w.key,
+			TTL:      w.statusSessionTTL().String(),
+			Behavior: api.SessionBehaviorDelete,
+		}, writeOptions)
+
+		if err != nil {
+			return "", fmt.Errorf("dynconf: session create failed: %w", err)
+		}
+	}
+
+	raw, _ := w.raw.Load().(rawData)
+	statusRecord := StatusRecord{
+		InstanceID:  w.options.InstanceID,
+		ModifyIndex: raw.ModifyIndex,
+		Hash:        hashData(raw.Data),
+		ReportTime:  w.options.Clock.Now(),
+	}
+
+	if err := w.LastError(); err != nil {
+		statusRecord.LastError = err.Error()
+	}
+
+	data, err := json.Marshal(&statusRecord)
+
+	if err != nil {
+		return sessionID, err
+	}
+
+	key := statusKey(w.key, w.options.InstanceID)
+	ok, _, err := client.KV().Acquire(&api.KVPair{Key: key, Value: data, Session: sessionID}, writeOptions)
+
+	if err != nil {
+		return sessionID, fmt.Errorf("dynconf: kv acquire failed; key=%q: %w", key, err)
+	}
+
+	if !ok {
+		return sessionID, fmt.Errorf("dynconf: kv acquire failed; key=%q: key held by another session", key)
+	}
+
+	return sessionID, nil
+}
+
+// deleteStatus deletes the status by destroying the given session.
+func (w *Watch) deleteStatus(client *api.Client, sessionID string) {
+	ctx, cancel := context.WithTimeout(context.Background(), statusDeletionTimeout)
+	defer cancel()
+
+	if _, err := client.Session().Destroy(sessionID, w.options.makeWriteOptions(ctx)); err != nil {
+		w.logger.Log(LogWarn, "dynconf_status_delete_failed", "key", w.key, "error", err)
+	}
+}
+
+// statusSessionTTL returns the TTL of sessions, which outlives a few intervals and
+// is within the range accepted by Consul.
+func (w *Watch) statusSessionTTL() time.Duration {
+	ttl := 3 * w.options.StatusInterval
+
+	if ttl < minSessionTTL {
+		return minSessionTTL
+	}
+
+	if ttl > maxSessionTTL {
+		return maxSessionTTL
+	}
+
+	return ttl
+}
+
+func (wo *watchOptions) makeWriteOptions(ctx context.Context) *api.WriteOptions {
+	queryOptions := wo.makeQueryOptions(ctx, wo.QueryOptions)
+	writeOptions := api.WriteOptions{
+		Namespace:  queryOptions.Namespace,
+		Datacenter: queryOptions.Datacenter,
+		Token:      queryOptions.Token,
+	}
+
+	return writeOptions.WithContext(ctx)
+}
+
+const (
+	minSessionTTL         = 10 * time.Second
+	maxSessionTTL         = 24 * time.Hour
+	statusDeletionTimeout = 5 * time.Second
+)
+
+// QueryConvergence reads the statuses of the watches on the given key reported by
+// the instances with WithStatusReporting, and then returns a report on which
+// instances have applied the latest data of the key and which haven't. The given
+// options, e.g. WithNamespace, should match those of the watches.
+func QueryConvergence(ctx context.Context, client *api.Client, key string,
+	options ...WatchOption) (*ConvergenceReport, error) {
+	var watchOptions watchOptions
+	watchOptions.Init(options)
+	queryOptions := watchOptions.makeQueryOptions(ctx, watchOptions.QueryOptions)
+	kvPair, _, err := client.KV().Get(key, queryOptions)
+
+	if err != nil {
+		return nil, fmt.Errorf("dynconf: kv get failed; key=%q: %w", key, err)
+	}
+
+	prefix := statusKey(key, "")
+	kvPairs, _, err := client.KV().List(prefix, queryOptions)
+
+	if err != nil {
+		return nil, fmt.Errorf("dynconf: kv list failed; prefix=%q: %w", prefix, err)
+	}
+
+	convergenceReport := ConvergenceReport{Key: key}
+
+	if kvPair != nil {
+		convergenceReport.LatestModifyIndex = kvPair.ModifyIndex
+	}
+
+	for _, kvPair := range kvPairs {
+		if strings.Contains(kvPair.Key[len(prefix):], "/") {
+			// The status of a watch on another key prefixed with the key.
+			continue
+		}
+
+		var statusRecord StatusRecord
+
+		if err := json.Unmarshal(kvPair.Value, &statusRecord); err != nil {
+			return nil, fmt.Errorf("dynconf: status unmarshal failed; key=%q: %w", kvPair.Key, err)
+		}
+
+		if statusRecord.ModifyIndex >= convergenceReport.LatestModifyIndex {
+			convergenceReport.Converged = append(convergenceReport.Converged, statusRecord)
+		} else {
+			convergenceReport.Pending = append(convergenceReport.Pending, statusRecord)
+		}
+	}
+
+	return &convergenceReport, nil
+}
+
+// ConvergenceReport represents a report on the convergence of the instances
+// watching a key.
+type ConvergenceReport struct {
+	// Key is the key.
+	Key string `json:"key"`
+
+	// LatestModifyIndex is the modify index of the latest data of the key, which is
+	// zero if the key doesn't exist.
+	LatestModifyIndex uint64 `json:"latest_modify_index"`
+
+	// Converged are the statuses of the instances which have applied the latest
+	// data of the key.
+	Converged []StatusRecord `json:"converged"`
+
+	// Pending are the statuses of the instances which haven't applied the latest
+	// data of the key yet, including the ones having rejected the data.
+	Pending []StatusRecord `json:"pending"`
+}
+
+// IsConverged returns whether all the instances have applied the latest data of
+// the key.
+func (cr *ConvergenceReport) IsConverged() bool {
+	return len(cr.Pending) == 0
+}
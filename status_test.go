@@ -0,0 +1,127 @@
+package dynconf_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/roy2220/dynconf"
+	"github.com/roy2220/dynconf/dynconftest"
+)
+
+func TestWithStatusReporting(t *testing.T) {
+	wr, c := makeWatcher(t)
+	_, err := c.KV().Put(&api.KVPair{
+		Key:   "hello21",
+		Value: []byte(`{"Foo": 1}`),
+	}, &api.WriteOptions{})
+	assert.NoError(t, err)
+	clock := dynconftest.NewClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	w, err := wr.AddWatch(context.Background(), "hello21", newValue, dynconf.WithClock(clock),
+		dynconf.WithStatusReporting("instance-1", time.Minute))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	readStatus := func() *dynconf.StatusRecord {
+		kvPair, _, err := c.KV().Get(dynconf.StatusPrefix+"hello21/instance-1", &api.QueryOptions{})
+		if !assert.NoError(t, err) || kvPair == nil {
+			return nil
+		}
+		var statusRecord dynconf.StatusRecord
+		assert.NoError(t, json.Unmarshal(kvPair.Value, &statusRecord))
+		return &statusRecord
+	}
+
+	var statusRecord *dynconf.StatusRecord
+	assert.Eventually(t, func() bool {
+		statusRecord = readStatus()
+		return statusRecord != nil
+	}, time.Second, 10*time.Millisecond)
+	if assert.NotNil(t, statusRecord) {
+		assert.Equal(t, "instance-1", statusRecord.InstanceID)
+		assert.Equal(t, w.ModifyIndex(), statusRecord.ModifyIndex)
+		assert.True(t, clock.Now().Equal(statusRecord.ReportTime))
+	}
+
+	_, err = c.KV().Put(&api.KVPair{
+		Key:   "hello21",
+		Value: []byte(`{"Foo": 2}`),
+	}, &api.WriteOptions{})
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		statusRecord = readStatus()
+		return w.Value().(*config).Foo == 2 && statusRecord != nil && statusRecord.ModifyIndex == w.ModifyIndex()
+	}, time.Second, 10*time.Millisecond)
+
+	clock.Advance(time.Minute)
+	assert.Eventually(t, func() bool {
+		statusRecord = readStatus()
+		return statusRecord != nil && clock.Now().Equal(statusRecord.ReportTime)
+	}, time.Second, 10*time.Millisecond)
+
+	w.Remove()
+	assert.Nil(t, readStatus())
+}
+
+func TestQueryConvergence(t *testing.T) {
+	wr, c := makeWatcher(t)
+	_, err := c.KV().Put(&api.KVPair{
+		Key:   "hello22",
+		Value: []byte(`{"Foo": 1}`),
+	}, &api.WriteOptions{})
+	assert.NoError(t, err)
+	clock := dynconftest.NewClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	w1, err := wr.AddWatch(context.Background(), "hello22", newValue, dynconf.WithClock(clock),
+		dynconf.WithStatusReporting("instance-1", time.Minute))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer w1.Remove()
+	w2, err := wr.AddWatch(context.Background(), "hello22", newValue, dynconf.WithClock(clock),
+		dynconf.WithStatusReporting("instance-2", time.Minute),
+		dynconf.WithValidator(func(value dynconf.Value) error {
+			if value.(*config).Foo > 1 {
+				return errors.New("foo too large")
+			}
+			return nil
+		}))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer w2.Remove()
+
+	var cr *dynconf.ConvergenceReport
+	assert.Eventually(t, func() bool {
+		cr, err = dynconf.QueryConvergence(context.Background(), c, "hello22")
+		return err == nil && len(cr.Converged) == 2
+	}, time.Second, 10*time.Millisecond)
+	if assert.NoError(t, err) {
+		assert.True(t, cr.IsConverged())
+	}
+
+	_, err = c.KV().Put(&api.KVPair{
+		Key:   "hello22",
+		Value: []byte(`{"Foo": 2}`),
+	}, &api.WriteOptions{})
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		cr, err = dynconf.QueryConvergence(context.Background(), c, "hello22")
+		return err == nil && len(cr.Pending) == 1 && cr.Pending[0].LastError != ""
+	}, time.Second, 10*time.Millisecond)
+	if assert.NoError(t, err) {
+		assert.False(t, cr.IsConverged())
+		if assert.Len(t, cr.Converged, 1) {
+			assert.Equal(t, "instance-1", cr.Converged[0].InstanceID)
+		}
+		if assert.Len(t, cr.Pending, 1) {
+			assert.Equal(t, "instance-2", cr.Pending[0].InstanceID)
+			assert.NotEmpty(t, cr.Pending[0].LastError)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+package dynconf
+
+// WithStrictUnmarshal returns an option to unmarshal values of the watch in strict
+// mode, i.e. to reject the data having fields unknown to the values, e.g. because
+// of misspelled field names, which are otherwise silently ignored. It takes effect
+// for values implementing StrictUnmarshaler, including values of type *ObjectValue
+// created by CodecValueFactory and structs bound with Bind, with the data of the
+// formats the codecs of which implement StrictCodec, including "json", "yaml" and
+// "toml".
+func WithStrictUnmarshal() WatchOption {
+	return func(wo *watchOptions) {
+		wo.StrictUnmarshal = true
+	}
+}
+
+func (wo *watchOptions) unmarshalValue(value Value, data []byte) error {
+	if wo.StrictUnmarshal {
+		if strictUnmarshaler, ok := value.(StrictUnmarshaler); ok {
+			return strictUnmarshaler.UnmarshalStrict(data)
+		}
+	}
+
+	return value.Unmarshal(data)
+}
@@ -0,0 +1,47 @@
+package dynconf_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/roy2220/dynconf"
+	"github.com/roy2220/dynconf/dynconftest"
+	"github.com/roy2220/dynconf/yamlcodec"
+)
+
+func TestWithStrictUnmarshal(t *testing.T) {
+	wr, b := dynconftest.NewWatcher(t)
+	b.Set("hello", []byte(`{"Foo": 1}`))
+
+	var cfg struct {
+		Foo int
+	}
+	bd, err := dynconf.Bind(context.Background(), wr, "hello", &cfg, dynconf.WithStrictUnmarshal())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer bd.Unbind()
+
+	events := bd.Watch().Events()
+	b.Set("hello", []byte(`{"Fooo": 2}`))
+	<-events
+	assert.Error(t, bd.Watch().LastError())
+	bd.RLock()
+	assert.Equal(t, 1, cfg.Foo)
+	bd.RUnlock()
+
+	type object struct {
+		Foo int `yaml:"foo"`
+	}
+	b.Set("world", []byte("foo: 1\nbar: 2\n"))
+	vf := yamlcodec.ValueFactory(func() interface{} { return new(object) })
+	w, err := wr.AddWatch(context.Background(), "world", vf)
+	if assert.NoError(t, err) {
+		assert.Equal(t, &object{Foo: 1}, w.ObjectValue())
+		w.Remove()
+	}
+	_, err = wr.AddWatch(context.Background(), "world", vf, dynconf.WithStrictUnmarshal())
+	assert.Error(t, err)
+}
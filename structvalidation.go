@@ -0,0 +1,51 @@
+package dynconf
+
+import "reflect"
+
+// StructValidator represents a validator of structs, e.g. *validator.Validate of
+// the package github.com/go-playground/validator, which validates structs by their
+// `validate` tags like `validate:"min=1,max=10"` or `validate:"oneof=a b"`.
+type StructValidator interface {
+	// Struct validates the given struct or pointer to struct.
+	Struct(s interface{}) (err error)
+}
+
+// WithStructValidator returns an option to validate the structs of values of the
+// watch with the given validator after unmarshaling, in addition to the other
+// validators. Values failing validation are rejected. It takes effect for values
+// of type *ObjectValue holding structs or pointers to structs, including structs
+// bound with Bind.
+func WithStructValidator(structValidator StructValidator) WatchOption {
+	return func(wo *watchOptions) {
+		wo.StructValidator = structValidator
+	}
+}
+
+func (wo *watchOptions) validateStruct(value Value) error {
+	if wo.StructValidator == nil {
+		return nil
+	}
+
+	objectValue, ok := value.(*ObjectValue)
+
+	if !ok {
+		return nil
+	}
+
+	object := objectValue.Object()
+	objectType := reflect.TypeOf(object)
+
+	if objectType == nil {
+		return nil
+	}
+
+	if objectType.Kind() == reflect.Ptr {
+		objectType = objectType.Elem()
+	}
+
+	if objectType.Kind() != reflect.Struct {
+		return nil
+	}
+
+	return wo.StructValidator.Struct(object)
+}
@@ -0,0 +1,51 @@
+package dynconf_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/roy2220/dynconf"
+	"github.com/roy2220/dynconf/dynconftest"
+)
+
+type maxValidator struct{}
+
+func (maxValidator) Struct(s interface{}) error {
+	if s.(*limitsConfig).Max > 10 {
+		return errors.New("Max: max=10")
+	}
+
+	return nil
+}
+
+type limitsConfig struct {
+	Max int `validate:"max=10"`
+}
+
+func TestWithStructValidator(t *testing.T) {
+	wr, b := dynconftest.NewWatcher(t)
+	b.Set("hello", []byte(`{"Max": 5}`))
+
+	var cfg limitsConfig
+	bd, err := dynconf.Bind(context.Background(), wr, "hello", &cfg, dynconf.WithStructValidator(maxValidator{}))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer bd.Unbind()
+
+	events := bd.Watch().Events()
+	b.Set("hello", []byte(`{"Max": 11}`))
+	<-events
+	assert.EqualError(t, bd.Watch().LastError(), "dynconf: value validation failed; key=\"hello\": Max: max=10")
+	bd.RLock()
+	assert.Equal(t, 5, cfg.Max)
+	bd.RUnlock()
+
+	w, err := wr.AddWatch(context.Background(), "hello", dynconf.NewStringValue, dynconf.WithStructValidator(maxValidator{}))
+	if assert.NoError(t, err) {
+		w.Remove()
+	}
+}
@@ -0,0 +1,139 @@
+package dynconf
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+)
+
+// WithTemplating returns an option to render the data of the watch as a template
+// of the package text/template, after decryption and decompression and before
+// migration and unmarshaling, so that the data can be specialized per host, e.g.
+// {"name": "{{ .Hostname }}"}. The template is executed with a TemplateInput with
+// the given metadata, and can use the functions listed in TemplateFuncs and env
+// besides the built-in ones. Only the environment variables with the given names
+// are exposed to the template, through .Env and env, so that the data in the
+// backend can't read secrets from the environment; env fails the rendering for
+// other names. Referring to a missing entry of a map, e.g. {{ .Env.FOO }} with the
+// environment variable FOO unset, fails the rendering, which can be avoided with
+// {{ env "FOO" | default "bar" }}.
+func WithTemplating(metadata map[string]string, envVars ...string) WatchOption {
+	return func(wo *watchOptions) {
+		wo.TemplatingEnabled = true
+		wo.TemplateMetadata = metadata
+		wo.TemplateEnvVars = envVars
+	}
+}
+
+// TemplateInput represents the input of the templates rendered for watches with
+// WithTemplating.
+type TemplateInput struct {
+	// Key is the key of the watch.
+	Key string
+
+	// Hostname is the host name reported by the kernel.
+	Hostname string
+
+	// Env is the environment variables given to WithTemplating which are set.
+	Env map[string]string
+
+	// Metadata is the metadata given to WithTemplating.
+	Metadata map[string]string
+}
+
+// TemplateFuncs are the functions, resembling those of the library Sprig, which
+// templates rendered for watches with WithTemplating can use.
+var TemplateFuncs = template.FuncMap{
+	"default": func(defaultValue interface{}, value interface{}) interface{} {
+		if value == nil || value == "" {
+			return defaultValue
+		}
+
+		return value
+	},
+	"upper":      strings.ToUpper,
+	"lower":      strings.ToLower,
+	"trim":       strings.TrimSpace,
+	"trimPrefix": func(prefix string, s string) string { return strings.TrimPrefix(s, prefix) },
+	"trimSuffix": func(suffix string, s string) string { return strings.TrimSuffix(s, suffix) },
+	"replace":    func(old string, new string, s string) string { return strings.Replace(s, old, new, -1) },
+	"contains":   func(substr string, s string) bool { return strings.Contains(s, substr) },
+	"hasPrefix":  func(prefix string, s string) bool { return strings.HasPrefix(s, prefix) },
+	"hasSuffix":  func(suffix string, s string) bool { return strings.HasSuffix(s, suffix) },
+	"split":      func(sep string, s string) []string { return strings.Split(s, sep) },
+	"join":       func(sep string, elems []string) string { return strings.Join(elems, sep) },
+	"quote":      func(s string) string { return fmt.Sprintf("%q", s) },
+	"b64enc":     func(s string) string { return base64.StdEncoding.EncodeToString([]byte(s)) },
+	"b64dec": func(s string) (string, error) {
+		data, err := base64.StdEncoding.DecodeString(s)
+		return string(data), err
+	},
+	"toJSON": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+	"required": func(message string, value interface{}) (interface{}, error) {
+		if value == nil || value == "" {
+			return nil, errors.New(message)
+		}
+
+		return value, nil
+	},
+}
+
+func (wo *watchOptions) renderData(key string, data []byte) ([]byte, error) {
+	if !wo.TemplatingEnabled {
+		return data, nil
+	}
+
+	hostname, _ := os.Hostname()
+	input := TemplateInput{
+		Key:      key,
+		Hostname: hostname,
+		Env:      environ(wo.TemplateEnvVars),
+		Metadata: wo.TemplateMetadata,
+	}
+
+	env := func(name string) (string, error) {
+		for _, envVar := range wo.TemplateEnvVars {
+			if envVar == name {
+				return input.Env[name], nil
+			}
+		}
+
+		return "", fmt.Errorf("environment variable not allowed; name=%q", name)
+	}
+
+	tmpl, err := template.New(key).Funcs(TemplateFuncs).Funcs(template.FuncMap{"env": env}).
+		Option("missingkey=error").Parse(string(data))
+
+	if err != nil {
+		return nil, fmt.Errorf("dynconf: data rendering failed; key=%q: %w", key, err)
+	}
+
+	var buffer bytes.Buffer
+
+	if err := tmpl.Execute(&buffer, input); err != nil {
+		return nil, fmt.Errorf("dynconf: data rendering failed; key=%q: %w", key, err)
+	}
+
+	return buffer.Bytes(), nil
+}
+
+// environ returns the environment variables with the given names which are set.
+func environ(names []string) map[string]string {
+	env := make(map[string]string, len(names))
+
+	for _, name := range names {
+		if value, ok := os.LookupEnv(name); ok {
+			env[name] = value
+		}
+	}
+
+	return env
+}
@@ -0,0 +1,55 @@
+package dynconf_test
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/roy2220/dynconf"
+	"github.com/roy2220/dynconf/dynconftest"
+)
+
+func TestWithTemplating(t *testing.T) {
+	os.Setenv("DYNCONF_TEST_REGION", "us")
+	defer os.Unsetenv("DYNCONF_TEST_REGION")
+	os.Setenv("DYNCONF_TEST_SECRET", "s3cr3t")
+	defer os.Unsetenv("DYNCONF_TEST_SECRET")
+	hostname, _ := os.Hostname()
+	wr, b := dynconftest.NewWatcher(t)
+	b.Set("hello", []byte(`{{ .Hostname }}/{{ .Key }}/{{ .Env.DYNCONF_TEST_REGION | upper }}/{{ .Metadata.zone }}`))
+
+	w, err := wr.AddWatch(context.Background(), "hello", dynconf.NewStringValue,
+		dynconf.WithTemplating(map[string]string{"zone": "a"}, "DYNCONF_TEST_REGION", "DYNCONF_TEST_MISSING"))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer w.Remove()
+	assert.Equal(t, hostname+"/hello/US/a", w.Value().(*dynconf.StringValue).Get())
+
+	events := w.Events()
+	b.Set("hello", []byte(`{{ env "DYNCONF_TEST_MISSING" | default "x" }}`))
+	<-events
+	assert.Equal(t, "x", w.Value().(*dynconf.StringValue).Get())
+
+	b.Set("hello", []byte(`{{ .Env.DYNCONF_TEST_MISSING }}`))
+	<-events
+	assert.Equal(t, "x", w.Value().(*dynconf.StringValue).Get())
+	assert.Error(t, w.LastError())
+
+	// environment variables not given to WithTemplating are not exposed
+	b.Set("hello", []byte(`{{ .Env.DYNCONF_TEST_SECRET }}`))
+	<-events
+	assert.Equal(t, "x", w.Value().(*dynconf.StringValue).Get())
+	if assert.Error(t, w.LastError()) {
+		assert.Contains(t, w.LastError().Error(), `map has no entry for key "DYNCONF_TEST_SECRET"`)
+	}
+
+	b.Set("hello", []byte(`{{ env "DYNCONF_TEST_SECRET" }}`))
+	<-events
+	assert.Equal(t, "x", w.Value().(*dynconf.StringValue).Get())
+	assert.EqualError(t, w.LastError(), `dynconf: data rendering failed; key="hello": template: hello:1:3: `+
+		`executing "hello" at <env "DYNCONF_TEST_SECRET">: error calling env: environment variable not allowed; `+
+		`name="DYNCONF_TEST_SECRET"`)
+}
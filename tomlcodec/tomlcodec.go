@@ -0,0 +1,56 @@
+// Package tomlcodec registers the codec for format "toml" once imported.
+package tomlcodec
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/BurntSushi/toml"
+
+	"github.com/roy2220/dynconf"
+)
+
+// Format is the format for which the codec is registered.
+const Format = "toml"
+
+// ValueFactory returns a value factory creating values of type *dynconf.ObjectValue,
+// which unmarshal objects, created by the given function, from TOML data.
+func ValueFactory(newObject func() interface{}) dynconf.ValueFactory {
+	return dynconf.CodecValueFactory(Format, newObject)
+}
+
+type codec struct{}
+
+var _ dynconf.StrictCodec = codec{}
+
+func (codec) Marshal(object interface{}) ([]byte, error) {
+	var buffer bytes.Buffer
+
+	if err := toml.NewEncoder(&buffer).Encode(object); err != nil {
+		return nil, err
+	}
+
+	return buffer.Bytes(), nil
+}
+
+func (codec) Unmarshal(data []byte, object interface{}) error {
+	return toml.Unmarshal(data, object)
+}
+
+func (codec) UnmarshalStrict(data []byte, object interface{}) error {
+	metaData, err := toml.Decode(string(data), object)
+
+	if err != nil {
+		return err
+	}
+
+	if undecodedKeys := metaData.Undecoded(); len(undecodedKeys) >= 1 {
+		return fmt.Errorf("toml: unknown fields %v", undecodedKeys)
+	}
+
+	return nil
+}
+
+func init() {
+	dynconf.RegisterCodec(Format, codec{})
+}
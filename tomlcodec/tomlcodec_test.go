@@ -0,0 +1,41 @@
+package tomlcodec_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/roy2220/dynconf"
+	"github.com/roy2220/dynconf/tomlcodec"
+)
+
+type config struct {
+	Foo int    `toml:"foo"`
+	Bar string `toml:"bar"`
+}
+
+func TestValueFactory(t *testing.T) {
+	v := tomlcodec.ValueFactory(func() interface{} { return new(config) })()
+	assert.NoError(t, v.Unmarshal([]byte("foo = 1\nbar = \"a\"\n")))
+	assert.Equal(t, &config{Foo: 1, Bar: "a"}, v.(*dynconf.ObjectValue).Object())
+
+	data, err := v.(*dynconf.ObjectValue).Marshal()
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	v2 := tomlcodec.ValueFactory(func() interface{} { return new(config) })()
+	assert.NoError(t, v2.Unmarshal(data))
+	assert.Equal(t, v.(*dynconf.ObjectValue).Object(), v2.(*dynconf.ObjectValue).Object())
+
+	assert.Error(t, v.Unmarshal([]byte("foo = ")))
+	assert.Error(t, v.Unmarshal([]byte("foo = \"a\"")))
+}
+
+func TestUnmarshalStrict(t *testing.T) {
+	var c config
+	assert.NoError(t, dynconf.UnmarshalStrict(tomlcodec.Format, []byte("foo = 1"), &c))
+	assert.Equal(t, config{Foo: 1}, c)
+	assert.EqualError(t, dynconf.UnmarshalStrict(tomlcodec.Format, []byte("foo = 1\nbaz = 2"), &c),
+		"toml: unknown fields [baz]")
+	assert.NoError(t, dynconf.Unmarshal(tomlcodec.Format, []byte("foo = 1\nbaz = 2"), &c))
+}
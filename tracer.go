@@ -0,0 +1,47 @@
+package dynconf
+
+import "context"
+
+// Tracer represents a tracer for watches.
+type Tracer interface {
+	// StartSpan starts a span with the given name and attributes, as alternating
+	// keys and values, and then returns the context carrying the span and the span.
+	// Keys are strings and values are strings, integers, byte slices or errors.
+	StartSpan(ctx context.Context, name string, keysAndValues ...interface{}) (context.Context, Span)
+}
+
+// Span represents a span started by a tracer.
+type Span interface {
+	// AddEvent adds an event with the given name and attributes, as alternating
+	// keys and values, to the span.
+	AddEvent(name string, keysAndValues ...interface{})
+
+	// RecordError records the given error as an event of the span and marks
+	// the span failed.
+	RecordError(err error)
+
+	// End ends the span.
+	End()
+}
+
+type nopTracer struct{}
+
+var _ Tracer = nopTracer{}
+
+func (nopTracer) StartSpan(ctx context.Context, _ string, _ ...interface{}) (context.Context, Span) {
+	return ctx, nopSpan{}
+}
+
+type nopSpan struct{}
+
+func (nopSpan) AddEvent(string, ...interface{}) {}
+func (nopSpan) RecordError(error)               {}
+func (nopSpan) End()                            {}
+
+func endSpan(span Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+	}
+
+	span.End()
+}
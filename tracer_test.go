@@ -0,0 +1,116 @@
+package dynconf_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/roy2220/dynconf"
+	"github.com/roy2220/dynconf/dynconftest"
+)
+
+func TestWithTracer(t *testing.T) {
+	wr, b := dynconftest.NewWatcher(t)
+	b.Set("hello", []byte("1"))
+	var tr recordingTracer
+	w, err := wr.AddWatch(context.Background(), "hello", dynconf.NewIntValue, dynconf.WithTracer(&tr))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer w.Remove()
+
+	spans := tr.EndedSpans()
+	if assert.Len(t, spans, 1) {
+		assert.Equal(t, "dynconf.populate_value", spans[0].Name)
+		assert.Equal(t, []interface{}{"key", "hello"}, spans[0].KeysAndValues)
+		assert.Empty(t, spans[0].Events)
+		assert.NoError(t, spans[0].Err)
+	}
+
+	events := w.Events()
+	b.Set("hello", []byte("2"))
+	<-events
+	assert.Eventually(t, func() bool { return len(tr.EndedSpans()) == 2 }, time.Second, time.Millisecond)
+	spans = tr.EndedSpans()
+	if assert.Len(t, spans, 2) {
+		assert.Equal(t, "dynconf.update_value", spans[1].Name)
+		assert.Equal(t, []interface{}{"key", "hello", "modify_index", w.ModifyIndex()}, spans[1].KeysAndValues)
+		assert.Equal(t, []string{"dynconf.value_unmarshaled", "dynconf.value_applied"}, spans[1].Events)
+		assert.NoError(t, spans[1].Err)
+	}
+
+	b.Set("hello", []byte("x"))
+	<-events
+	assert.Eventually(t, func() bool { return len(tr.EndedSpans()) == 3 }, time.Second, time.Millisecond)
+	spans = tr.EndedSpans()
+	if assert.Len(t, spans, 3) {
+		assert.Equal(t, "dynconf.update_value", spans[2].Name)
+		assert.Empty(t, spans[2].Events)
+		assert.EqualError(t, spans[2].Err, `strconv.ParseInt: parsing "x": invalid syntax`)
+	}
+}
+
+func TestWithTracerPopulationFailure(t *testing.T) {
+	wr, _ := dynconftest.NewWatcher(t)
+	var tr recordingTracer
+	_, err := wr.AddWatch(context.Background(), "hello", dynconf.NewIntValue, dynconf.WithTracer(&tr))
+	assert.Error(t, err)
+
+	spans := tr.EndedSpans()
+	if assert.Len(t, spans, 1) {
+		assert.Equal(t, "dynconf.populate_value", spans[0].Name)
+		assert.Equal(t, err, spans[0].Err)
+	}
+}
+
+type recordingTracer struct {
+	mu         sync.Mutex
+	endedSpans []recordedSpan
+}
+
+var _ dynconf.Tracer = (*recordingTracer)(nil)
+
+func (rt *recordingTracer) StartSpan(ctx context.Context, name string, keysAndValues ...interface{}) (context.Context, dynconf.Span) {
+	return ctx, &recordingSpan{
+		tracer: rt,
+		recordedSpan: recordedSpan{
+			Name:          name,
+			KeysAndValues: keysAndValues,
+		},
+	}
+}
+
+func (rt *recordingTracer) EndedSpans() []recordedSpan {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+	return append([]recordedSpan(nil), rt.endedSpans...)
+}
+
+type recordedSpan struct {
+	Name          string
+	KeysAndValues []interface{}
+	Events        []string
+	Err           error
+}
+
+type recordingSpan struct {
+	tracer *recordingTracer
+	recordedSpan
+}
+
+func (rs *recordingSpan) AddEvent(name string, _ ...interface{}) {
+	rs.Events = append(rs.Events, name)
+}
+
+func (rs *recordingSpan) RecordError(err error) {
+	rs.Err = err
+}
+
+func (rs *recordingSpan) End() {
+	rs.tracer.mu.Lock()
+	defer rs.tracer.mu.Unlock()
+	rs.tracer.endedSpans = append(rs.tracer.endedSpans, rs.recordedSpan)
+}
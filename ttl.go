@@ -0,0 +1,52 @@
+package dynconf
+
+import (
+	"sync/atomic"
+	"time"
+)
+
+// WithTTL returns an option to revert the value of the watch to the default one set
+// by WithDefaultValue once the given TTL has elapsed since the data were last
+// written, unless the key is written again, e.g. for break-glass settings which
+// should never stay enabled forever. The revert goes through the same callbacks,
+// subscribers and events as updates. As the TTL is counted from when the watch has
+// seen the data, it restarts when the watch is added again, e.g. by a restarted
+// process, so the key should still be cleaned up. It takes effect only for watches
+// on keys.
+func WithTTL(ttl time.Duration) WatchOption {
+	return func(wo *watchOptions) {
+		wo.TTL = ttl
+	}
+}
+
+func (w *Watch) resetTTLTimer() {
+	if w.options.TTL <= 0 || !w.options.HasDefaultValue {
+		return
+	}
+
+	w.stopTTLTimer()
+
+	w.ttlTimer = w.options.Clock.AfterFunc(w.options.TTL, func() {
+		atomic.StoreInt32(&w.ttlExpiry, 1)
+		w.requestRefresh()
+	})
+}
+
+func (w *Watch) stopTTLTimer() {
+	if w.ttlTimer != nil {
+		w.ttlTimer.Stop()
+		w.ttlTimer = nil
+	}
+}
+
+// takeTTLExpiry returns whether the TTL has elapsed since the last call.
+func (w *Watch) takeTTLExpiry() bool {
+	return atomic.SwapInt32(&w.ttlExpiry, 0) == 1
+}
+
+func (w *Watch) expireTTL(index uint64) {
+	_, span := w.options.Tracer.StartSpan(w.ctx, "dynconf.expire_ttl", "key", w.key)
+	defer span.End()
+	w.logger.Log(LogInfo, "dynconf_ttl_expired", "key", w.key, "ttl", w.options.TTL.String())
+	w.revertToDefaultValue(index, span)
+}
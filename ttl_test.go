@@ -0,0 +1,50 @@
+package dynconf_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/roy2220/dynconf"
+	"github.com/roy2220/dynconf/dynconftest"
+)
+
+func TestWithTTL(t *testing.T) {
+	clock := dynconftest.NewClock(time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC))
+	wr, b := dynconftest.NewWatcherWithClock(t, clock)
+	b.Set("limit", []byte("1"))
+	w, err := wr.AddWatch(context.Background(), "limit", dynconf.NewIntValue,
+		dynconf.WithDefaultValue([]byte("0")), dynconf.WithTTL(time.Hour))
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer w.Remove()
+
+	events := w.Events()
+	var values []int64
+	w.Subscribe(func(_ dynconf.Value, newValue dynconf.Value) {
+		values = append(values, newValue.(*dynconf.IntValue).Get())
+	})
+	assert.Equal(t, int64(1), w.Value().(*dynconf.IntValue).Get())
+
+	clock.Advance(30 * time.Minute)
+	b.Set("limit", []byte("2"))
+	e := (<-events).(*dynconf.UpdatedEvent)
+	assert.Equal(t, int64(2), e.NewValue.(*dynconf.IntValue).Get())
+	assert.Equal(t, clock.Now(), w.LastUpdateTime())
+
+	clock.Advance(45 * time.Minute)
+	assert.Equal(t, int64(2), w.Value().(*dynconf.IntValue).Get())
+
+	clock.Advance(15 * time.Minute)
+	e = (<-events).(*dynconf.UpdatedEvent)
+	assert.Equal(t, int64(0), e.NewValue.(*dynconf.IntValue).Get())
+	assert.Equal(t, int64(0), w.Value().(*dynconf.IntValue).Get())
+	assert.Equal(t, []int64{2, 0}, values)
+
+	b.Set("limit", []byte("3"))
+	e = (<-events).(*dynconf.UpdatedEvent)
+	assert.Equal(t, int64(3), e.NewValue.(*dynconf.IntValue).Get())
+}
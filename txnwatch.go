@@ -0,0 +1,312 @@
+package dynconf
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/hashicorp/consul/api"
+)
+
+// AddTxnWatch adds a watch on the given keys, which are always read in a single
+// transaction of Consul and applied as an atomic snapshot, and then returns the
+// watch. The value of the watch is of type *SnapshotValue, holding the values of
+// the existing keys created with the given value factory, and the watch is set on
+// the keys joined with ",". The watcher must be initialized with a client.
+func (w *Watcher) AddTxnWatch(ctx context.Context, keys []string, valueFactory ValueFactory,
+	options ...WatchOption) (*Watch, error) {
+	if w.client == nil {
+		return nil, fmt.Errorf("dynconf: txn watch failed; keys=%q: %w", keys, errClientRequired)
+	}
+
+	backend := txnBackend{
+		Client: w.client,
+		Keys:   keys,
+	}
+
+	return w.doAddWatch(ctx, &backend, strings.Join(keys, ","), func() Value {
+		return &SnapshotValue{valueFactory: valueFactory}
+	}, options)
+}
+
+// SnapshotValue presents a value holding the values of multiple keys in a snapshot.
+type SnapshotValue struct {
+	valueFactory ValueFactory
+	values       map[string]Value
+}
+
+var (
+	_ Value                       = (*SnapshotValue)(nil)
+	_ ValueValidator              = (*SnapshotValue)(nil)
+	_ ValueOutdatedCallback       = (*SnapshotValue)(nil)
+	_ ValueDeletedCallback        = (*SnapshotValue)(nil)
+	_ ValueUpdateRejectedCallback = (*SnapshotValue)(nil)
+	_ ValueWatchRemovedCallback   = (*SnapshotValue)(nil)
+)
+
+// Unmarshal implements Value.Unmarshal.
+func (sv *SnapshotValue) Unmarshal(data []byte) error {
+	var snapshot map[string][]byte
+
+	if err := json.Unmarshal(data, &snapshot); err != nil {
+		return err
+	}
+
+	values := make(map[string]Value, len(snapshot))
+
+	for key, data := range snapshot {
+		value := sv.valueFactory()
+
+		if err := value.Unmarshal(data); err != nil {
+			return fmt.Errorf("key=%q: %w", key, err)
+		}
+
+		values[key] = value
+	}
+
+	sv.values = values
+	return nil
+}
+
+// Validate implements ValueValidator.Validate, it validates the values held which
+// implement ValueValidator.
+func (sv *SnapshotValue) Validate() error {
+	for key, value := range sv.values {
+		if validator, ok := value.(ValueValidator); ok {
+			if err := validator.Validate(); err != nil {
+				return fmt.Errorf("key=%q: %w", key, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// OnOutdated implements ValueOutdatedCallback.OnOutdated, it calls OnOutdated of
+// the values held which implement ValueOutdatedCallback.
+func (sv *SnapshotValue) OnOutdated() {
+	for _, value := range sv.values {
+		if callback, ok := value.(ValueOutdatedCallback); ok {
+			callback.OnOutdated()
+		}
+	}
+}
+
+// OnDeleted implements ValueDeletedCallback.OnDeleted, it calls OnDeleted of the
+// values held which implement ValueDeletedCallback.
+func (sv *SnapshotValue) OnDeleted() {
+	for _, value := range sv.values {
+		if callback, ok := value.(ValueDeletedCallback); ok {
+			callback.OnDeleted()
+		}
+	}
+}
+
+// OnUpdateRejected implements ValueUpdateRejectedCallback.OnUpdateRejected, it
+// calls OnUpdateRejected of the values held which implement ValueUpdateRejectedCallback.
+func (sv *SnapshotValue) OnUpdateRejected(err error, data []byte) {
+	for _, value := range sv.values {
+		if callback, ok := value.(ValueUpdateRejectedCallback); ok {
+			callback.OnUpdateRejected(err, data)
+		}
+	}
+}
+
+// OnWatchRemoved implements ValueWatchRemovedCallback.OnWatchRemoved, it calls
+// OnWatchRemoved of the values held which implement ValueWatchRemovedCallback.
+func (sv *SnapshotValue) OnWatchRemoved() {
+	for _, value := range sv.values {
+		if callback, ok := value.(ValueWatchRemovedCallback); ok {
+			callback.OnWatchRemoved()
+		}
+	}
+}
+
+// String implements Value.String.
+func (sv *SnapshotValue) String() string {
+	keys := make([]string, 0, len(sv.values))
+
+	for key := range sv.values {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+	var builder strings.Builder
+
+	for i, key := range keys {
+		if i >= 1 {
+			builder.WriteString(", ")
+		}
+
+		fmt.Fprintf(&builder, "%s=%s", key, sv.values[key].String())
+	}
+
+	return builder.String()
+}
+
+// Values returns the values of the existing keys in the snapshot. The returned
+// map is read-only.
+func (sv *SnapshotValue) Values() map[string]Value {
+	return sv.values
+}
+
+// Value returns the value of the given key in the snapshot.
+func (sv *SnapshotValue) Value(key string) (Value, bool) {
+	value, ok := sv.values[key]
+	return value, ok
+}
+
+type txnBackend struct {
+	Client *api.Client
+	Keys   []string
+
+	mu        sync.Mutex
+	entry     compositeEntry
+	lastIndex uint64
+}
+
+var _ Backend = (*txnBackend)(nil)
+
+func (tb *txnBackend) Get(key string, queryOptions *api.QueryOptions) (*api.KVPair, *api.QueryMeta, error) {
+	if queryOptions == nil {
+		queryOptions = new(api.QueryOptions)
+	}
+
+	tb.mu.Lock()
+	entry := tb.entry
+	tb.mu.Unlock()
+
+	if entry.Index != 0 && queryOptions.WaitIndex != 0 && queryOptions.WaitIndex == entry.Index {
+		queries := make([]query, len(tb.Keys))
+
+		for i := range tb.Keys {
+			key := tb.Keys[i]
+			queries[i] = func(queryOptions *api.QueryOptions) (*api.QueryMeta, error) {
+				_, queryMeta, err := tb.Client.KV().Get(key, queryOptions)
+				return queryMeta, err
+			}
+		}
+
+		changed, err := waitForAnyChange(queries, entry.LayerIndexes, queryOptions)
+
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if !changed {
+			return entry.Result()
+		}
+	}
+
+	return tb.refresh(key, queryOptions)
+}
+
+func (tb *txnBackend) List(prefix string, _ *api.QueryOptions) (api.KVPairs, *api.QueryMeta, error) {
+	return nil, nil, fmt.Errorf("%w; prefix=%q", ErrListNotSupported, prefix)
+}
+
+func (tb *txnBackend) refresh(key string, queryOptions *api.QueryOptions) (*api.KVPair, *api.QueryMeta, error) {
+	queryOptions = queryOptions.WithContext(queryOptions.Context())
+	queryOptions.WaitIndex = 0
+	txnOps := make(api.TxnOps, len(tb.Keys))
+
+	for i, key := range tb.Keys {
+		txnOps[i] = &api.TxnOp{
+			KV: &api.KVTxnOp{
+				Verb:      api.KVGetTree,
+				Key:       key,
+				Namespace: queryOptions.Namespace,
+			},
+		}
+	}
+
+	ok, response, _, err := tb.Client.Txn().Txn(txnOps, queryOptions)
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("dynconf: kv txn failed; keys=%q: %w", tb.Keys, err)
+	}
+
+	if !ok {
+		return nil, nil, fmt.Errorf("dynconf: kv txn failed; keys=%q errors=%q", tb.Keys, txnErrorsToStrings(response.Errors))
+	}
+
+	snapshot := make(map[string][]byte, len(tb.Keys))
+	waitIndexes := make([]uint64, len(tb.Keys))
+
+	for _, result := range response.Results {
+		if result.KV == nil {
+			continue
+		}
+
+		for i, key := range tb.Keys {
+			if result.KV.Key == key {
+				snapshot[key] = result.KV.Value
+				waitIndexes[i] = result.KV.ModifyIndex
+			}
+		}
+	}
+
+	for i, key := range tb.Keys {
+		if _, ok := snapshot[key]; ok {
+			continue
+		}
+
+		// The wait index of a missing key is the index of the KV store.
+		kvPair, queryMeta, err := tb.Client.KV().Get(key, queryOptions)
+
+		if err != nil {
+			return nil, nil, fmt.Errorf("dynconf: kv get failed; key=%q: %w", key, err)
+		}
+
+		if kvPair == nil {
+			waitIndexes[i] = queryMeta.LastIndex
+		} else {
+			// The key has been created since the transaction, re-read immediately.
+			waitIndexes[i] = 0
+		}
+	}
+
+	var data []byte
+
+	if len(snapshot) >= 1 {
+		data, err = json.Marshal(snapshot)
+
+		if err != nil {
+			return nil, nil, fmt.Errorf("dynconf: snapshot marshal failed; keys=%q: %w", tb.Keys, err)
+		}
+	}
+
+	tb.mu.Lock()
+	entry := tb.entry
+
+	if entry.Index == 0 || !uint64sEqual(entry.LayerIndexes, waitIndexes) {
+		tb.lastIndex++
+		entry = compositeEntry{
+			Key:          key,
+			LayerIndexes: waitIndexes,
+			Index:        tb.lastIndex,
+			Exists:       len(snapshot) >= 1,
+			Data:         data,
+		}
+		tb.entry = entry
+	}
+
+	tb.mu.Unlock()
+	return entry.Result()
+}
+
+func txnErrorsToStrings(txnErrors api.TxnErrors) []string {
+	whats := make([]string, len(txnErrors))
+
+	for i, txnError := range txnErrors {
+		whats[i] = txnError.What
+	}
+
+	return whats
+}
+
+var errClientRequired = errors.New("client required")
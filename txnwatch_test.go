@@ -0,0 +1,53 @@
+package dynconf_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/roy2220/dynconf"
+	"github.com/roy2220/dynconf/dynconftest"
+)
+
+func TestWatcherAddTxnWatch(t *testing.T) {
+	wr, c := makeWatcher(t)
+	_, err := c.KV().Delete("hello19", &api.WriteOptions{})
+	assert.NoError(t, err)
+	_, err = c.KV().Put(&api.KVPair{
+		Key:   "hello18",
+		Value: []byte(`{"Foo": 1}`),
+	}, &api.WriteOptions{})
+	assert.NoError(t, err)
+	w, err := wr.AddTxnWatch(context.Background(), []string{"hello18", "hello19"}, newValue)
+	if assert.NoError(t, err) {
+		defer w.Remove()
+	}
+
+	sv := w.Value().(*dynconf.SnapshotValue)
+	assert.Len(t, sv.Values(), 1)
+	v, ok := sv.Value("hello18")
+	if assert.True(t, ok) {
+		v.(*config).Equals(t, &config{Foo: 1})
+	}
+
+	ok, _, _, err = c.Txn().Txn(api.TxnOps{
+		{KV: &api.KVTxnOp{Verb: api.KVSet, Key: "hello18", Value: []byte(`{"Foo": 2}`)}},
+		{KV: &api.KVTxnOp{Verb: api.KVSet, Key: "hello19", Value: []byte(`{"Foo": 2}`)}},
+	}, &api.QueryOptions{})
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	<-v.(*config).OutdatedEvent()
+	sv = w.Value().(*dynconf.SnapshotValue)
+	assert.Len(t, sv.Values(), 2)
+
+	for _, v := range sv.Values() {
+		v.(*config).Equals(t, &config{Foo: 2})
+	}
+
+	wr2, _ := dynconftest.NewWatcher(t)
+	_, err = wr2.AddTxnWatch(context.Background(), []string{"hello18"}, newValue)
+	assert.EqualError(t, err, "dynconf: txn watch failed; keys=[\"hello18\"]: client required")
+}
@@ -0,0 +1,34 @@
+//go:build go1.18
+// +build go1.18
+
+package dynconf
+
+import "context"
+
+// AddWatchT adds a watch on the given key to the given watcher, of which the values
+// are unmarshaled with the given function, and then returns the watch, so that the
+// values can be got as type T without type assertions, nor a Value implementation
+// for every type.
+func AddWatchT[T any](ctx context.Context, w *Watcher, key string, unmarshal func(data []byte) (T, error),
+	options ...WatchOption) (*WatchT[T], error) {
+	watch, err := w.AddWatch(ctx, key, ObjectValueFactory(func(data []byte) (interface{}, error) {
+		return unmarshal(data)
+	}), options...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &WatchT[T]{watch}, nil
+}
+
+// WatchT presents a watch added by AddWatchT, of which the values are of type T.
+type WatchT[T any] struct {
+	*Watch
+}
+
+// Value returns the latest value of the key on which the watch is set.
+func (wt *WatchT[T]) Value() T {
+	object, _ := wt.Watch.Value().(*ObjectValue).Object().(T)
+	return object
+}
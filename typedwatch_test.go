@@ -0,0 +1,55 @@
+//go:build go1.18
+// +build go1.18
+
+package dynconf_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/roy2220/dynconf"
+	"github.com/roy2220/dynconf/dynconftest"
+)
+
+func TestAddWatchT(t *testing.T) {
+	type config struct {
+		Foo int
+		Bar string
+	}
+	unmarshal := func(data []byte) (config, error) {
+		var c config
+		err := json.Unmarshal(data, &c)
+		return c, err
+	}
+	wr, b := dynconftest.NewWatcher(t)
+	b.Set("hello", []byte(`{"Foo": 1, "Bar": "a"}`))
+	w, err := dynconf.AddWatchT(context.Background(), wr, "hello", unmarshal)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer w.Remove()
+	assert.Equal(t, config{Foo: 1, Bar: "a"}, w.Value())
+
+	events := w.Events()
+	b.Set("hello", []byte(`{"Foo": 2, "Bar": "b"}`))
+	<-events
+	assert.Equal(t, config{Foo: 2, Bar: "b"}, w.Value())
+
+	b.Set("hello", []byte(`{"Foo": "x"}`))
+	<-events
+	assert.Equal(t, config{Foo: 2, Bar: "b"}, w.Value())
+
+	_, err = dynconf.AddWatchT(context.Background(), wr, "missing", unmarshal)
+	assert.True(t, errors.Is(err, dynconf.ErrKeyNotFound))
+
+	w2, err := dynconf.AddWatchT(context.Background(), wr, "missing", unmarshal,
+		dynconf.WithDefaultValue([]byte(`{"Foo": 3}`)))
+	if assert.NoError(t, err) {
+		defer w2.Remove()
+		assert.Equal(t, config{Foo: 3}, w2.Value())
+	}
+}
@@ -0,0 +1,86 @@
+package dynconf
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// WithUsageTracking returns an option to track the usage of the value of the watch,
+// i.e. the reads of the value with Watch.Value or the accessors built on it, and
+// for structs bound with Bind, the reads with Binding.RLock and Binding.Field, so
+// that unused keys and fields can be found out with Watch.Usage or the debug
+// handler. Reads of the fields of bound structs other than with Binding.Field are
+// only counted as reads of the value.
+func WithUsageTracking() WatchOption {
+	return func(wo *watchOptions) {
+		wo.UsageTracking = true
+	}
+}
+
+// Usage represents the usage of the value of a watch.
+type Usage struct {
+	// Reads is the number of the reads of the value.
+	Reads uint64 `json:"reads"`
+
+	// LastReadTime is the time of the last read of the value, which is zero if the
+	// value has never been read.
+	LastReadTime time.Time `json:"last_read_time"`
+
+	// FieldReads are the numbers of the reads of the fields of the bound struct,
+	// keyed by the names of the fields.
+	FieldReads map[string]uint64 `json:"field_reads,omitempty"`
+}
+
+type usage struct {
+	numberOfReads uint64 // accessed atomically, kept 64-bit aligned
+	lastReadTime  int64  // accessed atomically, kept 64-bit aligned
+
+	fieldReads sync.Map
+}
+
+// Usage returns the usage of the value of the watch, which is tracked only if the
+// watch has been added with WithUsageTracking.
+func (w *Watch) Usage() Usage {
+	usage := Usage{
+		Reads: atomic.LoadUint64(&w.usage.numberOfReads),
+	}
+
+	if lastReadTime := atomic.LoadInt64(&w.usage.lastReadTime); lastReadTime != 0 {
+		usage.LastReadTime = time.Unix(0, lastReadTime)
+	}
+
+	w.usage.fieldReads.Range(func(key interface{}, value interface{}) bool {
+		if usage.FieldReads == nil {
+			usage.FieldReads = make(map[string]uint64)
+		}
+
+		usage.FieldReads[key.(string)] = atomic.LoadUint64(value.(*uint64))
+		return true
+	})
+
+	return usage
+}
+
+func (w *Watch) recordRead() {
+	if !w.options.UsageTracking {
+		return
+	}
+
+	atomic.AddUint64(&w.usage.numberOfReads, 1)
+	atomic.StoreInt64(&w.usage.lastReadTime, w.options.Clock.Now().UnixNano())
+}
+
+func (w *Watch) recordFieldRead(fieldName string) {
+	if !w.options.UsageTracking {
+		return
+	}
+
+	numberOfReads, ok := w.usage.fieldReads.Load(fieldName)
+
+	if !ok {
+		numberOfReads, _ = w.usage.fieldReads.LoadOrStore(fieldName, new(uint64))
+	}
+
+	atomic.AddUint64(numberOfReads.(*uint64), 1)
+}
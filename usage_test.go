@@ -0,0 +1,71 @@
+package dynconf_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/roy2220/dynconf"
+	"github.com/roy2220/dynconf/dynconftest"
+)
+
+func TestWithUsageTracking(t *testing.T) {
+	wr, b := dynconftest.NewWatcher(t)
+	b.Set("hello", []byte("1"))
+	b.Set("world", []byte(`{"Foo": 1, "Bar": "a"}`))
+
+	w, err := wr.AddWatch(context.Background(), "hello", dynconf.NewIntValue, dynconf.WithUsageTracking())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer w.Remove()
+	assert.Zero(t, w.Usage().Reads)
+	assert.True(t, w.Usage().LastReadTime.IsZero())
+
+	events := w.Events()
+	b.Set("hello", []byte("2"))
+	<-events
+	assert.Zero(t, w.Usage().Reads)
+
+	w.Value()
+	w.Value()
+	assert.Equal(t, uint64(2), w.Usage().Reads)
+	assert.False(t, w.Usage().LastReadTime.IsZero())
+
+	var cfg struct {
+		Foo int
+		Bar string
+	}
+	bd, err := dynconf.Bind(context.Background(), wr, "world", &cfg, dynconf.WithUsageTracking())
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer bd.Unbind()
+	assert.Equal(t, 1, bd.Field("Foo"))
+	assert.Equal(t, 1, bd.Field("Foo"))
+	assert.Nil(t, bd.Field("Baz"))
+	bd.RLock()
+	bd.RUnlock()
+	assert.Equal(t, dynconf.Usage{
+		Reads:        1,
+		LastReadTime: bd.Watch().Usage().LastReadTime,
+		FieldReads:   map[string]uint64{"Foo": 2},
+	}, bd.Watch().Usage())
+
+	recorder := httptest.NewRecorder()
+	wr.DebugHandler(false).ServeHTTP(recorder, httptest.NewRequest("GET", "/", nil))
+	var statuses struct {
+		Watches []struct {
+			Key   string         `json:"key"`
+			Usage *dynconf.Usage `json:"usage"`
+		} `json:"watches"`
+	}
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &statuses))
+	if assert.Len(t, statuses.Watches, 2) && assert.NotNil(t, statuses.Watches[0].Usage) {
+		assert.Equal(t, "hello", statuses.Watches[0].Key)
+		assert.Equal(t, uint64(2), statuses.Watches[0].Usage.Reads)
+	}
+}
@@ -0,0 +1,285 @@
+package dynconf
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/hashicorp/consul/api"
+)
+
+// AddUserEventWatch adds a watch on the user events of Consul with the given name,
+// e.g. fired with "consul event", and then returns the watch. The events fired
+// after the watch has been added are delivered to the channel returned by
+// UserEventWatch.Events, with the payloads unmarshaled into values created with
+// the given value factory. Events with payloads failing unmarshaling or validation
+// are rejected. The watcher must be initialized with a client.
+func (w *Watcher) AddUserEventWatch(ctx context.Context, name string, valueFactory ValueFactory,
+	options ...WatchOption) (*UserEventWatch, error) {
+	if w.client == nil {
+		return nil, fmt.Errorf("dynconf: user event watch failed; name=%q: %w", name, errClientRequired)
+	}
+
+	userEventWatch := UserEventWatch{
+		watcher:      w,
+		event:        w.client.Event(),
+		logger:       w.logger,
+		name:         name,
+		valueFactory: valueFactory,
+		events:       make(chan *UserEvent, userEventBufferSize),
+	}
+
+	userEventWatch.options.Init(w.watchOptions(options))
+
+	if err := userEventWatch.populateEventIDs(ctx); err != nil {
+		return nil, err
+	}
+
+	if err := w.addWatch(&userEventWatch); err != nil {
+		return nil, err
+	}
+
+	return &userEventWatch, nil
+}
+
+// UserEvent represents a user event of Consul.
+type UserEvent struct {
+	// ID is the ID of the event.
+	ID string
+
+	// Name is the name of the event.
+	Name string
+
+	// Payload is the value unmarshaled from the payload of the event.
+	Payload Value
+
+	// LTime is the Lamport time of the event.
+	LTime uint64
+}
+
+// UserEventWatch presents a watch on user events of Consul.
+type UserEventWatch struct {
+	numberOfRecoveredPanics uint64 // accessed atomically, kept 64-bit aligned
+
+	watcher      *Watcher
+	event        *api.Event
+	logger       Logger
+	name         string
+	valueFactory ValueFactory
+	options      watchOptions
+	events       chan *UserEvent
+
+	index    uint64
+	eventIDs map[string]struct{}
+	ctx      context.Context
+	cancel   context.CancelFunc
+	wg       sync.WaitGroup
+}
+
+var _ watchLoop = (*UserEventWatch)(nil)
+
+// Remove removes the watch from the watcher, and then the channel returned by
+// Events is closed. Remove is idempotent.
+func (uew *UserEventWatch) Remove() {
+	uew.watcher.removeWatch(uew)
+	uew.stop()
+	uew.wait()
+}
+
+// RemoveContext removes the watch and waits for it to stop until the given context
+// is done. RemoveContext is idempotent.
+func (uew *UserEventWatch) RemoveContext(ctx context.Context) error {
+	if err := uew.watcher.removeWatchContext(ctx, uew); err != nil {
+		return fmt.Errorf("dynconf: watch remove failed; name=%q: %w", uew.name, err)
+	}
+
+	return nil
+}
+
+// Name returns the name of the events on which the watch is set.
+func (uew *UserEventWatch) Name() string {
+	return uew.name
+}
+
+// Events returns the channel delivering the events. Events are dropped if the
+// channel is full, which holds up to 64 events.
+func (uew *UserEventWatch) Events() <-chan *UserEvent {
+	return uew.events
+}
+
+// Synced returns a channel which is closed once the watch has been synced. A user
+// event watch is always synced as the events fired before it has been added are
+// ignored.
+func (uew *UserEventWatch) Synced() <-chan struct{} {
+	return syncedChannel
+}
+
+func (uew *UserEventWatch) populateEventIDs(ctx context.Context) error {
+	queryOptions := uew.options.makeQueryOptions(ctx, uew.options.QueryOptions)
+	userEvents, queryMeta, err := uew.listEvents(queryOptions)
+
+	if err != nil {
+		return err
+	}
+
+	uew.eventIDs = make(map[string]struct{}, len(userEvents))
+
+	for _, userEvent := range userEvents {
+		uew.eventIDs[userEvent.ID] = struct{}{}
+	}
+
+	uew.index = queryMeta.LastIndex
+	return nil
+}
+
+// listEvents lists the events with the given query options, once allowed by the
+// query rate limit of the watcher.
+func (uew *UserEventWatch) listEvents(queryOptions *api.QueryOptions) ([]*api.UserEvent, *api.QueryMeta, error) {
+	if err := uew.watcher.waitForQueryRate(queryOptions); err != nil {
+		return nil, nil, fmt.Errorf("dynconf: query dropped; name=%q: %w", uew.name, err)
+	}
+
+	userEvents, queryMeta, err := uew.event.List(uew.name, queryOptions)
+
+	if err != nil {
+		return nil, nil, fmt.Errorf("dynconf: event list failed; name=%q: %w", uew.name, err)
+	}
+
+	return userEvents, queryMeta, nil
+}
+
+func (uew *UserEventWatch) add() {
+	uew.ctx, uew.cancel = context.WithCancel(context.Background())
+	uew.wg.Add(1)
+
+	go func() {
+		defer uew.wg.Done()
+		uew.receiveEvents()
+	}()
+}
+
+func (uew *UserEventWatch) stop() {
+	uew.cancel()
+}
+
+func (uew *UserEventWatch) wait() {
+	uew.wg.Wait()
+}
+
+func (uew *UserEventWatch) receiveEvents() {
+	retry := retry{
+		RetryPolicy: uew.options.RetryPolicy,
+		Clock:       uew.options.Clock,
+	}
+
+	for {
+		queryOptions := uew.options.QueryOptions
+		queryOptions.WaitIndex = uew.index
+
+		var (
+			userEvents []*api.UserEvent
+			queryMeta  *api.QueryMeta
+		)
+
+		if _, err := retry.Do(uew.ctx, func() bool {
+			var err error
+			userEvents, queryMeta, err = uew.listEvents(uew.options.makeQueryOptions(uew.ctx, queryOptions))
+
+			if err != nil {
+				uew.logger.Log(LogWarn, "dynconf_event_list_failed", "name", uew.name, "error", err)
+				return false
+			}
+
+			return true
+		}); err != nil {
+			uew.logger.Log(LogInfo, "dynconf_watch_removed", "name", uew.name)
+			close(uew.events)
+			return
+		}
+
+		if queryMeta.LastIndex == uew.index {
+			continue
+		}
+
+		uew.handleEvents(userEvents)
+		// The index is a hash of the ID of the latest event, which isn't
+		// monotonic, so unlike the indexes of the KV store it's never reset.
+		uew.index = queryMeta.LastIndex
+	}
+}
+
+func (uew *UserEventWatch) handleEvents(userEvents []*api.UserEvent) {
+	eventIDs := make(map[string]struct{}, len(userEvents))
+
+	for _, userEvent := range userEvents {
+		eventIDs[userEvent.ID] = struct{}{}
+
+		if _, ok := uew.eventIDs[userEvent.ID]; ok {
+			continue
+		}
+
+		payload, err := uew.makePayload(userEvent)
+
+		if err != nil {
+			uew.logger.Log(LogError, "dynconf_event_rejected", "name", uew.name, "id", userEvent.ID, "error", err)
+
+			if callback := uew.options.UpdateRejectedCallback; callback != nil {
+				uew.call("on_update_rejected", func() { callback(err, userEvent.Payload) })
+			}
+
+			continue
+		}
+
+		uew.logger.Log(LogInfo, "dynconf_event_received", "name", uew.name, "id", userEvent.ID)
+
+		select {
+		case uew.events <- &UserEvent{
+			ID:      userEvent.ID,
+			Name:    userEvent.Name,
+			Payload: payload,
+			LTime:   userEvent.LTime,
+		}:
+		default:
+			uew.logger.Log(LogWarn, "dynconf_event_dropped", "name", uew.name, "id", userEvent.ID)
+		}
+	}
+
+	uew.eventIDs = eventIDs
+}
+
+func (uew *UserEventWatch) makePayload(userEvent *api.UserEvent) (Value, error) {
+	data, err := uew.options.decodeData(uew.name, userEvent.Payload, 0)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if err := uew.options.validateData(data); err != nil {
+		return nil, fmt.Errorf("dynconf: data validation failed; name=%q id=%q: %w", uew.name, userEvent.ID, err)
+	}
+
+	var value Value
+
+	if err := uew.protect("unmarshal", func() error {
+		value = uew.valueFactory()
+		return uew.options.unmarshalValue(value, data)
+	}); err != nil {
+		return nil, fmt.Errorf("dynconf: value unmarshal failed; name=%q id=%q: %w", uew.name, userEvent.ID, err)
+	}
+
+	if err := uew.protect("validate", func() error { return uew.options.validateValue(value) }); err != nil {
+		return nil, fmt.Errorf("dynconf: value validation failed; name=%q id=%q: %w", uew.name, userEvent.ID, err)
+	}
+
+	return value, nil
+}
+
+func (uew *UserEventWatch) protect(what string, f func() error) error {
+	return protect(uew.logger, uew.name, what, &uew.numberOfRecoveredPanics, f)
+}
+
+func (uew *UserEventWatch) call(what string, f func()) {
+	uew.protect(what, func() error { f(); return nil })
+}
+
+const userEventBufferSize = 64
@@ -0,0 +1,39 @@
+package dynconf_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/hashicorp/consul/api"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/roy2220/dynconf"
+)
+
+func TestWatcherAddUserEventWatch(t *testing.T) {
+	wr, c := makeWatcher(t)
+	_, _, err := c.Event().Fire(&api.UserEvent{Name: "flush-caches", Payload: []byte("old")}, nil)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	w, err := wr.AddUserEventWatch(context.Background(), "flush-caches", dynconf.NewStringValue)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	assert.Equal(t, "flush-caches", w.Name())
+
+	id, _, err := c.Event().Fire(&api.UserEvent{Name: "flush-caches", Payload: []byte("users")}, nil)
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+
+	e := <-w.Events()
+	assert.Equal(t, id, e.ID)
+	assert.Equal(t, "flush-caches", e.Name)
+	assert.Equal(t, "users", e.Payload.String())
+
+	w.Remove()
+	_, ok := <-w.Events()
+	assert.False(t, ok)
+}
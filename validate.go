@@ -0,0 +1,46 @@
+package dynconf
+
+import "fmt"
+
+// Validate validates the given data of the given key, e.g. a payload proposed in a
+// CI pipeline, with the same logic as watches added with the given value factory
+// and the given options, i.e. the data are checked, decoded, validated, unmarshaled
+// with the value factory and the value is validated, as if the data were written to
+// the key, and then returns the error rejecting the data, if any. The checks relative
+// to the current data of the key, e.g. those of WithChangeLimit, are not made.
+func Validate(key string, data []byte, valueFactory ValueFactory, options ...WatchOption) error {
+	w := Watch{
+		logger:       nopLogger{},
+		key:          key,
+		valueFactory: valueFactory,
+	}
+
+	w.options.Init(options)
+	decodedData, err := w.options.decodeData(key, data, 0)
+
+	if err != nil {
+		return err
+	}
+
+	if err := w.options.validateData(decodedData); err != nil {
+		return fmt.Errorf("dynconf: data validation failed; key=%q: %w", key, err)
+	}
+
+	if w.options.ScheduleEnabled {
+		if _, _, err := parseSchedule(decodedData); err != nil {
+			return fmt.Errorf("dynconf: schedule parse failed; key=%q: %w", key, err)
+		}
+	}
+
+	value, err := w.newValue(decodedData)
+
+	if err != nil {
+		return fmt.Errorf("dynconf: value unmarshal failed; key=%q: %w", key, err)
+	}
+
+	if err := w.validateValue(value); err != nil {
+		return fmt.Errorf("dynconf: value validation failed; key=%q: %w", key, err)
+	}
+
+	return nil
+}
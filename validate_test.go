@@ -0,0 +1,34 @@
+package dynconf_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/roy2220/dynconf"
+)
+
+func TestValidate(t *testing.T) {
+	positive := dynconf.WithValidator(func(value dynconf.Value) error {
+		if value.(*dynconf.IntValue).Get() <= 0 {
+			return errors.New("non-positive limit")
+		}
+
+		return nil
+	})
+
+	assert.NoError(t, dynconf.Validate("limit", []byte("1"), dynconf.NewIntValue, positive))
+	assert.EqualError(t, dynconf.Validate("limit", []byte("0"), dynconf.NewIntValue, positive),
+		"dynconf: value validation failed; key=\"limit\": non-positive limit")
+	assert.Error(t, dynconf.Validate("limit", []byte("a"), dynconf.NewIntValue, positive))
+	assert.True(t, errors.Is(dynconf.Validate("limit", []byte("100"), dynconf.NewIntValue, dynconf.WithMaxDataSize(2)),
+		dynconf.ErrDataTooLarge))
+
+	type object struct {
+		Foo int
+	}
+	vf := dynconf.JSONValueFactory(func() interface{} { return new(object) })
+	assert.NoError(t, dynconf.Validate("hello", []byte(`{"Foo": 1, "Bar": 2}`), vf))
+	assert.Error(t, dynconf.Validate("hello", []byte(`{"Foo": 1, "Bar": 2}`), vf, dynconf.WithStrictUnmarshal()))
+}
@@ -0,0 +1,208 @@
+// Package vaultbackend implements a fetcher reading secrets from the KV secrets
+// engine (version 2) of HashiCorp Vault, to be used with dynconf.PollingBackend.
+package vaultbackend
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/roy2220/dynconf"
+)
+
+// Fetcher presents a fetcher reading secrets from the KV secrets engine (version 2)
+// of Vault. The data of a key is the JSON of the data of the latest version of the
+// secret at the path of the key, and the version of the data is the version of the
+// secret.
+type Fetcher struct {
+	httpClient *http.Client
+	address    string
+	mountPath  string
+	tokenFunc  func() string
+}
+
+var _ dynconf.Fetcher = (*Fetcher)(nil)
+
+// Init initializes the fetcher with the given HTTP client, which defaults to
+// http.DefaultClient, the given address of Vault, the given mount path of the KV
+// secrets engine, which defaults to "secret", and the given function returning the
+// token, which is called before each request, and then returns the fetcher.
+func (f *Fetcher) Init(httpClient *http.Client, address string, mountPath string, tokenFunc func() string) *Fetcher {
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	if mountPath == "" {
+		mountPath = "secret"
+	}
+
+	f.httpClient = httpClient
+	f.address = strings.TrimSuffix(address, "/")
+	f.mountPath = strings.Trim(mountPath, "/")
+	f.tokenFunc = tokenFunc
+	return f
+}
+
+// Fetch implements dynconf.Fetcher.Fetch.
+func (f *Fetcher) Fetch(ctx context.Context, key string) ([]byte, string, bool, error) {
+	secret, err := f.ReadSecret(ctx, f.mountPath+"/data/"+strings.TrimPrefix(key, "/"))
+
+	if err != nil {
+		return nil, "", false, err
+	}
+
+	if secret == nil {
+		return nil, "", false, nil
+	}
+
+	var kvData struct {
+		Data     json.RawMessage `json:"data"`
+		Metadata struct {
+			Version int `json:"version"`
+		} `json:"metadata"`
+	}
+
+	if err := json.Unmarshal(secret.Data, &kvData); err != nil {
+		return nil, "", false, fmt.Errorf("vaultbackend: kv data unmarshal failed; key=%q: %w", key, err)
+	}
+
+	if len(kvData.Data) == 0 || string(kvData.Data) == "null" {
+		return nil, "", false, nil
+	}
+
+	return kvData.Data, strconv.Itoa(kvData.Metadata.Version), true, nil
+}
+
+// Secret represents a secret read from Vault.
+type Secret struct {
+	LeaseID       string          `json:"lease_id"`
+	LeaseDuration int             `json:"lease_duration"`
+	Renewable     bool            `json:"renewable"`
+	Data          json.RawMessage `json:"data"`
+}
+
+// ReadSecret reads the secret at the given path, e.g. the credentials generated
+// by a dynamic secrets engine, and then returns the secret, or nil if the secret
+// is missing.
+func (f *Fetcher) ReadSecret(ctx context.Context, path string) (*Secret, error) {
+	var secret Secret
+	ok, err := f.do(ctx, http.MethodGet, "/v1/"+strings.TrimPrefix(path, "/"), nil, &secret)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !ok {
+		return nil, nil
+	}
+
+	return &secret, nil
+}
+
+// RenewLease renews the lease with the given ID by the given increment, and then
+// returns the new duration of the lease.
+func (f *Fetcher) RenewLease(ctx context.Context, leaseID string, increment time.Duration) (time.Duration, error) {
+	request := struct {
+		LeaseID   string `json:"lease_id"`
+		Increment int    `json:"increment,omitempty"`
+	}{
+		LeaseID:   leaseID,
+		Increment: int(increment / time.Second),
+	}
+
+	var secret Secret
+	ok, err := f.do(ctx, http.MethodPut, "/v1/sys/leases/renew", request, &secret)
+
+	if err != nil {
+		return 0, err
+	}
+
+	if !ok {
+		return 0, fmt.Errorf("vaultbackend: lease not found; lease_id=%q", leaseID)
+	}
+
+	return time.Duration(secret.LeaseDuration) * time.Second, nil
+}
+
+// KeepLeaseRenewed renews the lease with the given ID and the given duration each
+// time two thirds of the duration has elapsed, until the given context is done or
+// a renewal fails, and then returns the error.
+func (f *Fetcher) KeepLeaseRenewed(ctx context.Context, leaseID string, leaseDuration time.Duration) error {
+	for {
+		timer := time.NewTimer(leaseDuration * 2 / 3)
+
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+
+		var err error
+		leaseDuration, err = f.RenewLease(ctx, leaseID, leaseDuration)
+
+		if err != nil {
+			return err
+		}
+
+		if leaseDuration <= 0 {
+			return fmt.Errorf("vaultbackend: lease not renewable; lease_id=%q", leaseID)
+		}
+	}
+}
+
+func (f *Fetcher) do(ctx context.Context, method string, path string, requestBody interface{}, responseBody interface{}) (bool, error) {
+	var body bytes.Buffer
+
+	if requestBody != nil {
+		if err := json.NewEncoder(&body).Encode(requestBody); err != nil {
+			return false, fmt.Errorf("vaultbackend: request marshal failed; path=%q: %w", path, err)
+		}
+	}
+
+	request, err := http.NewRequest(method, f.address+path, &body)
+
+	if err != nil {
+		return false, fmt.Errorf("vaultbackend: request creation failed; path=%q: %w", path, err)
+	}
+
+	request = request.WithContext(ctx)
+
+	if f.tokenFunc != nil {
+		request.Header.Set("X-Vault-Token", f.tokenFunc())
+	}
+
+	response, err := f.httpClient.Do(request)
+
+	if err != nil {
+		return false, fmt.Errorf("vaultbackend: request failed; path=%q: %w", path, err)
+	}
+
+	defer response.Body.Close()
+	data, err := ioutil.ReadAll(response.Body)
+
+	if err != nil {
+		return false, fmt.Errorf("vaultbackend: response read failed; path=%q: %w", path, err)
+	}
+
+	if response.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+
+	if response.StatusCode != http.StatusOK {
+		return false, fmt.Errorf("vaultbackend: unexpected response; path=%q status_code=%d body=%q",
+			path, response.StatusCode, data)
+	}
+
+	if err := json.Unmarshal(data, responseBody); err != nil {
+		return false, fmt.Errorf("vaultbackend: response unmarshal failed; path=%q: %w", path, err)
+	}
+
+	return true, nil
+}
@@ -0,0 +1,57 @@
+package vaultbackend_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/roy2220/dynconf/vaultbackend"
+)
+
+func TestFetcherFetch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Vault-Token") != "t0ken" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/v1/kv/data/app/config":
+			w.Write([]byte(`{"data": {"data": {"foo": 1}, "metadata": {"version": 3}}}`))
+		case "/v1/sys/leases/renew":
+			var request struct {
+				LeaseID string `json:"lease_id"`
+			}
+			json.NewDecoder(r.Body).Decode(&request)
+			assert.Equal(t, "db/creds/1", request.LeaseID)
+			w.Write([]byte(`{"lease_id": "db/creds/1", "lease_duration": 60, "renewable": true}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	f := new(vaultbackend.Fetcher).Init(nil, server.URL, "kv", func() string { return "t0ken" })
+	data, version, ok, err := f.Fetch(context.Background(), "app/config")
+	if assert.NoError(t, err) && assert.True(t, ok) {
+		assert.JSONEq(t, `{"foo": 1}`, string(data))
+		assert.Equal(t, "3", version)
+	}
+
+	_, _, ok, err = f.Fetch(context.Background(), "app/missing")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	leaseDuration, err := f.RenewLease(context.Background(), "db/creds/1", time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, time.Minute, leaseDuration)
+
+	f = new(vaultbackend.Fetcher).Init(nil, server.URL, "kv", func() string { return "expired" })
+	_, _, _, err = f.Fetch(context.Background(), "app/config")
+	assert.Error(t, err)
+}
@@ -0,0 +1,158 @@
+package dynconf
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// AddWatchGroup adds watches as specified by the given specifications, like
+// AddWatches, and then returns a group owning the watches.
+func (w *Watcher) AddWatchGroup(ctx context.Context, specs []WatchSpec) (*WatchGroup, error) {
+	watches, err := w.AddWatches(ctx, specs)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return NewWatchGroup(w.logger, watches...), nil
+}
+
+// NewWatchGroup creates a group owning the given watches and then returns the
+// group. The given logger may be nil.
+func NewWatchGroup(logger Logger, watches ...*Watch) *WatchGroup {
+	if logger == nil {
+		logger = nopLogger{}
+	}
+
+	wg := WatchGroup{
+		logger:  logger,
+		watches: watches,
+		ready:   make(chan struct{}),
+		removed: make(chan struct{}),
+	}
+
+	go wg.waitUntilReady()
+	return &wg
+}
+
+// WatchGroup presents a group of watches, on which an application component
+// depends as a whole.
+type WatchGroup struct {
+	logger  Logger
+	watches []*Watch
+	ready   chan struct{}
+	removed chan struct{}
+
+	eventsOnce sync.Once
+	events     chan GroupEvent
+	removeOnce sync.Once
+}
+
+// GroupEvent presents an event of a watch of a group.
+type GroupEvent struct {
+	Watch *Watch
+	Event Event
+}
+
+// Watches returns the watches of the group. The returned slice is read-only.
+func (wg *WatchGroup) Watches() []*Watch {
+	return wg.watches
+}
+
+// Watch returns the watch of the group on the given key, or nil if not found.
+func (wg *WatchGroup) Watch(key string) *Watch {
+	for _, watch := range wg.watches {
+		if watch.Key() == key {
+			return watch
+		}
+	}
+
+	return nil
+}
+
+// Ready returns a channel which is closed once all the watches of the group have
+// been synced.
+func (wg *WatchGroup) Ready() <-chan struct{} {
+	return wg.ready
+}
+
+// Events returns a channel emitting the events of all the watches of the group,
+// which is closed after the group has been removed. Like Watch.Events, events
+// are emitted only after the first call to Events, and are dropped if the channel
+// is full.
+func (wg *WatchGroup) Events() <-chan GroupEvent {
+	wg.eventsOnce.Do(func() {
+		wg.events = make(chan GroupEvent, eventBufferSize)
+		var forwarders sync.WaitGroup
+
+		for _, watch := range wg.watches {
+			watch := watch
+			events := watch.Events()
+			forwarders.Add(1)
+
+			go func() {
+				defer forwarders.Done()
+				wg.forwardEvents(watch, events)
+			}()
+		}
+
+		go func() {
+			forwarders.Wait()
+			close(wg.events)
+		}()
+	})
+
+	return wg.events
+}
+
+// Remove removes all the watches of the group. Remove is idempotent.
+func (wg *WatchGroup) Remove() {
+	wg.removeOnce.Do(func() {
+		close(wg.removed)
+
+		for _, watch := range wg.watches {
+			watch.Remove()
+		}
+	})
+}
+
+// RemoveContext removes all the watches of the group and waits for them to stop
+// until the given context is done. RemoveContext is idempotent.
+func (wg *WatchGroup) RemoveContext(ctx context.Context) error {
+	var err error
+
+	wg.removeOnce.Do(func() {
+		close(wg.removed)
+
+		for _, watch := range wg.watches {
+			if err2 := watch.RemoveContext(ctx); err == nil {
+				err = err2
+			}
+		}
+	})
+
+	return err
+}
+
+func (wg *WatchGroup) waitUntilReady() {
+	for _, watch := range wg.watches {
+		select {
+		case <-watch.Synced():
+		case <-wg.removed:
+			return
+		}
+	}
+
+	close(wg.ready)
+}
+
+func (wg *WatchGroup) forwardEvents(watch *Watch, events <-chan Event) {
+	for event := range events {
+		select {
+		case wg.events <- GroupEvent{watch, event}:
+		default:
+			wg.logger.Log(LogWarn, "dynconf_event_dropped", "key", watch.Key(), "event", fmt.Sprintf("%T", event))
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package dynconf_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/roy2220/dynconf"
+	"github.com/roy2220/dynconf/dynconftest"
+)
+
+func TestWatcherAddWatchGroup(t *testing.T) {
+	wr, b := dynconftest.NewWatcher(t)
+	b.Set("a", []byte("1"))
+	b.Set("b", []byte("2"))
+
+	g, err := wr.AddWatchGroup(context.Background(), []dynconf.WatchSpec{
+		{Key: "a", ValueFactory: dynconf.NewIntValue},
+		{Key: "b", ValueFactory: dynconf.NewIntValue},
+	})
+	if !assert.NoError(t, err) {
+		t.FailNow()
+	}
+	defer g.Remove()
+
+	<-g.Ready()
+	assert.Len(t, g.Watches(), 2)
+	assert.Equal(t, "2", g.Watch("b").Value().String())
+	assert.Nil(t, g.Watch("c"))
+
+	events := g.Events()
+	b.Set("b", []byte("3"))
+	e := <-events
+	assert.Equal(t, "b", e.Watch.Key())
+	assert.Equal(t, "3", e.Event.(*dynconf.UpdatedEvent).NewValue.String())
+
+	g.Remove()
+	for e := range events {
+		_, ok := e.Event.(*dynconf.WatchRemovedEvent)
+		assert.True(t, ok)
+	}
+}
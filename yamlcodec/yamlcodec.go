@@ -0,0 +1,42 @@
+// Package yamlcodec registers the codec for format "yaml" once imported.
+package yamlcodec
+
+import (
+	"bytes"
+	"io"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/roy2220/dynconf"
+)
+
+// Format is the format for which the codec is registered.
+const Format = "yaml"
+
+// ValueFactory returns a value factory creating values of type *dynconf.ObjectValue,
+// which unmarshal objects, created by the given function, from YAML data.
+func ValueFactory(newObject func() interface{}) dynconf.ValueFactory {
+	return dynconf.CodecValueFactory(Format, newObject)
+}
+
+type codec struct{}
+
+var _ dynconf.StrictCodec = codec{}
+
+func (codec) Marshal(object interface{}) ([]byte, error)      { return yaml.Marshal(object) }
+func (codec) Unmarshal(data []byte, object interface{}) error { return yaml.Unmarshal(data, object) }
+
+func (codec) UnmarshalStrict(data []byte, object interface{}) error {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+
+	if err := decoder.Decode(object); err != nil && err != io.EOF {
+		return err
+	}
+
+	return nil
+}
+
+func init() {
+	dynconf.RegisterCodec(Format, codec{})
+}
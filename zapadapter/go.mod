@@ -0,0 +1,11 @@
+module github.com/roy2220/dynconf/zapadapter
+
+go 1.13
+
+require (
+	github.com/roy2220/dynconf v0.0.0
+	github.com/stretchr/testify v1.7.0
+	go.uber.org/zap v1.16.0
+)
+
+replace github.com/roy2220/dynconf => ../
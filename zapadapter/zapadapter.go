@@ -0,0 +1,52 @@
+// Package zapadapter adapts zap loggers to dynconf loggers.
+package zapadapter
+
+import (
+	"go.uber.org/zap"
+
+	"github.com/roy2220/dynconf"
+)
+
+// Logger presents a dynconf logger backed by a zap logger.
+type Logger struct {
+	logger *zap.Logger
+}
+
+var _ dynconf.Logger = (*Logger)(nil)
+
+// Init initializes the logger with the given zap logger and then returns the logger.
+func (l *Logger) Init(logger *zap.Logger) *Logger {
+	l.logger = logger
+	return l
+}
+
+// Log implements dynconf.Logger.Log.
+func (l *Logger) Log(level dynconf.LogLevel, message string, keysAndValues ...interface{}) {
+	fields := make([]zap.Field, 0, len(keysAndValues)/2)
+
+	for i := 0; i+1 < len(keysAndValues); i += 2 {
+		key, _ := keysAndValues[i].(string)
+
+		switch value := keysAndValues[i+1].(type) {
+		case string:
+			fields = append(fields, zap.String(key, value))
+		case []byte:
+			fields = append(fields, zap.ByteString(key, value))
+		case error:
+			fields = append(fields, zap.NamedError(key, value))
+		default:
+			fields = append(fields, zap.Any(key, value))
+		}
+	}
+
+	switch level {
+	case dynconf.LogDebug:
+		l.logger.Debug(message, fields...)
+	case dynconf.LogInfo:
+		l.logger.Info(message, fields...)
+	case dynconf.LogWarn:
+		l.logger.Warn(message, fields...)
+	default:
+		l.logger.Error(message, fields...)
+	}
+}
@@ -0,0 +1,44 @@
+package zapadapter_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/roy2220/dynconf"
+	"github.com/roy2220/dynconf/zapadapter"
+)
+
+func TestLogger(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	l := new(zapadapter.Logger).Init(zap.New(core))
+
+	for _, tc := range []struct {
+		Level         dynconf.LogLevel
+		ExpectedLevel zapcore.Level
+	}{
+		{dynconf.LogDebug, zapcore.DebugLevel},
+		{dynconf.LogInfo, zapcore.InfoLevel},
+		{dynconf.LogWarn, zapcore.WarnLevel},
+		{dynconf.LogError, zapcore.ErrorLevel},
+	} {
+		l.Log(tc.Level, "dynconf_test", "key", "hello", "data", []byte("x"), "err", errors.New("oops"), "index", 1)
+
+		entries := logs.TakeAll()
+		if !assert.Len(t, entries, 1) {
+			t.FailNow()
+		}
+		assert.Equal(t, tc.ExpectedLevel, entries[0].Level)
+		assert.Equal(t, "dynconf_test", entries[0].Message)
+		assert.Equal(t, map[string]interface{}{
+			"key":   "hello",
+			"data":  "x",
+			"err":   "oops",
+			"index": int64(1),
+		}, entries[0].ContextMap())
+	}
+}
@@ -0,0 +1,11 @@
+module github.com/roy2220/dynconf/zerologadapter
+
+go 1.13
+
+require (
+	github.com/roy2220/dynconf v0.0.0
+	github.com/rs/zerolog v1.18.0
+	github.com/stretchr/testify v1.7.0
+)
+
+replace github.com/roy2220/dynconf => ../
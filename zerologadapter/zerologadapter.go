@@ -0,0 +1,54 @@
+// Package zerologadapter adapts zerolog loggers to dynconf loggers.
+package zerologadapter
+
+import (
+	"github.com/rs/zerolog"
+
+	"github.com/roy2220/dynconf"
+)
+
+// Logger presents a dynconf logger backed by a zerolog logger.
+type Logger struct {
+	logger *zerolog.Logger
+}
+
+var _ dynconf.Logger = (*Logger)(nil)
+
+// Init initializes the logger with the given zerolog logger and then returns the logger.
+func (l *Logger) Init(logger *zerolog.Logger) *Logger {
+	l.logger = logger
+	return l
+}
+
+// Log implements dynconf.Logger.Log.
+func (l *Logger) Log(level dynconf.LogLevel, message string, keysAndValues ...interface{}) {
+	var event *zerolog.Event
+
+	switch level {
+	case dynconf.LogDebug:
+		event = l.logger.Debug()
+	case dynconf.LogInfo:
+		event = l.logger.Info()
+	case dynconf.LogWarn:
+		event = l.logger.Warn()
+	default:
+		event = l.logger.Error()
+	}
+
+	for i := 0; i+1 < len(keysAndValues); i += 2 {
+		key, _ := keysAndValues[i].(string)
+
+		switch value := keysAndValues[i+1].(type) {
+		case string:
+			event.Str(key, value)
+		case []byte:
+			event.Bytes(key, value)
+		case error:
+			event.AnErr(key, value)
+		default:
+			event.Interface(key, value)
+		}
+	}
+
+	event.Msg(message)
+}
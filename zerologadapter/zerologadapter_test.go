@@ -0,0 +1,46 @@
+package zerologadapter_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/rs/zerolog"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/roy2220/dynconf"
+	"github.com/roy2220/dynconf/zerologadapter"
+)
+
+func TestLogger(t *testing.T) {
+	var buffer bytes.Buffer
+	zl := zerolog.New(&buffer)
+	l := new(zerologadapter.Logger).Init(&zl)
+
+	for _, tc := range []struct {
+		Level         dynconf.LogLevel
+		ExpectedLevel string
+	}{
+		{dynconf.LogDebug, "debug"},
+		{dynconf.LogInfo, "info"},
+		{dynconf.LogWarn, "warn"},
+		{dynconf.LogError, "error"},
+	} {
+		buffer.Reset()
+		l.Log(tc.Level, "dynconf_test", "key", "hello", "data", []byte("x"), "err", errors.New("oops"), "index", 1)
+
+		var entry map[string]interface{}
+		if !assert.NoError(t, json.Unmarshal(buffer.Bytes(), &entry)) {
+			t.FailNow()
+		}
+		assert.Equal(t, map[string]interface{}{
+			"level":   tc.ExpectedLevel,
+			"message": "dynconf_test",
+			"key":     "hello",
+			"data":    "x",
+			"err":     "oops",
+			"index":   float64(1),
+		}, entry)
+	}
+}
@@ -0,0 +1,10 @@
+module github.com/roy2220/dynconf/zstdcompression
+
+go 1.22
+
+require (
+	github.com/klauspost/compress v1.18.0
+	github.com/roy2220/dynconf v0.0.0
+)
+
+replace github.com/roy2220/dynconf => ../
@@ -0,0 +1,32 @@
+// Package zstdcompression registers the compression format "zstd" once imported.
+// It is a module of its own, since the zstd decoder requires a newer Go version
+// than the module github.com/roy2220/dynconf does.
+package zstdcompression
+
+import (
+	"io"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/roy2220/dynconf"
+)
+
+// Name is the name of the compression format registered.
+const Name = "zstd"
+
+// Magic is the magic number of the compression format registered.
+var Magic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+
+func newDecompressor(r io.Reader) (io.ReadCloser, error) {
+	decoder, err := zstd.NewReader(r, zstd.WithDecoderConcurrency(1))
+
+	if err != nil {
+		return nil, err
+	}
+
+	return decoder.IOReadCloser(), nil
+}
+
+func init() {
+	dynconf.RegisterCompression(Name, Magic, newDecompressor)
+}